@@ -3,10 +3,16 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/rules"
 	pb "github.com/schafer14/grpc-chess/service"
 )
 
@@ -14,6 +20,8 @@ type chessClient struct {
 	l log.Entry
 	c pb.ChessApplicationClient
 	e Engine
+	// the book go is answered from before the engine is asked, nil for none
+	b *polyglot.Book
 }
 
 // New creates a new client that can be used in grpc clients
@@ -21,8 +29,45 @@ func New(engine Engine, l log.Entry, client pb.ChessApplicationClient) Client {
 	return chessClient{e: engine, l: l, c: client}
 }
 
+// NewWithBook creates a client that answers go with a move from the book
+// while it has one for the position and only asks the engine once it does not
+func NewWithBook(engine Engine, book *polyglot.Book, l log.Entry, client pb.ChessApplicationClient) Client {
+	return chessClient{e: engine, l: l, c: client, b: book}
+}
+
+// retryInterval is how long the client waits between attempts to reconnect
+const retryInterval = 100 * time.Millisecond
+
+// sessionMetadata is the key of the gRPC metadata the session token is sent in when reconnecting
+const sessionMetadata = "session"
+
+// session is what the client keeps across the streams of a game
+type session struct {
+	// the engine's id and options, sent in each handshake
+	ident   EngineIdent
+	options []Option
+	// the token the server gave the session and how long the client has to reconnect, empty when it can not
+	token string
+	grace time.Duration
+	// whether the server writes castling as the king taking its own rook
+	chess960 bool
+	// the variant the server set, book moves are only played in standard chess
+	variant string
+	// whether the engine was sent go and has not answered with a bestmove
+	searching bool
+}
+
+// droppedError is returned by handleGameLogic when the stream to the server drops
+type droppedError struct {
+	err error
+}
+
+func (e *droppedError) Error() string {
+	return e.err.Error()
+}
+
 // Runs through the process of creating a chess game
-func (c chessClient) NewGameRequest() {
+func (c chessClient) NewGameRequest() error {
 	// Setup the logger
 	requestLogger := c.l.WithField("request", "newGameRequest")
 	requestLogger.Info("Requesting a new game")
@@ -32,44 +77,96 @@ func (c chessClient) NewGameRequest() {
 	defer cancel()
 
 	// Start UCI stream
-	stream, err := c.c.UCI(ctx)
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	stream, err := c.c.UCI(streamCtx)
 	if err != nil {
-		cancel()
+		cancelStream()
 		requestLogger.Errorln("Could not create game request", err)
-		return
+		return err
 	}
 
 	// Recieves a UCI message
 	uciMessage, err := stream.Recv()
 	if err != nil {
-		cancel()
+		cancelStream()
 		requestLogger.Errorln("Could not read message", err)
-		return
+		return err
 	}
 	requestLogger.Info(uciMessage.GetMessageType().String())
 
 	// Get engine ident and options
 	engineIdent, options, err := c.e.Init()
 	if err != nil {
-		cancel()
+		cancelStream()
 		requestLogger.Errorln("Could not init engine", err)
-		return
+		return err
 	}
-	err = stream.Send(&pb.UciRequest{
+	s := &session{ident: engineIdent, options: options, variant: rules.Standard.Name()}
+	engineChan := readEngine(ctx, c.e, requestLogger)
+
+	for {
+		err = c.handshake(stream, s, engineChan, requestLogger)
+		if err == nil {
+			err = handleGameLogic(stream, c.e, c.b, engineChan, s, requestLogger)
+		}
+		cancelStream()
+		dropped, ok := err.(*droppedError)
+		if !ok {
+			return err
+		}
+		if s.token == "" {
+			return dropped.err
+		}
+
+		requestLogger.Warnf("Lost the connection, reconnecting within %v", s.grace)
+		if err = stopSearch(c.e, engineChan, s); err != nil {
+			requestLogger.Errorln("Could not stop the engine's search", err)
+			return err
+		}
+		streamCtx, cancelStream = context.WithCancel(metadata.AppendToOutgoingContext(ctx, sessionMetadata, s.token))
+		if stream, err = c.reconnect(streamCtx, s.grace, requestLogger); err != nil {
+			cancelStream()
+			requestLogger.Errorln("Could not reconnect", err)
+			return err
+		}
+	}
+}
+
+// reconnect opens a new stream until one answers or the grace period of the session is over
+func (c chessClient) reconnect(ctx context.Context, grace time.Duration, logger *logrus.Entry) (pb.ChessApplication_UCIClient, error) {
+	deadline := time.Now().Add(grace)
+	for {
+		stream, err := c.c.UCI(ctx)
+		if err == nil {
+			if _, err = stream.Recv(); err == nil {
+				return stream, nil
+			}
+		}
+		if time.Now().Add(retryInterval).After(deadline) {
+			return nil, err
+		}
+		logger.Debug("Could not reconnect: ", err)
+		time.Sleep(retryInterval)
+	}
+}
+
+// handshake sends the engine's id and options once the server sent uci and
+// passes on the options the server sets until the engine is ready
+func (c chessClient) handshake(stream pb.ChessApplication_UCIClient, s *session, engineChan <-chan *pb.UciRequest, requestLogger *logrus.Entry) error {
+	err := stream.Send(&pb.UciRequest{
 		MessageType: pb.UciRequest_ID,
 		Id: &pb.UciRequest_Id{
-			Name:   engineIdent.Name,
-			Author: engineIdent.Author,
+			Name:   s.ident.Name,
+			Author: s.ident.Author,
 		},
 	})
 	if err != nil {
-		cancel()
 		requestLogger.Errorln("Could not send uci id request", err)
-		return
+		return &droppedError{err}
 	}
 
 	// Send Option messages
-	for _, opt := range options {
+	for _, opt := range s.options {
 		err = stream.Send(&pb.UciRequest{
 			MessageType: pb.UciRequest_OPTION,
 			Option: &pb.UciRequest_Option{
@@ -82,9 +179,8 @@ func (c chessClient) NewGameRequest() {
 			},
 		})
 		if err != nil {
-			cancel()
 			requestLogger.Errorln("Could not send option request", err)
-			return
+			return &droppedError{err}
 		}
 	}
 
@@ -93,9 +189,8 @@ func (c chessClient) NewGameRequest() {
 		MessageType: pb.UciRequest_UCIOK,
 	})
 	if err != nil {
-		cancel()
 		requestLogger.Errorln("Could not send uciok request", err)
-		return
+		return &droppedError{err}
 	}
 
 	// Listening for either setoption messages or isready messages
@@ -103,71 +198,265 @@ Loop:
 	for {
 		message, err := stream.Recv()
 		if err != nil {
-			cancel()
 			requestLogger.Errorln("Could read set option/is ready message", err)
-			return
+			return &droppedError{err}
 		}
 
 		switch message.GetMessageType() {
 		case pb.UciResponse_SETOPTION:
-			requestLogger.Infof("Setting option %v is not supported yet.", message.GetSetOption().GetName())
+			requestLogger.Infof("Setting option %v", message.GetSetOption().GetName())
+			err = c.e.Send(message)
 		case pb.UciResponse_ISREADY:
-			break Loop
+			if err = c.e.Send(message); err == nil {
+				break Loop
+			}
+		}
+		if err != nil {
+			requestLogger.Errorln("Could not send message to the engine", err)
+			return err
 		}
 	}
 
-	// Send an ready okay message
-	stream.Send(&pb.UciRequest{
-		MessageType: pb.UciRequest_READYOK,
-	})
+	// Wait for the engine to be ready before telling the server, anything
+	// the engine wrote while the client was reconnecting is discarded
+	for msg := range engineChan {
+		if msg.GetMessageType() != pb.UciRequest_READYOK {
+			continue
+		}
 
-	handleGameLogic(stream, requestLogger)
-	stream.Recv()
+		// Send an ready okay message
+		err = stream.Send(&pb.UciRequest{
+			MessageType: pb.UciRequest_READYOK,
+		})
+		if err != nil {
+			requestLogger.Errorln("Could not send readyok request", err)
+			return &droppedError{err}
+		}
+		return nil
+	}
+	requestLogger.Errorln("Engine did not become ready")
+	return fmt.Errorf("Engine stopped")
+}
+
+// stopSearch stops a search the server will not get the answer to, the
+// server asks again once the client reconnected within the grace period
+func stopSearch(engine Engine, engineChan <-chan *pb.UciRequest, s *session) error {
+	if !s.searching {
+		return nil
+	}
+	// The engine's output is read while stop is sent as the engine may not
+	// read its input until the lines it is writing are read
+	sent := make(chan error, 1)
+	go func() {
+		sent <- engine.Send(&pb.UciResponse{MessageType: pb.UciResponse_STOP})
+	}()
+	timeout := time.After(s.grace)
+	for sent != nil || s.searching {
+		select {
+		case err := <-sent:
+			if err != nil {
+				return err
+			}
+			sent = nil
+		case msg, ok := <-engineChan:
+			if !ok {
+				return fmt.Errorf("Engine stopped")
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE {
+				s.searching = false
+			}
+		case <-timeout:
+			return fmt.Errorf("Engine did not stop within %v", s.grace)
+		}
+	}
+	return nil
+}
+
+// readEngine reads messages from the engine into a channel that is closed when the engine stops
+func readEngine(ctx context.Context, engine Engine, logger *logrus.Entry) <-chan *pb.UciRequest {
+	output := make(chan *pb.UciRequest)
+	go func() {
+		defer close(output)
+		for {
+			out, err := engine.Read()
+			if _, ok := err.(*MalformedMessageError); ok {
+				logger.Warn(err)
+				continue
+			}
+			if err != nil {
+				return
+			}
+			select {
+			case output <- out:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return output
 }
 
 // handleGameLogic is responsible for managing the relationship between the engine and the server
-func handleGameLogic(stream pb.ChessApplication_UCIClient, logger *logrus.Entry) error {
+func handleGameLogic(stream pb.ChessApplication_UCIClient, engine Engine, book *polyglot.Book, engineChan <-chan *pb.UciRequest, s *session, logger *logrus.Entry) error {
 	// Setup a new context
 	ctx, cancel := context.WithCancel(stream.Context())
 	defer cancel()
 
-	// Setup a channel to listen for messages on
-	inChan := make(chan pb.UciResponse)
-	go func(input chan pb.UciResponse) {
+	// Setup a channel to listen for messages from the server on, streamErr
+	// is safe to read once the channel is closed
+	var streamErr error
+	inChan := make(chan *pb.UciResponse)
+	go func(input chan *pb.UciResponse) {
+		defer close(input)
 		for {
 			in, err := stream.Recv()
 			if err != nil {
-				cancel()
+				if err != io.EOF {
+					streamErr = err
+				}
+				return
+			}
+			select {
+			case input <- in:
+			case <-ctx.Done():
 				return
 			}
-			input <- *in
 		}
 	}(inChan)
 
+	// the position of the last position command, nil when it is not legal
+	var position *rules.Position
+	var ply int
+
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("Connection closed")
-			return fmt.Errorf("Context ended")
-		case msg := <-inChan:
+			logger.Info("Connection closed")
+			return &droppedError{fmt.Errorf("Context ended")}
+		case msg, ok := <-inChan:
+			if !ok && streamErr != nil {
+				logger.Warn("Server stream closed: ", streamErr)
+				return &droppedError{streamErr}
+			}
+			if !ok {
+				logger.Info("Server ended the game")
+				return nil
+			}
 			switch msg.GetMessageType() {
+			case pb.UciResponse_SETOPTION:
+				if strings.EqualFold(msg.GetSetOption().GetName(), "UCI_Chess960") {
+					s.chess960 = msg.GetSetOption().GetValue() == "true"
+				}
+				if strings.EqualFold(msg.GetSetOption().GetName(), "UCI_Variant") {
+					s.variant = msg.GetSetOption().GetValue()
+				}
 			case pb.UciResponse_POSITION:
-				logger.Warn("Unimplemented uci position")
+				position, ply = nil, 0
+				if strings.EqualFold(s.variant, rules.Standard.Name()) {
+					position, ply = playPosition(msg.GetPosition(), s.chess960)
+				}
 			case pb.UciResponse_GO:
-				logger.Warn("Unimplemented uci go")
-			case pb.UciResponse_UCINEWGAME:
-				logger.Warn("Unimplemented uci ucinewgame")
-			case pb.UciResponse_PONDERHIT:
-				logger.Warn("Unimplemented uci ponderhit")
-			case pb.UciResponse_STOP:
-				logger.Warn("Unimplemented uci stop")
+				if book == nil || position == nil || msg.GetGo().GetIsPonder() {
+					break
+				}
+				if move, ok := book.Choose(position, ply); ok {
+					logger.Debugf("Book move %v", move)
+					err := stream.Send(&pb.UciRequest{
+						MessageType: pb.UciRequest_BESTMOVE,
+						BestMove:    &pb.UciRequest_BestMove{Move: position.UCI(move, s.chess960)},
+					})
+					if err != nil {
+						logger.Errorln("Could not send book move to the server", err)
+						return &droppedError{err}
+					}
+					continue
+				}
+			}
+
+			switch msg.GetMessageType() {
+			case pb.UciResponse_POSITION, pb.UciResponse_GO, pb.UciResponse_UCINEWGAME,
+				pb.UciResponse_PONDERHIT, pb.UciResponse_STOP, pb.UciResponse_ISREADY,
+				pb.UciResponse_SETOPTION, pb.UciResponse_DEBUG:
+				if err := engine.Send(msg); err != nil {
+					logger.Errorln("Could not send message to the engine", err)
+					return err
+				}
+				if msg.GetMessageType() == pb.UciResponse_GO {
+					s.searching = true
+				}
 			case pb.UciResponse_QUIT:
-				logger.Warn("Unimplemented uci quit")
-				break
+				logger.Info("Server sent quit")
+				return engine.Send(msg)
+			case pb.UciResponse_GAME_CONTROL:
+				control := msg.GetGameControl()
+				c, ok := engine.(Controller)
+				if !ok {
+					if state := control.GetGameState(); state != nil {
+						logger.Infof("%v for game %v after %v moves, %v to move %v", control.GetMessageType(), state.GetId(), len(state.GetMoves()), state.GetTurn(), state.GetResult())
+						break
+					}
+					logger.Infof("%v %v by %v %v", control.GetMessageType(), control.GetControl().GetMessageType(), control.GetSide(), control.GetReason())
+					break
+				}
+				if err := c.Control(control); err != nil {
+					logger.Errorln("Could not send game control to the engine", err)
+					return err
+				}
+			case pb.UciResponse_SESSION:
+				s.token = msg.GetSession().GetToken()
+				s.grace = time.Duration(msg.GetSession().GetGrace()) * time.Millisecond
+				logger.Infof("Joined with a session that can reconnect within %v", s.grace)
+			case pb.UciResponse_VIOLATION:
+				// Violations are not part of UCI so the engine is not told
+				v := msg.GetViolation()
+				logger.Warnf("%v by %v (%v) at ply %v: %v, %v", v.GetCode(), v.GetEngine(), v.GetSide(), v.GetPly(), v.GetDetail(), v.GetAction())
 			default:
 				logger.Errorf("Unknown uci message %v", msg.GetMessageType())
-				break
 			}
+		case msg, ok := <-engineChan:
+			if !ok {
+				logger.Error("Engine stopped")
+				return fmt.Errorf("Engine stopped")
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE {
+				s.searching = false
+			}
+			err := stream.Send(msg)
+			if err == io.EOF {
+				// The server closed the stream, its last messages are still to be received
+				engineChan = nil
+				continue
+			}
+			if err != nil {
+				logger.Errorln("Could not send message to the server", err)
+				return &droppedError{err}
+			}
+		}
+	}
+}
+
+// playPosition returns the position of a position command and the number of
+// moves played to reach it, the position is nil when it is not legal. With
+// chess960 set the position is Chess960 and castling is the king taking its own rook.
+func playPosition(cmd *pb.UciResponse_Position, chess960 bool) (*rules.Position, int) {
+	parse := rules.ParseFEN
+	if chess960 {
+		parse = rules.ParseFEN960
+	}
+	fen := rules.StartFEN
+	if cmd.GetIsFen() {
+		fen = cmd.GetFen()
+	}
+	position, err := parse(fen)
+	if err != nil {
+		return nil, 0
+	}
+	for _, s := range cmd.GetMoves() {
+		m, err := position.ParseUCI(s, chess960)
+		if err != nil {
+			return nil, 0
 		}
+		position = position.Play(m)
 	}
+	return position, len(cmd.GetMoves())
 }
@@ -1,8 +1,14 @@
 package client
 
+import (
+	"fmt"
+
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
 // Client is a test implementation for a grpc client
 type Client interface {
-	NewGameRequest()
+	NewGameRequest() error
 }
 
 // Option is a object representing the UCI option object
@@ -53,4 +59,32 @@ type EngineIdent struct {
 type Engine interface {
 	// Id returns the engine name and the engine author
 	Init() (EngineIdent, []Option, error)
+	// Send writes a GUI command to the engine
+	Send(*pb.UciResponse) error
+	// Read blocks until the engine writes a message. A MalformedMessageError
+	// is returned for messages that can not be parsed, the engine is still
+	// usable after such an error
+	Read() (*pb.UciRequest, error)
+	// Close shuts the engine down
+	Close() error
+}
+
+// Controller is an engine that takes part in the lifecycle controls of its
+// games eg. a human agent. It sends controls as GAME_CONTROL messages from
+// Read and is told of the controls either player sent and of its own that
+// the server rejected.
+type Controller interface {
+	Control(*pb.ServerGameMessage) error
+}
+
+// MalformedMessageError is returned when an engine writes a message with invalid arguments
+type MalformedMessageError struct {
+	// The line the engine wrote
+	Line string
+	// Why the line could not be parsed
+	Reason string
+}
+
+func (e *MalformedMessageError) Error() string {
+	return fmt.Sprintf("Malformed engine message %q: %v", e.Line, e.Reason)
 }
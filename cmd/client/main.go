@@ -1,36 +1,99 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
-
-	"github.com/schafer14/grpc-chess/client"
-	engine "github.com/schafer14/grpc-chess/engine/uci"
-	pb "github.com/schafer14/grpc-chess/service"
 	"google.golang.org/grpc"
+
+	"github.com/schafer14/grpc-chess/daemon"
+	"github.com/schafer14/grpc-chess/polyglot"
 )
 
 func main() {
 	clientLogger := log.WithField("from", "client")
+
+	// client check -config engines.toml starts each engine and prints its options
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		if err := check(os.Args[2:]); err != nil {
+			clientLogger.Fatalln(err)
+		}
+		return
+	}
+
 	clientLogger.Info("Starting")
 
 	host := flag.String("host", ":8080", "The server host")
-	executable := flag.String("executable", "/home/banner/Documents/proj/Stockfish/stockfish-10-linux/Linux/stockfish_10_x64", "Path to the uci engine executable")
+	configPath := flag.String("config", "", "TOML file of the engines to keep connected")
+	executable := flag.String("executable", "", "Path to a uci engine executable to run without a config")
+	record := flag.String("record", "", "Directory to record a transcript of each session to")
+	bookPath := flag.String("book", "", "Polyglot .bin book to answer go from before asking the engines")
+	bookDepth := flag.Int("book-depth", 0, "Only play book moves for this many plies, no limit when zero")
+	seed := flag.Int64("seed", 1, "The seed of the random choice of book moves")
 
 	flag.Parse()
-	// Set up a connection to the server.
-	conn, err := grpc.Dial(*host, grpc.WithInsecure())
+
+	config, err := load(*configPath, *executable)
+	if err != nil {
+		clientLogger.Fatalln(err)
+	}
+	config.Dial = func(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		return grpc.Dial(*host, append([]grpc.DialOption{grpc.WithInsecure()}, opts...)...)
+	}
+	config.Record = *record
+	if *bookPath != "" {
+		book, err := polyglot.Open(*bookPath, *seed)
+		if err != nil {
+			clientLogger.Fatalln(err)
+		}
+		book.Depth = *bookDepth
+		config.Book = book
+	}
+
+	d, err := daemon.New(clientLogger, config)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		clientLogger.Fatalln(err)
 	}
-	defer conn.Close()
-	c := pb.NewChessApplicationClient(conn)
-	agent, err := engine.New(*executable)
+
+	// Stop the engines on an interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		clientLogger.Info("Stopping")
+		cancel()
+	}()
+
+	d.Run(ctx)
+}
+
+// check starts the engines of a config and prints what each sends in its handshake
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	configPath := flags.String("config", "", "TOML file of the engines to check")
+	executable := flags.String("executable", "", "Path to a uci engine executable to check without a config")
+	flags.Parse(args)
+
+	config, err := load(*configPath, *executable)
 	if err != nil {
-		clientLogger.Errorln(err)
+		return err
 	}
-	stockfish := client.New(agent, *clientLogger, c)
+	return daemon.Check(config, os.Stdout)
+}
 
-	stockfish.NewGameRequest()
+// load reads the config file or makes a config running a single executable
+func load(configPath, executable string) (daemon.Config, error) {
+	switch {
+	case configPath != "":
+		return daemon.Load(configPath)
+	case executable != "":
+		return daemon.Config{Engines: []daemon.EngineConfig{{Path: executable}}}, nil
+	}
+	return daemon.Config{}, fmt.Errorf("Either -config or -executable is needed")
 }
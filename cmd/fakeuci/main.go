@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+)
+
+func main() {
+	engineLogger := log.WithField("from", "fakeuci")
+
+	scriptPath := flag.String("script", "", "Path to the JSON script describing the engine")
+
+	flag.Parse()
+
+	script := fake.Script{Name: "fakeuci", Author: "grpc-chess"}
+	if *scriptPath != "" {
+		var err error
+		script, err = fake.Load(*scriptPath)
+		if err != nil {
+			engineLogger.Fatalln(err)
+		}
+	}
+
+	err := fake.Run(script, os.Stdin, os.Stdout)
+	if crash, ok := err.(*fake.CrashError); ok {
+		os.Exit(crash.ExitCode)
+	}
+	if err != nil {
+		engineLogger.Fatalln(err)
+	}
+}
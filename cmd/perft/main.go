@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/engine/uci"
+	"github.com/schafer14/grpc-chess/perft"
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+func main() {
+	logger := log.WithField("from", "perft")
+
+	if err := run(logger); err != nil {
+		logger.Fatalf("Perft failed %v", err)
+	}
+}
+
+func run(logger *log.Entry) error {
+	fen := flag.String("fen", "", "The position to count, the starting position when empty")
+	movesList := flag.String("moves", "", "Space separated moves in UCI notation played from the position")
+	depth := flag.Int("depth", 5, "How many plies deep to count")
+	divide := flag.Bool("divide", false, "Print the count below each move")
+	enginePath := flag.String("engine", "", "A UCI engine that supports go perft to compare the counts with")
+	engineArgs := flag.String("engine-args", "", "Space separated arguments to the engine")
+
+	flag.Parse()
+
+	p := rules.StartingPosition()
+	if *fen != "" {
+		var err error
+		if p, err = rules.ParseFEN(*fen); err != nil {
+			return err
+		}
+	}
+	moves := strings.Fields(*movesList)
+	for _, move := range moves {
+		m, err := p.ParseMove(move)
+		if err != nil {
+			return err
+		}
+		p = p.Play(m)
+	}
+
+	if *enginePath != "" {
+		return compare(p.FEN(), *depth, *enginePath, strings.Fields(*engineArgs), logger)
+	}
+
+	start := time.Now()
+	var nodes uint64
+	if *divide {
+		divided := perft.Divide(p, *depth)
+		for _, m := range divided {
+			fmt.Printf("%v: %v\n", m.Move, m.Nodes)
+		}
+		fmt.Println()
+		nodes = perft.Total(divided)
+	} else {
+		nodes = perft.Count(p, *depth)
+	}
+	fmt.Printf("Nodes searched: %v\n", nodes)
+	logger.Infof("Counted %v nodes in %v", nodes, time.Since(start))
+	return nil
+}
+
+// compare finds where the counts of an engine differ from ours
+func compare(fen string, depth int, path string, args []string, logger *log.Entry) error {
+	engine, err := uci.New(path, args...)
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	ident, _, err := engine.Init()
+	if err != nil {
+		return err
+	}
+	divider, ok := engine.(perft.Divider)
+	if !ok {
+		return fmt.Errorf("Engine %v cannot divide", ident.Name)
+	}
+
+	report, err := perft.Compare(fen, depth, divider)
+	if err != nil {
+		return err
+	}
+	if len(report.Differences) == 0 {
+		fmt.Printf("The counts of %v agree at depth %v\n", ident.Name, depth)
+		return nil
+	}
+
+	fmt.Printf("The counts of %v differ in %v after %v at depth %v\n", ident.Name, report.FEN, strings.Join(report.Moves, " "), report.Depth)
+	for _, d := range report.Differences {
+		fmt.Printf("%v: ours %v theirs %v\n", d.Move, d.Ours, d.Theirs)
+	}
+	return nil
+}
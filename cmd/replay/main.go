@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+const usage = `Usage:
+  replay engine -transcript FILE [-realtime]
+	act as the engine recorded in the transcript on stdin and stdout
+  replay server -transcript FILE [-host :8080]
+	replay the server side of the recorded session to connecting clients`
+
+func main() {
+	replayLogger := log.WithField("from", "replay")
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	path := flags.String("transcript", "", "Path to the transcript to replay")
+	realtime := flags.Bool("realtime", false, "Keep the recorded delays between engine lines")
+	host := flags.String("host", ":8080", "The host to serve the replayed session on")
+	flags.Parse(os.Args[2:])
+
+	entries, err := transcript.Load(*path)
+	if err != nil {
+		replayLogger.Fatalln(err)
+	}
+
+	switch os.Args[1] {
+	case "engine":
+		err = transcript.ReplayEngine(entries, os.Stdin, os.Stdout, *realtime)
+	case "server":
+		err = serve(*host, entries, replayLogger)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		replayLogger.Fatalln(err)
+	}
+}
+
+func serve(host string, entries []transcript.Entry, logger *log.Entry) error {
+	lis, err := net.Listen("tcp", host)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterChessApplicationServer(grpcServer, transcript.NewReplayServer(entries, func(err error) {
+		logger.Warn(err)
+	}))
+
+	logger.WithField("port", host).Info("Replaying session")
+	return grpcServer.Serve(lis)
+}
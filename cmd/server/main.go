@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/schafer14/grpc-chess/openings"
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+	"github.com/schafer14/grpc-chess/syzygy"
+	"github.com/schafer14/grpc-chess/transcript"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	serverLogger := log.WithField("from", "server")
+	serverLogger.Info("Starting")
+
+	err := run(*serverLogger)
+	if err != nil {
+		serverLogger.Fatalf("Could not start server %v", err)
+	}
+}
+
+func run(logger log.Entry) error {
+	host := flag.String("host", ":8080", "The server host")
+	gameTime := flag.Duration("time", 5*time.Minute, "The time each engine starts a game with")
+	increment := flag.Duration("increment", 3*time.Second, "The time added to an engine's clock after each move")
+	health := flag.Duration("health", time.Minute, "How often idle engines are checked, 0 to never check")
+	ponder := flag.Bool("ponder", false, "Let engines that support it think on their opponent's time")
+	reconnectGrace := flag.Duration("reconnect-grace", 30*time.Second, "How long an engine that lost its connection has to reconnect, 0 to end its games at once")
+	record := flag.String("record", "", "Directory to record a transcript of each UCI stream to")
+	openingsPath := flag.String("openings", "", "EPD, PGN or UCI position file of openings games start from")
+	order := flag.String("order", string(server.Sequential), "The order openings are played in: sequential or random")
+	seed := flag.Int64("seed", 1, "The seed of the random order of openings and of book moves")
+	plies := flag.Int("plies", 0, "Cut openings to this many moves, no limit when zero")
+	chess960 := flag.Bool("chess960", false, "Play Chess960, the openings are read as X-FEN or without -openings games start from the 960 starting positions")
+	variantName := flag.String("variant", "chess", "The variant games are played by, as named by UCI_Variant eg. crazyhouse or atomic")
+	repeat := flag.Bool("repeat", false, "Play each opening a second time with colours reversed")
+	bookPath := flag.String("book", "", "Polyglot .bin book the server plays moves from for the engines")
+	bookDepth := flag.Int("book-depth", 0, "Only play book moves for this many plies, no limit when zero")
+	syzygyPath := flag.String("syzygy", "", "Directories of Syzygy tables to adjudicate games with, separated like PATH")
+	syzygyPieces := flag.Int("syzygy-pieces", 0, "Adjudicate positions with this many pieces or fewer, the largest tables when zero")
+	resignScore := flag.Int("resign-score", 1000, "Adjudicate a loss once both engines score a side this many centipawns behind")
+	resignMoves := flag.Int("resign-moves", 0, "The moves in a row each engine must agree on a loss, never resign when zero")
+	drawScore := flag.Int("draw-score", 10, "Adjudicate a draw once both engines score the game within this many centipawns")
+	drawMoves := flag.Int("draw-moves", 0, "The moves in a row each engine must score a draw, never adjudicate a draw when zero")
+	drawAfter := flag.Int("draw-after", 40, "Only adjudicate draws from scores after this move")
+	maxMoves := flag.Int("max-moves", 0, "Draw games after this many moves, no limit when zero")
+	noPairing := flag.Bool("no-pairing", false, "Do not pair engines as they connect, only play games asked for eg. by an SPRT")
+	correspondencePath := flag.String("correspondence", "", "File correspondence games are kept in, correspondence games are off when empty")
+	daysPerMove := flag.Int("days-per-move", 3, "The days each side has for a move in correspondence games")
+	correspondenceMoveTime := flag.Duration("correspondence-movetime", time.Minute, "How long engines search for a move in correspondence games")
+
+	flag.Parse()
+
+	variant, err := rules.ParseVariant(*variantName)
+	if err != nil {
+		return err
+	}
+
+	var book *server.Book
+	if *openingsPath != "" {
+		suite, err := openings.Load(*openingsPath, *plies)
+		if err != nil {
+			return err
+		}
+		for i := range suite {
+			suite[i].Chess960 = *chess960
+		}
+		book = server.NewBook(suite, server.Order(*order), *seed)
+		logger.Infof("Loaded %v openings", book.Len())
+	} else if *chess960 {
+		suite, err := server.Chess960Openings()
+		if err != nil {
+			return err
+		}
+		book = server.NewBook(suite, server.Order(*order), *seed)
+	}
+
+	var moveBook *polyglot.Book
+	if *bookPath != "" {
+		var err error
+		if moveBook, err = polyglot.Open(*bookPath, *seed); err != nil {
+			return err
+		}
+		moveBook.Depth = *bookDepth
+		logger.Infof("Loaded %v book entries", moveBook.Len())
+	}
+
+	var tablebase *syzygy.Tablebase
+	if *syzygyPath != "" {
+		var err error
+		if tablebase, err = syzygy.Open(filepath.SplitList(*syzygyPath)...); err != nil {
+			return err
+		}
+		logger.Infof("Found tables of up to %v pieces", tablebase.MaxPieces())
+	}
+
+	adjudication := server.Adjudication{
+		ResignScore: *resignScore,
+		ResignMoves: *resignMoves,
+		DrawScore:   *drawScore,
+		DrawMoves:   *drawMoves,
+		DrawAfter:   *drawAfter,
+		MaxMoves:    *maxMoves,
+	}
+
+	lis, err := net.Listen("tcp", *host)
+
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	if *record != "" {
+		opts = append(opts, grpc.StreamInterceptor(transcript.ServerInterceptor(func() (*transcript.Writer, error) {
+			return transcript.Create(*record, "server")
+		})))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+
+	config := server.Config{Time: *gameTime, Increment: *increment, Ponder: *ponder, HealthInterval: *health, NoPairing: *noPairing, Book: book, RepeatOpenings: *repeat, MoveBook: moveBook, Tablebase: tablebase, TablebasePieces: *syzygyPieces, Adjudication: adjudication, Variant: variant, ReconnectGrace: *reconnectGrace}
+	scheduler := server.NewScheduler(&logger, config)
+	defer scheduler.Close()
+
+	if *correspondencePath != "" {
+		games, err := store.Open(*correspondencePath)
+		if err != nil {
+			return err
+		}
+		defer games.Close()
+		c, err := server.NewCorrespondence(&logger, scheduler, server.CorrespondenceConfig{
+			PerMove:  time.Duration(*daysPerMove) * 24 * time.Hour,
+			MoveTime: *correspondenceMoveTime,
+			Store:    games,
+		})
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+	}
+	pb.RegisterChessApplicationServer(grpcServer, server.NewChessService(logger, scheduler))
+
+	logger.WithField("port", *host).Info("Listening")
+	logger.Fatal(grpcServer.Serve(lis))
+
+	return nil
+}
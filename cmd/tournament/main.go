@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/schafer14/grpc-chess/openings"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+	"github.com/schafer14/grpc-chess/tournament"
+)
+
+func main() {
+	logger := log.WithField("from", "tournament")
+
+	if err := run(logger); err != nil {
+		logger.Fatalf("Tournament failed %v", err)
+	}
+}
+
+func run(logger *log.Entry) error {
+	host := flag.String("host", ":8080", "The server host engines connect to")
+	name := flag.String("name", "tournament", "The name of the tournament, rerun with the same name to resume it")
+	engines := flag.String("engines", "", "Comma separated names of the engines to play")
+	kind := flag.String("kind", string(tournament.RoundRobin), "The kind of tournament: round-robin, gauntlet, swiss or knockout")
+	rounds := flag.Int("rounds", 0, "How many times each pair meets in a round-robin or gauntlet, the rounds of a Swiss or the games of a knockout match")
+	concurrency := flag.Int("concurrency", 1, "How many games are played at once")
+	gameTime := flag.Duration("time", time.Minute, "The time each engine starts a game with")
+	increment := flag.Duration("increment", time.Second, "The time added to an engine's clock after each move")
+	ponder := flag.Bool("ponder", false, "Let engines that support it think on their opponent's time")
+	tiebreakGames := flag.Int("tiebreak-games", 2, "The games of a knockout tiebreak mini-match")
+	tiebreaks := flag.Int("tiebreaks", 1, "How many tiebreak mini-matches are played before an Armageddon game")
+	tiebreakTime := flag.Duration("tiebreak-time", 0, "The time each engine starts a tiebreak game with, the main time when zero")
+	tiebreakIncrement := flag.Duration("tiebreak-increment", 0, "The increment of tiebreak games")
+	openingsPath := flag.String("openings", "", "EPD, PGN or UCI position file of openings, each is played with both colours")
+	order := flag.String("order", string(server.Sequential), "The order openings are played in: sequential or random")
+	seed := flag.Int64("seed", 1, "The seed of the random order of openings")
+	plies := flag.Int("plies", 0, "Cut openings to this many moves, no limit when zero")
+	resignScore := flag.Int("resign-score", 1000, "Adjudicate a loss once both engines score a side this many centipawns behind")
+	resignMoves := flag.Int("resign-moves", 0, "The moves in a row each engine must agree on a loss, never resign when zero")
+	drawScore := flag.Int("draw-score", 10, "Adjudicate a draw once both engines score the game within this many centipawns")
+	drawMoves := flag.Int("draw-moves", 0, "The moves in a row each engine must score a draw, never adjudicate a draw when zero")
+	drawAfter := flag.Int("draw-after", 40, "Only adjudicate draws from scores after this move")
+	maxMoves := flag.Int("max-moves", 0, "Draw games after this many moves, no limit when zero")
+	chess960 := flag.Bool("chess960", false, "Play Chess960, the openings are read as X-FEN or without -openings random starting positions are played")
+	positions := flag.Int("chess960-positions", 10, "The number of random Chess960 starting positions played without -openings")
+	variantName := flag.String("variant", "chess", "The variant games are played by, as named by UCI_Variant eg. crazyhouse or atomic")
+	storePath := flag.String("store", "games.jsonl", "File the games are saved to")
+
+	flag.Parse()
+
+	variant, err := rules.ParseVariant(*variantName)
+	if err != nil {
+		return err
+	}
+
+	c := tournament.Config{
+		Name:              *name,
+		Kind:              tournament.Kind(*kind),
+		Rounds:            *rounds,
+		Concurrency:       *concurrency,
+		Time:              *gameTime,
+		Increment:         *increment,
+		Ponder:            ponder,
+		TiebreakGames:     *tiebreakGames,
+		Tiebreaks:         *tiebreaks,
+		TiebreakTime:      *tiebreakTime,
+		TiebreakIncrement: *tiebreakIncrement,
+		Adjudication: &server.Adjudication{
+			ResignScore: *resignScore,
+			ResignMoves: *resignMoves,
+			DrawScore:   *drawScore,
+			DrawMoves:   *drawMoves,
+			DrawAfter:   *drawAfter,
+			MaxMoves:    *maxMoves,
+		},
+		Variant: variant,
+	}
+	for _, engine := range strings.Split(*engines, ",") {
+		if engine = strings.TrimSpace(engine); engine != "" {
+			c.Engines = append(c.Engines, engine)
+		}
+	}
+	if *openingsPath != "" {
+		suite, err := openings.Load(*openingsPath, *plies)
+		if err != nil {
+			return err
+		}
+		for i := range suite {
+			suite[i].Chess960 = *chess960
+		}
+		book := server.NewBook(suite, server.Order(*order), *seed)
+		for i := 0; i < book.Len(); i++ {
+			c.Openings = append(c.Openings, book.Next())
+		}
+	} else if *chess960 {
+		if *positions < 1 || *positions > 960 {
+			return fmt.Errorf("Expecting 1 to 960 Chess960 positions got %v", *positions)
+		}
+		suite, err := server.Chess960Openings(rand.New(rand.NewSource(*seed)).Perm(960)[:*positions]...)
+		if err != nil {
+			return err
+		}
+		c.Openings = suite
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	games, err := store.Open(*storePath)
+	if err != nil {
+		return err
+	}
+	defer games.Close()
+
+	lis, err := net.Listen("tcp", *host)
+	if err != nil {
+		return err
+	}
+
+	config := server.Config{Time: *gameTime, Increment: *increment, Ponder: *ponder, NoPairing: true}
+	scheduler := server.NewScheduler(logger, config)
+	defer scheduler.Close()
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterChessApplicationServer(grpcServer, server.NewChessService(*logger, scheduler))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	logger.WithField("port", *host).Infof("Waiting for %v", strings.Join(c.Engines, ", "))
+	waitForEngines(scheduler, c.Engines)
+
+	table, err := tournament.Run(context.Background(), c, scheduler, games, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Print(table)
+	return nil
+}
+
+// waitForEngines blocks until an engine with each name has connected
+func waitForEngines(scheduler *server.Scheduler, engines []string) {
+	for {
+		connected := make(map[string]bool)
+		for _, info := range scheduler.Engines() {
+			connected[info.Name] = true
+		}
+
+		missing := false
+		for _, engine := range engines {
+			if !connected[engine] {
+				missing = true
+			}
+		}
+		if !missing {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	cli "github.com/schafer14/grpc-chess/client"
+)
+
+// Check starts each engine of a config once and writes the id and typed
+// options it sends to w along with the values the config sets. It returns an
+// error naming the engines that could not be started or that do not have
+// the options the config sets or can not take their values.
+func Check(config Config, w io.Writer) error {
+	if config.Start == nil {
+		config.Start = startUCI
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, e := range config.Engines {
+		fmt.Fprintf(w, "%v\n", e.Path)
+		if err := check(config, e, w); err != nil {
+			fmt.Fprintf(w, "  error: %v\n", err)
+			failed = append(failed, e.name())
+		}
+		fmt.Fprintln(w)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Check failed for %v", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// check starts an engine and writes what it sent in its handshake
+func check(config Config, e EngineConfig, w io.Writer) error {
+	process, err := config.Start(e, nil)
+	if err != nil {
+		return err
+	}
+	defer process.Close()
+
+	ident, options, err := process.Init()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  id name %v\n  id author %v\n", ident.Name, ident.Author)
+	if e.Name != "" {
+		fmt.Fprintf(w, "  joins as %v\n", e.Name)
+	}
+
+	values, missing, err := settings(e, options)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, option := range options {
+		set := ""
+		if value, ok := values[option.Name]; ok {
+			set = "set to " + value
+		}
+		fmt.Fprintf(tw, "  option\t%v\t%v\t%v\t%v\n", option.Name, option.Type, describe(option), set)
+	}
+	tw.Flush()
+
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%v has no option %v", ident.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// describe returns the default and the values an option can take
+func describe(option cli.Option) string {
+	var parts []string
+	if option.Type != "button" {
+		value := option.Default
+		if value == "" {
+			value = "<empty>"
+		}
+		parts = append(parts, "default "+value)
+	}
+	switch option.Type {
+	case "spin":
+		parts = append(parts, fmt.Sprintf("min %v max %v", option.Min, option.Max))
+	case "combo":
+		parts = append(parts, "var "+strings.Join(option.Var, " var "))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/engine/uci"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+// scripted starts the scripted engine named by the path of each engine
+func scripted(scripts ...fake.Script) func(EngineConfig, *transcript.Writer) (cli.Engine, error) {
+	return func(e EngineConfig, _ *transcript.Writer) (cli.Engine, error) {
+		for _, script := range scripts {
+			if script.Name != e.Path {
+				continue
+			}
+			commands, commandWriter := io.Pipe()
+			output, outputWriter := io.Pipe()
+			go func() {
+				outputWriter.CloseWithError(fake.Run(script, commands, outputWriter))
+			}()
+			return uci.NewFromPipes(output, commandWriter), nil
+		}
+		return nil, fmt.Errorf("No script for %v", e.Path)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	engine := fake.Script{Name: "engine", Author: "tester", Options: []string{
+		"name Threads type spin default 1 min 1 max 4",
+		"name Style type combo default Normal var Solid var Normal var Risky",
+		"name SyzygyPath type string default <empty>",
+		"name Clear Hash type button",
+	}}
+	config := Config{
+		Threads: 4,
+		Engines: []EngineConfig{{Path: "engine", Name: "engine-dev", Threads: 2, Syzygy: []string{"/tb"}, Options: map[string]interface{}{"style": "risky"}}},
+		Start:   scripted(engine),
+	}
+
+	var out bytes.Buffer
+	if err := Check(config, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"id name engine",
+		"id author tester",
+		"joins as engine-dev",
+		"Threads     spin    default 1 min 1 max 4",
+		"Style       combo   default Normal var Solid var Normal var Risky  set to risky",
+		"SyzygyPath  string  default <empty>",
+		"Clear Hash  button",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expecting %q in\n%v", line, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "set to 2") || !strings.Contains(out.String(), "set to /tb") {
+		t.Errorf("Expecting the threads and Syzygy path to be set in\n%v", out.String())
+	}
+
+	// Options the engine does not have or can not take fail the check
+	for _, options := range []map[string]interface{}{
+		{"Hash": 64},
+		{"Style": "Wild"},
+		{"Clear Hash": true},
+	} {
+		config.Engines[0].Options = options
+		out.Reset()
+		if err := Check(config, &out); err == nil || !strings.Contains(out.String(), "error:") {
+			t.Errorf("Expecting %v to fail the check got %v in\n%v", options, err, out.String())
+		}
+	}
+	config.Engines[0].Options = nil
+	config.Engines[0].Path = "missing"
+	if err := Check(config, &out); err == nil {
+		t.Error("Expecting an engine that can not start to fail the check")
+	}
+}
+
+func TestValidateOption(t *testing.T) {
+	spin := cli.Option{Name: "Hash", Type: "spin", Min: 1, Max: 1024}
+	check := cli.Option{Name: "Ponder", Type: "check"}
+	combo := cli.Option{Name: "Style", Type: "combo", Var: []string{"Solid", "Risky"}}
+	text := cli.Option{Name: "Book", Type: "string"}
+	for _, c := range []struct {
+		option cli.Option
+		value  string
+		valid  bool
+	}{
+		{spin, "64", true},
+		{spin, "0", false},
+		{spin, "2048", false},
+		{spin, "lots", false},
+		{check, "true", true},
+		{check, "yes", false},
+		{combo, "solid", true},
+		{combo, "Wild", false},
+		{text, "book.bin", true},
+		{cli.Option{Name: "Clear Hash", Type: "button"}, "true", false},
+	} {
+		if err := validateOption(c.option, c.value); (err == nil) != c.valid {
+			t.Errorf("Expecting %v for %v of %v got %v", c.valid, c.value, c.option.Name, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"google.golang.org/grpc"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+// defaultRetry is how long a connection waits before starting again after it failed
+const defaultRetry = 5 * time.Second
+
+// Config describes the engines a daemon keeps connected. The engines are
+// read from a TOML file, the rest is set by the program running the daemon.
+type Config struct {
+	// The threads the engines may search with at once, the number of CPUs when zero
+	Threads int `toml:"threads"`
+	// How long a connection waits before starting again after it failed, 5s when zero
+	Retry   Duration       `toml:"retry"`
+	Engines []EngineConfig `toml:"engine"`
+
+	// Dial connects to the server, each session gets its own connection
+	Dial func(opts ...grpc.DialOption) (*grpc.ClientConn, error) `toml:"-"`
+	// Start starts an engine process recording the lines it exchanges to t when it is not nil
+	Start func(e EngineConfig, t *transcript.Writer) (cli.Engine, error) `toml:"-"`
+	// The book go is answered from before the engines are asked, nil for none
+	Book *polyglot.Book `toml:"-"`
+	// The directory a transcript of each session is recorded to, none when empty
+	Record string `toml:"-"`
+}
+
+// Protocol is the protocol an engine speaks
+type Protocol string
+
+// The protocols of engines, only uci engines can be run
+const (
+	UCI Protocol = "uci"
+)
+
+// EngineConfig describes an engine executable and how it is run
+type EngineConfig struct {
+	// The name the engine joins the server with, the name in its id when empty
+	Name string `toml:"name"`
+	// The path of the engine executable and the arguments it is run with
+	Path string   `toml:"path"`
+	Args []string `toml:"args"`
+	// The directory the engine runs in, the daemon's when empty
+	Dir string `toml:"dir"`
+	// Environment variables the engine gets on top of the daemon's
+	Env map[string]string `toml:"env"`
+	// The protocol the engine speaks, uci when empty
+	Protocol Protocol `toml:"protocol"`
+	// How many processes of the engine are connected at once, 1 when zero
+	Instances int `toml:"instances"`
+	// The threads each process searches with, set as its Threads option
+	// when it has one, 1 when zero
+	Threads int `toml:"threads"`
+	// Directories of Syzygy tables, set as the engine's SyzygyPath option when it has one
+	Syzygy []string `toml:"syzygy"`
+	// The options set once the engine started by name, the server can not change them
+	Options map[string]interface{} `toml:"options"`
+}
+
+// Duration is a time.Duration written as a string eg. "1m30s" in a config file
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// Load reads the engines of a daemon from a TOML file and validates them
+func Load(path string) (Config, error) {
+	var c Config
+	meta, err := toml.DecodeFile(path, &c)
+	if err != nil {
+		return Config{}, fmt.Errorf("Could not read %v: %v", path, err)
+	}
+	if keys := meta.Undecoded(); len(keys) > 0 {
+		return Config{}, fmt.Errorf("Unknown setting %v in %v", keys[0], path)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, fmt.Errorf("Invalid config %v: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks the engines of a config can be run within its thread budget
+func (c Config) Validate() error {
+	if len(c.Engines) == 0 {
+		return fmt.Errorf("No engines are configured")
+	}
+	if c.Threads < 0 {
+		return fmt.Errorf("The thread budget can not be negative")
+	}
+
+	names := make(map[string]bool)
+	for i, e := range c.Engines {
+		if e.Path == "" {
+			return fmt.Errorf("Engine %v has no path", i+1)
+		}
+		if e.Name != "" && names[e.Name] {
+			return fmt.Errorf("Two engines are named %v", e.Name)
+		}
+		names[e.Name] = true
+		if e.Instances < 0 || e.Threads < 0 {
+			return fmt.Errorf("%v can not have negative instances or threads", e.name())
+		}
+		if e.threads() > c.threads() {
+			return fmt.Errorf("%v needs %v threads, more than the budget of %v", e.name(), e.threads(), c.threads())
+		}
+		if e.protocol() != UCI {
+			return fmt.Errorf("%v has an unknown protocol %v", e.name(), e.Protocol)
+		}
+		for key := range e.Env {
+			if key == "" || strings.Contains(key, "=") {
+				return fmt.Errorf("%v has an invalid environment variable %q", e.name(), key)
+			}
+		}
+		for name, value := range e.Options {
+			switch value.(type) {
+			case string, bool, int, int64, float64:
+			default:
+				return fmt.Errorf("%v sets option %v to %v which is not a string, number or boolean", e.name(), name, value)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Config) threads() int {
+	if c.Threads == 0 {
+		return runtime.NumCPU()
+	}
+	return c.Threads
+}
+
+func (c Config) retry() time.Duration {
+	if c.Retry.Duration == 0 {
+		return defaultRetry
+	}
+	return c.Retry.Duration
+}
+
+// name is what the engine is called in logs, its path when it is not named
+func (e EngineConfig) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return filepath.Base(e.Path)
+}
+
+func (e EngineConfig) protocol() Protocol {
+	if e.Protocol == "" {
+		return UCI
+	}
+	return Protocol(strings.ToLower(string(e.Protocol)))
+}
+
+func (e EngineConfig) instances() int {
+	if e.Instances == 0 {
+		return 1
+	}
+	return e.Instances
+}
+
+func (e EngineConfig) threads() int {
+	if e.Threads == 0 {
+		return 1
+	}
+	return e.Threads
+}
+
+// options returns the options the config sets on the engine by name as UCI values
+func (e EngineConfig) options() map[string]string {
+	options := make(map[string]string, len(e.Options))
+	for name, value := range e.Options {
+		options[name] = fmt.Sprint(value)
+	}
+	return options
+}
+
+// implied returns the options the engine is given from the rest of the
+// config, they are only set when the engine has them
+func (e EngineConfig) implied() map[string]string {
+	options := map[string]string{"Threads": strconv.Itoa(e.threads())}
+	if len(e.Syzygy) > 0 {
+		options["SyzygyPath"] = strings.Join(e.Syzygy, string(os.PathListSeparator))
+	}
+	return options
+}
@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "engines.toml")
+
+	err = ioutil.WriteFile(path, []byte(`
+threads = 6
+retry = "30s"
+
+[[engine]]
+name = "stockfish-dev"
+path = "/usr/games/stockfish"
+args = ["--bench-free"]
+dir = "/tmp"
+protocol = "UCI"
+instances = 2
+threads = 2
+syzygy = ["/tb/345", "/tb/6"]
+[engine.env]
+LD_LIBRARY_PATH = "/opt/lib"
+[engine.options]
+Hash = 256
+UCI_ShowWDL = true
+
+[[engine]]
+path = "/usr/games/fruit"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.threads() != 6 || c.retry() != 30*time.Second || len(c.Engines) != 2 {
+		t.Fatalf("Expecting a budget of 6 threads, a 30s retry and 2 engines got %+v", c)
+	}
+	stockfish, fruit := c.Engines[0], c.Engines[1]
+	if stockfish.instances() != 2 || stockfish.threads() != 2 {
+		t.Errorf("Expecting 2 instances with 2 threads got %+v", stockfish)
+	}
+	if options := stockfish.options(); !reflect.DeepEqual(options, map[string]string{"Hash": "256", "UCI_ShowWDL": "true"}) {
+		t.Errorf("Expecting the options as UCI values got %v", options)
+	}
+	if implied := stockfish.implied(); !reflect.DeepEqual(implied, map[string]string{"Threads": "2", "SyzygyPath": "/tb/345" + string(os.PathListSeparator) + "/tb/6"}) {
+		t.Errorf("Expecting the threads and Syzygy paths as options got %v", implied)
+	}
+	if stockfish.name() != "stockfish-dev" || stockfish.protocol() != UCI || stockfish.Dir != "/tmp" || stockfish.Env["LD_LIBRARY_PATH"] != "/opt/lib" || !reflect.DeepEqual(stockfish.Args, []string{"--bench-free"}) {
+		t.Errorf("Expecting how stockfish is run got %+v", stockfish)
+	}
+	if fruit.name() != "fruit" || fruit.instances() != 1 || fruit.threads() != 1 || len(fruit.options()) != 0 {
+		t.Errorf("Expecting one instance with one thread got %+v", fruit)
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.toml")); err == nil {
+		t.Error("Expecting an error for a missing file")
+	}
+
+	// Misspelt settings are not silently ignored
+	if err := ioutil.WriteFile(path, []byte("[[engine]]\npath = \"fruit\"\ninstance = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expecting an error for an unknown setting")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config Config
+	}{
+		{"no engines", Config{}},
+		{"no path", Config{Engines: []EngineConfig{{Instances: 2}}}},
+		{"negative instances", Config{Engines: []EngineConfig{{Path: "a", Instances: -1}}}},
+		{"over budget", Config{Threads: 2, Engines: []EngineConfig{{Path: "a", Threads: 3}}}},
+		{"xboard", Config{Engines: []EngineConfig{{Path: "a", Protocol: "xboard"}}}},
+		{"unknown protocol", Config{Engines: []EngineConfig{{Path: "a", Protocol: "cecp"}}}},
+		{"same name", Config{Engines: []EngineConfig{{Path: "a", Name: "x"}, {Path: "b", Name: "x"}}}},
+		{"invalid environment", Config{Engines: []EngineConfig{{Path: "a", Env: map[string]string{"A=B": "c"}}}}},
+		{"option table", Config{Engines: []EngineConfig{{Path: "a", Options: map[string]interface{}{"Hash": []int{1}}}}}},
+	} {
+		if err := c.config.Validate(); err == nil {
+			t.Errorf("Expecting an error for %v", c.name)
+		}
+	}
+
+	if err := (Config{Threads: 2, Engines: []EngineConfig{{Path: "a", Threads: 2, Instances: 4}, {Path: "b", Protocol: "uci"}}}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dial := func(opts ...grpc.DialOption) (*grpc.ClientConn, error) { return nil, nil }
+	for _, c := range []struct {
+		name   string
+		config Config
+	}{
+		{"no dial", Config{Engines: []EngineConfig{{Path: "a"}}}},
+		{"invalid config", Config{Dial: dial}},
+	} {
+		if _, err := New(logrus.NewEntry(logrus.New()), c.config); err == nil {
+			t.Errorf("Expecting an error for %v", c.name)
+		}
+	}
+
+	if _, err := New(logrus.NewEntry(logrus.New()), Config{Dial: dial, Threads: 2, Engines: []EngineConfig{{Path: "a", Threads: 2, Instances: 4}}}); err != nil {
+		t.Error(err)
+	}
+}
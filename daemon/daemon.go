@@ -0,0 +1,193 @@
+// Package daemon keeps several engines connected to the server from one
+// process. Each connection runs its own engine process that plays every game
+// and analysis the server gives it, a new process is started once the
+// connection ends. The engines share a budget of threads and a connection
+// only starts once its threads are free.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/engine/uci"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+// Daemon keeps the engines of a config connected to the server
+type Daemon struct {
+	config Config
+	logger *logrus.Entry
+	budget *budget
+}
+
+// New checks a config and creates a daemon for it, engines are started as
+// UCI executables when the config has no Start
+func New(l *logrus.Entry, config Config) (*Daemon, error) {
+	if config.Dial == nil {
+		return nil, fmt.Errorf("The daemon has no way to dial the server")
+	}
+	if config.Start == nil {
+		config.Start = startUCI
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Daemon{
+		config: config,
+		logger: l.WithField("request", "daemon"),
+		budget: newBudget(config.threads()),
+	}, nil
+}
+
+// Run connects every instance of the engines until ctx is done
+func (d *Daemon) Run(ctx context.Context) {
+	d.logger.Infof("Running %v engines with %v threads", len(d.config.Engines), d.config.threads())
+
+	var wg sync.WaitGroup
+	for _, e := range d.config.Engines {
+		for i := 0; i < e.instances(); i++ {
+			wg.Add(1)
+			go func(e EngineConfig, instance int) {
+				defer wg.Done()
+				d.serve(ctx, e, instance)
+			}(e, i)
+		}
+	}
+	wg.Wait()
+}
+
+// serve runs sessions for an instance of an engine one after the other
+func (d *Daemon) serve(ctx context.Context, e EngineConfig, instance int) {
+	logger := d.logger.WithField("engine", fmt.Sprintf("%v/%v", e.name(), instance))
+
+	for {
+		if !d.budget.acquire(ctx, e.threads()) {
+			return
+		}
+		err := d.session(ctx, e, logger)
+		d.budget.release(e.threads())
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			logger.Warnf("Session failed, starting again in %v: %v", d.config.retry(), err)
+		} else {
+			logger.Infof("Session ended, starting again in %v", d.config.retry())
+		}
+		select {
+		case <-time.After(d.config.retry()):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// session starts an engine process and connects it to the server until
+// the server ends the session, the connection is lost or ctx is done
+func (d *Daemon) session(ctx context.Context, e EngineConfig, logger *logrus.Entry) error {
+	var t *transcript.Writer
+	var opts []grpc.DialOption
+	if d.config.Record != "" {
+		var err error
+		t, err = transcript.Create(d.config.Record, "client-"+e.name())
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+		opts = append(opts, grpc.WithStreamInterceptor(t.ClientInterceptor))
+	}
+
+	process, err := d.config.Start(e, t)
+	if err != nil {
+		return err
+	}
+	conn, err := d.config.Dial(opts...)
+	if err != nil {
+		process.Close()
+		return err
+	}
+
+	// Closing the engine ends the session when ctx is done first
+	done := make(chan struct{})
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		process.Close()
+		conn.Close()
+	}()
+
+	c := cli.NewWithBook(&engine{Engine: process, config: e, logger: logger}, d.config.Book, *logger, pb.NewChessApplicationClient(conn))
+	err = c.NewGameRequest()
+	close(done)
+	<-closed
+	return err
+}
+
+// startUCI starts a UCI engine executable with the arguments, directory and environment of its config
+func startUCI(e EngineConfig, t *transcript.Writer) (cli.Engine, error) {
+	command := exec.Command(e.Path, e.Args...)
+	command.Dir = e.Dir
+	if len(e.Env) > 0 {
+		command.Env = os.Environ()
+		for key, value := range e.Env {
+			command.Env = append(command.Env, key+"="+value)
+		}
+	}
+	return uci.NewCommand(command, t)
+}
+
+// budget is the threads free for engines to search with
+type budget struct {
+	mu   sync.Mutex
+	free int
+	// closed and replaced each time threads are released
+	released chan struct{}
+}
+
+func newBudget(threads int) *budget {
+	return &budget{free: threads, released: make(chan struct{})}
+}
+
+// acquire waits until n threads are free and takes them, it returns false when ctx is done first
+func (b *budget) acquire(ctx context.Context, n int) bool {
+	for {
+		b.mu.Lock()
+		if b.free >= n {
+			b.free -= n
+			b.mu.Unlock()
+			return true
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release returns n threads to the budget
+func (b *budget) release(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.free += n
+	close(b.released)
+	b.released = make(chan struct{})
+}
@@ -0,0 +1,140 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// engine is an engine process with the options of its config set. The
+// options are hidden from the server so it can not change them.
+type engine struct {
+	cli.Engine
+	config EngineConfig
+	logger *logrus.Entry
+
+	// the names of the options the daemon set in lower case
+	pinned map[string]bool
+}
+
+// Init sets the options of the config once the engine sent them and
+// renames the engine when the config names it
+func (e *engine) Init() (cli.EngineIdent, []cli.Option, error) {
+	ident, options, err := e.Engine.Init()
+	if err != nil {
+		return ident, options, err
+	}
+	if e.config.Name != "" {
+		ident.Name = e.config.Name
+	}
+
+	values, missing, err := settings(e.config, options)
+	if err != nil {
+		return ident, nil, err
+	}
+	for _, name := range missing {
+		e.logger.Warnf("%v has no option %v", ident.Name, name)
+	}
+
+	e.pinned = make(map[string]bool)
+	var kept []cli.Option
+	for _, option := range options {
+		value, ok := values[option.Name]
+		if !ok {
+			kept = append(kept, option)
+			continue
+		}
+		e.pinned[strings.ToLower(option.Name)] = true
+
+		e.logger.Infof("Setting option %v to %v", option.Name, value)
+		err := e.Engine.Send(&pb.UciResponse{
+			MessageType: pb.UciResponse_SETOPTION,
+			SetOption:   &pb.UciResponse_SetOption{Name: option.Name, Value: value},
+		})
+		if err != nil {
+			return ident, nil, err
+		}
+	}
+
+	return ident, kept, nil
+}
+
+// Send passes a command on to the engine unless it changes an option the daemon set
+func (e *engine) Send(msg *pb.UciResponse) error {
+	if msg.GetMessageType() == pb.UciResponse_SETOPTION && e.pinned[strings.ToLower(msg.GetSetOption().GetName())] {
+		return nil
+	}
+	return e.Engine.Send(msg)
+}
+
+// settings matches the options a config sets to the options an engine
+// advertised. It returns the values to set by the engine's names for the
+// options and the options the config names that the engine does not have.
+func settings(config EngineConfig, options []cli.Option) (map[string]string, []string, error) {
+	explicit := make(map[string]string)
+	names := make(map[string]string)
+	for name, value := range config.options() {
+		explicit[strings.ToLower(name)] = value
+		names[strings.ToLower(name)] = name
+	}
+	implied := make(map[string]string)
+	for name, value := range config.implied() {
+		implied[strings.ToLower(name)] = value
+	}
+
+	values := make(map[string]string)
+	for _, option := range options {
+		name := strings.ToLower(option.Name)
+		value, ok := explicit[name]
+		if ok {
+			delete(explicit, name)
+		} else if value, ok = implied[name]; !ok {
+			continue
+		}
+		if err := validateOption(option, value); err != nil {
+			return nil, nil, err
+		}
+		values[option.Name] = value
+	}
+
+	var missing []string
+	for name := range explicit {
+		missing = append(missing, names[name])
+	}
+	sort.Strings(missing)
+	return values, missing, nil
+}
+
+// validateOption checks a value suits the type of an option
+func validateOption(option cli.Option, value string) error {
+	switch option.Type {
+	case "spin":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("Option %v must be a number got %v", option.Name, value)
+		}
+		if n < int(option.Min) || n > int(option.Max) {
+			return fmt.Errorf("Option %v must be between %v and %v got %v", option.Name, option.Min, option.Max, value)
+		}
+	case "check":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("Option %v must be true or false got %v", option.Name, value)
+		}
+	case "combo":
+		for _, v := range option.Var {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("Option %v must be one of %v got %v", option.Name, strings.Join(option.Var, ", "), value)
+	case "button":
+		return fmt.Errorf("Option %v is a button and can not be set", option.Name)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package fake implements a scriptable UCI engine used to test the GUI side of the protocol
+package fake
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CrashError is returned by Run when the script makes the engine crash
+type CrashError struct {
+	ExitCode int
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("Engine crashed with exit code %v", e.ExitCode)
+}
+
+type engine struct {
+	script Script
+	out    io.Writer
+	// the number of go commands received so far
+	searches int
+	// the reply to a ponder or infinite search waiting on ponderhit or stop
+	pending *Reply
+	ponder  bool
+	// the number of isready commands answered so far
+	readies int
+	// a hung engine ignores everything
+	hung bool
+}
+
+// Run plays the script reading GUI commands from in and writing engine output to out.
+// It returns when quit is received or the input ends.
+func Run(script Script, in io.Reader, out io.Writer) error {
+	e := engine{script: script, out: out}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		done, err := e.handle(scanner.Text())
+		if err != nil || done {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// handle responds to a single GUI command returning true once the engine should exit
+func (e *engine) handle(line string) (bool, error) {
+	tokens := strings.Fields(line)
+	if e.hung || len(tokens) == 0 {
+		return false, nil
+	}
+
+	switch tokens[0] {
+	case "uci":
+		return false, e.respond(e.script.Uci, e.handshake)
+	case "isready":
+		if e.script.HangAfter > 0 && e.readies >= e.script.HangAfter {
+			e.hung = true
+			return false, nil
+		}
+		e.readies++
+		return false, e.respond(e.script.IsReady, func() {
+			e.write("readyok")
+		})
+	case "go":
+		reply := e.nextSearch()
+		if reply.UntilStop {
+			e.pending = &reply
+			e.ponder = false
+			return false, nil
+		}
+		for _, token := range tokens[1:] {
+			if token == "ponder" || token == "infinite" {
+				e.pending = &reply
+				e.ponder = token == "ponder"
+				return false, nil
+			}
+		}
+		return false, e.respond(reply, e.bestMove(reply))
+	case "ponderhit":
+		if e.pending == nil || !e.ponder {
+			return false, nil
+		}
+		reply := *e.pending
+		e.pending = nil
+		return false, e.respond(reply, e.bestMove(reply))
+	case "stop":
+		if e.pending == nil {
+			return false, nil
+		}
+		reply := *e.pending
+		e.pending = nil
+		reply.Delay = 0
+		return false, e.respond(reply, e.bestMove(reply))
+	case "quit":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// respond waits, writes the reply output and then calls final unless the reply crashes or hangs
+func (e *engine) respond(reply Reply, final func()) error {
+	time.Sleep(time.Duration(reply.Delay))
+
+	if reply.Crash {
+		return &CrashError{ExitCode: reply.ExitCode}
+	}
+	if reply.Hang {
+		e.hung = true
+		return nil
+	}
+
+	for _, line := range reply.Output {
+		e.write(line)
+	}
+	final()
+	return nil
+}
+
+func (e *engine) handshake() {
+	if e.script.Name != "" {
+		e.write("id name " + e.script.Name)
+	}
+	if e.script.Author != "" {
+		e.write("id author " + e.script.Author)
+	}
+	for _, option := range e.script.Options {
+		e.write("option " + option)
+	}
+	e.write("uciok")
+
+	if e.script.CopyProtection != "" {
+		e.write("copyprotection checking")
+		e.write("copyprotection " + e.script.CopyProtection)
+	}
+	if e.script.Registration != "" {
+		e.write("registration checking")
+		e.write("registration " + e.script.Registration)
+	}
+}
+
+func (e *engine) bestMove(reply Reply) func() {
+	return func() {
+		if reply.BestMove == "" {
+			return
+		}
+		if reply.Ponder != "" {
+			e.write(fmt.Sprintf("bestmove %v ponder %v", reply.BestMove, reply.Ponder))
+			return
+		}
+		e.write("bestmove " + reply.BestMove)
+	}
+}
+
+func (e *engine) nextSearch() Reply {
+	if len(e.script.Go) == 0 {
+		return Reply{}
+	}
+
+	i := e.searches
+	if i >= len(e.script.Go) {
+		i = len(e.script.Go) - 1
+	}
+	e.searches++
+	return e.script.Go[i]
+}
+
+func (e *engine) write(line string) {
+	fmt.Fprintln(e.out, line)
+}
@@ -0,0 +1,93 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Script describes how a fake engine behaves
+type Script struct {
+	// The name the engine reports in `id name`
+	Name string `json:"name"`
+	// The author the engine reports in `id author`
+	Author string `json:"author"`
+	// Options advertised after the id lines, without the leading `option`
+	// eg. "name Hash type spin default 16 min 1 max 1024"
+	Options []string `json:"options"`
+	// When set the engine writes `copyprotection checking` followed by
+	// `copyprotection <status>` after uciok
+	CopyProtection string `json:"copyprotection"`
+	// When set the engine writes `registration checking` followed by
+	// `registration <status>` after uciok
+	Registration string `json:"registration"`
+	// How the engine responds to `uci`, output is written before uciok
+	Uci Reply `json:"uci"`
+	// How the engine responds to `isready`, output is written before readyok
+	IsReady Reply `json:"isready"`
+	// When set the engine hangs instead of answering isready once it has
+	// answered it this many times
+	HangAfter int `json:"hangAfter"`
+	// How the engine responds to each `go` command in turn. The last reply
+	// is repeated once the list runs out.
+	Go []Reply `json:"go"`
+}
+
+// Reply describes a single response of the engine
+type Reply struct {
+	// How long to wait before responding
+	Delay Duration `json:"delay"`
+	// Raw lines written before the response, eg. info lines or garbage
+	Output []string `json:"output"`
+	// The move sent in response to go, nothing is sent when empty
+	BestMove string `json:"bestmove"`
+	// The ponder move sent with the best move
+	Ponder string `json:"ponder"`
+	// Exit instead of responding
+	Crash bool `json:"crash"`
+	// The exit code used when crashing
+	ExitCode int `json:"exitCode"`
+	// Stop responding to anything
+	Hang bool `json:"hang"`
+	// Only respond to go once told to stop, as an infinite search does
+	UntilStop bool `json:"untilStop"`
+}
+
+// Duration is a time.Duration that reads from JSON strings such as "150ms"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("Invalid duration %q: %v", s, err)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads a script from a JSON file
+func Load(path string) (Script, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Script{}, err
+	}
+
+	var script Script
+	err = json.Unmarshal(b, &script)
+	if err != nil {
+		return Script{}, fmt.Errorf("Could not parse script %v: %v", path, err)
+	}
+
+	return script, nil
+}
@@ -1,90 +1,329 @@
 package uci
 
 import (
-	"bufio"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
 	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
 )
 
+// optionKeywords are the tokens that separate the fields of an option line
+var optionKeywords = map[string]bool{
+	"name":    true,
+	"type":    true,
+	"default": true,
+	"min":     true,
+	"max":     true,
+	"var":     true,
+}
+
+// infoKeywords are the tokens that separate the fields of an info line
+var infoKeywords = map[string]bool{
+	"depth":          true,
+	"seldepth":       true,
+	"time":           true,
+	"nodes":          true,
+	"pv":             true,
+	"multipv":        true,
+	"score":          true,
+	"currmove":       true,
+	"currmovenumber": true,
+	"hashfull":       true,
+	"nps":            true,
+	"tbhits":         true,
+	"sbhits":         true,
+	"cpuload":        true,
+	"string":         true,
+	"refutation":     true,
+	"currline":       true,
+}
+
+// parseLine converts a single line of engine output into a UciRequest.
+// Lines the GUI does not understand are ignored and return a nil message.
+func parseLine(line string) (*pb.UciRequest, error) {
+	tokens := strings.Fields(line)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	switch tokens[0] {
+	case "id":
+		var ident cli.EngineIdent
+		parseIdent(tokens, &ident)
+		return &pb.UciRequest{
+			MessageType: pb.UciRequest_ID,
+			Id:          &pb.UciRequest_Id{Name: ident.Name, Author: ident.Author},
+		}, nil
+	case "option":
+		option := parseOptions(line)
+		if option.Name == "" {
+			return nil, malformed(line, "option without a name")
+		}
+		return &pb.UciRequest{
+			MessageType: pb.UciRequest_OPTION,
+			Option: &pb.UciRequest_Option{
+				Name:    option.Name,
+				Type:    option.Type,
+				Default: option.Default,
+				Min:     option.Min,
+				Max:     option.Max,
+				Var:     option.Var,
+			},
+		}, nil
+	case "uciok":
+		return &pb.UciRequest{MessageType: pb.UciRequest_UCIOK}, nil
+	case "readyok":
+		return &pb.UciRequest{MessageType: pb.UciRequest_READYOK}, nil
+	case "bestmove":
+		return parseBestMove(line, tokens)
+	case "copyprotection":
+		if len(tokens) < 2 {
+			return nil, malformed(line, "copyprotection without a status")
+		}
+		return &pb.UciRequest{MessageType: pb.UciRequest_COPYPROTECTION, Copyprotection: tokens[1]}, nil
+	case "registration":
+		if len(tokens) < 2 {
+			return nil, malformed(line, "registration without a status")
+		}
+		return &pb.UciRequest{MessageType: pb.UciRequest_REGISTRATION, Registration: tokens[1]}, nil
+	case "info":
+		info, err := parseInfo(line, tokens)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: info}, nil
+	}
+
+	return nil, nil
+}
+
+func malformed(line, reason string) error {
+	return &cli.MalformedMessageError{Line: strings.TrimSpace(line), Reason: reason}
+}
+
 func parseIdent(tokens []string, ident *cli.EngineIdent) {
+	if len(tokens) < 2 {
+		return
+	}
 	switch tokens[1] {
 	case "name":
-		ident.Name = strings.Trim(strings.Join(tokens, " "), "\n")
+		ident.Name = strings.Join(tokens[2:], " ")
 	case "author":
-		ident.Author = strings.Trim(strings.Join(tokens, " "), "\n")
+		ident.Author = strings.Join(tokens[2:], " ")
 	}
 	return
 }
 
 func parseOptions(msg string) (option cli.Option) {
-	scanner := bufio.NewReader(strings.NewReader(msg))
-	scanner.Discard(6)
+	tokens := strings.Fields(msg)
+	if len(tokens) > 0 && tokens[0] == "option" {
+		tokens = tokens[1:]
+	}
 
-	for {
-		str, err := scanner.ReadString(' ')
-		if err != nil {
-			break
+	for i := 0; i < len(tokens); {
+		keyword := tokens[i]
+		if !optionKeywords[keyword] {
+			i++
+			continue
 		}
-		str = strings.Trim(str, " ")
 
-		switch str {
-		case "name":
-			option.Name, err = scanner.ReadString(' ')
-			if err != nil {
+		// Values run until the next keyword. Names may contain spaces so
+		// only the type keyword ends a name.
+		j := i + 1
+		for j < len(tokens) {
+			if keyword == "name" && tokens[j] == "type" {
 				break
 			}
-			for {
-				nextChar, _ := scanner.Peek(1)
-				if nextChar[0] < 'A' || nextChar[0] > 'Z' {
-					break
-				}
-				nextWord, err := scanner.ReadString(' ')
-				if err != nil {
-					break
-				}
-				option.Name += nextWord
-			}
-		case "type":
-			option.Type, err = scanner.ReadString(' ')
-			if err != nil {
+			if keyword != "name" && optionKeywords[tokens[j]] {
 				break
 			}
+			j++
+		}
+		value := strings.Join(tokens[i+1:j], " ")
+
+		switch keyword {
+		case "name":
+			option.Name = value
+		case "type":
+			option.Type = value
 		case "default":
-			option.Type, err = scanner.ReadString(' ')
-			if err != nil {
-				break
+			if value != "<empty>" {
+				option.Default = value
 			}
 		case "var":
-			v, err := scanner.ReadString(' ')
-			if err != nil {
-				break
-			}
-			option.Var = append(option.Var, v)
+			option.Var = append(option.Var, value)
 		case "min":
-			minStr, err := scanner.ReadString(' ')
-			if err != nil {
-				break
+			if min, err := strconv.Atoi(value); err == nil {
+				option.Min = int32(min)
 			}
-			minInt, err := strconv.Atoi(strings.Trim(minStr, " \n"))
-			if err != nil {
-				break
-			}
-			option.Min = int32(minInt)
 		case "max":
-			maxStr, _ := scanner.ReadString(' ')
+			if max, err := strconv.Atoi(value); err == nil {
+				option.Max = int32(max)
+			}
+		}
+		i = j
+	}
+
+	return option
+}
+
+func parseBestMove(line string, tokens []string) (*pb.UciRequest, error) {
+	if len(tokens) < 2 {
+		return nil, malformed(line, "bestmove without a move")
+	}
+
+	bestMove := &pb.UciRequest_BestMove{Move: tokens[1]}
+	if len(tokens) > 2 {
+		if tokens[2] != "ponder" || len(tokens) < 4 {
+			return nil, malformed(line, "expecting a ponder move")
+		}
+		bestMove.Ponder = []string{tokens[3]}
+	}
+
+	return &pb.UciRequest{MessageType: pb.UciRequest_BESTMOVE, BestMove: bestMove}, nil
+}
+
+func parseInfo(line string, tokens []string) (*pb.UciRequest_Info, error) {
+	info := &pb.UciRequest_Info{}
+
+	// untilKeyword returns the tokens following i up to the next info keyword
+	untilKeyword := func(i int) []string {
+		j := i + 1
+		for j < len(tokens) && !infoKeywords[tokens[j]] {
+			j++
+		}
+		return tokens[i+1 : j]
+	}
+
+	for i := 1; i < len(tokens); {
+		keyword := tokens[i]
+
+		switch keyword {
+		case "depth", "seldepth", "time", "nodes", "currmovenumber", "hashfull", "nps", "tbhits", "sbhits", "cpuload":
+			if i+1 >= len(tokens) {
+				return nil, malformed(line, fmt.Sprintf("%v without a value", keyword))
+			}
+			value, err := strconv.ParseUint(tokens[i+1], 10, 64)
+			// a number out of range is read as the largest value
+			if e, ok := err.(*strconv.NumError); err != nil && !(ok && e.Err == strconv.ErrRange) {
+				return nil, malformed(line, fmt.Sprintf("invalid %v %q", keyword, tokens[i+1]))
+			}
+			setInfoValue(info, keyword, value)
+			i += 2
+		case "multipv":
+			if i+1 >= len(tokens) {
+				return nil, malformed(line, "multipv without a value")
+			}
+			value, err := strconv.ParseInt(tokens[i+1], 10, 32)
 			if err != nil {
-				break
+				return nil, malformed(line, fmt.Sprintf("invalid multipv %q", tokens[i+1]))
+			}
+			info.Multipv = int32(value)
+			i += 2
+		case "currmove":
+			if i+1 >= len(tokens) {
+				return nil, malformed(line, "currmove without a move")
 			}
-			maxInt, err := strconv.Atoi(strings.Trim(maxStr, " \n"))
+			info.Currmove = tokens[i+1]
+			i += 2
+		case "score":
+			n, err := parseScore(line, tokens[i+1:], info)
 			if err != nil {
-				break
+				return nil, err
 			}
-			option.Max = int32(maxInt)
+			i += n + 1
+		case "pv":
+			info.Pv = untilKeyword(i)
+			i += len(info.Pv) + 1
+		case "refutation":
+			info.Refutation = untilKeyword(i)
+			i += len(info.Refutation) + 1
+		case "currline":
+			currline := untilKeyword(i)
+			info.Currline = strings.Join(currline, " ")
+			i += len(currline) + 1
+		case "string":
+			// string consumes the rest of the line
+			info.String_ = strings.Join(tokens[i+1:], " ")
+			i = len(tokens)
+		default:
+			i++
 		}
+	}
 
+	return info, nil
+}
+
+// setInfoValue sets a numeric field of an info message, values too large for
+// the field are clamped
+func setInfoValue(info *pb.UciRequest_Info, keyword string, value uint64) {
+	small := uint32(value)
+	if value > math.MaxUint32 {
+		small = math.MaxUint32
+	}
+	switch keyword {
+	case "depth":
+		info.Depth = small
+	case "seldepth":
+		info.Seldepth = small
+	case "time":
+		info.Time = value
+	case "nodes":
+		info.Nodes = value
+	case "currmovenumber":
+		info.Currmovenumber = small
+	case "hashfull":
+		info.Hashfull = small
+	case "nps":
+		info.Nps = value
+	case "tbhits":
+		info.Tbhits = value
+	case "sbhits":
+		info.Sbhits = value
+	case "cpuload":
+		info.Cpuload = small
 	}
+}
 
-	return option
+// parseScore reads the tokens following a score keyword and returns the number consumed
+func parseScore(line string, tokens []string, info *pb.UciRequest_Info) (int, error) {
+	score := &pb.UciRequest_Score{}
+	n := 0
+
+Loop:
+	for n < len(tokens) {
+		switch tokens[n] {
+		case "cp", "mate":
+			if n+1 >= len(tokens) {
+				return 0, malformed(line, fmt.Sprintf("score %v without a value", tokens[n]))
+			}
+			value, err := strconv.ParseInt(tokens[n+1], 10, 32)
+			if err != nil {
+				return 0, malformed(line, fmt.Sprintf("invalid score %v %q", tokens[n], tokens[n+1]))
+			}
+			if tokens[n] == "cp" {
+				score.Cp = int32(value)
+			} else {
+				score.Mate = int32(value)
+			}
+			n += 2
+		case "lowerbound":
+			score.Lower = 1
+			n++
+		case "upperbound":
+			score.Upper = 1
+			n++
+		default:
+			break Loop
+		}
+	}
+
+	if n == 0 {
+		return 0, malformed(line, "score without a value")
+	}
+	info.Score = score
+	return n, nil
 }
@@ -0,0 +1,120 @@
+package uci
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected *pb.UciRequest
+	}{
+		{
+			"id name Stockfish 10 64\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_ID, Id: &pb.UciRequest_Id{Name: "Stockfish 10 64"}},
+		},
+		{
+			"id author T. Romstad, M. Costalba, J. Kiiski, G. Linscott\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_ID, Id: &pb.UciRequest_Id{Author: "T. Romstad, M. Costalba, J. Kiiski, G. Linscott"}},
+		},
+		{
+			"option name Move Overhead type spin default 30 min 0 max 5000\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_OPTION, Option: &pb.UciRequest_Option{
+				Name: "Move Overhead", Type: "spin", Default: "30", Min: 0, Max: 5000,
+			}},
+		},
+		{
+			"option name UCI_Variant type combo default chess var chess var crazyhouse var atomic\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_OPTION, Option: &pb.UciRequest_Option{
+				Name: "UCI_Variant", Type: "combo", Default: "chess", Var: []string{"chess", "crazyhouse", "atomic"},
+			}},
+		},
+		{"uciok\n", &pb.UciRequest{MessageType: pb.UciRequest_UCIOK}},
+		{"readyok\n", &pb.UciRequest{MessageType: pb.UciRequest_READYOK}},
+		{
+			"bestmove e7e8q\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_BESTMOVE, BestMove: &pb.UciRequest_BestMove{Move: "e7e8q"}},
+		},
+		{
+			"bestmove e2e4 ponder c7c5\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_BESTMOVE, BestMove: &pb.UciRequest_BestMove{Move: "e2e4", Ponder: []string{"c7c5"}}},
+		},
+		{
+			"copyprotection ok\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_COPYPROTECTION, Copyprotection: "ok"},
+		},
+		{
+			"registration checking\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_REGISTRATION, Registration: "checking"},
+		},
+		{
+			"info depth 20 seldepth 31 multipv 1 score cp -35 lowerbound nodes 2563331 nps 1281665 hashfull 882 tbhits 3 time 2000 pv c7c5 g1f3 d7d6\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: &pb.UciRequest_Info{
+				Depth: 20, Seldepth: 31, Multipv: 1, Score: &pb.UciRequest_Score{Cp: -35, Lower: 1},
+				Nodes: 2563331, Nps: 1281665, Hashfull: 882, Tbhits: 3, Time: 2000,
+				Pv: []string{"c7c5", "g1f3", "d7d6"},
+			}},
+		},
+		{
+			"info depth 12 tbhits 7 sbhits 42 cpuload 950 currmove e2e4 currmovenumber 1\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: &pb.UciRequest_Info{
+				Depth: 12, Tbhits: 7, Sbhits: 42, Cpuload: 950, Currmove: "e2e4", Currmovenumber: 1,
+			}},
+		},
+		{
+			// long searches count past 32 bits, a depth that large is clamped
+			"info depth 99999999999 nodes 4294967296 nps 5000000000 tbhits 18446744073709551616 time 864000000 score cp 20 pv e2e4\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: &pb.UciRequest_Info{
+				Depth: math.MaxUint32, Nodes: 4294967296, Nps: 5000000000, Tbhits: math.MaxUint64, Time: 864000000,
+				Score: &pb.UciRequest_Score{Cp: 20}, Pv: []string{"e2e4"},
+			}},
+		},
+		{
+			"info string NNUE evaluation enabled\n",
+			&pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: &pb.UciRequest_Info{String_: "NNUE evaluation enabled"}},
+		},
+		{"Stockfish 10 64 by T. Romstad\n", nil},
+		{"\n", nil},
+	}
+
+	for _, test := range tests {
+		msg, err := parseLine(test.line)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", test.line, err)
+			continue
+		}
+		if !proto.Equal(msg, test.expected) {
+			t.Errorf("Parsing %q expecting %v got %v", test.line, test.expected, msg)
+		}
+	}
+}
+
+func TestParseLineMalformed(t *testing.T) {
+	lines := []string{
+		"bestmove\n",
+		"bestmove e2e4 ponder\n",
+		"copyprotection\n",
+		"registration\n",
+		"option type spin\n",
+		"info depth\n",
+		"info nodes -3\n",
+		"info sbhits many\n",
+		"info multipv one\n",
+		"info score cp\n",
+		"info score mate x\n",
+		"info currmove\n",
+	}
+
+	for _, line := range lines {
+		_, err := parseLine(line)
+		if _, ok := err.(*cli.MalformedMessageError); !ok {
+			t.Errorf("Expecting a malformed message error for %q got %v", line, err)
+		}
+	}
+}
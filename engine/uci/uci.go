@@ -2,42 +2,75 @@ package uci
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/transcript"
 )
 
+// closeTimeout is how long an engine has to exit after its input is closed
+const closeTimeout = time.Second
+
 type uci struct {
-	in  bufio.Reader
-	out io.Writer
-	cmd exec.Cmd
+	in  *bufio.Reader
+	out io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// New returns a new UCI instance running the executable at path with the given arguments
+func New(path string, args ...string) (cli.Engine, error) {
+	return NewRecorded(path, nil, args...)
 }
 
-// New returns a new UCI instance
-func New(path string) (cli.Engine, error) {
-	command := exec.Command(path)
+// NewRecorded returns a new UCI instance that records every line exchanged
+// with the engine to a transcript. No transcript is recorded when t is nil.
+func NewRecorded(path string, t *transcript.Writer, args ...string) (cli.Engine, error) {
+	return NewCommand(exec.Command(path, args...), t)
+}
 
+// NewCommand starts a command that has not been started as a UCI engine eg.
+// to run it in another directory, recording to t when it is not nil
+func NewCommand(command *exec.Cmd, t *transcript.Writer) (cli.Engine, error) {
+	var out io.WriteCloser
 	out, err := command.StdinPipe()
 	if err != nil {
 		return nil, err
 	}
-	in, err := command.StdoutPipe()
+	var in io.Reader
+	in, err = command.StdoutPipe()
 	if err != nil {
 		return nil, err
 	}
 
+	if t != nil {
+		out = t.EngineInput(out)
+		in = t.EngineOutput(in)
+	}
+
 	err = command.Start()
 
-	return uci{*bufio.NewReader(in), out, *command}, err
+	return &uci{bufio.NewReader(in), out, command}, err
+}
+
+// NewFromPipes returns a UCI instance for an engine that reads commands from
+// out and writes its output to in, such as an engine running in process
+func NewFromPipes(in io.Reader, out io.WriteCloser) cli.Engine {
+	return &uci{bufio.NewReader(in), out, nil}
 }
 
 // Init initializes the engine returning engine options and engine ident
-func (uci uci) Init() (ident cli.EngineIdent, options []cli.Option, err error) {
-	uci.out.Write([]byte("uci\n"))
+func (uci *uci) Init() (ident cli.EngineIdent, options []cli.Option, err error) {
+	err = uci.write("uci")
+	if err != nil {
+		return cli.EngineIdent{}, nil, err
+	}
 
-Loop:
 	for {
 		// read string
 		msg, err := uci.in.ReadString('\n')
@@ -45,11 +78,14 @@ Loop:
 			return cli.EngineIdent{}, nil, err
 		}
 
-		// match command
-		cmd := strings.Split(msg, " ")
+		// match command, engines may write anything they like before uciok
+		cmd := strings.Fields(msg)
+		if len(cmd) == 0 {
+			continue
+		}
 		switch cmd[0] {
-		case "uciok\n":
-			break Loop
+		case "uciok":
+			return ident, options, nil
 		case "option":
 			option := parseOptions(msg)
 			options = append(options, option)
@@ -57,6 +93,166 @@ Loop:
 			parseIdent(cmd, &ident)
 		}
 	}
+}
+
+// Send writes a GUI command to the engine
+func (uci *uci) Send(msg *pb.UciResponse) error {
+	switch msg.GetMessageType() {
+	case pb.UciResponse_UCI:
+		return uci.write("uci")
+	case pb.UciResponse_DEBUG:
+		if msg.GetDebug() {
+			return uci.write("debug on")
+		}
+		return uci.write("debug off")
+	case pb.UciResponse_ISREADY:
+		return uci.write("isready")
+	case pb.UciResponse_SETOPTION:
+		return uci.write(formatSetOption(msg.GetSetOption()))
+	case pb.UciResponse_REGISTER:
+		return uci.write("register later")
+	case pb.UciResponse_UCINEWGAME:
+		return uci.write("ucinewgame")
+	case pb.UciResponse_POSITION:
+		return uci.write(formatPosition(msg.GetPosition()))
+	case pb.UciResponse_GO:
+		return uci.write(formatGo(msg.GetGo()))
+	case pb.UciResponse_STOP:
+		return uci.write("stop")
+	case pb.UciResponse_PONDERHIT:
+		return uci.write("ponderhit")
+	case pb.UciResponse_QUIT:
+		return uci.write("quit")
+	}
+
+	return fmt.Errorf("Unknown uci message %v", msg.GetMessageType())
+}
+
+// Read blocks until the engine writes a message the GUI understands
+func (uci *uci) Read() (*pb.UciRequest, error) {
+	for {
+		line, err := uci.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := parseLine(line)
+		if err != nil || msg != nil {
+			return msg, err
+		}
+	}
+}
+
+// Divide sends go perft for a position reached by playing moves from a FEN,
+// the starting position when it is empty, and returns the number of
+// positions depth-1 moves below each move. Engines that support it eg.
+// Stockfish write a move: nodes line for each move and end with Nodes searched.
+func (uci *uci) Divide(fen string, moves []string, depth int) (map[string]uint64, error) {
+	if err := uci.write(formatPosition(&pb.UciResponse_Position{IsFen: fen != "", Fen: fen, Moves: moves})); err != nil {
+		return nil, err
+	}
+	if err := uci.write(fmt.Sprintf("go perft %v", depth)); err != nil {
+		return nil, err
+	}
 
-	return ident, options, nil
+	counts := make(map[string]uint64)
+	for {
+		line, err := uci.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) >= 2 && tokens[0] == "Nodes" && tokens[1] == "searched:" {
+			return counts, nil
+		}
+		if len(tokens) != 2 || !strings.HasSuffix(tokens[0], ":") {
+			continue
+		}
+		nodes, err := strconv.ParseUint(tokens[1], 10, 64)
+		if err != nil {
+			return nil, malformed(line, "perft count is not a number")
+		}
+		counts[strings.TrimSuffix(tokens[0], ":")] = nodes
+	}
+}
+
+// Close closes the engine input and waits for the process to exit, killing it
+// if it does not exit in time
+func (uci *uci) Close() error {
+	uci.out.Close()
+	if uci.cmd == nil {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- uci.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(closeTimeout):
+		uci.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (uci *uci) write(cmd string) error {
+	_, err := io.WriteString(uci.out, cmd+"\n")
+	return err
+}
+
+func formatSetOption(opt *pb.UciResponse_SetOption) string {
+	cmd := "setoption name " + opt.GetName()
+	if opt.GetValue() != "" {
+		cmd += " value " + opt.GetValue()
+	}
+	return cmd
+}
+
+func formatPosition(pos *pb.UciResponse_Position) string {
+	cmd := "position startpos"
+	if pos.GetIsFen() {
+		cmd = "position fen " + pos.GetFen()
+	}
+	if len(pos.GetMoves()) > 0 {
+		cmd += " moves " + strings.Join(pos.GetMoves(), " ")
+	}
+	return cmd
+}
+
+func formatGo(g *pb.UciResponse_Go) string {
+	cmd := []string{"go"}
+	if len(g.GetSearchmoves()) > 0 {
+		cmd = append(cmd, "searchmoves")
+		cmd = append(cmd, g.GetSearchmoves()...)
+	}
+	if g.GetIsPonder() {
+		cmd = append(cmd, "ponder")
+	}
+
+	values := []struct {
+		name  string
+		value uint32
+	}{
+		{"wtime", g.GetWtime()},
+		{"btime", g.GetBtime()},
+		{"winc", g.GetWinc()},
+		{"binc", g.GetBinc()},
+		{"movestogo", g.GetMovestogo()},
+		{"depth", g.GetDepth()},
+		{"nodes", g.GetNodes()},
+		{"movetime", g.GetMovetime()},
+	}
+	for _, v := range values {
+		if v.value > 0 {
+			cmd = append(cmd, fmt.Sprintf("%v %v", v.name, v.value))
+		}
+	}
+
+	if g.GetIsInfinite() {
+		cmd = append(cmd, "infinite")
+	}
+	return strings.Join(cmd, " ")
 }
@@ -0,0 +1,395 @@
+package uci
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// fakeUci is the path to the fakeuci binary built for the tests
+var fakeUci string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "fakeuci")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fakeUci = filepath.Join(dir, "fakeuci")
+	build := exec.Command("go", "build", "-o", fakeUci, "github.com/schafer14/grpc-chess/cmd/fakeuci")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Println("Could not build fakeuci", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func start(t *testing.T, script string) cli.Engine {
+	t.Helper()
+
+	engine, err := New(fakeUci, "-script", filepath.Join("testdata", script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func initialise(t *testing.T, engine cli.Engine) {
+	t.Helper()
+
+	if _, _, err := engine.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func send(t *testing.T, engine cli.Engine, msg *pb.UciResponse) {
+	t.Helper()
+
+	if err := engine.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type readResult struct {
+	msg *pb.UciRequest
+	err error
+}
+
+// read returns the next message from the engine failing the test if nothing arrives in time
+func read(t *testing.T, engine cli.Engine) (*pb.UciRequest, error) {
+	t.Helper()
+
+	select {
+	case res := <-readAsync(engine):
+		return res.msg, res.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the engine")
+	}
+	return nil, nil
+}
+
+func readAsync(engine cli.Engine) chan readResult {
+	result := make(chan readResult, 1)
+	go func() {
+		msg, err := engine.Read()
+		result <- readResult{msg, err}
+	}()
+	return result
+}
+
+func expect(t *testing.T, engine cli.Engine, messageType pb.UciRequest_MessageType) *pb.UciRequest {
+	t.Helper()
+
+	msg, err := read(t, engine)
+	if err != nil {
+		t.Fatalf("Expecting %v got error %v", messageType, err)
+	}
+	if msg.GetMessageType() != messageType {
+		t.Fatalf("Expecting %v got %v", messageType, msg.GetMessageType())
+	}
+	return msg
+}
+
+func TestInit(t *testing.T) {
+	engine := start(t, "handshake.json")
+	defer engine.Close()
+
+	ident, options, err := engine.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedIdent := cli.EngineIdent{Name: "Fake Engine 1.0", Author: "The grpc-chess authors"}
+	if ident != expectedIdent {
+		t.Errorf("Expecting ident %+v got %+v", expectedIdent, ident)
+	}
+
+	expectedOptions := []cli.Option{
+		{Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024},
+		{Name: "Skill Level", Type: "spin", Default: "20", Min: 0, Max: 20},
+		{Name: "Ponder", Type: "check", Default: "false"},
+		{Name: "Style", Type: "combo", Default: "Normal", Var: []string{"Solid", "Normal", "Risky"}},
+		{Name: "Clear Hash", Type: "button"},
+		{Name: "SyzygyPath", Type: "string"},
+	}
+	if !reflect.DeepEqual(options, expectedOptions) {
+		t.Errorf("Expecting options\n%+v\ngot\n%+v", expectedOptions, options)
+	}
+}
+
+func TestInitCrash(t *testing.T) {
+	engine := start(t, "crash-on-uci.json")
+	defer engine.Close()
+
+	if _, _, err := engine.Init(); err == nil {
+		t.Fatal("Expecting an error from an engine that exits during the handshake")
+	}
+}
+
+func TestInitDelay(t *testing.T) {
+	engine := start(t, "slow.json")
+	defer engine.Close()
+
+	begin := time.Now()
+	ident, _, err := engine.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ident.Name != "Slow" {
+		t.Errorf("Expecting name Slow got %v", ident.Name)
+	}
+	if elapsed := time.Since(begin); elapsed < 50*time.Millisecond {
+		t.Errorf("Expecting the handshake to take at least 50ms took %v", elapsed)
+	}
+}
+
+func TestReadHandshake(t *testing.T) {
+	engine := start(t, "handshake.json")
+	defer engine.Close()
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_UCI})
+
+	id := expect(t, engine, pb.UciRequest_ID)
+	if id.GetId().GetName() != "Fake Engine 1.0" {
+		t.Errorf("Expecting name got %v", id.GetId())
+	}
+	id = expect(t, engine, pb.UciRequest_ID)
+	if id.GetId().GetAuthor() != "The grpc-chess authors" {
+		t.Errorf("Expecting author got %v", id.GetId())
+	}
+	for i := 0; i < 6; i++ {
+		expect(t, engine, pb.UciRequest_OPTION)
+	}
+	expect(t, engine, pb.UciRequest_UCIOK)
+}
+
+func TestReadyOk(t *testing.T) {
+	engine := start(t, "handshake.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{
+		MessageType: pb.UciResponse_SETOPTION,
+		SetOption:   &pb.UciResponse_SetOption{Name: "Hash", Value: "128"},
+	})
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_ISREADY})
+	expect(t, engine, pb.UciRequest_READYOK)
+}
+
+func TestCopyProtectionAndRegistration(t *testing.T) {
+	engine := start(t, "protection.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	expected := []struct {
+		messageType pb.UciRequest_MessageType
+		status      string
+	}{
+		{pb.UciRequest_COPYPROTECTION, "checking"},
+		{pb.UciRequest_COPYPROTECTION, "ok"},
+		{pb.UciRequest_REGISTRATION, "checking"},
+		{pb.UciRequest_REGISTRATION, "error"},
+	}
+	for _, e := range expected {
+		msg := expect(t, engine, e.messageType)
+		status := msg.GetCopyprotection()
+		if e.messageType == pb.UciRequest_REGISTRATION {
+			status = msg.GetRegistration()
+		}
+		if status != e.status {
+			t.Errorf("Expecting %v %v got %v", e.messageType, e.status, status)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	engine := start(t, "search.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_UCINEWGAME})
+	send(t, engine, &pb.UciResponse{
+		MessageType: pb.UciResponse_POSITION,
+		Position:    &pb.UciResponse_Position{},
+	})
+	send(t, engine, &pb.UciResponse{
+		MessageType: pb.UciResponse_GO,
+		Go:          &pb.UciResponse_Go{Wtime: 60000, Btime: 60000, Winc: 1000, Binc: 1000},
+	})
+
+	info := expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if info.GetDepth() != 1 || info.GetScore().GetCp() != 20 || !reflect.DeepEqual(info.GetPv(), []string{"e2e4"}) {
+		t.Errorf("Unexpected info %v", info)
+	}
+	info = expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if info.GetMultipv() != 2 || info.GetScore().GetMate() != -3 || info.GetScore().GetUpper() != 1 {
+		t.Errorf("Unexpected info %v", info)
+	}
+	info = expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if info.GetCurrmove() != "d2d4" || info.GetCurrmovenumber() != 2 {
+		t.Errorf("Unexpected info %v", info)
+	}
+	info = expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if !reflect.DeepEqual(info.GetRefutation(), []string{"d1h5", "g6h5"}) || info.GetCurrline() != "1 e2e4 e7e5" {
+		t.Errorf("Unexpected info %v", info)
+	}
+	info = expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if info.GetString_() != "hello from the engine" {
+		t.Errorf("Unexpected info %v", info)
+	}
+
+	best := expect(t, engine, pb.UciRequest_BESTMOVE).GetBestMove()
+	if best.GetMove() != "e2e4" || !reflect.DeepEqual(best.GetPonder(), []string{"e7e5"}) {
+		t.Errorf("Unexpected best move %v", best)
+	}
+
+	send(t, engine, &pb.UciResponse{
+		MessageType: pb.UciResponse_POSITION,
+		Position:    &pb.UciResponse_Position{Moves: []string{"e2e4", "e7e5"}},
+	})
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Movetime: 100}})
+	best = expect(t, engine, pb.UciRequest_BESTMOVE).GetBestMove()
+	if best.GetMove() != "d2d4" || len(best.GetPonder()) != 0 {
+		t.Errorf("Unexpected best move %v", best)
+	}
+}
+
+func TestPonderHit(t *testing.T) {
+	engine := start(t, "search.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{IsPonder: true}})
+
+	result := readAsync(engine)
+	select {
+	case res := <-result:
+		t.Fatalf("Expecting the engine to wait for ponderhit got %v %v", res.msg, res.err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_PONDERHIT})
+	select {
+	case res := <-result:
+		if res.err != nil || res.msg.GetMessageType() != pb.UciRequest_INFO {
+			t.Fatalf("Expecting info after ponderhit got %v %v", res.msg, res.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the engine")
+	}
+}
+
+func TestStop(t *testing.T) {
+	engine := start(t, "slow.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{IsInfinite: true}})
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_STOP})
+
+	best := expect(t, engine, pb.UciRequest_BESTMOVE).GetBestMove()
+	if best.GetMove() != "g1f3" {
+		t.Errorf("Expecting g1f3 got %v", best.GetMove())
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	engine := start(t, "malformed.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Depth: 3}})
+
+	for _, line := range []string{"info depth twelve", "bestmove", "info score"} {
+		msg, err := read(t, engine)
+		malformed, ok := err.(*cli.MalformedMessageError)
+		if !ok {
+			t.Fatalf("Expecting a malformed message error for %q got %v %v", line, msg, err)
+		}
+		if malformed.Line != line {
+			t.Errorf("Expecting line %q got %q", line, malformed.Line)
+		}
+	}
+
+	// The engine is still usable after malformed output
+	info := expect(t, engine, pb.UciRequest_INFO).GetInfo()
+	if info.GetDepth() != 3 {
+		t.Errorf("Expecting depth 3 got %v", info.GetDepth())
+	}
+	expect(t, engine, pb.UciRequest_BESTMOVE)
+}
+
+func TestCrash(t *testing.T) {
+	engine := start(t, "crash.json")
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Depth: 1}})
+	if _, err := read(t, engine); err == nil {
+		t.Fatal("Expecting an error reading from a crashed engine")
+	}
+
+	err := engine.Close()
+	exit, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("Expecting an exit error got %v", err)
+	}
+	if status := exit.ProcessState.ExitCode(); status != 3 {
+		t.Errorf("Expecting exit code 3 got %v", status)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	engine := start(t, "hang.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_ISREADY})
+	select {
+	case res := <-readAsync(engine):
+		t.Fatalf("Expecting a hung engine to stay silent got %v %v", res.msg, res.err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestQuit(t *testing.T) {
+	engine := start(t, "handshake.json")
+	initialise(t, engine)
+
+	send(t, engine, &pb.UciResponse{MessageType: pb.UciResponse_QUIT})
+	if err := engine.Close(); err != nil {
+		t.Errorf("Expecting a clean exit after quit got %v", err)
+	}
+}
+
+func TestDivide(t *testing.T) {
+	engine := start(t, "perft.json")
+	defer engine.Close()
+	initialise(t, engine)
+
+	divider, ok := engine.(interface {
+		Divide(fen string, moves []string, depth int) (map[string]uint64, error)
+	})
+	if !ok {
+		t.Fatal("Expecting the engine to divide")
+	}
+	counts, err := divider.Divide("", []string{"e2e4"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]uint64{"a2a3": 1, "b2b3": 2}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expecting %v got %v", expected, counts)
+	}
+}
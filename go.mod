@@ -3,6 +3,7 @@ module github.com/schafer14/grpc-chess
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/golang/protobuf v1.3.2
 	github.com/sirupsen/logrus v1.4.2
 	google.golang.org/grpc v1.24.0
@@ -0,0 +1,121 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+)
+
+// scored returns a script playing moves with the same score for each
+func scored(name string, cp int, moves ...string) fake.Script {
+	script := fake.Script{Name: name, Author: "harness"}
+	for _, move := range moves {
+		script.Go = append(script.Go, fake.Reply{
+			Output:   []string{fmt.Sprintf("info depth 10 score cp %v pv %v", cp, move)},
+			BestMove: move,
+		})
+	}
+	return script
+}
+
+// The knights go out and back without repeating a position three times in 6 moves
+var (
+	whiteKnight = []string{"g1f3", "f3g1", "g1f3", "f3g1"}
+	blackKnight = []string{"g8f6", "f6g8", "g8f6", "f6g8"}
+)
+
+func TestResignAdjudication(t *testing.T) {
+	adjudication := server.Adjudication{ResignScore: 1000, ResignMoves: 2}
+	h := New(server.Config{Time: 10 * time.Second, Adjudication: adjudication})
+	defer h.Close()
+
+	w, b := start(t, h, scored("white", 1500, whiteKnight...), scored("black", -1200, blackKnight...), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	// Both engines agree for two moves each
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, server.ResignAdjudication)
+	if len(record.Moves) != 4 {
+		t.Errorf("Expecting the game to be resigned after 4 moves got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestResignNeedsAgreement(t *testing.T) {
+	adjudication := server.Adjudication{ResignScore: 1000, ResignMoves: 2, MaxMoves: 3}
+	h := New(server.Config{Time: 10 * time.Second, Adjudication: adjudication})
+	defer h.Close()
+
+	// Black does not think it is lost
+	w, b := start(t, h, scored("white", 1500, whiteKnight...), scored("black", -300, blackKnight...), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.Draw, server.MaxMovesAdjudication)
+	if len(record.Moves) != 6 {
+		t.Errorf("Expecting the game to stop after 3 moves got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestDrawAdjudication(t *testing.T) {
+	adjudication := server.Adjudication{DrawScore: 10, DrawMoves: 2, DrawAfter: 1}
+	h := New(server.Config{Time: 10 * time.Second, Adjudication: adjudication})
+	defer h.Close()
+
+	w, b := start(t, h, scored("white", 5, whiteKnight...), scored("black", -5, blackKnight...), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	// The scores of the first move do not count
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.Draw, server.DrawAdjudication)
+	if len(record.Moves) != 6 {
+		t.Errorf("Expecting the game to be drawn after 6 moves got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestGameAdjudication(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	var players []*Player
+	for _, script := range []fake.Script{moves("white", whiteKnight...), moves("black", blackKnight...)} {
+		p, err := h.Connect(script, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(script.Name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	// The game's rules replace the scheduler's
+	record, err := h.Scheduler.Play(context.Background(), server.Game{
+		White:        "white",
+		Black:        "black",
+		Adjudication: &server.Adjudication{MaxMoves: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectOutcome(t, record, rules.Draw, server.MaxMovesAdjudication)
+	if len(record.Moves) != 2 {
+		t.Errorf("Expecting the game to stop after a move got %v", record.Moves)
+	}
+
+	finish(t, h, players...)
+}
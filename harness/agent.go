@@ -0,0 +1,123 @@
+package harness
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// Agent is a scripted engine that the test also sends game controls through
+// the way a human agent would
+type Agent struct {
+	cli.Engine
+
+	// out is filled from the engine once the client first reads, the engine
+	// reads its own handshake in Init
+	out      chan read
+	pump     sync.Once
+	controls chan *pb.ClientGameMessage
+
+	mu       sync.Mutex
+	received []*pb.ServerGameMessage
+}
+
+// read is the result of reading from the scripted engine
+type read struct {
+	msg *pb.UciRequest
+	err error
+}
+
+func newAgent(e cli.Engine) *Agent {
+	return &Agent{Engine: e, out: make(chan read), controls: make(chan *pb.ClientGameMessage)}
+}
+
+// Read returns the next message of the scripted engine or the next control the test sent
+func (a *Agent) Read() (*pb.UciRequest, error) {
+	a.pump.Do(func() {
+		go func() {
+			defer close(a.out)
+			for {
+				msg, err := a.Engine.Read()
+				a.out <- read{msg, err}
+				if _, ok := err.(*cli.MalformedMessageError); err != nil && !ok {
+					return
+				}
+			}
+		}()
+	})
+
+	select {
+	case r, ok := <-a.out:
+		if !ok {
+			return nil, io.EOF
+		}
+		return r.msg, r.err
+	case control := <-a.controls:
+		return &pb.UciRequest{MessageType: pb.UciRequest_GAME_CONTROL, GameControl: control}, nil
+	}
+}
+
+// Control implements client.Controller recording the controls the server reports
+func (a *Agent) Control(msg *pb.ServerGameMessage) error {
+	a.mu.Lock()
+	a.received = append(a.received, msg)
+	a.mu.Unlock()
+	return nil
+}
+
+// Request sends a game control to the server once the client reads from the agent
+func (a *Agent) Request(t pb.ClientGameMessage_MessageType) {
+	a.controls <- &pb.ClientGameMessage{MessageType: t}
+}
+
+// RequestGame sends a control for a correspondence game once the client reads from the agent
+func (a *Agent) RequestGame(t pb.ClientGameMessage_MessageType, game string, moves ...string) {
+	a.controls <- &pb.ClientGameMessage{MessageType: t, Game: game, Moves: moves}
+}
+
+// Received returns the controls the server reported to the agent
+func (a *Agent) Received() []*pb.ServerGameMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*pb.ServerGameMessage(nil), a.received...)
+}
+
+// WaitFor polls until the server reported a control of type c to the agent as a message of type t
+func (a *Agent) WaitFor(t pb.ServerGameMessage_MessageType, c pb.ClientGameMessage_MessageType, timeout time.Duration) (*pb.ServerGameMessage, error) {
+	msg := a.waitUntil(func(msg *pb.ServerGameMessage) bool {
+		return msg.GetMessageType() == t && msg.GetControl().GetMessageType() == c
+	}, timeout)
+	if msg == nil {
+		return nil, fmt.Errorf("Timed out waiting for %v %v", t, c)
+	}
+	return msg, nil
+}
+
+// WaitForState polls until the server sent the agent a message of type t with the state of a correspondence game
+func (a *Agent) WaitForState(t pb.ServerGameMessage_MessageType, timeout time.Duration) (*pb.GameState, error) {
+	msg := a.waitUntil(func(msg *pb.ServerGameMessage) bool {
+		return msg.GetMessageType() == t && msg.GetGameState() != nil
+	}, timeout)
+	if msg == nil {
+		return nil, fmt.Errorf("Timed out waiting for the game state in %v", t)
+	}
+	return msg.GetGameState(), nil
+}
+
+// waitUntil polls for the first message received that matches returning nil once the timeout passes
+func (a *Agent) waitUntil(match func(*pb.ServerGameMessage) bool, timeout time.Duration) *pb.ServerGameMessage {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, msg := range a.Received() {
+			if match(msg) {
+				return msg
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
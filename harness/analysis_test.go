@@ -0,0 +1,189 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+const afterE4 = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+
+var analyst = fake.Script{
+	Name:    "analyst",
+	Options: []string{"name MultiPV type spin default 1 min 1 max 500"},
+	Go: []fake.Reply{{
+		Output: []string{
+			"info depth 9 seldepth 12 multipv 1 score cp 18 nodes 9000 nps 90000 pv c7c5",
+			"info depth 10 seldepth 14 multipv 1 score cp 20 nodes 10000 nps 100000 time 100 pv c7c5 g1f3",
+			"info depth 10 seldepth 14 multipv 2 score cp 35 nodes 12000 nps 100000 time 120 pv e7e5 g1f3 b8c6",
+			"info depth 10 currmove g8f6 currmovenumber 3",
+		},
+		BestMove: "c7c5",
+	}},
+}
+
+// analyze runs an analysis returning all updates streamed back
+func analyze(ctx context.Context, h *Harness, req *pb.AnalysisRequest) ([]*pb.AnalysisUpdate, error) {
+	conn, err := h.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewChessApplicationClient(conn).Analyze(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*pb.AnalysisUpdate
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return updates, nil
+		}
+		if err != nil {
+			return updates, err
+		}
+		updates = append(updates, update)
+	}
+}
+
+func connectAnalyst(t *testing.T, h *Harness, script fake.Script) *Player {
+	t.Helper()
+
+	p, err := h.Connect(script, Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Recorder.WaitFor(script.Name, "< READYOK", timeout); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAnalyze(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	p := connectAnalyst(t, h, analyst)
+	defer p.Close()
+
+	updates, err := analyze(context.Background(), h, &pb.AnalysisRequest{Fen: afterE4, Depth: 10, Multipv: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 5 {
+		t.Fatalf("Expecting 4 info updates and a final update got %v", updates)
+	}
+
+	last := updates[len(updates)-1]
+	if last.GetBestmove() != "c7c5" || last.GetBestmoveSan() != "c5" || last.GetEngine() != "analyst" {
+		t.Errorf("Unexpected final update %v", last)
+	}
+	if last.GetCurrmove() != "g8f6" || last.GetCurrmoveSan() != "Nf6" {
+		t.Errorf("Expecting the current move g8f6 as Nf6 got %v %v", last.GetCurrmove(), last.GetCurrmoveSan())
+	}
+	if last.GetDepth() != 10 || last.GetSeldepth() != 14 || last.GetNodes() != 12000 || last.GetNps() != 100000 {
+		t.Errorf("Unexpected search statistics %v", last)
+	}
+
+	lines := last.GetLines()
+	if len(lines) != 2 {
+		t.Fatalf("Expecting 2 lines got %v", lines)
+	}
+	expected := []struct {
+		cp  int32
+		pv  string
+		san string
+	}{
+		{-20, "[c7c5 g1f3]", "[c5 Nf3]"},
+		{-35, "[e7e5 g1f3 b8c6]", "[e5 Nf3 Nc6]"},
+	}
+	for i, e := range expected {
+		line := lines[i]
+		if line.GetMultipv() != uint32(i+1) || line.GetScore().GetCp() != e.cp {
+			t.Errorf("Expecting line %v with score %v got %v", i+1, e.cp, line)
+		}
+		if fmt.Sprint(line.GetPv()) != e.pv || fmt.Sprint(line.GetSan()) != e.san {
+			t.Errorf("Expecting pv %v %v got %v %v", e.pv, e.san, line.GetPv(), line.GetSan())
+		}
+	}
+
+	pattern := []string{Wildcard, "< READYOK",
+		"> SETOPTION", "> UCINEWGAME", "> ISREADY", "< READYOK", "> POSITION", "> GO",
+		"< INFO", "< INFO", "< INFO", "< INFO", "< BESTMOVE", "> SETOPTION"}
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("analyst")), pattern); err != nil {
+		t.Error(err)
+	}
+
+	// The engine is back in the lobby so it can analyse again
+	if _, err := analyze(context.Background(), h, &pb.AnalysisRequest{Depth: 10}); err != nil {
+		t.Errorf("Expecting a second analysis to succeed got %v", err)
+	}
+}
+
+func TestAnalyzeCancel(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	script := analyst
+	script.Go = []fake.Reply{{Output: []string{"info depth 30 score cp 5 pv e2e4"}, BestMove: "e2e4"}}
+	p := connectAnalyst(t, h, script)
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		h.Recorder.WaitFor("analyst", "> GO", timeout)
+		cancel()
+	}()
+
+	// Without limits the engine searches until the caller goes away
+	_, err := analyze(ctx, h, &pb.AnalysisRequest{})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Expecting the analysis to be cancelled got %v", err)
+	}
+	if err := h.Recorder.WaitFor("analyst", "< BESTMOVE", timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := analyze(context.Background(), h, &pb.AnalysisRequest{Movetime: 100})
+	if err != nil {
+		t.Fatalf("Expecting the engine to analyse again got %v", err)
+	}
+	if len(updates) == 0 || updates[len(updates)-1].GetBestmove() != "e2e4" {
+		t.Errorf("Unexpected updates %v", updates)
+	}
+
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("analyst")), []string{Wildcard, "> GO", "> STOP", "< INFO", "< BESTMOVE", Wildcard}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	if _, err := analyze(context.Background(), h, &pb.AnalysisRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expecting no engine to be available got %v", err)
+	}
+
+	p := connectAnalyst(t, h, analyst)
+	defer p.Close()
+
+	for _, req := range []*pb.AnalysisRequest{
+		{Fen: "not a fen"},
+		{Moves: []string{"e2e5"}},
+		{Fen: afterE4, Searchmoves: []string{"e2e4"}},
+	} {
+		if _, err := analyze(context.Background(), h, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expecting %v to be invalid got %v", req, err)
+		}
+	}
+}
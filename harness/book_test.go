@@ -0,0 +1,91 @@
+package harness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// mateInOne is the position after 1. f3 e5 2. g4 where black mates with Qh4
+const mateInOne = "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2"
+
+func TestBook(t *testing.T) {
+	book := server.NewBook([]server.Opening{{FEN: mateInOne}, {Moves: []string{"e2e4"}}}, server.Sequential, 1)
+	h := New(server.Config{Time: 10 * time.Second, Book: book, RepeatOpenings: true})
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "d8h4"), moves("black", "d8h4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	// The opening is played with both colours
+	first, second := waitGameOver(t, h), waitGameOver(t, h)
+	expectOutcome(t, first, rules.BlackWins, rules.Checkmate)
+	expectOutcome(t, second, rules.BlackWins, rules.Checkmate)
+	if first.White != "white" || second.White != "black" {
+		t.Errorf("Expecting colours to be reversed got %v then %v with white", first.White, second.White)
+	}
+	for _, record := range []server.GameRecord{first, second} {
+		if record.FEN != mateInOne || len(record.Moves) != 1 {
+			t.Errorf("Expecting the game to start from the book got %v %v", record.FEN, record.Moves)
+		}
+	}
+
+	// Engines are sent the book position
+	for _, name := range []string{"white", "black"} {
+		for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+			msg, ok := m.Msg.(*pb.UciResponse)
+			if !ok || msg.GetMessageType() != pb.UciResponse_POSITION {
+				continue
+			}
+			if position := msg.GetPosition(); !position.GetIsFen() || position.GetFen() != mateInOne {
+				t.Errorf("Expecting %v to be sent the book position got %v", name, position)
+			}
+		}
+	}
+
+	finish(t, h, w, b)
+
+	// The next pair of engines gets the next opening
+	if next := book.Next(); next.FEN != "" || len(next.Moves) != 1 || next.Moves[0] != "e2e4" {
+		t.Errorf("Expecting the second opening got %+v", next)
+	}
+	if next := book.Next(); next.FEN != mateInOne {
+		t.Errorf("Expecting the book to start again got %+v", next)
+	}
+}
+
+func TestRandomBook(t *testing.T) {
+	var openings []server.Opening
+	for _, move := range []string{"e2e4", "d2d4", "c2c4", "g1f3", "b2b3", "f2f4"} {
+		openings = append(openings, server.Opening{Moves: []string{move}})
+	}
+
+	order := func(seed int64) []string {
+		book := server.NewBook(openings, server.Random, seed)
+		var moves []string
+		for i := 0; i < 2*book.Len(); i++ {
+			moves = append(moves, book.Next().Moves[0])
+		}
+		return moves
+	}
+
+	a, b := order(7), order(7)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expecting the same seed to give the same order got %v and %v", a, b)
+		}
+	}
+
+	// Every opening is used once before any is repeated
+	seen := make(map[string]bool)
+	for _, move := range a[:len(openings)] {
+		if seen[move] {
+			t.Errorf("Expecting %v once in the first pass got %v", move, a)
+		}
+		seen[move] = true
+	}
+}
@@ -0,0 +1,67 @@
+package harness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// castling960 is a Chess960 position where both kings can castle with the rooks on b and g
+const castling960 = "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1"
+
+// positions returns the moves of each position command sent to an engine
+func positions(h *Harness, name string) [][]string {
+	var moves [][]string
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_POSITION {
+			moves = append(moves, msg.GetPosition().GetMoves())
+		}
+	}
+	return moves
+}
+
+func TestChess960(t *testing.T) {
+	book := server.NewBook([]server.Opening{{FEN: castling960, Chess960: true}}, server.Sequential, 1)
+	h := New(server.Config{Time: 10 * time.Second, Book: book, Adjudication: server.Adjudication{MaxMoves: 2}})
+	defer h.Close()
+
+	// White plays Chess960 and castles by taking its rook, black castles by
+	// moving its king two squares
+	white := moves("white", "e1b1", "a2a3")
+	white.Options = []string{"name UCI_Chess960 type check default false"}
+	w, b := start(t, h, white, moves("black", "e8c8", "a7a6"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.Draw, server.MaxMovesAdjudication)
+	if !record.Chess960 || !reflect.DeepEqual(record.Moves, []string{"e1b1", "e8b8", "a2a3", "a7a6"}) {
+		t.Errorf("Expecting a Chess960 game with both sides castling queen side got %v", record.Moves)
+	}
+
+	for name, expected := range map[string]string{"white": "true", "black": ""} {
+		value := ""
+		for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+			if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetSetOption().GetName() == "UCI_Chess960" {
+				value = msg.GetSetOption().GetValue()
+			}
+		}
+		if value != expected {
+			t.Errorf("Expecting %v to be sent UCI_Chess960 %q got %q", name, expected, value)
+		}
+	}
+
+	// Each engine is sent the moves with castling written its way
+	if last := positions(h, "white"); !reflect.DeepEqual(last[len(last)-1], []string{"e1b1", "e8b8"}) {
+		t.Errorf("Expecting white to be sent castling as king takes rook got %v", last)
+	}
+	if last := positions(h, "black"); !reflect.DeepEqual(last[len(last)-1], []string{"e1c1", "e8c8", "a2a3"}) {
+		t.Errorf("Expecting black to be sent castling as a king move got %v", last)
+	}
+
+	finish(t, h, w, b)
+}
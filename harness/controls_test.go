@@ -0,0 +1,274 @@
+package harness
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+	"github.com/schafer14/grpc-chess/tournament"
+)
+
+// slow makes an engine's nth search take long enough for a control to arrive during it
+func slow(script fake.Script, n int) fake.Script {
+	script.Go[n].Delay = fake.Duration(200 * time.Millisecond)
+	return script
+}
+
+// startAgents connects a white and then a black agent so they are paired in that order
+func startAgents(t *testing.T, h *Harness, white, black fake.Script) (*Player, *Agent, *Player, *Agent) {
+	t.Helper()
+
+	w, wa, err := h.ConnectAgent(white, Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Recorder.WaitFor(white.Name, "< READYOK", timeout); err != nil {
+		t.Fatal(err)
+	}
+	b, ba, err := h.ConnectAgent(black, Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Recorder.WaitFor(black.Name, "> UCINEWGAME", timeout); err != nil {
+		t.Fatal(err)
+	}
+	return w, wa, b, ba
+}
+
+// waitSearches waits until the server has sent an engine n go commands
+func waitSearches(t *testing.T, h *Harness, name string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for count(h.Recorder.Sequence(h.Recorder.StreamOf(name)), "> GO") < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %v searches of %v", n, name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// expectControl waits for the server to report a control to an agent
+func expectControl(t *testing.T, a *Agent, result pb.ServerGameMessage_MessageType, control pb.ClientGameMessage_MessageType) *pb.ServerGameMessage {
+	t.Helper()
+
+	msg, err := a.WaitFor(result, control, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestResign(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, white, b, black := startAgents(t, h, moves("white", "e2e4"), slow(moves("black", "e7e5"), 0))
+	defer w.Close()
+	defer b.Close()
+
+	// White resigns while black is thinking
+	waitSearches(t, h, "black", 1)
+	white.Request(pb.ClientGameMessage_RESIGN)
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, server.Resignation)
+	if msg := expectControl(t, black, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_RESIGN); msg.GetSide() != "white" || msg.GetMove() != "e2e4" || msg.GetSan() != "e4" {
+		t.Errorf("Expecting black to be told white resigned after e4 got %v", msg)
+	}
+	expectControl(t, white, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_RESIGN)
+	if record.Clocks[rules.Black] >= config.Time || record.Clocks[rules.White] > config.Time {
+		t.Errorf("Expecting black's clock to have run got %v", record.Clocks)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestDrawOffer(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, white, b, black := startAgents(t, h, moves("white", "e2e4"), slow(moves("black", "e7e5"), 0))
+	defer w.Close()
+	defer b.Close()
+
+	waitSearches(t, h, "black", 1)
+	black.Request(pb.ClientGameMessage_ACCEPT_DRAW)
+	msg := expectControl(t, black, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_ACCEPT_DRAW)
+	if msg.GetReason() != "The opponent has not offered a draw" {
+		t.Errorf("Expecting accepting a draw that was not offered to be rejected got %v", msg)
+	}
+	if len(white.Received()) != 0 {
+		t.Errorf("Expecting white not to be told of a rejected control got %v", white.Received())
+	}
+
+	white.Request(pb.ClientGameMessage_OFFER_DRAW)
+	expectControl(t, black, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_OFFER_DRAW)
+	white.Request(pb.ClientGameMessage_OFFER_DRAW)
+	expectControl(t, white, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_OFFER_DRAW)
+	black.Request(pb.ClientGameMessage_ACCEPT_DRAW)
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.Draw, server.DrawAgreement)
+	if !reflect.DeepEqual(record.Moves, []string{"e2e4"}) {
+		t.Errorf("Expecting the draw before black moved got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestTakeback(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	// Black's first search is stopped when white takes e2e4 back
+	w, white, b, black := startAgents(t, h, moves("white", "e2e4", "f2f3", "g2g4"), slow(moves("black", "e7e6", "e7e5", "d8h4"), 0))
+	defer w.Close()
+	defer b.Close()
+
+	// Black has no move to take back
+	waitSearches(t, h, "black", 1)
+	black.Request(pb.ClientGameMessage_REQUEST_TAKEBACK)
+	expectControl(t, black, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_REQUEST_TAKEBACK)
+
+	white.Request(pb.ClientGameMessage_REQUEST_TAKEBACK)
+	expectControl(t, black, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_REQUEST_TAKEBACK)
+	black.Request(pb.ClientGameMessage_ACCEPT_TAKEBACK)
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if !reflect.DeepEqual(record.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting e2e4 to be taken back got %v", record.Moves)
+	}
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("black")), []string{Wildcard, "< GAME_CONTROL", "> GAME_CONTROL", "> STOP", "< BESTMOVE", "> POSITION", "> GO", Wildcard}); err != nil {
+		t.Error(err)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestAbort(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, white, b, _ := startAgents(t, h, slow(moves("white", "e2e4"), 0), moves("black", "e7e5"))
+	defer w.Close()
+	defer b.Close()
+
+	waitSearches(t, h, "white", 1)
+	white.Request(pb.ClientGameMessage_ABORT)
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.NoResult, server.Aborted)
+
+	finish(t, h, w, b)
+}
+
+func TestPause(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	// White's first search is stopped by the pause and its move discarded
+	w, white, b, _ := startAgents(t, h, slow(moves("white", "a2a3", "f2f3", "g2g4"), 0), moves("black", "e7e5", "d8h4"))
+	defer w.Close()
+	defer b.Close()
+
+	white.Request(pb.ClientGameMessage_RESUME)
+	expectControl(t, white, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_RESUME)
+
+	waitSearches(t, h, "white", 1)
+	white.Request(pb.ClientGameMessage_PAUSE)
+	expectControl(t, white, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_PAUSE)
+	white.Request(pb.ClientGameMessage_PAUSE)
+	expectControl(t, white, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_PAUSE)
+	if err := h.Recorder.WaitFor("white", "< BESTMOVE", timeout); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("white")), "> GO"); n != 1 {
+		t.Errorf("Expecting no search while the game is paused got %v", n)
+	}
+	white.Request(pb.ClientGameMessage_RESUME)
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if !reflect.DeepEqual(record.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting the move of the paused search to be discarded got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestAdjournment(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	w, white, err := h.ConnectAgent(slow(moves("white", "f2f3", "g2g4"), 1), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	b, err := h.Connect(moves("black", "e7e5", "d8h4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	for _, name := range []string{"white", "black"} {
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := tournament.Config{Name: "adjourned", Kind: tournament.Gauntlet, Engines: []string{"white", "black"}}
+	games := store.NewMemory()
+	errs := make(chan error)
+	go func() {
+		_, err := tournament.Run(context.Background(), c, h.Scheduler, games, logrus.NewEntry(logrus.New()))
+		errs <- err
+	}()
+
+	// Aborting is too late once both sides have moved
+	waitSearches(t, h, "white", 2)
+	white.Request(pb.ClientGameMessage_ABORT)
+	expectControl(t, white, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_ABORT)
+	white.Request(pb.ClientGameMessage_ADJOURN)
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "adjourned") {
+		t.Fatalf("Expecting the tournament to stop at the adjourned game got %v", err)
+	}
+
+	saved, _ := games.Tournament("adjourned")
+	if len(saved) != 1 || saved[0].Termination != string(server.Adjourned) || !reflect.DeepEqual(saved[0].Moves, []string{"f2f3", "e7e5"}) {
+		t.Fatalf("Expecting the adjourned game in the store got %+v", saved)
+	}
+	if clock := saved[0].BlackClock; clock <= 0 || clock > 10*time.Second {
+		t.Errorf("Expecting black's clock to be kept got %v", clock)
+	}
+
+	// The search adjournment cut short still answers
+	deadline := time.Now().Add(timeout)
+	for count(h.Recorder.Sequence(h.Recorder.StreamOf("white")), "< BESTMOVE") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for white's best move")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Running the tournament again resumes the game
+	if _, err := tournament.Run(context.Background(), c, h.Scheduler, games, logrus.NewEntry(logrus.New())); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ = games.Tournament("adjourned")
+	if len(saved) != 1 || saved[0].Result != "0-1" || !reflect.DeepEqual(saved[0].Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting the resumed game to finish got %+v", saved)
+	}
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,216 @@
+package harness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// correspondence starts correspondence games for the harness' engines
+func correspondence(t *testing.T, h *Harness, config server.CorrespondenceConfig) *server.Correspondence {
+	t.Helper()
+
+	c, err := server.NewCorrespondence(logrus.NewEntry(h.Logger), h.Scheduler, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitMoves waits until n moves have been played in a correspondence game
+func waitMoves(t *testing.T, c *server.Correspondence, id string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		state, _ := c.State(id)
+		if len(state.GetMoves()) >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %v moves in %v got %v", n, id, state.GetMoves())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCorrespondence(t *testing.T) {
+	h := New(server.Config{NoPairing: true})
+	defer h.Close()
+
+	games := store.NewMemory()
+	c := correspondence(t, h, server.CorrespondenceConfig{PerMove: time.Hour, MoveTime: 10 * time.Millisecond, Store: games})
+	defer c.Close()
+
+	// The game starts before either engine is connected
+	id, err := c.Start("white", "black", server.Opening{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, white, err := h.ConnectAgent(moves("white", "f2f3", "g2g4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	state, err := white.WaitForState(pb.ServerGameMessage_YOUR_MOVE, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GetId() != id || state.GetTurn() != "white" || state.GetDeadline() <= time.Now().Unix() {
+		t.Errorf("Expecting white to be told it is its move got %v", state)
+	}
+
+	b, err := h.Connect(moves("black", "e7e5", "d8h4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	saved, _, _ := games.Get(id)
+	if saved.Result != "0-1" || !reflect.DeepEqual(saved.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting the finished game in the store got %+v", saved)
+	}
+
+	// White is told the move black played in both notations
+	var notified [][2]string
+	for _, msg := range white.Received() {
+		if msg.GetMessageType() == pb.ServerGameMessage_YOUR_MOVE {
+			notified = append(notified, [2]string{msg.GetMove(), msg.GetSan()})
+		}
+	}
+	if !reflect.DeepEqual(notified, [][2]string{{"", ""}, {"e7e5", "e5"}}) {
+		t.Errorf("Expecting no move before the first and e7e5 as e5 after it got %v", notified)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestCorrespondenceTimeout(t *testing.T) {
+	h := New(server.Config{NoPairing: true})
+	defer h.Close()
+
+	games := store.NewMemory()
+	first := correspondence(t, h, server.CorrespondenceConfig{PerMove: 100 * time.Millisecond, Store: games})
+	id, err := first.Start("white", "black", server.Opening{Moves: []string{"e2e4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	// The deadline is enforced by the games picked up from the store without either engine connected
+	c := correspondence(t, h, server.CorrespondenceConfig{Store: games})
+	defer c.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, server.TimeForfeit)
+	if state, _ := c.State(id); state.GetResult() != "1-0" || state.GetDeadline() != 0 {
+		t.Errorf("Expecting black to lose on time got %v", state)
+	}
+}
+
+func TestCorrespondenceReconnect(t *testing.T) {
+	h := New(server.Config{NoPairing: true})
+	defer h.Close()
+
+	c := correspondence(t, h, server.CorrespondenceConfig{PerMove: time.Hour, MoveTime: 10 * time.Millisecond})
+	defer c.Close()
+	id, err := c.Start("white", "black", server.Opening{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// White moves and disconnects before black moves
+	w, err := h.Connect(moves("white", "f2f3"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitMoves(t, c, id, 1)
+	w.Close()
+
+	b, err := h.Connect(moves("black", "e7e5", "d8h4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	waitMoves(t, c, id, 2)
+
+	// White picks the game up once it is back
+	w, white, err := h.ConnectAgent(moves("white", "g2g4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	state, err := white.WaitForState(pb.ServerGameMessage_YOUR_MOVE, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.GetMoves(), []string{"f2f3", "e7e5"}) {
+		t.Errorf("Expecting white to be told it is its move after e7e5 got %v", state)
+	}
+	white.RequestGame(pb.ClientGameMessage_GAME_STATE_REQUEST, "")
+	if state, err = white.WaitForState(pb.ServerGameMessage_GAME_STATE_RESPONSE, timeout); err != nil {
+		t.Fatal(err)
+	}
+	if state.GetId() != id || state.GetWhite() != "white" || state.GetBlack() != "black" || len(state.GetMoves()) < 2 {
+		t.Errorf("Expecting the state of the game got %v", state)
+	}
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+
+	finish(t, h, w, b)
+}
+
+func TestPremove(t *testing.T) {
+	h := New(server.Config{NoPairing: true})
+	defer h.Close()
+
+	c := correspondence(t, h, server.CorrespondenceConfig{PerMove: time.Hour, MoveTime: 10 * time.Millisecond})
+	defer c.Close()
+	id, err := c.Start("white", "black", server.Opening{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, white, err := h.ConnectAgent(moves("white", "f2f3"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	waitMoves(t, c, id, 1)
+
+	// White answers e7e5 with g2g4 without being asked
+	white.RequestGame(pb.ClientGameMessage_PREMOVE, id, "e7e5", "a1a1")
+	expectControl(t, white, pb.ServerGameMessage_REJECTED, pb.ClientGameMessage_PREMOVE)
+	white.RequestGame(pb.ClientGameMessage_PREMOVE, id, "e7e5", "g2g4")
+	if msg := expectControl(t, white, pb.ServerGameMessage_CONTROL, pb.ClientGameMessage_PREMOVE); msg.GetSide() != "white" {
+		t.Errorf("Expecting white's premove to be accepted got %v", msg)
+	}
+
+	b, err := h.Connect(moves("black", "e7e5", "d8h4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if !reflect.DeepEqual(record.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting the premove to be played got %v", record.Moves)
+	}
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("white")), "> GO"); n != 1 {
+		t.Errorf("Expecting white to search once got %v", n)
+	}
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,149 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/daemon"
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+// startDaemon runs a daemon on the harness that starts the script named by
+// the path of each engine. It returns how many processes of an engine were
+// started and a function stopping the daemon.
+func startDaemon(t *testing.T, h *Harness, config daemon.Config, scripts ...fake.Script) (func(name string) int, func()) {
+	t.Helper()
+
+	var mu sync.Mutex
+	starts := make(map[string]int)
+	config.Dial = h.Dial
+	config.Start = func(e daemon.EngineConfig, _ *transcript.Writer) (cli.Engine, error) {
+		mu.Lock()
+		starts[e.Path]++
+		mu.Unlock()
+		for _, script := range scripts {
+			if script.Name == e.Path {
+				return startEngine(script), nil
+			}
+		}
+		return nil, fmt.Errorf("No script for %v", e.Path)
+	}
+
+	d, err := daemon.New(logrus.NewEntry(h.Logger), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+
+	count := func(name string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return starts[name]
+	}
+	stop := func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			t.Error("Timed out waiting for the daemon to stop")
+		}
+	}
+	return count, stop
+}
+
+// waitCapacity waits until the pool has n connections of an engine
+func waitCapacity(t *testing.T, h *Harness, name string, n int) server.EngineInfo {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, e := range h.Scheduler.Engines() {
+			if e.Name == name && e.Capacity == n {
+				return e
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %v connections of %v got %v", n, name, h.Scheduler.Engines())
+	return server.EngineInfo{}
+}
+
+func TestDaemon(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	white := moves("white", "f2f3", "g2g4")
+	white.Options = []string{"name Threads type spin default 1 min 1 max 8", "name Hash type spin default 16 min 1 max 1024", "name Ponder type check default false"}
+	_, stop := startDaemon(t, h, daemon.Config{
+		Threads: 8,
+		Engines: []daemon.EngineConfig{
+			{Path: "white", Instances: 2, Threads: 2, Options: map[string]interface{}{"hash": 64}},
+			{Path: "black", Name: "black-dev", Instances: 2},
+		},
+	}, white, moves("black", "e7e5", "d8h4"))
+	defer stop()
+
+	// The options the daemon set are hidden from the server
+	if e := waitCapacity(t, h, "white", 2); len(e.Options) != 1 || e.Options[0].GetName() != "Ponder" {
+		t.Errorf("Expecting only Ponder to be advertised got %v", e.Options)
+	}
+	waitCapacity(t, h, "black-dev", 2)
+
+	// Both games are played at once
+	records := make(chan server.GameRecord, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			record, err := h.Scheduler.Play(context.Background(), server.Game{White: "white", Black: "black-dev"})
+			if err != nil {
+				t.Error(err)
+			}
+			records <- record
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		expectOutcome(t, <-records, rules.BlackWins, rules.Checkmate)
+	}
+}
+
+func TestDaemonThreadBudget(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	starts, stop := startDaemon(t, h, daemon.Config{
+		Threads: 2,
+		Retry:   daemon.Duration{Duration: 10 * time.Millisecond},
+		Engines: []daemon.EngineConfig{{Path: "solo", Instances: 3}},
+	}, moves("solo", "e2e4"))
+	defer stop()
+
+	// The third instance waits for threads to be free
+	waitCapacity(t, h, "solo", 2)
+	time.Sleep(50 * time.Millisecond)
+	if n := starts("solo"); n != 2 {
+		t.Errorf("Expecting 2 processes within the budget got %v", n)
+	}
+
+	// New processes are started once the server tells the engines to quit
+	h.Scheduler.Close()
+	deadline := time.Now().Add(timeout)
+	for starts("solo") < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for new processes got %v", starts("solo"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
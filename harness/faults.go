@@ -0,0 +1,56 @@
+package harness
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Faults describes failures injected into a client connection
+type Faults struct {
+	// Delay is added before every message the client sends or receives
+	Delay time.Duration
+	// DisconnectAfter drops the stream once the client has sent and received
+	// this many messages in total, zero never disconnects
+	DisconnectAfter int
+}
+
+// intercept is a grpc.StreamClientInterceptor injecting the faults into the stream
+func (f Faults) intercept(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &faultyStream{ClientStream: stream, faults: f, cancel: cancel}, nil
+}
+
+type faultyStream struct {
+	grpc.ClientStream
+	faults Faults
+	cancel context.CancelFunc
+	count  int64
+}
+
+// before is called before every message, delaying it or dropping the stream
+func (s *faultyStream) before() {
+	time.Sleep(s.faults.Delay)
+
+	count := atomic.AddInt64(&s.count, 1)
+	if s.faults.DisconnectAfter > 0 && count > int64(s.faults.DisconnectAfter) {
+		s.cancel()
+	}
+}
+
+func (s *faultyStream) SendMsg(m interface{}) error {
+	s.before()
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *faultyStream) RecvMsg(m interface{}) error {
+	s.before()
+	return s.ClientStream.RecvMsg(m)
+}
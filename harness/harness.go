@@ -0,0 +1,217 @@
+// Package harness runs the chess service and scripted engine clients in process
+// over an in-memory gRPC connection so whole games can be tested end to end
+package harness
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/engine/fake"
+	engine "github.com/schafer14/grpc-chess/engine/uci"
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+const bufferSize = 1024 * 1024
+
+// Harness is a chess service listening on an in-memory connection
+type Harness struct {
+	// Recorder holds every message exchanged on the UCI streams
+	Recorder *Recorder
+	// Logger is used by the service and the clients
+	Logger *logrus.Logger
+	// Scheduler holds the pool of connected engines
+	Scheduler *server.Scheduler
+
+	listener *bufconn.Listener
+	server   *grpc.Server
+
+	mu      sync.Mutex
+	records []server.GameRecord
+	over    chan server.GameRecord
+}
+
+// New starts the chess service on an in-memory listener
+func New(config server.Config) *Harness {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	h := &Harness{
+		Recorder: &Recorder{},
+		Logger:   logger,
+		listener: bufconn.Listen(bufferSize),
+		over:     make(chan server.GameRecord, 16),
+	}
+
+	onGameOver := config.OnGameOver
+	config.OnGameOver = func(record server.GameRecord) {
+		if onGameOver != nil {
+			onGameOver(record)
+		}
+		h.mu.Lock()
+		h.records = append(h.records, record)
+		h.mu.Unlock()
+
+		select {
+		case h.over <- record:
+		default:
+		}
+	}
+
+	h.server = grpc.NewServer(grpc.StreamInterceptor(h.Recorder.intercept))
+	h.Scheduler = server.NewScheduler(logrus.NewEntry(logger), config)
+	pb.RegisterChessApplicationServer(h.server, server.NewChessService(*logrus.NewEntry(logger), h.Scheduler))
+	go h.server.Serve(h.listener)
+
+	return h
+}
+
+// Dial creates a client connection to the service
+func (h *Harness) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return h.listener.Dial()
+	}
+	opts = append([]grpc.DialOption{grpc.WithContextDialer(dialer), grpc.WithInsecure()}, opts...)
+	return grpc.Dial("bufconn", opts...)
+}
+
+// GameOver returns a channel receiving the record of each game as it finishes
+func (h *Harness) GameOver() <-chan server.GameRecord {
+	return h.over
+}
+
+// Records returns the records of all finished games
+func (h *Harness) Records() []server.GameRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]server.GameRecord(nil), h.records...)
+}
+
+// Close tells the engines to quit and stops the service
+func (h *Harness) Close() {
+	h.Scheduler.Close()
+	h.server.Stop()
+	h.listener.Close()
+}
+
+// Player is a chess client connected to the harness and backed by a scripted engine
+type Player struct {
+	// Engine is the driver for the scripted engine
+	Engine cli.Engine
+
+	conn *grpc.ClientConn
+	done chan struct{}
+	err  error
+
+	mu sync.Mutex
+	// cancels the client's latest stream
+	cancel context.CancelFunc
+}
+
+// Connect starts a scripted engine and a chess client that joins the pool and requests a game
+func (h *Harness) Connect(script fake.Script, faults Faults) (*Player, error) {
+	return h.ConnectWithBook(script, faults, nil)
+}
+
+// ConnectWithBook connects a scripted engine with a client that answers go
+// from the book before asking the engine
+func (h *Harness) ConnectWithBook(script fake.Script, faults Faults, book *polyglot.Book) (*Player, error) {
+	return h.connect(startEngine(script), script.Name, faults, book)
+}
+
+// ConnectAgent connects a scripted engine the test also sends game controls through
+func (h *Harness) ConnectAgent(script fake.Script, faults Faults) (*Player, *Agent, error) {
+	agent := newAgent(startEngine(script))
+	p, err := h.connect(agent, script.Name, faults, nil)
+	return p, agent, err
+}
+
+func (h *Harness) connect(e cli.Engine, name string, faults Faults, book *polyglot.Book) (*Player, error) {
+	p := &Player{Engine: e, done: make(chan struct{})}
+	conn, err := h.Dial(grpc.WithStreamInterceptor(p.track(faults.intercept)))
+	if err != nil {
+		return nil, err
+	}
+	p.conn = conn
+
+	c := cli.NewWithBook(p.Engine, book, *h.Logger.WithField("engine", name), pb.NewChessApplicationClient(conn))
+
+	go func() {
+		defer close(p.done)
+		p.err = c.NewGameRequest()
+	}()
+
+	return p, nil
+}
+
+// Done is closed when the client has been told to quit or lost its connection
+func (p *Player) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the error the client finished with, it is only valid after Done is closed
+func (p *Player) Err() error {
+	return p.err
+}
+
+// track wraps a stream interceptor to keep the cancel of the latest stream
+func (p *Player) track(intercept grpc.StreamClientInterceptor) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		stream, err := intercept(ctx, desc, cc, method, streamer, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		p.mu.Lock()
+		p.cancel = cancel
+		p.mu.Unlock()
+		return stream, nil
+	}
+}
+
+// Drop drops the client's stream the way a network failure would, the
+// client reconnects when the server gave it a session
+func (p *Player) Drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// Disconnect closes the client connection so the client can not reconnect
+func (p *Player) Disconnect() {
+	p.conn.Close()
+}
+
+// Close stops the engine and closes the client connection
+func (p *Player) Close() {
+	p.Engine.Close()
+	p.conn.Close()
+}
+
+// startEngine runs a scripted engine in process connected to a UCI driver
+func startEngine(script fake.Script) cli.Engine {
+	commands, commandWriter := io.Pipe()
+	output, outputWriter := io.Pipe()
+
+	go func() {
+		err := fake.Run(script, commands, outputWriter)
+		// a crashed engine stops reading commands as well as writing output
+		commands.CloseWithError(io.ErrClosedPipe)
+		outputWriter.CloseWithError(err)
+	}()
+
+	return engine.NewFromPipes(output, commandWriter)
+}
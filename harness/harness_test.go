@@ -0,0 +1,464 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+const timeout = 10 * time.Second
+
+var config = server.Config{Time: 10 * time.Second}
+
+func moves(name string, moves ...string) fake.Script {
+	script := fake.Script{Name: name, Author: "harness"}
+	for _, move := range moves {
+		script.Go = append(script.Go, fake.Reply{BestMove: move})
+	}
+	return script
+}
+
+// start connects a white and then a black engine so they are paired in that order
+func start(t *testing.T, h *Harness, white, black fake.Script, whiteFaults, blackFaults Faults) (*Player, *Player) {
+	t.Helper()
+
+	w, err := h.Connect(white, whiteFaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Recorder.WaitFor(white.Name, "< READYOK", timeout); err != nil {
+		t.Fatal(err)
+	}
+	b, err := h.Connect(black, blackFaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Recorder.WaitFor(black.Name, "> UCINEWGAME", timeout); err != nil {
+		t.Fatal(err)
+	}
+	return w, b
+}
+
+func waitGameOver(t *testing.T, h *Harness) server.GameRecord {
+	t.Helper()
+
+	select {
+	case record := <-h.GameOver():
+		return record
+	case <-time.After(timeout):
+		t.Fatal("Timed out waiting for the game to finish")
+	}
+	return server.GameRecord{}
+}
+
+func waitDone(t *testing.T, players ...*Player) {
+	t.Helper()
+
+	for _, p := range players {
+		select {
+		case <-p.Done():
+		case <-time.After(timeout):
+			t.Fatal("Timed out waiting for the client to finish")
+		}
+	}
+}
+
+// waitIdle waits for every engine in the pool to be without a job
+func waitIdle(t *testing.T, h *Harness) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		idle := true
+		for _, e := range h.Scheduler.Engines() {
+			idle = idle && e.Idle == e.Capacity
+		}
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the engines to be idle")
+}
+
+// finish waits for the engines to return to the pool, tells them to quit and
+// waits for the clients to finish
+func finish(t *testing.T, h *Harness, players ...*Player) {
+	t.Helper()
+
+	waitIdle(t, h)
+	h.Scheduler.Close()
+	waitDone(t, players...)
+}
+
+func expectOutcome(t *testing.T, record server.GameRecord, result rules.Result, termination rules.Termination) {
+	t.Helper()
+
+	if record.Outcome.Result != result || record.Outcome.Termination != termination {
+		t.Errorf("Expecting %v by %v got %v", result, termination, record.Outcome)
+	}
+}
+
+func TestGameSequence(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	white := moves("white", "f2f3", "g2g4")
+	white.Options = []string{"name Hash type spin default 16 min 1 max 1024"}
+	black := moves("black", "e7e5", "d8h4")
+
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if record.White != "white" || record.Black != "black" {
+		t.Errorf("Unexpected players %v vs %v", record.White, record.Black)
+	}
+
+	finish(t, h, w, b)
+	if w.Err() != nil || b.Err() != nil {
+		t.Errorf("Expecting clients to finish cleanly got %v and %v", w.Err(), b.Err())
+	}
+
+	handshake := []string{"> UCI", "< ID", Wildcard, "< UCIOK", "> SETOPTION", "> ISREADY", "< READYOK", "> UCINEWGAME", "> ISREADY", "< READYOK"}
+	move := []string{"> POSITION", "> GO", "< BESTMOVE"}
+
+	whitePattern := append([]string{}, handshake...)
+	whitePattern = append(whitePattern, move...)
+	whitePattern = append(whitePattern, move...)
+	whitePattern = append(whitePattern, "> QUIT")
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("white")), whitePattern); err != nil {
+		t.Error(err)
+	}
+
+	blackPattern := append([]string{}, handshake...)
+	blackPattern = append(blackPattern, move...)
+	blackPattern = append(blackPattern, move...)
+	blackPattern = append(blackPattern, "> QUIT")
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("black")), blackPattern); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRepetition(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	white := moves("white", "g1f3", "f3g1", "g1f3", "f3g1")
+	black := moves("black", "g8f6", "f6g8", "g8f6", "f6g8")
+
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.Draw, rules.ThreefoldRepetition)
+	if len(record.Moves) != 8 {
+		t.Errorf("Expecting 8 moves got %v", record.Moves)
+	}
+}
+
+func TestIllegalMove(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "e2e4"), moves("black", "e2e4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.WhiteWins, server.IllegalMove)
+	finish(t, h, w, b)
+}
+
+func TestTimeForfeit(t *testing.T) {
+	h := New(server.Config{Time: 100 * time.Millisecond})
+	defer h.Close()
+
+	white := fake.Script{Name: "white", Go: []fake.Reply{{Delay: fake.Duration(300 * time.Millisecond), BestMove: "e2e4"}}}
+	w, b := start(t, h, white, moves("black", "e7e5"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.BlackWins, server.TimeForfeit)
+	finish(t, h, w, b)
+}
+
+func TestEngineCrash(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	black := fake.Script{Name: "black", Go: []fake.Reply{{Crash: true, ExitCode: 1}}}
+	w, b := start(t, h, moves("white", "e2e4"), black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.WhiteWins, server.Disconnection)
+	finish(t, h, w, b)
+	if b.Err() == nil {
+		t.Error("Expecting the client of the crashed engine to fail")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "g1f3", "f3g1"), moves("black", "g8f6", "f6g8"), Faults{}, Faults{DisconnectAfter: 9})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.WhiteWins, server.Disconnection)
+	finish(t, h, w, b)
+	if w.Err() != nil {
+		t.Errorf("Expecting the connected client to finish cleanly got %v", w.Err())
+	}
+	if b.Err() == nil {
+		t.Error("Expecting the disconnected client to fail")
+	}
+}
+
+func TestDelays(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	delay := Faults{Delay: 2 * time.Millisecond}
+	w, b := start(t, h, moves("white", "f2f3", "g2g4"), moves("black", "e7e5", "d8h4"), delay, delay)
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.BlackWins, rules.Checkmate)
+	finish(t, h, w, b)
+}
+
+func TestConcurrentGames(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	const games = 4
+	for i := 0; i < games; i++ {
+		white := moves(fmt.Sprintf("white-%v", i), "f2f3", "g2g4")
+		white.Go[0].Delay = fake.Duration(20 * time.Millisecond)
+		black := moves(fmt.Sprintf("black-%v", i), "e7e5", "d8h4")
+
+		w, b := start(t, h, white, black, Faults{}, Faults{})
+		defer w.Close()
+		defer b.Close()
+	}
+
+	for i := 0; i < games; i++ {
+		expectOutcome(t, waitGameOver(t, h), rules.BlackWins, rules.Checkmate)
+	}
+}
+
+var ponderOption = "name Ponder type check default false"
+
+// ponders returns the moves each go ponder command sent on a stream was pondering on
+func ponders(h *Harness, name string) []string {
+	var moves []string
+	var position []string
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+		msg, ok := m.Msg.(*pb.UciResponse)
+		if !ok {
+			continue
+		}
+		switch msg.GetMessageType() {
+		case pb.UciResponse_POSITION:
+			position = msg.GetPosition().GetMoves()
+		case pb.UciResponse_GO:
+			if msg.GetGo().GetIsPonder() {
+				moves = append(moves, position[len(position)-1])
+			}
+		}
+	}
+	return moves
+}
+
+func TestPonderHit(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Ponder: true})
+	defer h.Close()
+
+	white := fake.Script{Name: "white", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "f2f3", Ponder: "e7e5"},
+		{BestMove: "g2g4", Ponder: "d8h4"},
+	}}
+	black := fake.Script{Name: "black", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "e7e5", Ponder: "g2g4"},
+		{BestMove: "d8h4"},
+	}}
+
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.BlackWins, rules.Checkmate)
+	finish(t, h, w, b)
+
+	handshake := []string{"> UCI", "< ID", Wildcard, "< UCIOK", "> SETOPTION", "> ISREADY", "< READYOK", "> SETOPTION", "> UCINEWGAME", "> ISREADY", "< READYOK"}
+	whitePattern := append(append([]string{}, handshake...),
+		"> POSITION", "> GO", "< BESTMOVE",
+		"> POSITION", "> GO", "> PONDERHIT", "< BESTMOVE",
+		"> POSITION", "> GO", "> STOP", Wildcard, "> QUIT")
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("white")), whitePattern); err != nil {
+		t.Error(err)
+	}
+	blackPattern := append(append([]string{}, handshake...),
+		"> POSITION", "> GO", "< BESTMOVE",
+		"> POSITION", "> GO", "> PONDERHIT", "< BESTMOVE", "> QUIT")
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("black")), blackPattern); err != nil {
+		t.Error(err)
+	}
+
+	if moves := ponders(h, "white"); fmt.Sprint(moves) != "[e7e5 d8h4]" {
+		t.Errorf("Expecting white to ponder on e7e5 and d8h4 got %v", moves)
+	}
+	if moves := ponders(h, "black"); fmt.Sprint(moves) != "[g2g4]" {
+		t.Errorf("Expecting black to ponder on g2g4 got %v", moves)
+	}
+}
+
+func TestPonderMiss(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Ponder: true})
+	defer h.Close()
+
+	// The stopped ponder search answers a2a3 which must not be played
+	white := fake.Script{Name: "white", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "f2f3", Ponder: "d7d5"},
+		{BestMove: "a2a3"},
+		{BestMove: "g2g4"},
+	}}
+	black := moves("black", "e7e5", "d8h4")
+
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if fmt.Sprint(record.Moves) != "[f2f3 e7e5 g2g4 d8h4]" {
+		t.Errorf("Unexpected moves %v", record.Moves)
+	}
+	finish(t, h, w, b)
+
+	whitePattern := []string{Wildcard,
+		"> POSITION", "> GO", "< BESTMOVE",
+		"> POSITION", "> GO", "> STOP", "< BESTMOVE",
+		"> POSITION", "> GO", "< BESTMOVE", "> QUIT"}
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("white")), whitePattern); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPonderAtGameEnd(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Ponder: true, NoPairing: true})
+	defer h.Close()
+
+	// White is still pondering when black mates so its stopped search answers
+	// after the game is over
+	white := fake.Script{Name: "white", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "g2g4", Ponder: "a7a6"}, {BestMove: "b2b3"},
+		{BestMove: "g2g4", Ponder: "a7a6"}, {BestMove: "b2b3"},
+	}}
+	black := moves("black", "d8h4", "d8h4")
+	var players []*Player
+	for _, script := range []fake.Script{white, black} {
+		p, err := h.Connect(script, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(script.Name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	for i := 0; i < 2; i++ {
+		record, err := h.Scheduler.Play(context.Background(), server.Game{
+			White:   "white",
+			Black:   "black",
+			Opening: server.Opening{Moves: []string{"f2f3", "e7e5"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+		if len(record.Violations) != 0 {
+			t.Errorf("Expecting game %v to have no violations got %v", i+1, record.Violations)
+		}
+	}
+
+	finish(t, h, players...)
+}
+
+func TestPonderTime(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Ponder: true})
+	defer h.Close()
+
+	white := fake.Script{Name: "white", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "g1f3", Ponder: "g8f6"},
+		{BestMove: "f3g1", Ponder: "f6g8"},
+		{BestMove: "g1f3", Ponder: "g8f6"},
+		{BestMove: "f3g1", Ponder: "f6g8"},
+	}}
+	black := moves("black", "g8f6", "f6g8", "g8f6", "f6g8")
+	for i := range black.Go {
+		black.Go[i].Delay = fake.Duration(100 * time.Millisecond)
+	}
+
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.Draw, rules.ThreefoldRepetition)
+	finish(t, h, w, b)
+	if hits := len(ponders(h, "white")); hits != 4 {
+		t.Errorf("Expecting white to ponder 4 times got %v", hits)
+	}
+
+	// The time white spends pondering is black's, so only black's clock runs down
+	var last *pb.UciResponse_Go
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("black")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_GO {
+			last = msg.GetGo()
+		}
+	}
+	if last.GetWtime() < 9900 {
+		t.Errorf("Expecting white's pondering not to be charged got %vms left", last.GetWtime())
+	}
+	if last.GetBtime() > 9700 {
+		t.Errorf("Expecting black's searches to be charged got %vms left", last.GetBtime())
+	}
+}
+
+func TestPonderDisabled(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	white := fake.Script{Name: "white", Options: []string{ponderOption}, Go: []fake.Reply{
+		{BestMove: "f2f3", Ponder: "e7e5"},
+		{BestMove: "g2g4", Ponder: "d8h4"},
+	}}
+	w, b := start(t, h, white, moves("black", "e7e5", "d8h4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.BlackWins, rules.Checkmate)
+	finish(t, h, w, b)
+	if moves := ponders(h, "white"); len(moves) != 0 {
+		t.Errorf("Expecting white not to ponder got %v", moves)
+	}
+
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("white")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetSetOption().GetName() == "Ponder" && msg.GetSetOption().GetValue() != "false" {
+			t.Errorf("Expecting pondering to be switched off got %v", msg.GetSetOption())
+		}
+	}
+}
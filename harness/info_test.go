@@ -0,0 +1,96 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// anomalies returns the anomalies counted for an engine
+func anomalies(h *Harness, name string) map[server.Anomaly]int {
+	for _, e := range h.Scheduler.Engines() {
+		if e.Name == name {
+			return e.Anomalies
+		}
+	}
+	return nil
+}
+
+func TestInfoValidation(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	script := analyst
+	script.Go = []fake.Reply{{
+		Output: []string{
+			// the king can not reach e8
+			"info depth 5 score cp 20 pv c7c5 g1f3 e1e8",
+			// the hash can not be more than full
+			"info depth 6 hashfull 2000 score cp 500 pv e7e5",
+			// there is no mate in one
+			"info depth 7 score mate 1 pv c7c5",
+			"info depth 7 refutation e7e5 g1f3 a1a8",
+		},
+		BestMove: "c7c5",
+	}}
+	p := connectAnalyst(t, h, script)
+	defer p.Close()
+
+	updates, err := analyze(context.Background(), h, &pb.AnalysisRequest{Fen: afterE4, Depth: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("Expecting 2 info updates and a final update got %v", updates)
+	}
+	line := updates[0].GetLines()[0]
+	if fmt.Sprint(line.GetPv()) != "[c7c5 g1f3]" || line.GetScore().GetCp() != -20 {
+		t.Errorf("Expecting the PV cut before the illegal move got %v", line)
+	}
+	line = updates[len(updates)-1].GetLines()[0]
+	if line.GetScore().GetMate() != 0 || line.GetScore().GetCp() != -20 || fmt.Sprint(line.GetPv()) != "[c7c5]" {
+		t.Errorf("Expecting the mate score to be dropped got %v", line)
+	}
+
+	expected := map[server.Anomaly]int{server.IllegalPV: 1, server.MalformedInfo: 1, server.ImpossibleMate: 1, server.IllegalRefutation: 1}
+	if counts := anomalies(h, "analyst"); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expecting anomalies %v got %v", expected, counts)
+	}
+
+	finish(t, h, p)
+}
+
+func TestMatchInfoValidation(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	white := moves("white", "f2f3", "g2g4")
+	white.Go[0].Output = []string{"info depth 3 score cp 10 pv f2f3 e7e5 e1e8"}
+	black := moves("black", "e7e5", "d8h4")
+	black.Go[1].Output = []string{
+		"info depth 1 score mate 1 pv d8h4",
+		// the PV mates in one
+		"info depth 2 score mate 3 pv d8h4",
+	}
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+
+	if counts := anomalies(h, "white"); !reflect.DeepEqual(counts, map[server.Anomaly]int{server.IllegalPV: 1}) {
+		t.Errorf("Expecting an illegal PV from white got %v", counts)
+	}
+	if counts := anomalies(h, "black"); !reflect.DeepEqual(counts, map[server.Anomaly]int{server.ImpossibleMate: 1}) {
+		t.Errorf("Expecting an impossible mate from black got %v", counts)
+	}
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,81 @@
+package harness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+)
+
+// lineBook returns a book playing a line of moves from the starting position
+func lineBook(t *testing.T, line ...string) *polyglot.Book {
+	t.Helper()
+
+	var entries []polyglot.Entry
+	p := rules.StartingPosition()
+	for _, s := range line {
+		m, err := p.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, polyglot.Entry{Key: polyglot.Key(p), Move: polyglot.EncodeMove(p, m), Weight: 1})
+		p = p.Play(m)
+	}
+	return polyglot.New(entries, 1)
+}
+
+func TestServerBookMoves(t *testing.T) {
+	book := lineBook(t, "f2f3", "e7e5", "g2g4", "d8h4")
+	book.Depth = 3
+	h := New(server.Config{Time: 10 * time.Second, MoveBook: book})
+	defer h.Close()
+
+	// White is never asked to move and black only after the book
+	w, b := start(t, h, moves("white"), moves("black", "d8h4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if len(record.Moves) != 4 {
+		t.Errorf("Expecting the book moves in the record got %v", record.Moves)
+	}
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("white")), "> GO"); n != 0 {
+		t.Errorf("Expecting white to play from the book got %v go commands", n)
+	}
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("black")), "> GO"); n != 1 {
+		t.Errorf("Expecting black to be asked for one move got %v", n)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestClientBookMoves(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second})
+	defer h.Close()
+
+	w, err := h.ConnectWithBook(moves("white"), Faults{}, lineBook(t, "f2f3", "e7e5", "g2g4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := h.Recorder.WaitFor("white", "< READYOK", timeout); err != nil {
+		t.Fatal(err)
+	}
+	b, err := h.Connect(moves("black", "e7e5", "d8h4"), Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// The client answers for white so the engine is never asked to move
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("white")), "< BESTMOVE"); n != 2 {
+		t.Errorf("Expecting two book moves from white got %v", n)
+	}
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,148 @@
+package harness
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+func TestReconnect(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, ReconnectGrace: 2 * time.Second})
+	defer h.Close()
+
+	// White's stream drops during its first search, the move it finds is lost
+	w, white, b, _ := startAgents(t, h, slow(moves("white", "a2a3", "f2f3", "g2g4"), 0), moves("black", "e7e5", "d8h4"))
+	defer w.Close()
+	defer b.Close()
+
+	waitSearches(t, h, "white", 1)
+	// The go is recorded as the server sends it, give the client time to pass it on
+	time.Sleep(50 * time.Millisecond)
+	w.Drop()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if !reflect.DeepEqual(record.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting white to be asked for its move again got %v", record.Moves)
+	}
+
+	state, err := white.WaitForState(pb.ServerGameMessage_GAME_STATE_RESPONSE, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.GetMoves()) != 0 || state.GetTurn() != "white" || state.GetBlack() != "black" {
+		t.Errorf("Expecting the position white reconnected in got %v", state)
+	}
+	if clock := state.GetTimeState().GetWhiteTimeRemaining(); clock >= 10000 || state.GetTimeState().GetBlackTimeRemaining() != 10000 {
+		t.Errorf("Expecting white's clock to have run got %v", state.GetTimeState())
+	}
+
+	streams := h.Recorder.StreamsOf("white")
+	if len(streams) != 2 {
+		t.Fatalf("Expecting white to reconnect once got %v streams", len(streams))
+	}
+	if err := Match(h.Recorder.Sequence(streams[0]), []string{Wildcard, "< READYOK", "> SESSION", Wildcard}); err != nil {
+		t.Error(err)
+	}
+	if err := Match(h.Recorder.Sequence(streams[1]), []string{"> UCI", "< ID", "< UCIOK", "> SETOPTION", "> ISREADY", "< READYOK", "> GAME_CONTROL", "> POSITION", "> GO", "< BESTMOVE", Wildcard}); err != nil {
+		t.Error(err)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestReconnectDuringAnalysis(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true, ReconnectGrace: 2 * time.Second})
+	defer h.Close()
+
+	// The stream drops during the first search, the analysis is searched again
+	p := connectAnalyst(t, h, slow(fake.Script{Name: "analyst", Go: []fake.Reply{
+		{Output: []string{"info depth 1 score cp 5 pv e7e5"}, BestMove: "e7e5"},
+		{Output: []string{"info depth 8 score cp 20 pv c7c5"}, BestMove: "c7c5"},
+	}}, 0))
+	defer p.Close()
+
+	type result struct {
+		updates []*pb.AnalysisUpdate
+		err     error
+	}
+	results := make(chan result, 1)
+	go func() {
+		updates, err := analyze(context.Background(), h, &pb.AnalysisRequest{Fen: afterE4, Movetime: 1000})
+		results <- result{updates, err}
+	}()
+
+	waitSearches(t, h, "analyst", 1)
+	time.Sleep(50 * time.Millisecond)
+	p.Drop()
+
+	var r result
+	select {
+	case r = <-results:
+	case <-time.After(timeout):
+		t.Fatal("Timed out waiting for the analysis")
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if len(r.updates) == 0 {
+		t.Fatal("Expecting updates from the analysis")
+	}
+	if last := r.updates[len(r.updates)-1]; last.GetBestmove() != "c7c5" || last.GetDepth() != 8 {
+		t.Errorf("Expecting the best move of the search after the reconnection got %v", last)
+	}
+
+	streams := h.Recorder.StreamsOf("analyst")
+	if len(streams) != 2 {
+		t.Fatalf("Expecting the analyst to reconnect once got %v streams", len(streams))
+	}
+	if err := Match(h.Recorder.Sequence(streams[1]), []string{"> UCI", "< ID", "< UCIOK", "> SETOPTION", "> ISREADY", "< READYOK", "> POSITION", "> GO", "< INFO", "< BESTMOVE"}); err != nil {
+		t.Error(err)
+	}
+
+	finish(t, h, p)
+}
+
+func TestReconnectGraceExpires(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, ReconnectGrace: 300 * time.Millisecond})
+	defer h.Close()
+
+	w, _, b, _ := startAgents(t, h, slow(moves("white", "f2f3"), 0), moves("black", "e7e5"))
+	defer w.Close()
+	defer b.Close()
+
+	waitSearches(t, h, "white", 1)
+	w.Disconnect()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, server.Disconnection)
+	waitDone(t, w)
+
+	finish(t, h, b)
+}
+
+func TestClockRunsWhileDisconnected(t *testing.T) {
+	h := New(server.Config{Time: 500 * time.Millisecond, ReconnectGrace: 5 * time.Second})
+	defer h.Close()
+
+	w, _, b, _ := startAgents(t, h, slow(moves("white", "f2f3"), 0), moves("black", "e7e5"))
+	defer w.Close()
+	defer b.Close()
+
+	waitSearches(t, h, "white", 1)
+	w.Disconnect()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, server.TimeForfeit)
+	if record.Clocks[rules.White] != 0 {
+		t.Errorf("Expecting white's clock to run out got %v", record.Clocks)
+	}
+
+	finish(t, h, b)
+}
@@ -0,0 +1,190 @@
+package harness
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// Wildcard matches any number of messages in a sequence pattern
+const Wildcard = "..."
+
+// Message is a message recorded on a UCI stream
+type Message struct {
+	// Stream numbers the streams in the order they were opened starting at 0
+	Stream int
+	// FromServer is true for messages the server sent
+	FromServer bool
+	// Type is the message type eg. UCINEWGAME
+	Type string
+	// Msg is the message itself
+	Msg proto.Message
+	// Time is when the message was sent or received by the server
+	Time time.Time
+}
+
+// String returns the message direction and type, "> GO" for messages from
+// the server and "< BESTMOVE" for messages from the client
+func (m Message) String() string {
+	if m.FromServer {
+		return "> " + m.Type
+	}
+	return "< " + m.Type
+}
+
+// Recorder records every message on the UCI streams of the server
+type Recorder struct {
+	mu       sync.Mutex
+	streams  int
+	messages []Message
+}
+
+// intercept is a grpc.StreamServerInterceptor recording the messages on a stream
+func (r *Recorder) intercept(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	r.mu.Lock()
+	stream := r.streams
+	r.streams++
+	r.mu.Unlock()
+
+	return handler(srv, &recordingStream{ServerStream: ss, recorder: r, stream: stream})
+}
+
+func (r *Recorder) record(stream int, fromServer bool, msg interface{}) {
+	m := Message{Stream: stream, FromServer: fromServer, Time: time.Now()}
+	switch msg := msg.(type) {
+	case *pb.UciResponse:
+		m.Type = msg.GetMessageType().String()
+		m.Msg = proto.Clone(msg)
+	case *pb.UciRequest:
+		m.Type = msg.GetMessageType().String()
+		m.Msg = proto.Clone(msg)
+	default:
+		m.Type = fmt.Sprintf("%T", msg)
+	}
+
+	r.mu.Lock()
+	r.messages = append(r.messages, m)
+	r.mu.Unlock()
+}
+
+// Messages returns all messages recorded so far
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.messages...)
+}
+
+// Stream returns the messages recorded on one stream
+func (r *Recorder) Stream(stream int) []Message {
+	var messages []Message
+	for _, m := range r.Messages() {
+		if m.Stream == stream {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// StreamOf returns the stream of the engine that identified itself with name or -1
+func (r *Recorder) StreamOf(name string) int {
+	for _, m := range r.Messages() {
+		if req, ok := m.Msg.(*pb.UciRequest); ok && req.GetId().GetName() == name {
+			return m.Stream
+		}
+	}
+	return -1
+}
+
+// StreamsOf returns the streams of an engine in the order they were opened eg. when it reconnected
+func (r *Recorder) StreamsOf(name string) []int {
+	var streams []int
+	for _, m := range r.Messages() {
+		if req, ok := m.Msg.(*pb.UciRequest); ok && req.GetId().GetName() == name {
+			streams = append(streams, m.Stream)
+		}
+	}
+	return streams
+}
+
+// Sequence returns the direction and type of each message on a stream
+func (r *Recorder) Sequence(stream int) []string {
+	var sequence []string
+	for _, m := range r.Stream(stream) {
+		sequence = append(sequence, m.String())
+	}
+	return sequence
+}
+
+// Match checks a sequence of messages against a pattern. The pattern lists
+// the expected messages in the form returned by Sequence and may contain
+// Wildcard to match any number of messages.
+func Match(sequence, pattern []string) error {
+	if matchFrom(sequence, pattern) {
+		return nil
+	}
+	return fmt.Errorf("Sequence\n\t%v\ndoes not match\n\t%v", strings.Join(sequence, ", "), strings.Join(pattern, ", "))
+}
+
+func matchFrom(sequence, pattern []string) bool {
+	if len(pattern) == 0 {
+		return len(sequence) == 0
+	}
+	if pattern[0] == Wildcard {
+		for i := 0; i <= len(sequence); i++ {
+			if matchFrom(sequence[i:], pattern[1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(sequence) == 0 || sequence[0] != pattern[0] {
+		return false
+	}
+	return matchFrom(sequence[1:], pattern[1:])
+}
+
+// recordingStream records the messages sent and received on a server stream
+type recordingStream struct {
+	grpc.ServerStream
+	recorder *Recorder
+	stream   int
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.recorder.record(s.stream, true, m)
+	}
+	return err
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.recorder.record(s.stream, false, m)
+	}
+	return err
+}
+
+// WaitFor polls until the engine that identified itself with name has a
+// message matching message, in the form returned by Sequence, on its stream
+func (r *Recorder) WaitFor(name, message string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if stream := r.StreamOf(name); stream >= 0 {
+			for _, m := range r.Sequence(stream) {
+				if m == message {
+					return nil
+				}
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return fmt.Errorf("Timed out waiting for %q from %v", message, name)
+}
@@ -0,0 +1,300 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// count returns how many times a message appears in a sequence
+func count(sequence []string, message string) int {
+	n := 0
+	for _, m := range sequence {
+		if m == message {
+			n++
+		}
+	}
+	return n
+}
+
+func waitQueued(t *testing.T, h *Harness, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if h.Scheduler.Queued() == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %v queued jobs", n)
+}
+
+func TestEngineReuse(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	// Each engine plays a game as white and a game as black
+	white := moves("white", "f2f3", "g2g4", "e7e5", "d8h4")
+	black := moves("black", "e7e5", "d8h4", "f2f3", "g2g4")
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.BlackWins, rules.Checkmate)
+
+	record, err := h.Scheduler.Play(context.Background(), server.Game{White: "black", Black: "white"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	if record.White != "black" || record.Black != "white" {
+		t.Errorf("Unexpected players %v vs %v", record.White, record.Black)
+	}
+
+	finish(t, h, w, b)
+	if w.Err() != nil || b.Err() != nil {
+		t.Errorf("Expecting clients to finish cleanly got %v and %v", w.Err(), b.Err())
+	}
+
+	streams := 0
+	for _, m := range h.Recorder.Messages() {
+		if m.String() == "> UCI" {
+			streams++
+		}
+	}
+	if streams != 2 {
+		t.Errorf("Expecting the engines to connect once got %v connections", streams)
+	}
+	for _, name := range []string{"white", "black"} {
+		if n := count(h.Recorder.Sequence(h.Recorder.StreamOf(name)), "> UCINEWGAME"); n != 2 {
+			t.Errorf("Expecting %v to start 2 games got %v", name, n)
+		}
+	}
+}
+
+func TestEngineRegistry(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	twin := moves("twin", "e2e4")
+	twin.Author = "twins"
+	twin.Options = []string{"name Hash type spin default 16 min 1 max 1024"}
+	first, second := start(t, h, twin, twin, Faults{}, Faults{})
+	defer first.Close()
+	defer second.Close()
+
+	expectOutcome(t, waitGameOver(t, h), rules.WhiteWins, server.IllegalMove)
+
+	solo := connectAnalyst(t, h, moves("solo", "e2e4"))
+	defer solo.Close()
+	waitIdle(t, h)
+
+	engines := h.Scheduler.Engines()
+	if len(engines) != 2 {
+		t.Fatalf("Expecting 2 engines got %v", engines)
+	}
+	if e := engines[0]; e.Name != "solo" || e.Capacity != 1 || e.Idle != 1 {
+		t.Errorf("Unexpected engine %+v", e)
+	}
+	if e := engines[1]; e.Name != "twin" || e.Author != "twins" || e.Capacity != 2 || e.Idle != 2 || len(e.Options) != 1 {
+		t.Errorf("Unexpected engine %+v", e)
+	}
+
+	solo.Close()
+	deadline := time.Now().Add(timeout)
+	for len(h.Scheduler.Engines()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expecting the disconnected engine to leave the pool got %v", h.Scheduler.Engines())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	p := connectAnalyst(t, h, fake.Script{Name: "solo", Go: []fake.Reply{{BestMove: "e2e4"}}})
+	defer p.Close()
+
+	// An analysis without limits keeps the engine busy until it is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	busy := make(chan error, 1)
+	go func() {
+		_, err := analyze(ctx, h, &pb.AnalysisRequest{})
+		busy <- err
+	}()
+	if err := h.Recorder.WaitFor("solo", "> GO", timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan error, 2)
+	go func() {
+		_, err := analyze(context.Background(), h, &pb.AnalysisRequest{Movetime: 111})
+		results <- err
+	}()
+	waitQueued(t, h, 1)
+	go func() {
+		_, err := analyze(context.Background(), h, &pb.AnalysisRequest{Movetime: 222, Priority: 1})
+		results <- err
+	}()
+	waitQueued(t, h, 2)
+
+	cancel()
+	<-busy
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var movetimes []uint32
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("solo")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_GO {
+			movetimes = append(movetimes, msg.GetGo().GetMovetime())
+		}
+	}
+	if fmt.Sprint(movetimes) != "[0 222 111]" {
+		t.Errorf("Expecting the higher priority analysis to run first got %v", movetimes)
+	}
+}
+
+func TestFairQueue(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	p := connectAnalyst(t, h, fake.Script{Name: "solo", Go: []fake.Reply{{BestMove: "e2e4"}}})
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	busy := make(chan error, 1)
+	go func() {
+		_, err := analyze(ctx, h, &pb.AnalysisRequest{})
+		busy <- err
+	}()
+	if err := h.Recorder.WaitFor("solo", "> GO", timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// One submitter queues three analyses before another queues one
+	queued := []struct {
+		submitter string
+		movetime  uint32
+	}{{"a", 1}, {"a", 2}, {"a", 3}, {"b", 4}}
+	results := make(chan error, len(queued))
+	for i, q := range queued {
+		q := q
+		go func() {
+			_, err := analyze(context.Background(), h, &pb.AnalysisRequest{Movetime: q.movetime, Submitter: q.submitter})
+			results <- err
+		}()
+		waitQueued(t, h, i+1)
+	}
+
+	cancel()
+	<-busy
+	for range queued {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var movetimes []uint32
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("solo")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_GO {
+			movetimes = append(movetimes, msg.GetGo().GetMovetime())
+		}
+	}
+	if fmt.Sprint(movetimes) != "[0 1 4 2 3]" {
+		t.Errorf("Expecting the submitters to take turns got %v", movetimes)
+	}
+}
+
+func TestGamePonder(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Ponder: true, NoPairing: true})
+	defer h.Close()
+
+	var players []*Player
+	for _, name := range []string{"white", "black"} {
+		script := moves(name, "d8h4")
+		script.Options = []string{ponderOption}
+		p, err := h.Connect(script, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	// A game that does not say whether engines ponder keeps the scheduler's setting
+	off := false
+	for _, ponder := range []*bool{nil, &off} {
+		record, err := h.Scheduler.Play(context.Background(), server.Game{
+			White:   "white",
+			Black:   "black",
+			Ponder:  ponder,
+			Opening: server.Opening{Moves: []string{"f2f3", "e7e5", "g2g4"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	}
+
+	var values []string
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("black")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetSetOption().GetName() == "Ponder" {
+			values = append(values, msg.GetSetOption().GetValue())
+		}
+	}
+	if fmt.Sprint(values) != "[true false]" {
+		t.Errorf("Expecting pondering to be switched on then off got %v", values)
+	}
+
+	finish(t, h, players...)
+}
+
+func TestHealthCheck(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, HealthInterval: 10 * time.Millisecond, ReadyTimeout: 100 * time.Millisecond})
+	defer h.Close()
+
+	fit := connectAnalyst(t, h, fake.Script{Name: "fit"})
+	defer fit.Close()
+
+	deadline := time.Now().Add(timeout)
+	for count(h.Recorder.Sequence(h.Recorder.StreamOf("fit")), "< READYOK") < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for health checks")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The unresponsive engine is told of its violation and to quit once it
+	// stops answering isready
+	sick := fake.Script{Name: "sick", HangAfter: 1}
+	s, err := h.Connect(sick, Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	waitDone(t, s)
+	if err := Match(h.Recorder.Sequence(h.Recorder.StreamOf("sick")), []string{Wildcard, "> ISREADY", "> VIOLATION", "> QUIT"}); err != nil {
+		t.Error(err)
+	}
+
+	engines := h.Scheduler.Engines()
+	if len(engines) != 1 || engines[0].Name != "fit" {
+		t.Errorf("Expecting only the healthy engine in the pool got %v", engines)
+	}
+}
@@ -0,0 +1,193 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// foolsMate leaves black to mate in one so each engine wins its game as black
+var foolsMate = &pb.Opening{Moves: []string{"f2f3", "e7e5", "g2g4"}}
+
+// runSPRT runs a test returning all updates streamed back
+func runSPRT(ctx context.Context, h *Harness, req *pb.SPRTRequest) ([]*pb.SPRTUpdate, error) {
+	conn, err := h.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewChessApplicationClient(conn).SPRT(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*pb.SPRTUpdate
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return updates, nil
+		}
+		if err != nil {
+			return updates, err
+		}
+		updates = append(updates, update)
+	}
+}
+
+func connectPair(t *testing.T, h *Harness) []*Player {
+	t.Helper()
+
+	var players []*Player
+	for _, name := range []string{"candidate", "baseline"} {
+		p, err := h.Connect(moves(name, "d8h4", "d8h4", "d8h4", "d8h4", "d8h4"), Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+	return players
+}
+
+func TestSPRT(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+	players := connectPair(t, h)
+	defer players[0].Close()
+	defer players[1].Close()
+
+	// Splitting every pair shows the candidate is not 50 Elo stronger
+	updates, err := runSPRT(context.Background(), h, &pb.SPRTRequest{
+		Candidate: "candidate",
+		Baseline:  "baseline",
+		Elo0:      0,
+		Elo1:      50,
+		Openings:  []*pb.Opening{foolsMate},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("Expecting 1 update got %v", len(updates))
+	}
+	last := updates[0]
+	if last.GetResult() != pb.SPRTUpdate_H0 || last.GetLlr() > last.GetLower() {
+		t.Errorf("Expecting H0 got %v with LLR %v", last.GetResult(), last.GetLlr())
+	}
+	if last.GetGames() != 2 || last.GetWins() != 1 || last.GetLosses() != 1 || fmt.Sprint(last.GetPentanomial()) != "[0 0 1 0 0]" {
+		t.Errorf("Unexpected results %v", last)
+	}
+
+	// A smaller difference needs more pairs than the limit
+	updates, err = runSPRT(context.Background(), h, &pb.SPRTRequest{
+		Candidate:   "candidate",
+		Baseline:    "baseline",
+		Elo0:        0,
+		Elo1:        5,
+		Openings:    []*pb.Opening{foolsMate},
+		MaxPairs:    2,
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("Expecting 2 updates got %v", len(updates))
+	}
+	if updates[0].GetResult() != pb.SPRTUpdate_RUNNING || updates[1].GetResult() != pb.SPRTUpdate_INCONCLUSIVE {
+		t.Errorf("Expecting the test to run then stop inconclusive got %v and %v", updates[0].GetResult(), updates[1].GetResult())
+	}
+	if updates[1].GetGames() != 4 {
+		t.Errorf("Expecting 4 games got %v", updates[1].GetGames())
+	}
+
+	finish(t, h, players...)
+}
+
+func TestSPRTCancel(t *testing.T) {
+	h := New(server.Config{Time: time.Minute, NoPairing: true})
+	defer h.Close()
+
+	// The engines only move when told to stop so the game outlasts the test
+	var players []*Player
+	for _, name := range []string{"candidate", "baseline"} {
+		p, err := h.Connect(fake.Script{Name: name, Go: []fake.Reply{{UntilStop: true, BestMove: "d8h4"}}}, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := runSPRT(ctx, h, &pb.SPRTRequest{
+			Candidate: "candidate",
+			Baseline:  "baseline",
+			Elo0:      0,
+			Elo1:      5,
+			Openings:  []*pb.Opening{foolsMate},
+		})
+		done <- err
+	}()
+	if err := h.Recorder.WaitFor("baseline", "> GO", timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// The game being played is aborted rather than played to the end
+	start := time.Now()
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Errorf("Expecting the test to be cancelled got %v", err)
+	}
+	if err := h.Recorder.WaitFor("baseline", "> STOP", timeout); err != nil {
+		t.Fatal(err)
+	}
+	waitIdle(t, h)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expecting the engines to be released as the test is cancelled took %v", elapsed)
+	}
+
+	finish(t, h, players...)
+}
+
+func TestSPRTErrors(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	req := &pb.SPRTRequest{Candidate: "candidate", Baseline: "baseline", Elo0: 0, Elo1: 5}
+	if _, err := runSPRT(context.Background(), h, req); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expecting the engines to be unavailable got %v", err)
+	}
+
+	players := connectPair(t, h)
+	defer players[0].Close()
+	defer players[1].Close()
+
+	for _, req := range []*pb.SPRTRequest{
+		{Candidate: "candidate", Baseline: "candidate", Elo0: 0, Elo1: 5},
+		{Candidate: "candidate", Baseline: "baseline", Elo0: 5, Elo1: 0},
+		{Candidate: "candidate", Baseline: "baseline", Elo0: 0, Elo1: 5, Alpha: 0.7},
+		{Candidate: "candidate", Baseline: "baseline", Elo0: 0, Elo1: 5, Openings: []*pb.Opening{{Moves: []string{"e2e5"}}}},
+	} {
+		if _, err := runSPRT(context.Background(), h, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expecting %v to be invalid got %v", req, err)
+		}
+	}
+}
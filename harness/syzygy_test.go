@@ -0,0 +1,39 @@
+package harness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/syzygy"
+)
+
+// queenTakesKnight is a position where white's queen takes the knight leaving KQvK
+const queenTakesKnight = "4k3/8/8/8/8/8/3n4/3QK3 w - - 0 1"
+
+func TestTablebaseAdjudication(t *testing.T) {
+	tb, err := syzygy.Open("../syzygy/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := server.NewBook([]server.Opening{{FEN: queenTakesKnight}}, server.Sequential, 1)
+	h := New(server.Config{Time: 10 * time.Second, Book: book, Tablebase: tb, TablebasePieces: 3})
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "d1d2"), moves("black"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	// The game is over as soon as the capture brings it into the tables
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, server.TablebaseAdjudication)
+	if len(record.Moves) != 1 {
+		t.Errorf("Expecting the game to end after the capture got %v", record.Moves)
+	}
+	if n := count(h.Recorder.Sequence(h.Recorder.StreamOf("black")), "> GO"); n != 0 {
+		t.Errorf("Expecting black not to be asked to move got %v go commands", n)
+	}
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,109 @@
+package harness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+	"github.com/schafer14/grpc-chess/tournament"
+)
+
+func TestTournament(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	// The opening leaves black to mate so black wins every game
+	var players []*Player
+	names := []string{"a", "b", "c"}
+	for _, name := range names {
+		p, err := h.Connect(moves(name, "d8h4", "d8h4"), Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+	for _, name := range names {
+		if n := count(h.Recorder.Sequence(h.Recorder.StreamOf(name)), "> UCINEWGAME"); n != 0 {
+			t.Fatalf("Expecting %v not to be paired as it connects got %v games", name, n)
+		}
+	}
+
+	c := tournament.Config{
+		Name:        "harness",
+		Kind:        tournament.RoundRobin,
+		Engines:     names,
+		Openings:    []server.Opening{{Moves: []string{"f2f3", "e7e5", "g2g4"}}},
+		Concurrency: 2,
+	}
+	games := store.NewMemory()
+	table, err := tournament.Run(context.Background(), c, h.Scheduler, games, logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, standing := range table.Standings {
+		if standing.Games != 4 || standing.Wins != 2 || standing.Losses != 2 {
+			t.Errorf("Expecting %v to win 2 of 4 got %+v", standing.Engine, standing)
+		}
+	}
+	saved, _ := games.Tournament("harness")
+	for _, game := range saved {
+		if game.Result != "0-1" || len(game.Moves) != 4 {
+			t.Errorf("Expecting black to mate after the opening got %v %v", game.Result, game.Moves)
+		}
+	}
+
+	finish(t, h, players...)
+}
+
+func TestUnequalClocks(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	var players []*Player
+	for _, name := range []string{"white", "black"} {
+		p, err := h.Connect(moves(name, "d8h4"), Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	record, err := h.Scheduler.Play(context.Background(), server.Game{
+		White:     "white",
+		Black:     "black",
+		Time:      10 * time.Second,
+		BlackTime: 8 * time.Second,
+		Opening:   server.Opening{Moves: []string{"f2f3", "e7e5", "g2g4"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+
+	var first *pb.UciResponse_Go
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf("black")) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_GO && first == nil {
+			first = msg.GetGo()
+		}
+	}
+	if first.GetWtime() != 10000 || first.GetBtime() != 8000 {
+		t.Errorf("Expecting black to start with less time got %vms and %vms", first.GetWtime(), first.GetBtime())
+	}
+
+	finish(t, h, players...)
+}
@@ -0,0 +1,85 @@
+package harness
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/engine/fake"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// variants is the UCI_Variant option of an engine that plays king of the hill
+const variants = "name UCI_Variant type combo default chess var chess var kingofthehill"
+
+func TestVariant(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, Variant: rules.KingOfTheHill})
+	defer h.Close()
+
+	// White's king walks to the centre
+	white := moves("white", "e2e4", "e1e2", "e2e3", "e3d4")
+	white.Options = []string{variants}
+	black := moves("black", "a7a6", "a6a5", "a5a4")
+	black.Options = []string{variants}
+	w, b := start(t, h, white, black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, rules.VariantEnd)
+	if record.Variant != "kingofthehill" {
+		t.Errorf("Expecting a king of the hill game got %q", record.Variant)
+	}
+
+	for _, name := range []string{"white", "black"} {
+		value := ""
+		for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+			if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetSetOption().GetName() == "UCI_Variant" {
+				value = msg.GetSetOption().GetValue()
+			}
+		}
+		if value != "kingofthehill" {
+			t.Errorf("Expecting %v to be sent UCI_Variant kingofthehill got %q", name, value)
+		}
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestVariantRefused(t *testing.T) {
+	h := New(server.Config{Time: 10 * time.Second, NoPairing: true})
+	defer h.Close()
+
+	plays := moves("plays", "e2e4")
+	plays.Options = []string{variants}
+	var players []*Player
+	for _, script := range []fake.Script{plays, moves("chess", "e7e5")} {
+		p, err := h.Connect(script, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if err := h.Recorder.WaitFor(script.Name, "< READYOK", timeout); err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+
+	// The engine without the variant is not given the game
+	_, err := h.Scheduler.Play(context.Background(), server.Game{White: "plays", Black: "chess", Variant: rules.KingOfTheHill})
+	if err == nil {
+		t.Error("Expecting the game to be refused")
+	}
+	if _, err := h.Scheduler.Play(context.Background(), server.Game{White: "plays", Black: "chess", Variant: rules.Atomic}); err == nil {
+		t.Error("Expecting a variant neither engine lists to be refused")
+	}
+	for _, name := range []string{"plays", "chess"} {
+		if n := count(h.Recorder.Sequence(h.Recorder.StreamOf(name)), "> UCINEWGAME"); n != 0 {
+			t.Errorf("Expecting %v not to start a game got %v", name, n)
+		}
+	}
+
+	finish(t, h, players...)
+}
@@ -0,0 +1,133 @@
+package harness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// reported returns the violations reported to an engine
+func reported(h *Harness, name string) []*pb.UciResponse_Violation {
+	var violations []*pb.UciResponse_Violation
+	for _, m := range h.Recorder.Stream(h.Recorder.StreamOf(name)) {
+		if msg, ok := m.Msg.(*pb.UciResponse); ok && msg.GetMessageType() == pb.UciResponse_VIOLATION {
+			violations = append(violations, msg.GetViolation())
+		}
+	}
+	return violations
+}
+
+// expectViolations checks the violations recorded with a game and that both engines were told of them
+func expectViolations(t *testing.T, h *Harness, record server.GameRecord, expected []server.ViolationReport) {
+	t.Helper()
+
+	if !reflect.DeepEqual(record.Violations, expected) {
+		t.Errorf("Expecting violations %v got %v", expected, record.Violations)
+	}
+	for _, name := range []string{record.White, record.Black} {
+		violations := reported(h, name)
+		if len(violations) != len(expected) {
+			t.Errorf("Expecting %v violations reported to %v got %v", len(expected), name, violations)
+			continue
+		}
+		for i, v := range violations {
+			if v.GetEngine() != expected[i].Engine || v.GetSide() != expected[i].Color.String() || int(v.GetPly()) != expected[i].Ply {
+				t.Errorf("Expecting %v reported to %v got %v", expected[i], name, v)
+			}
+		}
+	}
+}
+
+func TestIllegalMoveViolation(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "e2e4"), moves("black", "e2e4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, server.IllegalMove)
+	expectViolations(t, h, record, []server.ViolationReport{
+		{Engine: "black", Color: rules.Black, Violation: server.IllegalBestMove, Action: server.Forfeit, Ply: 1, Detail: "bestmove e2e4"},
+	})
+	if v := reported(h, "white"); len(v) == 1 && (v[0].GetCode() != pb.UciResponse_Violation_ILLEGAL_BESTMOVE || v[0].GetAction() != pb.UciResponse_Violation_FORFEIT) {
+		t.Errorf("Expecting an illegal bestmove forfeit got %v", v[0])
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestRetryViolation(t *testing.T) {
+	h := New(server.Config{
+		Time:       10 * time.Second,
+		Violations: server.ViolationPolicy{Actions: map[server.Violation]server.Action{server.IllegalBestMove: server.Retry}},
+	})
+	defer h.Close()
+
+	// Black is asked again after its first illegal move and forfeits after its second
+	w, b := start(t, h, moves("white", "f2f3", "g2g4"), moves("black", "e2e4", "e7e5", "a1a1", "a1a1"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.WhiteWins, server.IllegalMove)
+	expectViolations(t, h, record, []server.ViolationReport{
+		{Engine: "black", Color: rules.Black, Violation: server.IllegalBestMove, Action: server.Retry, Ply: 1, Detail: "bestmove e2e4"},
+		{Engine: "black", Color: rules.Black, Violation: server.IllegalBestMove, Action: server.Retry, Ply: 3, Detail: "bestmove a1a1"},
+		{Engine: "black", Color: rules.Black, Violation: server.IllegalBestMove, Action: server.Forfeit, Ply: 3, Detail: "bestmove a1a1"},
+	})
+	if record.Moves[1] != "e7e5" {
+		t.Errorf("Expecting the move black was asked for again got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestWrongNotation(t *testing.T) {
+	h := New(server.Config{
+		Time:       10 * time.Second,
+		Violations: server.ViolationPolicy{Actions: map[server.Violation]server.Action{server.WrongNotation: server.Warn}},
+	})
+	defer h.Close()
+
+	w, b := start(t, h, moves("white", "f3", "g2g4"), moves("black", "e7e5", "Qd8-h4"), Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	expectViolations(t, h, record, []server.ViolationReport{
+		{Engine: "white", Color: rules.White, Violation: server.WrongNotation, Action: server.Warn, Ply: 0, Detail: "bestmove f3"},
+		{Engine: "black", Color: rules.Black, Violation: server.WrongNotation, Action: server.Warn, Ply: 3, Detail: "bestmove Qd8-h4"},
+	})
+	if !reflect.DeepEqual(record.Moves, []string{"f2f3", "e7e5", "g2g4", "d8h4"}) {
+		t.Errorf("Expecting the moves as they were meant got %v", record.Moves)
+	}
+
+	finish(t, h, w, b)
+}
+
+func TestBestMoveWithoutGo(t *testing.T) {
+	h := New(config)
+	defer h.Close()
+
+	// Black writes a best move whenever it is asked whether it is ready
+	black := moves("black", "e7e5", "d8h4")
+	black.IsReady.Output = []string{"bestmove e7e5"}
+	w, b := start(t, h, moves("white", "f2f3", "g2g4"), black, Faults{}, Faults{})
+	defer w.Close()
+	defer b.Close()
+
+	record := waitGameOver(t, h)
+	expectOutcome(t, record, rules.BlackWins, rules.Checkmate)
+	expectViolations(t, h, record, []server.ViolationReport{
+		{Engine: "black", Color: rules.Black, Violation: server.BestMoveWithoutGo, Action: server.Warn, Ply: 0, Detail: "bestmove e7e5 while waiting for readyok"},
+	})
+
+	finish(t, h, w, b)
+}
@@ -0,0 +1,145 @@
+// Package openings reads the positions games start from out of EPD files,
+// PGN files and files of UCI position commands
+package openings
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+)
+
+// Load reads the openings in a file by its extension, .epd and .pgn files
+// are read as EPD and PGN and any other file as UCI position commands. When
+// plies is more than zero each opening is cut to that many moves.
+func Load(path string, plies int) ([]server.Opening, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var openings []server.Opening
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epd":
+		openings, err = ReadEPD(f)
+	case ".pgn":
+		openings, err = ReadPGN(f)
+	default:
+		openings, err = ReadPositions(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid openings in %v: %v", path, err)
+	}
+
+	if plies > 0 {
+		for i := range openings {
+			if len(openings[i].Moves) > plies {
+				openings[i].Moves = openings[i].Moves[:plies]
+			}
+		}
+	}
+	return openings, nil
+}
+
+// ReadEPD reads a position from each line of EPD. The halfmove clock and
+// fullmove number are taken from the hmvc and fmvn operations when present.
+func ReadEPD(r io.Reader) ([]server.Opening, error) {
+	var openings []server.Opening
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("Expecting 4 position fields on line %v got %v", line, len(fields))
+		}
+
+		halfmove, fullmove := "0", "1"
+		for _, op := range strings.Split(strings.Join(fields[4:], " "), ";") {
+			op := strings.Fields(op)
+			if len(op) != 2 {
+				continue
+			}
+			switch op[0] {
+			case "hmvc":
+				halfmove = op[1]
+			case "fmvn":
+				fullmove = op[1]
+			}
+		}
+
+		fen := strings.Join(append(fields[:4:4], halfmove, fullmove), " ")
+		if _, err := rules.ParseFEN(fen); err != nil {
+			return nil, fmt.Errorf("Invalid position on line %v: %v", line, err)
+		}
+		openings = append(openings, server.Opening{FEN: fen})
+	}
+	return openings, scanner.Err()
+}
+
+// ReadPositions reads an opening from each line in the form of a UCI
+// position command without the command, `startpos moves e2e4` or
+// `fen <fen> moves e2e4`. Blank lines and lines starting with # are skipped.
+func ReadPositions(r io.Reader) ([]server.Opening, error) {
+	var openings []server.Opening
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var opening server.Opening
+		switch fields[0] {
+		case "startpos":
+			fields = fields[1:]
+		case "fen":
+			i := 1
+			for i < len(fields) && fields[i] != "moves" {
+				i++
+			}
+			opening.FEN = strings.Join(fields[1:i], " ")
+			fields = fields[i:]
+		default:
+			return nil, fmt.Errorf("Expecting startpos or fen on line %v", line)
+		}
+		if len(fields) > 0 {
+			if fields[0] != "moves" {
+				return nil, fmt.Errorf("Expecting moves on line %v", line)
+			}
+			opening.Moves = fields[1:]
+		}
+
+		if err := check(opening); err != nil {
+			return nil, fmt.Errorf("Invalid opening on line %v: %v", line, err)
+		}
+		openings = append(openings, opening)
+	}
+	return openings, scanner.Err()
+}
+
+// check replays an opening to make sure its position and moves are legal
+func check(opening server.Opening) error {
+	position := rules.StartingPosition()
+	if opening.FEN != "" {
+		var err error
+		if position, err = rules.ParseFEN(opening.FEN); err != nil {
+			return err
+		}
+	}
+	for _, s := range opening.Moves {
+		m, err := position.ParseMove(s)
+		if err != nil {
+			return err
+		}
+		position = position.Play(m)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package openings
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const epd = `rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 id "e4";
+r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - hmvc 2; fmvn 3; id "open";
+
+4k3/8/8/8/8/8/8/4K2R w K -
+`
+
+const pgn = `[Event "Suite"]
+[Site "?"]
+[Result "*"]
+
+1. e4 {the king's pawn} e5 2. Nf3 (2. f4 exf4) 2... Nc6 $1 3. Bb5 a6 4. Ba4 Nf6 5. 0-0 *
+
+[Event "From a position"]
+[FEN "4k3/8/8/8/8/8/4P3/4K2R w K - 0 1"]
+[Result "1-0"]
+
+% an escaped line 1. d4
+1. O-O! Kd7 2. e8=Q+ ; never reached
+1-0
+
+[Event "No result"]
+
+1. d4 d5 2. c4 e6?!
+`
+
+func TestReadEPD(t *testing.T) {
+	openings, err := ReadEPD(strings.NewReader(epd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+	}
+	if len(openings) != len(expected) {
+		t.Fatalf("Expecting %v openings got %v", len(expected), len(openings))
+	}
+	for i, o := range openings {
+		if o.FEN != expected[i] || len(o.Moves) != 0 {
+			t.Errorf("Expecting %v got %+v", expected[i], o)
+		}
+	}
+
+	if _, err := ReadEPD(strings.NewReader("8/8/8/8 w - -\n")); err == nil {
+		t.Error("Expecting an invalid position to fail")
+	}
+}
+
+func TestReadPGN(t *testing.T) {
+	// The second game fails on the promotion as the pawn is on e2
+	if _, err := ReadPGN(strings.NewReader(pgn)); err == nil || !strings.Contains(err.Error(), "game 2") {
+		t.Fatalf("Expecting game 2 to be invalid got %v", err)
+	}
+
+	openings, err := ReadPGN(strings.NewReader(strings.Replace(pgn, "2. e8=Q+", "2. e4", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		" [e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1]",
+		"4k3/8/8/8/8/8/4P3/4K2R w K - 0 1 [e1g1 e8d7 e2e4]",
+		" [d2d4 d7d5 c2c4 e7e6]",
+	}
+	if len(openings) != len(expected) {
+		t.Fatalf("Expecting %v openings got %v", len(expected), len(openings))
+	}
+	for i, o := range openings {
+		if got := fmt.Sprintf("%v %v", o.FEN, o.Moves); got != expected[i] {
+			t.Errorf("Expecting %v got %v", expected[i], got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"suite.pgn": "1. e4 e5 2. Nf3 Nc6 *\n",
+		"suite.epd": epd,
+		"suite.txt": "# UCI positions\nstartpos moves e2e4 e7e5 g1f3\nfen 4k3/8/8/8/8/8/8/4K2R w K - 0 1 moves e1g1\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, expected := range map[string]string{
+		"suite.pgn": "[{ [e2e4 e7e5] false}]",
+		"suite.epd": "3 openings",
+		"suite.txt": "[{ [e2e4 e7e5] false} {4k3/8/8/8/8/8/8/4K2R w K - 0 1 [e1g1] false}]",
+	} {
+		openings, err := Load(filepath.Join(dir, name), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := fmt.Sprint(openings)
+		if strings.HasSuffix(expected, "openings") {
+			got = fmt.Sprintf("%v openings", len(openings))
+		}
+		if got != expected {
+			t.Errorf("Expecting %v from %v got %v", expected, name, got)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad.txt"), []byte("startpos moves e2e5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(filepath.Join(dir, "bad.txt"), 0); err == nil {
+		t.Error("Expecting an illegal move to fail")
+	}
+}
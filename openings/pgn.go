@@ -0,0 +1,177 @@
+package openings
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+)
+
+// ReadPGN reads the main line of each game of a PGN file as an opening that
+// starts from the game's FEN tag or the standard starting position. Comments,
+// variations and numeric annotation glyphs are skipped.
+func ReadPGN(r io.Reader) ([]server.Opening, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		openings []server.Opening
+		fen      string
+		san      []string
+		started  bool
+		depth    int
+	)
+	finish := func() error {
+		if !started {
+			return nil
+		}
+		opening, err := pgnOpening(fen, san)
+		if err != nil {
+			return fmt.Errorf("Invalid game %v: %v", len(openings)+1, err)
+		}
+		openings = append(openings, opening)
+		fen, san, started = "", nil, false
+		return nil
+	}
+
+	tokens, err := pgnTokens(string(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch {
+		case token == "(":
+			depth++
+		case token == ")":
+			depth--
+		case depth > 0:
+			// moves of a variation
+		case strings.HasPrefix(token, "["):
+			// a tag after moves starts the next game of a file without results
+			if len(san) > 0 {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+			started = true
+			if name, value := pgnTag(token); name == "FEN" {
+				fen = value
+			}
+		case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+			started = true
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(token, "$"):
+			// a numeric annotation glyph
+		default:
+			if move := trimMoveNumber(token); move != "" {
+				started = true
+				san = append(san, move)
+			}
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return openings, nil
+}
+
+// pgnTokens splits PGN into tags, moves, move numbers, results, annotation
+// glyphs and parentheses dropping comments and escaped lines
+func pgnTokens(text string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	flush := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '%' && (i == 0 || text[i-1] == '\n'):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == ';':
+			flush()
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '{':
+			flush()
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated comment")
+			}
+			i += end
+		case c == '[':
+			flush()
+			end := strings.IndexByte(text[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated tag")
+			}
+			tokens = append(tokens, text[i:i+end+1])
+			i += end
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			token.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// trimMoveNumber removes a move number like 12. or 12... from the start of a token
+func trimMoveNumber(token string) string {
+	digits := strings.TrimLeft(token, "0123456789")
+	if len(digits) == len(token) || !strings.HasPrefix(digits, ".") {
+		return token
+	}
+	return strings.TrimLeft(digits, ".")
+}
+
+// pgnTag returns the name and value of a tag like [FEN "..."]
+func pgnTag(token string) (name, value string) {
+	tag := strings.TrimSpace(strings.Trim(token, "[]"))
+	i := strings.IndexFunc(tag, unicode.IsSpace)
+	if i < 0 {
+		return tag, ""
+	}
+	return tag[:i], strings.Trim(strings.TrimSpace(tag[i:]), `"`)
+}
+
+// pgnOpening replays moves in SAN from a position converting them to UCI notation
+func pgnOpening(fen string, san []string) (server.Opening, error) {
+	position := rules.StartingPosition()
+	if fen != "" {
+		var err error
+		if position, err = rules.ParseFEN(fen); err != nil {
+			return server.Opening{}, err
+		}
+	}
+
+	opening := server.Opening{FEN: fen}
+	for _, s := range san {
+		m, err := position.ParseSAN(s)
+		if err != nil {
+			return server.Opening{}, err
+		}
+		opening.Moves = append(opening.Moves, m.String())
+		position = position.Play(m)
+	}
+	return opening, nil
+}
@@ -0,0 +1,159 @@
+// Package perft counts the leaf nodes of the tree of legal moves below a
+// position. The counts of well known positions catch move generation bugs and
+// comparing the counts below each move with another engine finds the position
+// a bug shows up in.
+package perft
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// Count returns the number of positions depth moves below a position
+func Count(p *rules.Position, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	c := counter{positions: make([]rules.Position, depth), moves: make([][]rules.Move, depth)}
+	return c.count(p, depth)
+}
+
+// counter keeps a position and a move list for each ply so counting does
+// not allocate
+type counter struct {
+	positions []rules.Position
+	moves     [][]rules.Move
+}
+
+func (c *counter) count(p *rules.Position, depth int) uint64 {
+	moves := p.AppendLegalMoves(c.moves[depth-1][:0])
+	c.moves[depth-1] = moves
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	next := &c.positions[depth-1]
+	var nodes uint64
+	for _, m := range moves {
+		p.PlayTo(next, m)
+		nodes += c.count(next, depth-1)
+	}
+	return nodes
+}
+
+// Move is the number of positions below a move
+type Move struct {
+	// The move in UCI notation, castling is the king taking its own rook in Chess960
+	Move  string
+	Nodes uint64
+}
+
+// Divide returns the number of positions depth-1 moves below each legal move
+// of a position ordered by move
+func Divide(p *rules.Position, depth int) []Move {
+	var moves []Move
+	for _, m := range p.LegalMoves() {
+		moves = append(moves, Move{Move: m.String(), Nodes: Count(p.Play(m), depth-1)})
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].Move < moves[j].Move })
+	return moves
+}
+
+// Total returns the number of positions below all the moves of a divide
+func Total(moves []Move) uint64 {
+	var nodes uint64
+	for _, m := range moves {
+		nodes += m.Nodes
+	}
+	return nodes
+}
+
+// Divider divides a position reached by playing moves in UCI notation from a
+// FEN, the starting position when it is empty, eg. an engine that supports go perft
+type Divider interface {
+	Divide(fen string, moves []string, depth int) (map[string]uint64, error)
+}
+
+// Difference is a move counted differently by the two sides of a comparison,
+// a count of zero on one side is a move that side does not think is legal
+type Difference struct {
+	Move   string
+	Ours   uint64
+	Theirs uint64
+}
+
+// Report is where a comparison found the counts differ
+type Report struct {
+	// The moves in UCI notation from the position compared to the position
+	// the counts differ at and the depth of the divide there
+	Moves []string
+	FEN   string
+	Depth int
+	// The moves counted differently, empty when the counts agree
+	Differences []Difference
+}
+
+// Compare divides a position given as a FEN, the starting position when it
+// is empty, both with this package and with another divider. When the counts
+// differ it follows the first move counted differently by both until it
+// reaches the position where a move is missing or the counts one move deep
+// differ.
+func Compare(fen string, depth int, other Divider) (Report, error) {
+	p := rules.StartingPosition()
+	if fen != "" {
+		var err error
+		if p, err = rules.ParseFEN(fen); err != nil {
+			return Report{}, err
+		}
+	}
+	if depth < 1 {
+		return Report{}, fmt.Errorf("Expecting a depth of at least 1 got %v", depth)
+	}
+
+	var moves []string
+	for ; ; depth-- {
+		theirs, err := other.Divide(fen, moves, depth)
+		if err != nil {
+			return Report{}, err
+		}
+		report := Report{Moves: moves, FEN: p.FEN(), Depth: depth, Differences: differences(Divide(p, depth), theirs)}
+
+		next := ""
+		for _, d := range report.Differences {
+			if d.Ours > 0 && d.Theirs > 0 {
+				next = d.Move
+				break
+			}
+		}
+		if next == "" || depth == 1 {
+			return report, nil
+		}
+
+		m, err := p.ParseMove(next)
+		if err != nil {
+			return Report{}, err
+		}
+		p = p.Play(m)
+		moves = append(moves[:len(moves):len(moves)], next)
+	}
+}
+
+// differences returns the moves counted differently ordered by move
+func differences(ours []Move, theirs map[string]uint64) []Difference {
+	var diffs []Difference
+	seen := make(map[string]bool)
+	for _, m := range ours {
+		seen[m.Move] = true
+		if theirs[m.Move] != m.Nodes {
+			diffs = append(diffs, Difference{Move: m.Move, Ours: m.Nodes, Theirs: theirs[m.Move]})
+		}
+	}
+	for move, nodes := range theirs {
+		if !seen[move] {
+			diffs = append(diffs, Difference{Move: move, Theirs: nodes})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Move < diffs[j].Move })
+	return diffs
+}
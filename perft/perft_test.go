@@ -0,0 +1,144 @@
+package perft
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// positions are the standard perft positions with their counts from depth 1,
+// the deeper counts are only checked without -short
+var positions = []struct {
+	name   string
+	fen    string
+	counts []uint64
+}{
+	{"start", rules.StartFEN, []uint64{20, 400, 8902, 197281}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890, 3894594}},
+	{"chess960", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672}},
+}
+
+func TestCount(t *testing.T) {
+	for _, test := range positions {
+		p, err := rules.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range test.counts {
+			depth := i + 1
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := Count(p, depth); nodes != expected {
+				t.Errorf("Expecting %v nodes at depth %v of %v got %v", expected, depth, test.name, nodes)
+			}
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	moves := Divide(rules.StartingPosition(), 2)
+	if len(moves) != 20 || Total(moves) != 400 || moves[0] != (Move{Move: "a2a3", Nodes: 20}) {
+		t.Errorf("Expecting 20 moves with 20 replies each got %v", moves)
+	}
+}
+
+// buggy divides like this package except that it misses a move in one position
+type buggy struct {
+	hash uint64
+	move string
+}
+
+func (b buggy) Divide(fen string, moves []string, depth int) (map[string]uint64, error) {
+	p := rules.StartingPosition()
+	if fen != "" {
+		var err error
+		if p, err = rules.ParseFEN(fen); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range moves {
+		m, err := p.ParseMove(s)
+		if err != nil {
+			return nil, err
+		}
+		p = p.Play(m)
+	}
+
+	counts := make(map[string]uint64)
+	for _, m := range b.moves(p) {
+		counts[m.String()] = b.count(p.Play(m), depth-1)
+	}
+	return counts, nil
+}
+
+func (b buggy) moves(p *rules.Position) []rules.Move {
+	var moves []rules.Move
+	for _, m := range p.LegalMoves() {
+		if p.Hash() != b.hash || m.String() != b.move {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func (b buggy) count(p *rules.Position, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	var nodes uint64
+	for _, m := range b.moves(p) {
+		nodes += b.count(p.Play(m), depth-1)
+	}
+	return nodes
+}
+
+func TestCompare(t *testing.T) {
+	const afterA6 = "rnbqkbnr/1ppppppp/p7/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2"
+	enPassant, _ := rules.ParseFEN("rnbqkbnr/1pp1pppp/p7/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3")
+
+	// The other engine does not capture en passant
+	report, err := Compare(afterA6, 3, buggy{hash: enPassant.Hash(), move: "e5d6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Report{
+		Moves:       []string{"e4e5", "d7d5"},
+		FEN:         enPassant.FEN(),
+		Depth:       1,
+		Differences: []Difference{{Move: "e5d6", Ours: 1}},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expecting %+v got %+v", expected, report)
+	}
+
+	report, err = Compare("", 3, buggy{})
+	if err != nil || len(report.Differences) != 0 || len(report.Moves) != 0 {
+		t.Errorf("Expecting the counts to agree got %+v %v", report, err)
+	}
+}
+
+func BenchmarkCount(b *testing.B) {
+	for _, test := range positions[:2] {
+		p, err := rules.ParseFEN(test.fen)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			start := time.Now()
+			var nodes uint64
+			for i := 0; i < b.N; i++ {
+				nodes += Count(p, 4)
+			}
+			b.Logf("%.0f nodes/s", float64(nodes)/time.Since(start).Seconds())
+		})
+	}
+}
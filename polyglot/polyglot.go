@@ -0,0 +1,250 @@
+// Package polyglot reads and writes opening books in the Polyglot .bin format
+// and picks book moves for positions
+package polyglot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// entrySize is the number of bytes of an entry in a book file
+const entrySize = 16
+
+// Entry is a move of a book for the position with the key
+type Entry struct {
+	// The key of the position
+	Key uint64
+	// The move in the Polyglot encoding
+	Move uint16
+	// How often the move is played relative to the other moves of the position
+	Weight uint16
+	// Learning data, unused
+	Learn uint32
+}
+
+// Book is a Polyglot opening book
+type Book struct {
+	// Depth is the number of plies from the start of a game the book is used
+	// for, the book is used while it has moves when zero
+	Depth int
+
+	mu      sync.Mutex
+	entries []Entry
+	rand    *rand.Rand
+}
+
+// New creates a book of entries, moves are picked at random with the seed so
+// the same seed picks the same moves
+func New(entries []Entry, seed int64) *Book {
+	b := &Book{
+		entries: append([]Entry(nil), entries...),
+		rand:    rand.New(rand.NewSource(seed)),
+	}
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return b.entries[i].Key < b.entries[j].Key
+	})
+	return b
+}
+
+// Open reads a book file
+func Open(path string, seed int64) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := Read(f, seed)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid book %v: %v", path, err)
+	}
+	return b, nil
+}
+
+// Read reads a book of 16 byte big endian entries
+func Read(r io.Reader, seed int64) (*Book, error) {
+	var entries []Entry
+	r = bufio.NewReader(r)
+	buf := make([]byte, entrySize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("Expecting %v byte entries, the last entry has %v bytes", entrySize, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+	return New(entries, seed), nil
+}
+
+// Write writes entries as a book sorted by key
+func Write(w io.Writer, entries []Entry) error {
+	sorted := New(entries, 0).entries
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, entrySize)
+	for _, e := range sorted {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Len returns the number of entries in the book
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns the entries for a position
+func (b *Book) Entries(p *rules.Position) []Entry {
+	key := Key(p)
+	i := sort.Search(len(b.entries), func(i int) bool {
+		return b.entries[i].Key >= key
+	})
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+	return b.entries[i:j]
+}
+
+// Choose picks a legal move of the position at random weighted by how often
+// the book plays it. ply is the number of moves played since the start of the
+// game, no move is picked once the book's depth is reached.
+func (b *Book) Choose(p *rules.Position, ply int) (rules.Move, bool) {
+	if b.Depth > 0 && ply >= b.Depth {
+		return rules.Move{}, false
+	}
+
+	var moves []rules.Move
+	var weights []int
+	total := 0
+	for _, e := range b.Entries(p) {
+		m, err := DecodeMove(p, e.Move)
+		if err != nil || e.Weight == 0 {
+			continue
+		}
+		moves = append(moves, m)
+		weights = append(weights, int(e.Weight))
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return rules.Move{}, false
+	}
+
+	b.mu.Lock()
+	n := b.rand.Intn(total)
+	b.mu.Unlock()
+	for i, w := range weights {
+		if n < w {
+			return moves[i], true
+		}
+		n -= w
+	}
+	return moves[len(moves)-1], true
+}
+
+// Key returns the key of a position as described by the Polyglot book format
+func Key(p *rules.Position) uint64 {
+	var key uint64
+	for sq := rules.Square(0); sq < 64; sq++ {
+		piece := p.Piece(sq)
+		if piece == rules.NoPiece {
+			continue
+		}
+		kind := 2 * int(piece.Type()-rules.Pawn)
+		if piece.Color() == rules.White {
+			kind++
+		}
+		key ^= random64[64*kind+8*sq.Rank()+sq.File()]
+	}
+
+	for i, right := range []rules.CastlingRights{rules.WhiteKingSide, rules.WhiteQueenSide, rules.BlackKingSide, rules.BlackQueenSide} {
+		if p.Castling()&right != 0 {
+			key ^= random64[castlingKeys+i]
+		}
+	}
+	if canCaptureEnPassant(p) {
+		key ^= random64[enPassantKeys+p.EnPassant().File()]
+	}
+	if p.Turn() == rules.White {
+		key ^= random64[turnKey]
+	}
+	return key
+}
+
+// canCaptureEnPassant returns true when a pawn of the side to move is beside
+// the pawn that just moved two squares, only then the en passant file is part
+// of the key
+func canCaptureEnPassant(p *rules.Position) bool {
+	ep := p.EnPassant()
+	if ep == rules.NoSquare {
+		return false
+	}
+	rank := ep.Rank() - 1
+	if p.Turn() == rules.Black {
+		rank = ep.Rank() + 1
+	}
+	for _, file := range []int{ep.File() - 1, ep.File() + 1} {
+		if file >= 0 && file < 8 && p.Piece(rules.NewSquare(file, rank)) == rules.NewPiece(p.Turn(), rules.Pawn) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeMove encodes a legal move of a position as a book move, castling is
+// encoded as the king taking its own rook
+func EncodeMove(p *rules.Position, m rules.Move) uint16 {
+	to := p.Notation(m, true).To
+
+	var promotion uint16
+	if m.Promotion != rules.NoPieceType {
+		promotion = uint16(m.Promotion - rules.Pawn)
+	}
+	return promotion<<12 | uint16(m.From.Rank())<<9 | uint16(m.From.File())<<6 | uint16(to.Rank())<<3 | uint16(to.File())
+}
+
+// DecodeMove decodes a book move and checks it is legal in the position
+func DecodeMove(p *rules.Position, move uint16) (rules.Move, error) {
+	m := rules.Move{
+		From: rules.NewSquare(int(move>>6&7), int(move>>9&7)),
+		To:   rules.NewSquare(int(move&7), int(move>>3&7)),
+	}
+	if promotion := move >> 12 & 7; promotion != 0 {
+		m.Promotion = rules.Pawn + rules.PieceType(promotion)
+	}
+
+	// castling is the king taking its own rook
+	king, rook := p.Piece(m.From), p.Piece(m.To)
+	if king.Type() == rules.King && rook == rules.NewPiece(king.Color(), rules.Rook) {
+		return p.ParseUCI(m.String(), true)
+	}
+
+	if !p.IsLegal(m) {
+		return rules.Move{}, fmt.Errorf("Illegal book move %v in position %v", m, p.FEN())
+	}
+	return m, nil
+}
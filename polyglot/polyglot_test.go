@@ -0,0 +1,182 @@
+package polyglot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+func position(t *testing.T, moves ...string) *rules.Position {
+	p := rules.StartingPosition()
+	for _, s := range moves {
+		m, err := p.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p = p.Play(m)
+	}
+	return p
+}
+
+func TestOpen(t *testing.T) {
+	book, err := Open("testdata/small.bin", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Len() != 10 {
+		t.Fatalf("Expecting 10 entries got %v", book.Len())
+	}
+
+	castle, ok := book.Choose(position(t, "e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "g8f6"), 6)
+	if !ok || castle.String() != "e1g1" {
+		t.Errorf("Expecting to castle got %v %v", castle, ok)
+	}
+	// c7c5 has no weight so e7e5 is always played
+	for i := 0; i < 10; i++ {
+		if m, ok := book.Choose(position(t, "e2e4"), 1); !ok || m.String() != "e7e5" {
+			t.Errorf("Expecting e7e5 got %v %v", m, ok)
+		}
+	}
+	if m, ok := book.Choose(position(t, "g1f3"), 1); ok {
+		t.Errorf("Expecting no move out of the book got %v", m)
+	}
+
+	if _, err := Read(strings.NewReader("short"), 1); err == nil {
+		t.Error("Expecting a partial entry to fail")
+	}
+}
+
+func TestChoose(t *testing.T) {
+	book, err := Open("testdata/small.bin", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		m, ok := book.Choose(rules.StartingPosition(), 0)
+		if !ok {
+			t.Fatal("Expecting a book move in the starting position")
+		}
+		counts[m.String()]++
+	}
+	// e2e4 has a weight of 3 and d2d4 a weight of 1
+	if len(counts) != 2 || counts["e2e4"] < 2800 || counts["e2e4"] > 3200 {
+		t.Errorf("Expecting e2e4 three times as often as d2d4 got %v", counts)
+	}
+
+	book.Depth = 4
+	if _, ok := book.Choose(position(t, "e2e4", "e7e5", "g1f3"), 3); !ok {
+		t.Error("Expecting a book move before the depth")
+	}
+	if m, ok := book.Choose(position(t, "e2e4", "e7e5", "g1f3", "b8c6"), 4); ok {
+		t.Errorf("Expecting no move at the depth got %v", m)
+	}
+}
+
+func TestKey(t *testing.T) {
+	// The keys given with the Polyglot book format
+	for _, test := range []struct {
+		moves []string
+		key   uint64
+	}{
+		{nil, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	} {
+		if key := Key(position(t, test.moves...)); key != test.key {
+			t.Errorf("Expecting the key of %v to be %#016x got %#016x", test.moves, test.key, key)
+		}
+	}
+
+	// The en passant square is only part of the key when the capture is possible
+	fen := func(fen string) *rules.Position {
+		p, err := rules.ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	if Key(position(t, "e2e4")) != Key(fen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")) {
+		t.Error("Expecting an en passant square without a capture to be ignored")
+	}
+	capture := position(t, "e2e4", "a7a6", "e4e5", "d7d5")
+	if Key(capture) == Key(fen("rnbqkbnr/1pp1pppp/p7/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3")) {
+		t.Error("Expecting an en passant capture to change the key")
+	}
+	if Key(rules.StartingPosition()) == Key(fen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1")) {
+		t.Error("Expecting the side to move to change the key")
+	}
+	if Key(rules.StartingPosition()) == Key(fen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w Kkq - 0 1")) {
+		t.Error("Expecting castling rights to change the key")
+	}
+}
+
+func TestMoves(t *testing.T) {
+	for _, test := range []struct {
+		fen     string
+		move    string
+		encoded uint16
+	}{
+		{rules.StartFEN, "e2e4", 0x031c},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", 0x0107},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", 0x0f38},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", 0x4c79},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", 0x1c79},
+	} {
+		p, err := rules.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := p.ParseMove(test.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded := EncodeMove(p, m); encoded != test.encoded {
+			t.Errorf("Expecting %v to encode as %#x got %#x", test.move, test.encoded, encoded)
+		}
+		if decoded, err := DecodeMove(p, test.encoded); err != nil || decoded != m {
+			t.Errorf("Expecting %#x to decode as %v got %v %v", test.encoded, test.move, decoded, err)
+		}
+	}
+
+	if _, err := DecodeMove(rules.StartingPosition(), 0x0124); err == nil {
+		t.Error("Expecting an illegal move to fail")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p := rules.StartingPosition()
+	m, _ := p.ParseMove("g1f3")
+	entries := []Entry{
+		{Key: 3, Move: 1, Weight: 2, Learn: 4},
+		{Key: Key(p), Move: EncodeMove(p, m), Weight: 1},
+		{Key: 1, Move: 2, Weight: 3},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 3*entrySize {
+		t.Fatalf("Expecting %v bytes got %v", 3*entrySize, buf.Len())
+	}
+	book, err := Read(&buf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.entries[0] != entries[2] || book.entries[1] != entries[0] {
+		t.Errorf("Expecting entries sorted by key got %v", book.entries)
+	}
+	if got, ok := book.Choose(p, 0); !ok || got != m {
+		t.Errorf("Expecting %v got %v %v", m, got, ok)
+	}
+}
@@ -0,0 +1,245 @@
+package rules
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Bitboards have a bit set for each square of a set eg. the squares of the
+// white knights, a1 is the lowest bit and h8 the highest.
+const (
+	fileA uint64 = 0x0101010101010101
+	fileH        = fileA << 7
+	rank1 uint64 = 0xff
+	rank8        = rank1 << 56
+	// darkSquares are the squares of the color of a1
+	darkSquares uint64 = 0xaa55aa55aa55aa55
+)
+
+// lsb returns the lowest square of a non-empty bitboard
+func lsb(b uint64) Square {
+	return Square(bits.TrailingZeros64(b))
+}
+
+// magic finds the attacks of a slider in a table indexed by multiplying the
+// blockers on its lines by a number that maps each set of blockers to an
+// entry with the same attacks
+type magic struct {
+	mask    uint64
+	number  uint64
+	shift   uint
+	attacks []uint64
+}
+
+func (m *magic) index(occupied uint64) uint64 {
+	return ((occupied & m.mask) * m.number) >> m.shift
+}
+
+// attackTables are the attacks from each square computed at start up
+type attackTables struct {
+	// pawn is the squares a pawn of each color attacks
+	pawn   [2][64]uint64
+	knight [64]uint64
+	king   [64]uint64
+	// between is the squares strictly between two squares on a line and line
+	// the whole line through them, both are empty for squares on no line
+	between [64][64]uint64
+	line    [64][64]uint64
+	bishop  [64]magic
+	rook    [64]magic
+}
+
+var attacks = newAttackTables()
+
+func newAttackTables() *attackTables {
+	t := &attackTables{}
+	for sq := Square(0); sq < 64; sq++ {
+		t.knight[sq] = steps(sq, knightDeltas)
+		t.king[sq] = steps(sq, kingDeltas)
+		t.pawn[White][sq] = steps(sq, []delta{{-1, 1}, {1, 1}})
+		t.pawn[Black][sq] = steps(sq, []delta{{-1, -1}, {1, -1}})
+	}
+
+	for a := Square(0); a < 64; a++ {
+		for _, deltas := range [][]delta{bishopDeltas, rookDeltas} {
+			for _, d := range deltas {
+				ray := uint64(0)
+				for b := offset(a, d); b != NoSquare; b = offset(b, d) {
+					t.between[a][b] = ray
+					ray |= squareBit(b)
+				}
+			}
+			for b := Square(0); b < 64; b++ {
+				if attacks := slide(a, 0, deltas); attacks&squareBit(b) != 0 {
+					t.line[a][b] = attacks&slide(b, 0, deltas) | squareBit(a) | squareBit(b)
+				}
+			}
+		}
+	}
+
+	for sq := Square(0); sq < 64; sq++ {
+		t.bishop[sq] = newMagic(sq, bishopDeltas, bishopMagics[sq])
+		t.rook[sq] = newMagic(sq, rookDeltas, rookMagics[sq])
+	}
+	return t
+}
+
+// steps returns the squares one step of each delta away from sq
+func steps(sq Square, deltas []delta) uint64 {
+	b := uint64(0)
+	for _, d := range deltas {
+		if to := offset(sq, d); to != NoSquare {
+			b |= squareBit(to)
+		}
+	}
+	return b
+}
+
+// slide returns the squares a slider on sq attacks along deltas stopping at
+// the first occupied square in each direction
+func slide(sq Square, occupied uint64, deltas []delta) uint64 {
+	b := uint64(0)
+	for _, d := range deltas {
+		for to := offset(sq, d); to != NoSquare; to = offset(to, d) {
+			b |= squareBit(to)
+			if occupied&squareBit(to) != 0 {
+				break
+			}
+		}
+	}
+	return b
+}
+
+// newMagic fills the table of attacks of a slider on sq indexed by a magic number
+func newMagic(sq Square, deltas []delta, number uint64) magic {
+	// the last square of a line is attacked whether or not it is occupied
+	edges := (rank1|rank8)&^(rank1<<uint(8*sq.Rank())) | (fileA|fileH)&^(fileA<<uint(sq.File()))
+	m := magic{mask: slide(sq, 0, deltas) &^ edges, number: number}
+	n := bits.OnesCount64(m.mask)
+	m.shift = uint(64 - n)
+	m.attacks = make([]uint64, 1<<uint(n))
+
+	// enumerate the subsets of the mask
+	for b := uint64(0); ; {
+		attacks := slide(sq, b, deltas)
+		if index := m.index(b); m.attacks[index] == 0 {
+			m.attacks[index] = attacks
+		} else if m.attacks[index] != attacks {
+			panic(fmt.Sprintf("magic number %x of %v maps different attacks to one entry", number, sq))
+		}
+		if b = (b - m.mask) & m.mask; b == 0 {
+			return m
+		}
+	}
+}
+
+func bishopAttacks(sq Square, occupied uint64) uint64 {
+	m := &attacks.bishop[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+func rookAttacks(sq Square, occupied uint64) uint64 {
+	m := &attacks.rook[sq]
+	return m.attacks[m.index(occupied)]
+}
+
+// pieces returns the squares of the pieces of color c and type pt
+func (p *Position) pieces(c Color, pt PieceType) uint64 {
+	return p.colors[c] & p.types[pt]
+}
+
+// occupied returns the squares of every piece
+func (p *Position) occupied() uint64 {
+	return p.colors[White] | p.colors[Black]
+}
+
+// put places a piece on a square replacing any piece there
+func (p *Position) put(sq Square, piece Piece) {
+	p.remove(sq)
+	p.board[sq] = piece
+	p.colors[piece.Color()] |= squareBit(sq)
+	p.types[piece.Type()] |= squareBit(sq)
+	p.hash ^= zobrist.pieces[piece][sq]
+}
+
+// remove takes any piece off a square
+func (p *Position) remove(sq Square) {
+	piece := p.board[sq]
+	if piece == NoPiece {
+		return
+	}
+	p.board[sq] = NoPiece
+	p.colors[piece.Color()] &^= squareBit(sq)
+	p.types[piece.Type()] &^= squareBit(sq)
+	p.hash ^= zobrist.pieces[piece][sq]
+}
+
+// attackers returns the pieces of color by attacking sq with the given
+// squares occupied, kings are only counted with kings set
+func (p *Position) attackers(sq Square, by Color, occupied uint64, kings bool) uint64 {
+	b := attacks.pawn[by.Other()][sq]&p.types[Pawn] |
+		attacks.knight[sq]&p.types[Knight] |
+		bishopAttacks(sq, occupied)&(p.types[Bishop]|p.types[Queen]) |
+		rookAttacks(sq, occupied)&(p.types[Rook]|p.types[Queen])
+	if kings {
+		b |= attacks.king[sq] & p.types[King]
+	}
+	return b & p.colors[by] & occupied
+}
+
+// pinned returns the pieces of color c that may only move along the line
+// between their king and an attacking slider
+func (p *Position) pinned(c Color, king Square) uint64 {
+	them := c.Other()
+	snipers := rookAttacks(king, 0)&(p.pieces(them, Rook)|p.pieces(them, Queen)) |
+		bishopAttacks(king, 0)&(p.pieces(them, Bishop)|p.pieces(them, Queen))
+	occupied := p.occupied()
+	pinned := uint64(0)
+	for ; snipers != 0; snipers &= snipers - 1 {
+		blockers := attacks.between[king][lsb(snipers)] & occupied
+		if blockers != 0 && blockers&(blockers-1) == 0 {
+			pinned |= blockers & p.colors[c]
+		}
+	}
+	return pinned
+}
+
+// The magic numbers of each square found by trying sparse random numbers
+// until one maps every set of blockers to an entry with its attacks
+var bishopMagics = [64]uint64{
+	0x0020010400808600, 0xa008010410820000, 0x1004440082038008, 0x0904040098084800,
+	0x600c052000520541, 0x4002010420402022, 0x0011040104400480, 0x0200104104202080,
+	0x1200210204080080, 0x6c18600204e20682, 0x00002202004200e0, 0x0100044404810840,
+	0x0400220211108110, 0x020002011009000c, 0x00a00200a2084210, 0x0202008098011000,
+	0x0c40002004019206, 0x116042040804c500, 0x419002080a80200a, 0x0004000844000800,
+	0x000404b080a04800, 0x4608080482012002, 0x44040500a0880841, 0x2002100909050d00,
+	0x008404004030a400, 0x0090709004040080, 0x11444043040d0204, 0x0008080100202020,
+	0x0801001181004000, 0x4140822002021000, 0x0102089092009006, 0x540a042100540203,
+	0x0050100409482820, 0x8010880900041004, 0x0000230100500414, 0x0000200800050810,
+	0x8294064010040100, 0x9010100220044404, 0x154202022004008e, 0x0009420220008401,
+	0x0071080840110401, 0x2000a40420400201, 0x0802619048001004, 0x209280a058000500,
+	0x2004044810100a00, 0x0a0208d000804300, 0x000638a80d000684, 0x0001910401000080,
+	0x0800420210400200, 0x0004404410090100, 0x8020808400880000, 0x0400081042120c21,
+	0x4009001022120001, 0x4902220802082000, 0x0410841000820290, 0x0820020401002440,
+	0x0800420041084000, 0x000010818c05a000, 0x000301804213d000, 0x0800040018208801,
+	0x1b80000004104405, 0x2500214084184884, 0x1000628801050400, 0x8040229e24002080,
+}
+
+var rookMagics = [64]uint64{
+	0x018010a040018000, 0x0040002000401001, 0x290010a841e00100, 0x29001000050900a0,
+	0x4080030400800800, 0x1200040200100801, 0x2200208200040851, 0x220000820425004c,
+	0x0104800740008020, 0x0420400020005000, 0x0844801000200480, 0x4004808008001000,
+	0x4009000410080100, 0x0003000400020900, 0x4804000810020104, 0x0074800641800900,
+	0x0080004000402000, 0x1c90004040002000, 0x4000430020010113, 0x82c501000b100120,
+	0x0848808004020800, 0x4522808004000200, 0x0000010100020004, 0x400206000092411c,
+	0x818004444000a000, 0x0180a000c0005002, 0x000b104100200100, 0x8000090100201001,
+	0x0214080080040080, 0x0002018200041008, 0x0000020400010810, 0x0101040200004891,
+	0x2048204004800084, 0x8840201000404000, 0x0038801000802000, 0x8012000822001040,
+	0x0080080080800400, 0x0000020080800400, 0x4022220184000850, 0x0000204102000084,
+	0x0001e44000848004, 0x0009500120064000, 0x0000820120460010, 0x084200200a420010,
+	0x0000080004008080, 0x3010400420080110, 0x0000414210040008, 0x0010348400460001,
+	0x0080002000401040, 0x0460200088400080, 0x8201822000100280, 0x0600100008008280,
+	0x00c0800800040080, 0x0024040080020080, 0x0000100801a20400, 0x040001040040a200,
+	0x8308160080412102, 0x0050108200210046, 0x6008200040090211, 0x0000042008100101,
+	0x0283000800100205, 0x0002008810010402, 0x0490102200880104, 0x4010808844050222,
+}
@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMagics(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for sq := Square(0); sq < 64; sq++ {
+		for i := 0; i < 200; i++ {
+			occupied := r.Uint64() & r.Uint64()
+			if got, expected := bishopAttacks(sq, occupied), slide(sq, occupied, bishopDeltas); got != expected {
+				t.Fatalf("Expecting bishop attacks %x from %v got %x", expected, sq, got)
+			}
+			if got, expected := rookAttacks(sq, occupied), slide(sq, occupied, rookDeltas); got != expected {
+				t.Fatalf("Expecting rook attacks %x from %v got %x", expected, sq, got)
+			}
+		}
+	}
+}
+
+func TestIncrementalHash(t *testing.T) {
+	if h := StartingPosition().Hash(); h != 2905512168068252549 {
+		t.Errorf("Expecting the hash of the starting position not to change got %v", h)
+	}
+
+	r := rand.New(rand.NewSource(3))
+	for _, v := range Variants {
+		for game := 0; game < 20; game++ {
+			p := NewPosition(v)
+			for ply := 0; ply < 80; ply++ {
+				moves := p.LegalMoves()
+				if len(moves) == 0 {
+					break
+				}
+				p = p.Play(moves[r.Intn(len(moves))])
+
+				if p.hash != p.zobristHash() {
+					t.Fatalf("Expecting the hash of %v to match the pieces", p.FEN())
+				}
+				for sq, piece := range p.board {
+					bit := squareBit(Square(sq))
+					if piece != NoPiece && p.pieces(piece.Color(), piece.Type())&bit == 0 || piece == NoPiece && p.occupied()&bit != 0 {
+						t.Fatalf("Expecting the bitboards of %v to match the board at %v", p.FEN(), Square(sq))
+					}
+				}
+			}
+		}
+	}
+}
+
+// pv is a line an engine might send in an info line
+var pv = strings.Fields("e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7 f1e1 b7b5 a4b3 d7d6")
+
+func BenchmarkLegalMoves(b *testing.B) {
+	p, _ := ParseFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	moves := make([]Move, 0, 256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		moves = p.AppendLegalMoves(moves[:0])
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	initial := *StartingPosition()
+	b.ReportAllocs()
+	start := time.Now()
+	var p, next Position
+	for i := 0; i < b.N; i++ {
+		p = initial
+		for _, s := range pv {
+			m, err := p.ParseMove(s)
+			if err != nil {
+				b.Fatal(err)
+			}
+			p.PlayTo(&next, m)
+			p = next
+		}
+	}
+	b.Logf("%.0f moves/s", float64(b.N*len(pv))/time.Since(start).Seconds())
+}
@@ -0,0 +1,153 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, parse func(string) (*Position, error), fen string) *Position {
+	t.Helper()
+
+	p, err := parse(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func play(t *testing.T, p *Position, moves ...string) *Position {
+	t.Helper()
+
+	for _, s := range moves {
+		m, err := p.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p = p.Play(m)
+	}
+	return p
+}
+
+func TestChess960Positions(t *testing.T) {
+	seen := map[string]bool{}
+	for n := 0; n < 960; n++ {
+		p, err := Chess960(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rank := strings.Split(p.FEN(), "/")[7][:8]
+		seen[rank] = true
+
+		bishops, rooks := []int{}, []int{}
+		king := strings.IndexByte(rank, 'K')
+		for file := range rank {
+			switch rank[file] {
+			case 'B':
+				bishops = append(bishops, file)
+			case 'R':
+				rooks = append(rooks, file)
+			}
+		}
+		if len(bishops) != 2 || bishops[0]%2 == bishops[1]%2 || len(rooks) != 2 || king < rooks[0] || king > rooks[1] {
+			t.Fatalf("Invalid Chess960 position %v: %v", n, rank)
+		}
+		if !p.Chess960() || p.Castling() != WhiteKingSide|WhiteQueenSide|BlackKingSide|BlackQueenSide {
+			t.Errorf("Expecting position %v to be Chess960 with all castling rights got %v", n, p.FEN())
+		}
+	}
+	if len(seen) != 960 {
+		t.Errorf("Expecting 960 different positions got %v", len(seen))
+	}
+
+	for n, rank := range map[int]string{0: "bbqnnrkr", 518: "rnbqkbnr", 959: "rkrnnqbb"} {
+		p, _ := Chess960(n)
+		if !strings.HasPrefix(p.FEN(), rank+"/") {
+			t.Errorf("Expecting position %v to be %v got %v", n, rank, p.FEN())
+		}
+	}
+	if _, err := Chess960(960); err == nil {
+		t.Error("Expecting an error for position 960")
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	p := parse(t, ParseFEN960, "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1")
+	if p.CastlingRook(WhiteKingSide) != NewSquare(6, 0) || p.CastlingRook(BlackQueenSide) != NewSquare(1, 7) {
+		t.Errorf("Expecting the rooks on g1 and b8 to castle got %v", p.FEN())
+	}
+
+	tests := []struct {
+		move string
+		fen  string
+	}{
+		{"e1g1", "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R3RK1 b kq - 1 1"},
+		{"e1b1", "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/2KR2R1 b kq - 1 1"},
+	}
+	for _, test := range tests {
+		if next := play(t, p, test.move); next.FEN() != test.fen {
+			t.Errorf("Expecting %v after %v got %v", test.fen, test.move, next.FEN())
+		}
+	}
+
+	// Moving a rook loses its right
+	if next := play(t, p, "b1a1"); next.Castling() != WhiteKingSide|BlackKingSide|BlackQueenSide {
+		t.Errorf("Expecting white to lose the queen side right got %v", next.Castling())
+	}
+	// The king may stay where it is and castling is named in SAN
+	king := parse(t, ParseFEN960, "4k3/8/8/8/8/8/8/6KR w K - 0 1")
+	m, _ := king.ParseMove("g1h1")
+	if san := king.SAN(m); san != "O-O" {
+		t.Errorf("Expecting O-O got %v", san)
+	}
+	if next := king.Play(m); next.FEN() != "4k3/8/8/8/8/8/8/5RK1 b - - 1 1" {
+		t.Errorf("Expecting the rook to move next to the king got %v", next.FEN())
+	}
+	// The king may not pass through check
+	if _, err := parse(t, ParseFEN960, "4kr2/8/8/8/8/8/8/1R2K1R1 w K - 0 1").ParseMove("e1g1"); err == nil {
+		t.Error("Expecting castling through check to be illegal")
+	}
+}
+
+func TestCastlingFEN(t *testing.T) {
+	tests := []struct {
+		fen, expected string
+		chess960      bool
+	}{
+		{StartFEN, StartFEN, false},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", StartFEN, true},
+		{"4k3/8/8/8/8/8/8/R3KR1R w F - 0 1", "4k3/8/8/8/8/8/8/R3KR1R w F - 0 1", true},
+		{"4k3/8/8/8/8/8/8/R3K1R1 w KQ - 0 1", "4k3/8/8/8/8/8/8/R3K1R1 w Q - 0 1", false},
+	}
+	for _, test := range tests {
+		p := parse(t, ParseFEN, test.fen)
+		if p.FEN() != test.expected || p.Chess960() != test.chess960 {
+			t.Errorf("Expecting %v to be %v got %v", test.fen, test.expected, p.FEN())
+		}
+	}
+}
+
+func TestNotation(t *testing.T) {
+	standard := play(t, StartingPosition(), "e2e4", "e7e5", "g1f3", "b8c6", "f1c4", "f8c5")
+	m, _ := standard.ParseMove("e1g1")
+	if s := standard.UCI(m, true); s != "e1h1" {
+		t.Errorf("Expecting e1h1 got %v", s)
+	}
+	if parsed, err := standard.ParseUCI("e1h1", true); err != nil || parsed != m {
+		t.Errorf("Expecting e1g1 got %v %v", parsed, err)
+	}
+
+	chess960 := parse(t, ParseFEN960, "1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w KQkq - 0 1")
+	for standard, king := range map[string]string{"e1g1": "e1g1", "e1c1": "e1b1"} {
+		parsed, err := chess960.ParseUCI(standard, false)
+		if err != nil || parsed.String() != king {
+			t.Errorf("Expecting %v to be %v got %v %v", standard, king, parsed, err)
+		}
+		if s := chess960.UCI(parsed, false); s != standard {
+			t.Errorf("Expecting %v got %v", standard, s)
+		}
+	}
+	// A king move that could also be castling is a king move
+	if m, err := parse(t, ParseFEN960, "4k3/8/8/8/8/8/8/RK6 w Q - 0 1").ParseUCI("b1c1", false); err != nil || m.String() != "b1c1" {
+		t.Errorf("Expecting the king move b1c1 got %v %v", m, err)
+	}
+}
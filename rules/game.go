@@ -0,0 +1,281 @@
+package rules
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+)
+
+// Result is the result of a game
+type Result int
+
+// The possible results
+const (
+	NoResult Result = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// String returns the result in PGN notation
+func (r Result) String() string {
+	switch r {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// Win returns the result of a win by color c
+func Win(c Color) Result {
+	if c == White {
+		return WhiteWins
+	}
+	return BlackWins
+}
+
+// Termination describes why a game ended
+type Termination string
+
+// The terminations detected by the rules
+const (
+	Checkmate            Termination = "checkmate"
+	Stalemate            Termination = "stalemate"
+	FiftyMoveRule        Termination = "fifty move rule"
+	ThreefoldRepetition  Termination = "threefold repetition"
+	InsufficientMaterial Termination = "insufficient material"
+)
+
+// Outcome is the result of a game and the reason it ended
+type Outcome struct {
+	Result      Result
+	Termination Termination
+}
+
+func (o Outcome) String() string {
+	if o.Result == NoResult {
+		return "*"
+	}
+	return fmt.Sprintf("%v (%v)", o.Result, o.Termination)
+}
+
+// Game is a sequence of moves from a starting position
+type Game struct {
+	positions []*Position
+	moves     []Move
+	hashes    []uint64
+}
+
+// NewGame creates a game starting from a position
+func NewGame(start *Position) *Game {
+	return &Game{positions: []*Position{start}, hashes: []uint64{start.Hash()}}
+}
+
+// Start returns the starting position of the game
+func (g *Game) Start() *Position {
+	return g.positions[0]
+}
+
+// Position returns the current position of the game
+func (g *Game) Position() *Position {
+	return g.positions[len(g.positions)-1]
+}
+
+// Moves returns the moves played so far
+func (g *Game) Moves() []Move {
+	return g.moves
+}
+
+// Play checks a move is legal and plays it
+func (g *Game) Play(m Move) error {
+	if g.Outcome().Result != NoResult {
+		return fmt.Errorf("Game is over")
+	}
+
+	p := g.Position()
+	if !p.IsLegal(m) {
+		return fmt.Errorf("Illegal move %v in position %v", m, p.FEN())
+	}
+
+	next := p.Play(m)
+	g.positions = append(g.positions, next)
+	g.moves = append(g.moves, m)
+	g.hashes = append(g.hashes, next.Hash())
+	return nil
+}
+
+// LastMove returns the last move played and the position it was played from,
+// ok is false before the first move
+func (g *Game) LastMove() (m Move, from *Position, ok bool) {
+	if len(g.moves) == 0 {
+		return Move{}, nil, false
+	}
+	return g.moves[len(g.moves)-1], g.positions[len(g.positions)-2], true
+}
+
+// Outcome returns the outcome of the game or an outcome with NoResult while it is in progress
+func (g *Game) Outcome() Outcome {
+	p := g.Position()
+	v := p.rules()
+
+	if o := v.outcome(p); o.Result != NoResult {
+		return o
+	}
+	if len(p.LegalMoves()) == 0 {
+		return v.noMoves(p)
+	}
+	if v.insufficientMaterial(p) {
+		return Outcome{Result: Draw, Termination: InsufficientMaterial}
+	}
+	if p.halfmoves >= 100 {
+		return Outcome{Result: Draw, Termination: FiftyMoveRule}
+	}
+	if g.repetitions() >= 3 {
+		return Outcome{Result: Draw, Termination: ThreefoldRepetition}
+	}
+
+	return Outcome{}
+}
+
+// repetitions returns how many times the current position has occurred
+func (g *Game) repetitions() int {
+	current := len(g.hashes) - 1
+	count := 0
+	// Positions before the last capture or pawn move can not repeat
+	for i := current; i >= 0 && i >= current-g.Position().halfmoves; i-- {
+		if g.hashes[i] == g.hashes[current] {
+			count++
+		}
+	}
+	return count
+}
+
+// insufficientMaterial returns true when neither side can possibly checkmate
+func (p *Position) insufficientMaterial() bool {
+	if p.types[Pawn]|p.types[Rook]|p.types[Queen] != 0 {
+		return false
+	}
+	knights := bits.OnesCount64(p.types[Knight])
+	bishops := [2]int{
+		bits.OnesCount64(p.types[Bishop] & darkSquares),
+		bits.OnesCount64(p.types[Bishop] &^ darkSquares),
+	}
+
+	if knights+bishops[0]+bishops[1] <= 1 {
+		return true
+	}
+	// Any number of bishops all on the same color of square can not mate
+	return knights == 0 && (bishops[0] == 0 || bishops[1] == 0)
+}
+
+var zobrist = newZobristKeys()
+
+type zobristKeys struct {
+	pieces    [16][64]uint64
+	black     uint64
+	castling  [16]uint64
+	enPassant [8]uint64
+	// the pockets of crazyhouse by color, piece type and count
+	pockets [2][7][32]uint64
+	// the checks given in three-check by color and count
+	checks [2][4]uint64
+}
+
+func newZobristKeys() *zobristKeys {
+	r := rand.New(rand.NewSource(1070372))
+	keys := &zobristKeys{black: r.Uint64()}
+	for piece := range keys.pieces {
+		for sq := range keys.pieces[piece] {
+			keys.pieces[piece][sq] = r.Uint64()
+		}
+	}
+	for i := range keys.castling {
+		keys.castling[i] = r.Uint64()
+	}
+	for i := range keys.enPassant {
+		keys.enPassant[i] = r.Uint64()
+	}
+	// the variant keys come last so the keys of standard chess do not change
+	for c := range keys.pockets {
+		for pt := range keys.pockets[c] {
+			for n := range keys.pockets[c][pt] {
+				keys.pockets[c][pt][n] = r.Uint64()
+			}
+		}
+	}
+	for c := range keys.checks {
+		for n := range keys.checks[c] {
+			keys.checks[c][n] = r.Uint64()
+		}
+	}
+	return keys
+}
+
+// Hash returns a Zobrist hash of the position used to detect repetitions
+func (p *Position) Hash() uint64 {
+	h := p.hash
+	if p.turn == Black {
+		h ^= zobrist.black
+	}
+	h ^= zobrist.castling[p.castling]
+	if p.canCaptureEnPassant() {
+		h ^= zobrist.enPassant[p.enPassant.File()]
+	}
+	return h
+}
+
+// zobristHash returns the part of the hash kept up to date as moves are
+// played computed from scratch
+func (p *Position) zobristHash() uint64 {
+	var h uint64
+	for sq, piece := range p.board {
+		if piece != NoPiece {
+			h ^= zobrist.pieces[piece][sq]
+		}
+	}
+	for c := range p.pockets {
+		for pt, n := range p.pockets[c] {
+			if n > 0 {
+				h ^= zobrist.pockets[c][pt][n]
+			}
+		}
+		if n := p.checks[c]; n > 0 {
+			h ^= zobrist.checks[c][n]
+		}
+	}
+	return h
+}
+
+// setPocket sets how many pieces of a type color c may drop
+func (p *Position) setPocket(c Color, pt PieceType, n int) {
+	if old := p.pockets[c][pt]; old > 0 {
+		p.hash ^= zobrist.pockets[c][pt][old]
+	}
+	if n > 0 {
+		p.hash ^= zobrist.pockets[c][pt][n]
+	}
+	p.pockets[c][pt] = n
+}
+
+// setChecks sets how many checks color c has given
+func (p *Position) setChecks(c Color, n int) {
+	if old := p.checks[c]; old > 0 {
+		p.hash ^= zobrist.checks[c][old]
+	}
+	if n > 0 {
+		p.hash ^= zobrist.checks[c][n]
+	}
+	p.checks[c] = n
+}
+
+// canCaptureEnPassant returns true when a pawn of the side to move is beside the en passant square
+func (p *Position) canCaptureEnPassant() bool {
+	if p.enPassant == NoSquare {
+		return false
+	}
+	return attacks.pawn[p.turn.Other()][p.enPassant]&p.pieces(p.turn, Pawn) != 0
+}
@@ -0,0 +1,436 @@
+package rules
+
+import "fmt"
+
+type delta struct {
+	file, rank int
+}
+
+var (
+	knightDeltas = []delta{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingDeltas   = []delta{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+	bishopDeltas = []delta{{1, 1}, {1, -1}, {-1, -1}, {-1, 1}}
+	rookDeltas   = []delta{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	promotions   = []PieceType{Queen, Rook, Bishop, Knight}
+)
+
+// offset returns the square d away from sq or NoSquare when it is off the board
+func offset(sq Square, d delta) Square {
+	file, rank := sq.File()+d.file, sq.Rank()+d.rank
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return NoSquare
+	}
+	return NewSquare(file, rank)
+}
+
+func pawnDirection(c Color) int {
+	if c == White {
+		return 1
+	}
+	return -1
+}
+
+// isAttacked returns true when a piece of color by attacks sq, kings are
+// only counted with kings set
+func (p *Position) isAttacked(sq Square, by Color, kings bool) bool {
+	if sq == NoSquare {
+		return false
+	}
+	return p.attackers(sq, by, p.occupied(), kings) != 0
+}
+
+// InCheck returns true when the side to move is in check
+func (p *Position) InCheck() bool {
+	return p.inCheck(p.turn)
+}
+
+// pseudoLegalMoves appends all moves of the pieces on the board ignoring
+// whether the king is left in check
+func (p *Position) pseudoLegalMoves(moves []Move) []Move {
+	us := p.turn
+	own, occupied := p.colors[us], p.occupied()
+
+	moves = p.pawnMoves(moves)
+	for b := p.pieces(us, Knight); b != 0; b &= b - 1 {
+		from := lsb(b)
+		moves = appendMoves(moves, from, attacks.knight[from]&^own)
+	}
+	for b := p.pieces(us, Bishop) | p.pieces(us, Queen); b != 0; b &= b - 1 {
+		from := lsb(b)
+		moves = appendMoves(moves, from, bishopAttacks(from, occupied)&^own)
+	}
+	for b := p.pieces(us, Rook) | p.pieces(us, Queen); b != 0; b &= b - 1 {
+		from := lsb(b)
+		moves = appendMoves(moves, from, rookAttacks(from, occupied)&^own)
+	}
+	for b := p.pieces(us, King); b != 0; b &= b - 1 {
+		from := lsb(b)
+		moves = appendMoves(moves, from, attacks.king[from]&^own)
+		moves = p.castlingMoves(moves, from)
+	}
+	return moves
+}
+
+// appendMoves appends a move from a square to each square of a bitboard
+func appendMoves(moves []Move, from Square, targets uint64) []Move {
+	for ; targets != 0; targets &= targets - 1 {
+		moves = append(moves, Move{From: from, To: lsb(targets)})
+	}
+	return moves
+}
+
+func (p *Position) pawnMoves(moves []Move) []Move {
+	us := p.turn
+	v := p.rules()
+	dir := 8 * pawnDirection(us)
+	empty := ^p.occupied()
+	last := rank8
+	if us == Black {
+		last = rank1
+	}
+	enemies := p.colors[us.Other()]
+	if p.enPassant != NoSquare {
+		enemies |= squareBit(p.enPassant)
+	}
+
+	addPawnMoves := func(from Square, targets uint64) {
+		for ; targets != 0; targets &= targets - 1 {
+			to := lsb(targets)
+			if squareBit(to)&last == 0 {
+				moves = append(moves, Move{From: from, To: to})
+				continue
+			}
+			for _, promotion := range v.promotions() {
+				moves = append(moves, Move{From: from, To: to, Promotion: promotion})
+			}
+		}
+	}
+
+	for b := p.pieces(us, Pawn); b != 0; b &= b - 1 {
+		from := lsb(b)
+		if to := int(from) + dir; to >= 0 && to < 64 && empty&squareBit(Square(to)) != 0 {
+			addPawnMoves(from, squareBit(Square(to)))
+			if to2 := to + dir; v.doublePush(us, from.Rank()) && empty&squareBit(Square(to2)) != 0 {
+				moves = append(moves, Move{From: from, To: Square(to2)})
+			}
+		}
+		addPawnMoves(from, attacks.pawn[us][from]&enemies)
+	}
+	return moves
+}
+
+// castlingMoves adds the castling moves of the king. The king and rook end
+// on the g and f files or the c and d files wherever they start, the squares
+// between them and their destinations must be empty and the king may not pass
+// through check.
+func (p *Position) castlingMoves(moves []Move, from Square) []Move {
+	us := p.turn
+	them := us.Other()
+	if !p.rules().castles() || p.inCheck(us) {
+		return moves
+	}
+
+	rank := from.Rank()
+	for _, kingSide := range []bool{true, false} {
+		right := castlingRight(us, kingSide)
+		if p.castling&right == 0 {
+			continue
+		}
+		rook := p.castlingRooks[rightIndex(right)]
+		kingTo, rookTo := castlingSquares(rank, kingSide)
+
+		ok := true
+		low, high := span(from, rook, kingTo, rookTo)
+		for file := low; file <= high && ok; file++ {
+			sq := NewSquare(file, rank)
+			ok = sq == from || sq == rook || p.board[sq] == NoPiece
+		}
+		low, high = span(from, kingTo)
+		for file := low; file <= high && ok; file++ {
+			ok = !p.attacked(NewSquare(file, rank), them)
+		}
+		if !ok {
+			continue
+		}
+
+		if p.chess960 {
+			moves = append(moves, Move{From: from, To: rook})
+		} else {
+			moves = append(moves, Move{From: from, To: kingTo})
+		}
+	}
+	return moves
+}
+
+// castlingSquares returns where the king and rook end after castling
+func castlingSquares(rank int, kingSide bool) (king, rook Square) {
+	if kingSide {
+		return NewSquare(6, rank), NewSquare(5, rank)
+	}
+	return NewSquare(2, rank), NewSquare(3, rank)
+}
+
+// span returns the lowest and highest file of squares on a rank
+func span(squares ...Square) (int, int) {
+	low, high := 7, 0
+	for _, sq := range squares {
+		if sq.File() < low {
+			low = sq.File()
+		}
+		if sq.File() > high {
+			high = sq.File()
+		}
+	}
+	return low, high
+}
+
+// IsCastling returns true when a move is castling, the king moving two squares
+// or in Chess960 the king taking its own rook
+func (p *Position) IsCastling(m Move) bool {
+	piece := p.board[m.From]
+	if piece.Type() != King {
+		return false
+	}
+	if p.chess960 {
+		return p.board[m.To] == NewPiece(piece.Color(), Rook)
+	}
+	return m.From.File()-m.To.File() == 2 || m.To.File()-m.From.File() == 2
+}
+
+// castlingRook returns the square of the rook of a castling move
+func (p *Position) castlingRook(m Move) Square {
+	if p.chess960 {
+		return m.To
+	}
+	if m.To.File() > m.From.File() {
+		return NewSquare(7, m.From.Rank())
+	}
+	return NewSquare(0, m.From.Rank())
+}
+
+// Notation returns a move of the position with castling written as the king
+// moving two squares or with chess960 set as the king taking its own rook
+func (p *Position) Notation(m Move, chess960 bool) Move {
+	if chess960 == p.chess960 || !p.IsCastling(m) {
+		return m
+	}
+	if chess960 {
+		return Move{From: m.From, To: p.castlingRook(m)}
+	}
+	king, _ := castlingSquares(m.From.Rank(), p.castlingRook(m).File() > m.From.File())
+	return Move{From: m.From, To: king}
+}
+
+// UCI returns a move of the position in UCI notation with castling written
+// as the king moving two squares or with chess960 set as the king taking its
+// own rook
+func (p *Position) UCI(m Move, chess960 bool) string {
+	return p.Notation(m, chess960).String()
+}
+
+// ParseUCI parses a move in UCI notation with castling written as the king
+// moving two squares or with chess960 set as the king taking its own rook and
+// checks it is legal. A Chess960 king move that could also be castling is
+// taken as the king move.
+func (p *Position) ParseUCI(s string, chess960 bool) (Move, error) {
+	if chess960 == p.chess960 {
+		return p.ParseMove(s)
+	}
+	m, err := ParseMove(s)
+	if err != nil {
+		return Move{}, err
+	}
+
+	found := false
+	var castling Move
+	for _, legal := range p.LegalMoves() {
+		if !p.IsCastling(legal) && legal == m {
+			return m, nil
+		}
+		if p.IsCastling(legal) && p.Notation(legal, chess960) == m {
+			castling, found = legal, true
+		}
+	}
+	if !found {
+		return Move{}, fmt.Errorf("Illegal move %v in position %v", s, p.FEN())
+	}
+	return castling, nil
+}
+
+// LegalMoves returns all the legal moves in the position
+func (p *Position) LegalMoves() []Move {
+	return p.AppendLegalMoves(make([]Move, 0, 64))
+}
+
+// AppendLegalMoves appends the legal moves in the position to moves. Standard
+// chess positions do not allocate when moves has room for them.
+func (p *Position) AppendLegalMoves(moves []Move) []Move {
+	if p.variant != nil {
+		return append(moves, p.variantMoves()...)
+	}
+
+	n := len(moves)
+	moves = p.pseudoLegalMoves(moves)
+	us := p.turn
+	king := p.kingSquare(us)
+	checkers := p.attackers(king, us.Other(), p.occupied(), true)
+	pinned := p.pinned(us, king)
+
+	legal := moves[:n]
+	for _, m := range moves[n:] {
+		if p.isLegal(m, king, checkers, pinned) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// isLegal returns true when a pseudo-legal move of standard chess does not
+// leave the king in check
+func (p *Position) isLegal(m Move, king Square, checkers, pinned uint64) bool {
+	us, them := p.turn, p.turn.Other()
+	occupied := p.occupied()
+
+	switch {
+	case p.IsCastling(m):
+		// the rook no longer blocks the destination of the king once it moves
+		kingTo, rookTo := castlingSquares(m.From.Rank(), p.castlingRook(m).File() > m.From.File())
+		occupied = occupied&^squareBit(m.From)&^squareBit(p.castlingRook(m)) | squareBit(kingTo) | squareBit(rookTo)
+		return p.attackers(kingTo, them, occupied, true) == 0
+	case m.From == king:
+		return p.attackers(m.To, them, occupied&^squareBit(king), true) == 0
+	case m.To == p.enPassant && p.board[m.From].Type() == Pawn:
+		captured := Square(int(m.To) - 8*pawnDirection(us))
+		occupied = occupied&^squareBit(m.From)&^squareBit(captured) | squareBit(m.To)
+		return p.attackers(king, them, occupied, true) == 0
+	}
+
+	if pinned&squareBit(m.From) != 0 && attacks.line[king][m.From]&squareBit(m.To) == 0 {
+		return false
+	}
+	switch {
+	case checkers == 0:
+		return true
+	case checkers&(checkers-1) != 0:
+		return false
+	}
+	checker := lsb(checkers)
+	return (attacks.between[king][checker]|checkers)&squareBit(m.To) != 0
+}
+
+// variantMoves returns the legal moves of a variant by playing each move
+// and letting the variant decide
+func (p *Position) variantMoves() []Move {
+	v := p.rules()
+	moves := v.drops(p, p.pseudoLegalMoves(make([]Move, 0, 64)))
+	legal := moves[:0]
+	next := &Position{}
+	for _, m := range moves {
+		p.PlayTo(next, m)
+		if v.legal(p, next, m) {
+			legal = append(legal, m)
+		}
+	}
+	return v.filter(p, legal)
+}
+
+// IsLegal returns true when the move is legal in the position
+func (p *Position) IsLegal(m Move) bool {
+	var buf [256]Move
+	for _, legal := range p.AppendLegalMoves(buf[:0]) {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMove parses a move in UCI notation and checks it is legal in the position
+func (p *Position) ParseMove(s string) (Move, error) {
+	m, err := ParseMove(s)
+	if err != nil {
+		return Move{}, err
+	}
+	if !p.IsLegal(m) {
+		return Move{}, fmt.Errorf("Illegal move %v in position %v", s, p.FEN())
+	}
+	return m, nil
+}
+
+// Play returns the position after making a move. The move is not checked for legality.
+func (p *Position) Play(m Move) *Position {
+	next := &Position{}
+	p.PlayTo(next, m)
+	return next
+}
+
+// PlayTo sets next to the position after making a move without allocating,
+// next may not be p. The move is not checked for legality.
+func (p *Position) PlayTo(next *Position, m Move) {
+	p.play(next, m)
+	if p.variant != nil {
+		p.variant.played(p, next, m)
+	}
+}
+
+// play makes a move by the rules of standard chess and drops
+func (p *Position) play(next *Position, m Move) {
+	*next = *p
+	piece := p.board[m.From]
+	captured := p.board[m.To]
+	us := p.turn
+
+	next.enPassant = NoSquare
+	next.halfmoves++
+	if us == Black {
+		next.fullmoves++
+	}
+	next.turn = us.Other()
+
+	if p.IsCastling(m) {
+		rook := p.castlingRook(m)
+		kingTo, rookTo := castlingSquares(m.From.Rank(), rook.File() > m.From.File())
+		next.remove(m.From)
+		next.remove(rook)
+		next.put(kingTo, piece)
+		next.put(rookTo, NewPiece(us, Rook))
+		next.castling &^= castlingRight(us, true) | castlingRight(us, false)
+		return
+	}
+
+	if m.Drop != NoPieceType {
+		next.put(m.To, NewPiece(us, m.Drop))
+		next.setPocket(us, m.Drop, p.pockets[us][m.Drop]-1)
+		return
+	}
+
+	next.remove(m.From)
+	next.put(m.To, piece)
+	if captured != NoPiece {
+		next.halfmoves = 0
+	}
+
+	switch piece.Type() {
+	case Pawn:
+		next.halfmoves = 0
+		dir := 8 * pawnDirection(us)
+		if m.To == p.enPassant {
+			next.remove(Square(int(m.To) - dir))
+		}
+		if int(m.To)-int(m.From) == 2*dir {
+			next.enPassant = Square(int(m.From) + dir)
+		}
+		if m.Promotion != NoPieceType {
+			next.put(m.To, NewPiece(us, m.Promotion))
+		}
+	case King:
+		next.castling &^= castlingRight(us, true) | castlingRight(us, false)
+	}
+
+	// moving or capturing a rook loses its right
+	for i, rook := range p.castlingRooks {
+		if right := CastlingRights(1 << uint(i)); p.castling&right != 0 && (m.From == rook || m.To == rook) {
+			next.castling &^= right
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LAN returns a legal move in Long Algebraic Notation eg. Ng1-f3, Bb5xc6+,
+// e7-e8=Q, O-O or N@f3 for a drop
+func (p *Position) LAN(m Move) string {
+	piece := p.board[m.From]
+
+	var lan string
+	switch {
+	case m.Drop != NoPieceType:
+		lan = m.String()
+	case p.IsCastling(m) && p.castlingRook(m).File() > m.From.File():
+		lan = "O-O"
+	case p.IsCastling(m):
+		lan = "O-O-O"
+	default:
+		if piece.Type() != Pawn {
+			lan = strings.ToUpper(pieceTypeChars[piece.Type() : piece.Type()+1])
+		}
+		lan += m.From.String()
+		if p.captures(m) {
+			lan += "x"
+		} else {
+			lan += "-"
+		}
+		lan += m.To.String()
+		if m.Promotion != NoPieceType {
+			lan += "=" + strings.ToUpper(pieceTypeChars[m.Promotion:m.Promotion+1])
+		}
+	}
+	return lan + p.checkSuffix(m)
+}
+
+// ParseLAN parses a move in Long Algebraic Notation and checks it is legal in
+// the position. Check marks and annotations are ignored and the - or x
+// between the squares and the = of a promotion may be left out.
+func (p *Position) ParseLAN(s string) (Move, error) {
+	for _, m := range p.LegalMoves() {
+		if matches(s, p.LAN(m)) {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("Illegal move %v in position %v", s, p.FEN())
+}
+
+// Notation is a way of writing moves
+type Notation int
+
+// The notations moves are converted between
+const (
+	// UCINotation is long algebraic notation as engines write it eg. e2e4 or e7e8q
+	UCINotation Notation = iota
+	// SANNotation is Standard Algebraic Notation eg. exd8=Q+
+	SANNotation
+	// LANNotation is Long Algebraic Notation eg. e7xd8=Q+
+	LANNotation
+)
+
+// Format writes a legal move in a notation, castling in UCI is written as
+// the king taking its own rook in Chess960 positions
+func (p *Position) Format(m Move, n Notation) string {
+	switch n {
+	case SANNotation:
+		return p.SAN(m)
+	case LANNotation:
+		return p.LAN(m)
+	}
+	return m.String()
+}
+
+// Parse parses a move in a notation and checks it is legal in the position
+func (p *Position) Parse(s string, n Notation) (Move, error) {
+	switch n {
+	case SANNotation:
+		return p.ParseSAN(s)
+	case LANNotation:
+		return p.ParseLAN(s)
+	}
+	return p.ParseMove(s)
+}
+
+// Convert converts a line of moves played from the position from one
+// notation to another. It returns the moves converted before the first move
+// that is not legal with the error.
+func (p *Position) Convert(moves []string, from, to Notation) ([]string, error) {
+	converted := make([]string, 0, len(moves))
+	for _, s := range moves {
+		m, err := p.Parse(s, from)
+		if err != nil {
+			return converted, err
+		}
+		converted = append(converted, p.Format(m, to))
+		p = p.Play(m)
+	}
+	return converted, nil
+}
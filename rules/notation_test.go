@@ -0,0 +1,83 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLAN(t *testing.T) {
+	tests := []struct {
+		fen, move, lan, san string
+	}{
+		{StartFEN, "g1f3", "Ng1-f3", "Nf3"},
+		{StartFEN, "e2e4", "e2-e4", "e4"},
+		{"3r3k/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7d8q", "e7xd8=Q+", "exd8=Q+"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", "O-O-O", "O-O-O"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "e5xd6", "exd6"},
+	}
+	for _, test := range tests {
+		p := parse(t, ParseFEN, test.fen)
+		m, err := p.ParseMove(test.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lan := p.LAN(m); lan != test.lan {
+			t.Errorf("Expecting %v to be %v got %v", test.move, test.lan, lan)
+		}
+		if parsed, err := p.ParseLAN(test.lan); err != nil || parsed != m {
+			t.Errorf("Expecting %v to parse as %v got %v %v", test.lan, test.move, parsed, err)
+		}
+		if san := p.Format(m, SANNotation); san != test.san {
+			t.Errorf("Expecting %v to be %v got %v", test.move, test.san, san)
+		}
+	}
+
+	drop := play(t, NewPosition(Crazyhouse), "e2e4", "d7d5", "e4d5", "d8d5")
+	if m, _ := ParseMove("P@e6"); drop.LAN(m) != "P@e6" {
+		t.Errorf("Expecting the drop P@e6 got %v", drop.LAN(m))
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	p := parse(t, ParseFEN, "3r2k1/4P3/8/8/8/8/8/R3K2R w KQ - 0 1")
+	for san, move := range map[string]string{
+		"exd8=Q+": "e7d8q",
+		"exd8Q":   "e7d8q",
+		"ed8=N":   "e7d8n",
+		"0-0":     "e1g1",
+		"O-O-O":   "",
+		"Ra1-a7":  "",
+		"Rxa7":    "",
+		"Ra7!?":   "a1a7",
+	} {
+		m, err := p.ParseSAN(san)
+		if move == "" {
+			if err == nil {
+				t.Errorf("Expecting %v not to parse got %v", san, m)
+			}
+			continue
+		}
+		if err != nil || m.String() != move {
+			t.Errorf("Expecting %v to be %v got %v %v", san, move, m, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	san := []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"}
+	uci, err := StartingPosition().Convert(san, SANNotation, UCINotation)
+	if err != nil || !reflect.DeepEqual(uci, []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"}) {
+		t.Errorf("Expecting the moves in UCI notation got %v %v", uci, err)
+	}
+	lan, err := StartingPosition().Convert(uci, UCINotation, LANNotation)
+	if err != nil || lan[len(lan)-1] != "Qh5xf7#" {
+		t.Errorf("Expecting the mate in LAN got %v %v", lan, err)
+	}
+
+	// The moves before an illegal move are converted
+	converted, err := StartingPosition().Convert([]string{"e4", "e4"}, SANNotation, UCINotation)
+	if err == nil || !reflect.DeepEqual(converted, []string{"e2e4"}) {
+		t.Errorf("Expecting the first move and an error got %v %v", converted, err)
+	}
+}
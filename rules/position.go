@@ -0,0 +1,556 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// StartFEN is the FEN of the standard starting position
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// CastlingRights records which castling moves are still available
+type CastlingRights uint8
+
+// The individual castling rights
+const (
+	WhiteKingSide CastlingRights = 1 << iota
+	WhiteQueenSide
+	BlackKingSide
+	BlackQueenSide
+)
+
+func (c CastlingRights) String() string {
+	s := ""
+	for i, char := range "KQkq" {
+		if c&(1<<uint(i)) != 0 {
+			s += string(char)
+		}
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Position is a chess position including the side to move and move counters
+type Position struct {
+	board [64]Piece
+	// colors and types are bitboards of the squares of the pieces of each color and type
+	colors    [2]uint64
+	types     [7]uint64
+	turn      Color
+	castling  CastlingRights
+	enPassant Square
+	halfmoves int
+	fullmoves int
+	// chess960 is set for Chess960 positions where castling is the king taking its own rook
+	chess960 bool
+	// castlingRooks is the square of the rook of each castling right
+	castlingRooks [4]Square
+	// variant is the rules of the position, nil for standard chess
+	variant Variant
+	// pockets counts the pieces of each type each color may drop in crazyhouse
+	pockets [2][7]int
+	// promoted has a bit set for each square holding a promoted piece in crazyhouse
+	promoted uint64
+	// checks counts the checks each color has given in three-check
+	checks [2]int
+	// hash is the Zobrist hash of the pieces, pockets and checks kept up to
+	// date as moves are played
+	hash uint64
+}
+
+// StartingPosition returns the standard starting position
+func StartingPosition() *Position {
+	p, _ := ParseFEN(StartFEN)
+	return p
+}
+
+// Chess960 returns Chess960 starting position n from 0 to 959, 518 is the
+// standard starting position
+func Chess960(n int) (*Position, error) {
+	if n < 0 || n > 959 {
+		return nil, fmt.Errorf("Invalid Chess960 position %v", n)
+	}
+
+	var rank [8]byte
+	empty := func(i int) int {
+		for file := range rank {
+			if rank[file] == 0 {
+				if i == 0 {
+					return file
+				}
+				i--
+			}
+		}
+		return -1
+	}
+	rank[2*(n%4)+1] = 'b'
+	n /= 4
+	rank[2*(n%4)] = 'b'
+	n /= 4
+	rank[empty(n%6)] = 'q'
+	n /= 6
+	knights := [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}[n]
+	// the second knight is placed first so the first knight's square is not taken
+	rank[empty(knights[1])] = 'n'
+	rank[empty(knights[0])] = 'n'
+	for _, piece := range []byte{'r', 'k', 'r'} {
+		rank[empty(0)] = piece
+	}
+
+	black := string(rank[:])
+	fen := fmt.Sprintf("%v/pppppppp/8/8/8/8/PPPPPPPP/%v w KQkq - 0 1", black, strings.ToUpper(black))
+	return ParseFEN960(fen)
+}
+
+// ParseFEN parses a position in Forsyth-Edwards Notation. Castling rights
+// given by the files of the rooks as in Shredder-FEN make it a Chess960 position.
+func ParseFEN(fen string) (*Position, error) {
+	return parseFEN(fen, Standard, false)
+}
+
+// ParseFEN960 parses a Chess960 position in X-FEN or Shredder-FEN, KQkq are
+// the rights of the outermost rooks
+func ParseFEN960(fen string) (*Position, error) {
+	return parseFEN(fen, Standard, true)
+}
+
+// ParseVariantFEN parses a position of a variant. Crazyhouse pockets are
+// given in brackets after the board or as a ninth rank with promoted pieces
+// followed by ~. Three-check counters are the checks left to give as a 3+3
+// field after the en passant square or the checks given as a trailing +0+0.
+func ParseVariantFEN(v Variant, fen string) (*Position, error) {
+	return parseFEN(fen, v, false)
+}
+
+func parseFEN(fen string, v Variant, chess960 bool) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("Invalid FEN %q: expecting at least 4 fields", fen)
+	}
+
+	p := &Position{enPassant: NoSquare, fullmoves: 1, chess960: chess960}
+	if v != Standard {
+		p.variant = v
+	}
+
+	board := fields[0]
+	if v == ThreeCheck {
+		var err error
+		if fields, err = p.parseChecks(fields); err != nil {
+			return nil, fmt.Errorf("Invalid FEN %q: %v", fen, err)
+		}
+	}
+	if v == Crazyhouse {
+		var err error
+		if board, err = p.parsePockets(board); err != nil {
+			return nil, fmt.Errorf("Invalid FEN %q: %v", fen, err)
+		}
+	}
+
+	ranks := strings.Split(board, "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("Invalid FEN %q: expecting 8 ranks", fen)
+	}
+	for i, rank := range ranks {
+		file := 0
+		for j := 0; j < len(rank); j++ {
+			char := rank[j]
+			if char >= '1' && char <= '8' {
+				file += int(char - '0')
+				continue
+			}
+			if char == '~' && v == Crazyhouse && file > 0 && j > 0 && rank[j-1] > '8' {
+				p.promoted |= squareBit(NewSquare(file-1, 7-i))
+				continue
+			}
+			piece, ok := pieceFromChar(char)
+			if !ok || file > 7 {
+				return nil, fmt.Errorf("Invalid FEN %q: bad rank %q", fen, rank)
+			}
+			p.put(NewSquare(file, 7-i), piece)
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("Invalid FEN %q: bad rank %q", fen, rank)
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+	default:
+		return nil, fmt.Errorf("Invalid FEN %q: bad side to move %q", fen, fields[1])
+	}
+
+	if err := p.parseCastling(fields[2]); err != nil {
+		return nil, fmt.Errorf("Invalid FEN %q: %v", fen, err)
+	}
+
+	if fields[3] != "-" {
+		sq, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid FEN %q: bad en passant square %q", fen, fields[3])
+		}
+		p.enPassant = sq
+	}
+
+	if len(fields) > 4 {
+		halfmoves, err := strconv.Atoi(fields[4])
+		if err != nil || halfmoves < 0 {
+			return nil, fmt.Errorf("Invalid FEN %q: bad halfmove clock %q", fen, fields[4])
+		}
+		p.halfmoves = halfmoves
+	}
+	if len(fields) > 5 {
+		fullmoves, err := strconv.Atoi(fields[5])
+		if err != nil || fullmoves < 1 {
+			return nil, fmt.Errorf("Invalid FEN %q: bad fullmove number %q", fen, fields[5])
+		}
+		p.fullmoves = fullmoves
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid FEN %q: %v", fen, err)
+	}
+
+	p.hash = p.zobristHash()
+	return p, nil
+}
+
+// validate checks the position could be reached in a game
+func (p *Position) validate() error {
+	return p.rules().validate(p)
+}
+
+// parsePockets reads the crazyhouse pockets from the end of the board field
+// and returns the board without them
+func (p *Position) parsePockets(board string) (string, error) {
+	var pocket string
+	switch {
+	case strings.HasSuffix(board, "]"):
+		i := strings.IndexByte(board, '[')
+		if i < 0 {
+			return "", fmt.Errorf("bad pocket %q", board)
+		}
+		board, pocket = board[:i], board[i+1:len(board)-1]
+	case strings.Count(board, "/") == 8:
+		i := strings.LastIndexByte(board, '/')
+		board, pocket = board[:i], board[i+1:]
+	}
+	for i := 0; i < len(pocket); i++ {
+		piece, ok := pieceFromChar(pocket[i])
+		if !ok || piece.Type() == King {
+			return "", fmt.Errorf("bad pocket %q", pocket)
+		}
+		p.pockets[piece.Color()][piece.Type()]++
+	}
+	return board, nil
+}
+
+// parseChecks reads the three-check counters and returns the other fields
+func (p *Position) parseChecks(fields []string) ([]string, error) {
+	parse := func(s string) (int, int, bool) {
+		var a, b int
+		if n, err := fmt.Sscanf(s, "%d+%d", &a, &b); err != nil || n != 2 || a < 0 || b < 0 || a > 3 || b > 3 {
+			return 0, 0, false
+		}
+		return a, b, true
+	}
+
+	last := fields[len(fields)-1]
+	if strings.HasPrefix(last, "+") {
+		white, black, ok := parse(last[1:])
+		if !ok {
+			return nil, fmt.Errorf("bad checks %q", last)
+		}
+		p.checks = [2]int{white, black}
+		return fields[:len(fields)-1], nil
+	}
+	if len(fields) > 4 && strings.Contains(fields[4], "+") {
+		white, black, ok := parse(fields[4])
+		if !ok {
+			return nil, fmt.Errorf("bad checks %q", fields[4])
+		}
+		p.checks = [2]int{3 - white, 3 - black}
+		return append(fields[:4:4], fields[5:]...), nil
+	}
+	return fields, nil
+}
+
+// castlingRight returns the castling right of a color on one side of its king
+func castlingRight(c Color, kingSide bool) CastlingRights {
+	right := WhiteKingSide
+	if !kingSide {
+		right = WhiteQueenSide
+	}
+	if c == Black {
+		right <<= 2
+	}
+	return right
+}
+
+// rightIndex returns the index of a single castling right
+func rightIndex(right CastlingRights) int {
+	i := 0
+	for right > 1 {
+		right >>= 1
+		i++
+	}
+	return i
+}
+
+// parseCastling reads the castling rights of a FEN. Rights the placement of
+// kings and rooks does not allow are dropped. The files of the rooks may be
+// given as in Shredder-FEN, otherwise K and Q are the rooks on the h and a
+// files or in Chess960 the outermost rooks.
+func (p *Position) parseCastling(field string) error {
+	if field == "-" {
+		return nil
+	}
+	if strings.ContainsAny(field, "ABCDEFGHabcdefgh") {
+		p.chess960 = true
+	}
+
+	for _, char := range field {
+		c, upper := White, unicode.ToUpper(char)
+		if char != upper {
+			c = Black
+		}
+		king := p.kingSquare(c)
+		rank := 0
+		if c == Black {
+			rank = 7
+		}
+
+		rook := NoSquare
+		switch {
+		case upper == 'K' || upper == 'Q':
+			kingSide := upper == 'K'
+			if !p.chess960 {
+				rook = NewSquare(0, rank)
+				if kingSide {
+					rook = NewSquare(7, rank)
+				}
+				if king != NewSquare(4, rank) || p.board[rook] != NewPiece(c, Rook) {
+					continue
+				}
+				break
+			}
+			if king == NoSquare || king.Rank() != rank {
+				continue
+			}
+			rook = p.outermostRook(c, king, kingSide)
+		case upper >= 'A' && upper <= 'H':
+			rook = NewSquare(int(upper-'A'), rank)
+		default:
+			return fmt.Errorf("bad castling rights %q", field)
+		}
+
+		if rook == NoSquare || p.board[rook] != NewPiece(c, Rook) || king == NoSquare || king.Rank() != rank || king == rook {
+			continue
+		}
+		right := castlingRight(c, rook.File() > king.File())
+		p.castling |= right
+		p.castlingRooks[rightIndex(right)] = rook
+	}
+	return nil
+}
+
+// outermostRook returns the rook furthest from the king on one side of it on its first rank
+func (p *Position) outermostRook(c Color, king Square, kingSide bool) Square {
+	file, step := 0, 1
+	if kingSide {
+		file, step = 7, -1
+	}
+	for ; file != king.File(); file += step {
+		if sq := NewSquare(file, king.Rank()); p.board[sq] == NewPiece(c, Rook) {
+			return sq
+		}
+	}
+	return NoSquare
+}
+
+// castlingString returns the castling rights in X-FEN, the file of the rook
+// is given when another rook is further from the king on the same side
+func (p *Position) castlingString() string {
+	if !p.chess960 {
+		return p.castling.String()
+	}
+	s := ""
+	for i, char := range "KQkq" {
+		right := CastlingRights(1 << uint(i))
+		if p.castling&right == 0 {
+			continue
+		}
+		c := White
+		if right&(BlackKingSide|BlackQueenSide) != 0 {
+			c = Black
+		}
+		rook := p.castlingRooks[i]
+		if p.outermostRook(c, p.kingSquare(c), right&(WhiteKingSide|BlackKingSide) != 0) == rook {
+			s += string(char)
+			continue
+		}
+		file := rune('A' + rook.File())
+		if c == Black {
+			file = unicode.ToLower(file)
+		}
+		s += string(file)
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// FEN returns the position in Forsyth-Edwards Notation
+func (p *Position) FEN() string {
+	var b strings.Builder
+
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := p.board[NewSquare(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				b.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			b.WriteString(piece.String())
+			if p.promoted&squareBit(NewSquare(file, rank)) != 0 {
+				b.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			b.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			b.WriteByte('/')
+		}
+	}
+
+	if p.variant == Crazyhouse {
+		b.WriteByte('[')
+		for _, c := range []Color{White, Black} {
+			for pt := Queen; pt >= Pawn; pt-- {
+				b.WriteString(strings.Repeat(NewPiece(c, pt).String(), p.pockets[c][pt]))
+			}
+		}
+		b.WriteByte(']')
+	}
+
+	turn := "w"
+	if p.turn == Black {
+		turn = "b"
+	}
+
+	enPassant := p.enPassant.String()
+	if p.variant == ThreeCheck {
+		enPassant += fmt.Sprintf(" %v+%v", 3-p.checks[White], 3-p.checks[Black])
+	}
+
+	return fmt.Sprintf("%v %v %v %v %v %v", b.String(), turn, p.castlingString(), enPassant, p.halfmoves, p.fullmoves)
+}
+
+func (p *Position) String() string {
+	return p.FEN()
+}
+
+// Piece returns the piece on a square
+func (p *Position) Piece(sq Square) Piece {
+	return p.board[sq]
+}
+
+// Turn returns the side to move
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+// Castling returns the remaining castling rights
+func (p *Position) Castling() CastlingRights {
+	return p.castling
+}
+
+// CastlingRook returns the square of the rook of a single castling right or
+// NoSquare when the right has been lost
+func (p *Position) CastlingRook(right CastlingRights) Square {
+	if p.castling&right == 0 {
+		return NoSquare
+	}
+	return p.castlingRooks[rightIndex(right)]
+}
+
+// Chess960 returns true for Chess960 positions where castling is written as
+// the king taking its own rook
+func (p *Position) Chess960() bool {
+	return p.chess960
+}
+
+// Variant returns the rules of the position
+func (p *Position) Variant() Variant {
+	return p.rules()
+}
+
+// Pocket returns how many pieces of a type color c may drop in crazyhouse
+func (p *Position) Pocket(c Color, pt PieceType) int {
+	return p.pockets[c][pt]
+}
+
+// Checks returns how many checks color c has given in three-check
+func (p *Position) Checks(c Color) int {
+	return p.checks[c]
+}
+
+// EnPassant returns the square a pawn can be captured en passant on or NoSquare
+func (p *Position) EnPassant() Square {
+	return p.enPassant
+}
+
+// HalfmoveClock returns the number of halfmoves since the last capture or pawn move
+func (p *Position) HalfmoveClock() int {
+	return p.halfmoves
+}
+
+// FullmoveNumber returns the number of the current move starting at 1
+func (p *Position) FullmoveNumber() int {
+	return p.fullmoves
+}
+
+func (p *Position) kingSquare(c Color) Square {
+	king := p.pieces(c, King)
+	if king == 0 {
+		return NoSquare
+	}
+	return lsb(king)
+}
+
+// rules returns the variant of the position
+func (p *Position) rules() Variant {
+	if p.variant == nil {
+		return Standard
+	}
+	return p.variant
+}
+
+// attacked returns true when a piece of color by attacks sq under the rules of the variant
+func (p *Position) attacked(sq Square, by Color) bool {
+	return p.rules().attacked(p, sq, by)
+}
+
+// inCheck returns true when the king of color c is in check under the rules of the variant
+func (p *Position) inCheck(c Color) bool {
+	return p.rules().inCheck(p, c)
+}
+
+func squareBit(sq Square) uint64 {
+	return 1 << uint(sq)
+}
@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SAN returns a legal move in Standard Algebraic Notation eg. Nbd7, exd6,
+// O-O+ or N@f3 for a drop
+func (p *Position) SAN(m Move) string {
+	piece := p.board[m.From]
+
+	var san string
+	switch {
+	case m.Drop != NoPieceType:
+		san = m.String()
+	case p.IsCastling(m) && p.castlingRook(m).File() > m.From.File():
+		san = "O-O"
+	case p.IsCastling(m):
+		san = "O-O-O"
+	case piece.Type() == Pawn:
+		if m.From.File() != m.To.File() {
+			san = m.From.String()[:1] + "x"
+		}
+		san += m.To.String()
+		if m.Promotion != NoPieceType {
+			san += "=" + strings.ToUpper(pieceTypeChars[m.Promotion:m.Promotion+1])
+		}
+	default:
+		san = strings.ToUpper(pieceTypeChars[piece.Type():piece.Type()+1]) + p.disambiguation(m)
+		if p.board[m.To] != NoPiece {
+			san += "x"
+		}
+		san += m.To.String()
+	}
+	return san + p.checkSuffix(m)
+}
+
+// checkSuffix returns + for a move that gives check, # for mate and nothing otherwise
+func (p *Position) checkSuffix(m Move) string {
+	next := p.Play(m)
+	if !next.InCheck() {
+		return ""
+	}
+	if len(next.LegalMoves()) == 0 {
+		return "#"
+	}
+	return "+"
+}
+
+// disambiguation returns the file, rank or square needed to tell a piece move
+// apart from moves of other pieces of the same type to the same square
+func (p *Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From.File() == m.From.File()
+		sameRank = sameRank || other.From.Rank() == m.From.Rank()
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return m.From.String()[:1]
+	case !sameRank:
+		return m.From.String()[1:]
+	default:
+		return m.From.String()
+	}
+}
+
+// SANLine returns a line of moves in UCI notation in Standard Algebraic
+// Notation. The line stops before the first move that is not legal.
+func (p *Position) SANLine(moves []string) []string {
+	var san []string
+	for _, s := range moves {
+		m, err := p.ParseMove(s)
+		if err != nil {
+			break
+		}
+		san = append(san, p.SAN(m))
+		p = p.Play(m)
+	}
+	return san
+}
+
+// ParseSAN parses a move in Standard Algebraic Notation and checks it is
+// legal in the position. Check marks and annotations like !? are ignored,
+// captures and promotions may be written without x and = and castling may be
+// written with zeros.
+func (p *Position) ParseSAN(s string) (Move, error) {
+	for _, m := range p.LegalMoves() {
+		if matches(s, p.SAN(m)) {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("Illegal move %v in position %v", s, p.FEN())
+}
+
+// matches returns true when a move in algebraic notation is the notation of a
+// legal move, check marks, annotations and the x, = and - of the legal move
+// may be left out and castling may be written with zeros
+func matches(s, legal string) bool {
+	s = strings.Replace(strings.TrimRight(s, "+#!?"), "0", "O", -1)
+	legal = strings.TrimRight(legal, "+#")
+
+	i := 0
+	for j := 0; j < len(legal); j++ {
+		switch {
+		case i < len(s) && s[i] == legal[j]:
+			i++
+		case !strings.ContainsRune("x=-", rune(legal[j])):
+			return false
+		}
+	}
+	return i == len(s)
+}
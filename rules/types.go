@@ -0,0 +1,179 @@
+// Package rules implements the rules of chess used to referee games
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Color is the color of a piece or a player
+type Color uint8
+
+// The two colors
+const (
+	White Color = iota
+	Black
+)
+
+// Other returns the opposing color
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// PieceType is the kind of a piece without its color
+type PieceType uint8
+
+// The piece types
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+var pieceTypeChars = " pnbrqk"
+
+// Piece is a colored piece
+type Piece uint8
+
+// NoPiece is an empty square
+const NoPiece Piece = 0
+
+// NewPiece creates a piece of the given color and type
+func NewPiece(c Color, pt PieceType) Piece {
+	return Piece(uint8(c)<<3 | uint8(pt))
+}
+
+// Color returns the color of the piece
+func (p Piece) Color() Color {
+	return Color(p >> 3)
+}
+
+// Type returns the type of the piece
+func (p Piece) Type() PieceType {
+	return PieceType(p & 7)
+}
+
+// String returns the FEN character of the piece
+func (p Piece) String() string {
+	if p == NoPiece {
+		return "."
+	}
+	char := pieceTypeChars[p.Type() : p.Type()+1]
+	if p.Color() == White {
+		return strings.ToUpper(char)
+	}
+	return char
+}
+
+func pieceFromChar(char byte) (Piece, bool) {
+	i := strings.IndexByte(pieceTypeChars, char|0x20)
+	if i < 1 {
+		return NoPiece, false
+	}
+	if char >= 'A' && char <= 'Z' {
+		return NewPiece(White, PieceType(i)), true
+	}
+	return NewPiece(Black, PieceType(i)), true
+}
+
+// Square is an index into the board from a1 = 0 to h8 = 63
+type Square int8
+
+// NoSquare is used when there is no square eg. no en passant square
+const NoSquare Square = -1
+
+// NewSquare creates a square from a file and rank in the range 0-7
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// File returns the file of the square from 0 (a) to 7 (h)
+func (s Square) File() int {
+	return int(s) & 7
+}
+
+// Rank returns the rank of the square from 0 (1) to 7 (8)
+func (s Square) Rank() int {
+	return int(s) >> 3
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// ParseSquare parses a square in algebraic notation eg. e4
+func ParseSquare(s string) (Square, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare, fmt.Errorf("Invalid square %q", s)
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+// Move is a move from one square to another with an optional promotion
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+	// Drop is the piece put on To from the pocket in crazyhouse, From is To
+	Drop PieceType
+}
+
+// String returns the move in UCI long algebraic notation eg. e7e8q or N@f3 for a drop
+func (m Move) String() string {
+	if m.Drop != NoPieceType {
+		return strings.ToUpper(pieceTypeChars[m.Drop:m.Drop+1]) + "@" + m.To.String()
+	}
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += pieceTypeChars[m.Promotion : m.Promotion+1]
+	}
+	return s
+}
+
+// ParseMove parses a move in UCI notation without checking it is legal
+func ParseMove(s string) (Move, error) {
+	if len(s) == 4 && s[1] == '@' {
+		pt := strings.IndexByte("PNBRQ", s[0])
+		to, err := ParseSquare(s[2:])
+		if pt < 0 || err != nil {
+			return Move{}, fmt.Errorf("Invalid move %q", s)
+		}
+		return Move{From: to, To: to, Drop: Pawn + PieceType(pt)}, nil
+	}
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, fmt.Errorf("Invalid move %q", s)
+	}
+
+	from, err := ParseSquare(s[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("Invalid move %q", s)
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("Invalid move %q", s)
+	}
+
+	m := Move{From: from, To: to}
+	if len(s) == 5 {
+		i := strings.IndexByte("nbrqk", s[4])
+		if i < 0 {
+			return Move{}, fmt.Errorf("Invalid promotion in move %q", s)
+		}
+		m.Promotion = Knight + PieceType(i)
+	}
+	return m, nil
+}
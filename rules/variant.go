@@ -0,0 +1,552 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VariantEnd is the termination of a game won or drawn by a rule of its variant
+const VariantEnd Termination = "variant end"
+
+// Variant is a set of rules a game is played by. The variants are named as
+// in the UCI_Variant option of engines that play them.
+type Variant interface {
+	// Name returns the UCI name of the variant eg. crazyhouse
+	Name() string
+	// StartFEN returns the FEN of the starting position
+	StartFEN() string
+
+	// validate checks a position could be reached in a game
+	validate(p *Position) error
+	// castles is false when castling is not allowed
+	castles() bool
+	// promotions returns the pieces a pawn may promote to
+	promotions() []PieceType
+	// doublePush returns true when a pawn of color c on rank may move two squares
+	doublePush(c Color, rank int) bool
+	// drops adds the moves placing pieces from the pocket of the side to move
+	drops(p *Position, moves []Move) []Move
+	// attacked returns true when a piece of color by attacks sq
+	attacked(p *Position, sq Square, by Color) bool
+	// inCheck returns true when the king of color c is in check
+	inCheck(p *Position, c Color) bool
+	// legal returns true when a move to next is legal for the side to move of p
+	legal(p, next *Position, m Move) bool
+	// filter removes the legal moves the variant forbids
+	filter(p *Position, moves []Move) []Move
+	// played updates next for the effects of a move the variant adds
+	played(p, next *Position, m Move)
+	// outcome returns the result of a rule of the variant that ends the game
+	outcome(p *Position) Outcome
+	// noMoves returns the outcome when the side to move has no legal moves
+	noMoves(p *Position) Outcome
+	// insufficientMaterial returns true when neither side can possibly win
+	insufficientMaterial(p *Position) bool
+}
+
+// The variants
+var (
+	Standard      Variant = standard{}
+	Crazyhouse    Variant = crazyhouse{}
+	ThreeCheck    Variant = threeCheck{}
+	KingOfTheHill Variant = kingOfTheHill{}
+	Atomic        Variant = atomic{}
+	Antichess     Variant = antichess{}
+	Horde         Variant = horde{}
+	RacingKings   Variant = racingKings{}
+)
+
+// Variants lists every variant
+var Variants = []Variant{Standard, Crazyhouse, ThreeCheck, KingOfTheHill, Atomic, Antichess, Horde, RacingKings}
+
+// ParseVariant returns the variant with a UCI name, case is ignored
+func ParseVariant(name string) (Variant, error) {
+	for _, v := range Variants {
+		if strings.EqualFold(v.Name(), name) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown variant %q", name)
+}
+
+// NewPosition returns the starting position of a variant
+func NewPosition(v Variant) *Position {
+	p, _ := ParseVariantFEN(v, v.StartFEN())
+	return p
+}
+
+// standard is standard chess and the rules the other variants change
+type standard struct{}
+
+func (standard) Name() string {
+	return "chess"
+}
+
+func (standard) StartFEN() string {
+	return StartFEN
+}
+
+func (standard) validate(p *Position) error {
+	for _, c := range []Color{White, Black} {
+		if err := p.validateKings(c, 1); err != nil {
+			return err
+		}
+	}
+	if err := p.validatePawns(White, Black); err != nil {
+		return err
+	}
+	return p.validateCheck()
+}
+
+func (standard) castles() bool {
+	return true
+}
+
+func (standard) promotions() []PieceType {
+	return promotions
+}
+
+func (standard) doublePush(c Color, rank int) bool {
+	if c == White {
+		return rank == 1
+	}
+	return rank == 6
+}
+
+func (standard) drops(p *Position, moves []Move) []Move {
+	return moves
+}
+
+func (standard) attacked(p *Position, sq Square, by Color) bool {
+	return p.isAttacked(sq, by, true)
+}
+
+func (standard) inCheck(p *Position, c Color) bool {
+	return p.attacked(p.kingSquare(c), c.Other())
+}
+
+func (standard) legal(p, next *Position, m Move) bool {
+	return !next.inCheck(p.turn)
+}
+
+func (standard) filter(p *Position, moves []Move) []Move {
+	return moves
+}
+
+func (standard) played(p, next *Position, m Move) {}
+
+func (standard) outcome(p *Position) Outcome {
+	return Outcome{}
+}
+
+func (standard) noMoves(p *Position) Outcome {
+	if p.InCheck() {
+		return Outcome{Result: Win(p.turn.Other()), Termination: Checkmate}
+	}
+	return Outcome{Result: Draw, Termination: Stalemate}
+}
+
+func (standard) insufficientMaterial(p *Position) bool {
+	return p.insufficientMaterial()
+}
+
+// validateKings checks color c has n kings
+func (p *Position) validateKings(c Color, n int) error {
+	kings := 0
+	for _, piece := range p.board {
+		if piece == NewPiece(c, King) {
+			kings++
+		}
+	}
+	if kings != n {
+		return fmt.Errorf("expecting %v %v king found %v", n, c, kings)
+	}
+	return nil
+}
+
+// validatePawns checks no pawn of the colors given is on the first or last rank
+func (p *Position) validatePawns(colors ...Color) error {
+	for _, c := range colors {
+		for file := 0; file < 8; file++ {
+			if p.board[NewSquare(file, 0)] == NewPiece(c, Pawn) || p.board[NewSquare(file, 7)] == NewPiece(c, Pawn) {
+				return fmt.Errorf("pawn on the first or last rank")
+			}
+		}
+	}
+	return nil
+}
+
+// validateCheck checks the side not to move is not in check
+func (p *Position) validateCheck() error {
+	if p.inCheck(p.turn.Other()) {
+		return fmt.Errorf("the side not to move is in check")
+	}
+	return nil
+}
+
+// captures returns true when a move takes a piece
+func (p *Position) captures(m Move) bool {
+	if m.Drop != NoPieceType || p.IsCastling(m) {
+		return false
+	}
+	return p.board[m.To] != NoPiece || (m.To == p.enPassant && p.board[m.From].Type() == Pawn)
+}
+
+// crazyhouse puts the pieces a player captures in their pocket from where
+// they may be dropped on any empty square instead of moving
+type crazyhouse struct {
+	standard
+}
+
+func (crazyhouse) Name() string {
+	return "crazyhouse"
+}
+
+func (crazyhouse) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+// drops adds a drop of each piece in the pocket on each empty square, pawns
+// may not be dropped on the first or last rank
+func (crazyhouse) drops(p *Position, moves []Move) []Move {
+	for pt := Pawn; pt <= Queen; pt++ {
+		if p.pockets[p.turn][pt] == 0 {
+			continue
+		}
+		for sq, piece := range p.board {
+			rank := Square(sq).Rank()
+			if piece == NoPiece && (pt != Pawn || (rank != 0 && rank != 7)) {
+				moves = append(moves, Move{From: Square(sq), To: Square(sq), Drop: pt})
+			}
+		}
+	}
+	return moves
+}
+
+// played puts a captured piece in the pocket, a promoted piece goes back as a pawn
+func (crazyhouse) played(p, next *Position, m Move) {
+	if m.Drop != NoPieceType || p.IsCastling(m) {
+		return
+	}
+	if p.captures(m) {
+		pt := Pawn
+		if captured := p.board[m.To]; captured != NoPiece && p.promoted&squareBit(m.To) == 0 {
+			pt = captured.Type()
+		}
+		next.setPocket(p.turn, pt, next.pockets[p.turn][pt]+1)
+	}
+
+	promoted := p.promoted&squareBit(m.From) != 0 || m.Promotion != NoPieceType
+	next.promoted &^= squareBit(m.From) | squareBit(m.To)
+	if promoted {
+		next.promoted |= squareBit(m.To)
+	}
+}
+
+func (crazyhouse) insufficientMaterial(p *Position) bool {
+	return false
+}
+
+// threeCheck is won by checking the opposing king three times
+type threeCheck struct {
+	standard
+}
+
+func (threeCheck) Name() string {
+	return "3check"
+}
+
+func (threeCheck) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+// played counts a check, moves after the game is won do not count
+func (threeCheck) played(p, next *Position, m Move) {
+	if next.InCheck() && next.checks[p.turn] < 3 {
+		next.setChecks(p.turn, next.checks[p.turn]+1)
+	}
+}
+
+func (threeCheck) outcome(p *Position) Outcome {
+	for _, c := range []Color{White, Black} {
+		if p.checks[c] >= 3 {
+			return Outcome{Result: Win(c), Termination: VariantEnd}
+		}
+	}
+	return Outcome{}
+}
+
+// kingOfTheHill is also won by moving the king to one of the four centre squares
+type kingOfTheHill struct {
+	standard
+}
+
+func (kingOfTheHill) Name() string {
+	return "kingofthehill"
+}
+
+func (kingOfTheHill) outcome(p *Position) Outcome {
+	for _, c := range []Color{White, Black} {
+		king := p.kingSquare(c)
+		if king != NoSquare && king.File() >= 3 && king.File() <= 4 && king.Rank() >= 3 && king.Rank() <= 4 {
+			return Outcome{Result: Win(c), Termination: VariantEnd}
+		}
+	}
+	return Outcome{}
+}
+
+func (kingOfTheHill) insufficientMaterial(p *Position) bool {
+	return false
+}
+
+// atomic explodes the pieces around a capture except pawns and the capturing
+// piece with them. Blowing up the opposing king wins, kings may not capture
+// and do not give check to each other.
+type atomic struct {
+	standard
+}
+
+func (atomic) Name() string {
+	return "atomic"
+}
+
+func (atomic) attacked(p *Position, sq Square, by Color) bool {
+	return p.isAttacked(sq, by, false)
+}
+
+func (atomic) inCheck(p *Position, c Color) bool {
+	king, other := p.kingSquare(c), p.kingSquare(c.Other())
+	if king == NoSquare || other == NoSquare {
+		return false
+	}
+	// touching kings can not be taken
+	if file, rank := king.File()-other.File(), king.Rank()-other.Rank(); file >= -1 && file <= 1 && rank >= -1 && rank <= 1 {
+		return false
+	}
+	return p.attacked(king, c.Other())
+}
+
+func (atomic) legal(p, next *Position, m Move) bool {
+	us := p.turn
+	if p.board[m.From].Type() == King && p.captures(m) {
+		return false
+	}
+	if next.kingSquare(us) == NoSquare {
+		return false
+	}
+	return next.kingSquare(us.Other()) == NoSquare || !next.inCheck(us)
+}
+
+func (atomic) played(p, next *Position, m Move) {
+	if !p.captures(m) {
+		return
+	}
+	next.remove(m.To)
+	for b := attacks.king[m.To] &^ next.types[Pawn]; b != 0; b &= b - 1 {
+		next.remove(lsb(b))
+	}
+	// exploded kings and rooks lose their rights
+	for i, rook := range next.castlingRooks {
+		c := White
+		if i >= 2 {
+			c = Black
+		}
+		if next.board[rook] != NewPiece(c, Rook) || next.kingSquare(c) == NoSquare {
+			next.castling &^= CastlingRights(1 << uint(i))
+		}
+	}
+}
+
+func (atomic) outcome(p *Position) Outcome {
+	if p.kingSquare(p.turn) == NoSquare {
+		return Outcome{Result: Win(p.turn.Other()), Termination: VariantEnd}
+	}
+	return Outcome{}
+}
+
+// insufficientMaterial is true when only the kings are left
+func (atomic) insufficientMaterial(p *Position) bool {
+	for _, piece := range p.board {
+		if piece != NoPiece && piece.Type() != King {
+			return false
+		}
+	}
+	return true
+}
+
+// antichess is won by losing every piece or having no moves. Capturing is
+// compulsory, the king is an ordinary piece that pawns may promote to and
+// there is no castling.
+type antichess struct {
+	standard
+}
+
+func (antichess) Name() string {
+	return "antichess"
+}
+
+func (antichess) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+}
+
+func (antichess) validate(p *Position) error {
+	return p.validatePawns(White, Black)
+}
+
+func (antichess) castles() bool {
+	return false
+}
+
+func (antichess) promotions() []PieceType {
+	return []PieceType{Queen, Rook, Bishop, Knight, King}
+}
+
+func (antichess) inCheck(p *Position, c Color) bool {
+	return false
+}
+
+func (antichess) legal(p, next *Position, m Move) bool {
+	return true
+}
+
+func (antichess) filter(p *Position, moves []Move) []Move {
+	var captures []Move
+	for _, m := range moves {
+		if p.captures(m) {
+			captures = append(captures, m)
+		}
+	}
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+func (antichess) noMoves(p *Position) Outcome {
+	return Outcome{Result: Win(p.turn), Termination: VariantEnd}
+}
+
+func (antichess) insufficientMaterial(p *Position) bool {
+	return false
+}
+
+// horde has white's king replaced by 36 pawns, black wins by capturing all
+// of them. White's pawns on the first rank may move two squares.
+type horde struct {
+	standard
+}
+
+func (horde) Name() string {
+	return "horde"
+}
+
+func (horde) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+func (horde) validate(p *Position) error {
+	if err := p.validateKings(White, 0); err != nil {
+		return err
+	}
+	if err := p.validateKings(Black, 1); err != nil {
+		return err
+	}
+	if err := p.validatePawns(Black); err != nil {
+		return err
+	}
+	for file := 0; file < 8; file++ {
+		if p.board[NewSquare(file, 7)] == NewPiece(White, Pawn) {
+			return fmt.Errorf("pawn on the last rank")
+		}
+	}
+	return p.validateCheck()
+}
+
+func (horde) doublePush(c Color, rank int) bool {
+	if c == White {
+		return rank <= 1
+	}
+	return rank == 6
+}
+
+// noMoves is a win for black once white has no pieces left
+func (horde) noMoves(p *Position) Outcome {
+	for _, piece := range p.board {
+		if piece != NoPiece && piece.Color() == p.turn {
+			return standard{}.noMoves(p)
+		}
+	}
+	return Outcome{Result: Win(p.turn.Other()), Termination: VariantEnd}
+}
+
+func (horde) insufficientMaterial(p *Position) bool {
+	return false
+}
+
+// racingKings is won by moving the king to the eighth rank first. Giving check
+// is not allowed and black draws by reaching the eighth rank right after white.
+type racingKings struct {
+	standard
+}
+
+func (racingKings) Name() string {
+	return "racingkings"
+}
+
+func (racingKings) StartFEN() string {
+	return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1"
+}
+
+func (racingKings) validate(p *Position) error {
+	for _, c := range []Color{White, Black} {
+		if err := p.validateKings(c, 1); err != nil {
+			return err
+		}
+		if p.inCheck(c) {
+			return fmt.Errorf("the %v king is in check", c)
+		}
+	}
+	for _, piece := range p.board {
+		if piece.Type() == Pawn {
+			return fmt.Errorf("pawns are not allowed")
+		}
+	}
+	return nil
+}
+
+func (racingKings) castles() bool {
+	return false
+}
+
+func (racingKings) legal(p, next *Position, m Move) bool {
+	return !next.inCheck(White) && !next.inCheck(Black)
+}
+
+func (racingKings) outcome(p *Position) Outcome {
+	white, black := p.kingSquare(White).Rank() == 7, p.kingSquare(Black).Rank() == 7
+	switch {
+	case white && black:
+		return Outcome{Result: Draw, Termination: VariantEnd}
+	case black:
+		return Outcome{Result: BlackWins, Termination: VariantEnd}
+	case !white:
+		return Outcome{}
+	}
+	// black gets a move to draw
+	if p.turn == Black {
+		for _, m := range p.LegalMoves() {
+			if m.From == p.kingSquare(Black) && m.To.Rank() == 7 {
+				return Outcome{}
+			}
+		}
+	}
+	return Outcome{Result: WhiteWins, Termination: VariantEnd}
+}
+
+func (racingKings) noMoves(p *Position) Outcome {
+	return Outcome{Result: Draw, Termination: Stalemate}
+}
+
+func (racingKings) insufficientMaterial(p *Position) bool {
+	return false
+}
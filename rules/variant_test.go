@@ -0,0 +1,167 @@
+package rules
+
+import "testing"
+
+func variantFEN(v Variant) func(string) (*Position, error) {
+	return func(fen string) (*Position, error) {
+		return ParseVariantFEN(v, fen)
+	}
+}
+
+// outcome plays moves from a position and returns the outcome of the game
+func outcome(t *testing.T, p *Position, moves ...string) Outcome {
+	t.Helper()
+
+	g := NewGame(p)
+	for _, s := range moves {
+		m, err := g.Position().ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.Play(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g.Outcome()
+}
+
+func legal(p *Position, s string) bool {
+	_, err := p.ParseMove(s)
+	return err == nil
+}
+
+func TestVariantFEN(t *testing.T) {
+	for _, v := range Variants {
+		p := NewPosition(v)
+		if p == nil || p.FEN() != v.StartFEN() || p.Variant() != v {
+			t.Errorf("Expecting the %v starting position %v got %v", v.Name(), v.StartFEN(), p)
+		}
+		if parsed, err := ParseVariant(v.Name()); err != nil || parsed != v {
+			t.Errorf("Expecting to find %v got %v %v", v.Name(), parsed, err)
+		}
+	}
+	if _, err := ParseVariant("shogi"); err == nil {
+		t.Error("Expecting an error for an unknown variant")
+	}
+
+	tests := []struct {
+		v             Variant
+		fen, expected string
+	}{
+		{Crazyhouse, "4k3/8/8/8/8/8/8/Q~3K3[QNpp] w - - 0 1", "4k3/8/8/8/8/8/8/Q~3K3[QNpp] w - - 0 1"},
+		{Crazyhouse, "4k3/8/8/8/8/8/8/4K3/Pn w - - 0 1", "4k3/8/8/8/8/8/8/4K3[Pn] w - - 0 1"},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K3 w - - 0 1 +2+0", "4k3/8/8/8/8/8/8/4K3 w - - 1+3 0 1"},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K3 w - - 2+1 0 1", "4k3/8/8/8/8/8/8/4K3 w - - 2+1 0 1"},
+	}
+	for _, test := range tests {
+		if p := parse(t, variantFEN(test.v), test.fen); p.FEN() != test.expected {
+			t.Errorf("Expecting %v to be %v got %v", test.fen, test.expected, p.FEN())
+		}
+	}
+	if _, err := ParseVariantFEN(Crazyhouse, "4k3/8/8/8/8/8/8/4K3[K] w - - 0 1"); err == nil {
+		t.Error("Expecting an error for a king in the pocket")
+	}
+}
+
+func TestCrazyhouse(t *testing.T) {
+	p := play(t, NewPosition(Crazyhouse), "e2e4", "d7d5", "e4d5", "d8d5")
+	if p.Pocket(White, Pawn) != 1 || p.Pocket(Black, Pawn) != 1 {
+		t.Fatalf("Expecting each side to have a pawn in its pocket got %v", p.FEN())
+	}
+	if !legal(p, "P@e6") || legal(p, "P@e8") || legal(p, "P@a1") || legal(p, "N@e6") {
+		t.Errorf("Expecting pawns to be dropped only on empty squares off the back ranks in %v", p.FEN())
+	}
+	if next := play(t, p, "P@e6"); next.FEN() != "rnb1kbnr/ppp1pppp/4P3/3q4/8/8/PPPP1PPP/RNBQKBNR[p] b KQkq - 1 3" {
+		t.Errorf("Expecting the pawn to be dropped got %v", next.FEN())
+	}
+
+	// A promoted piece goes back to the pocket as a pawn
+	promoted := parse(t, variantFEN(Crazyhouse), "r3k3/8/8/8/8/8/8/Q~3K3[] b - - 0 1")
+	if next := play(t, promoted, "a8a1"); next.FEN() != "4k3/8/8/8/8/8/8/r3K3[p] w - - 0 2" {
+		t.Errorf("Expecting a pawn in the pocket got %v", next.FEN())
+	}
+	if m, err := ParseMove("N@f3"); err != nil || m.Drop != Knight || m.String() != "N@f3" {
+		t.Errorf("Expecting a knight drop got %v %v", m, err)
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	p := parse(t, variantFEN(ThreeCheck), "4k3/8/8/8/8/8/8/4K2R w - - 1+3 0 1")
+	if o := outcome(t, p, "h1h8"); o != (Outcome{Result: WhiteWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting the third check to win got %v", o)
+	}
+	if p.Hash() == parse(t, variantFEN(ThreeCheck), "4k3/8/8/8/8/8/8/4K2R w - - 2+3 0 1").Hash() {
+		t.Error("Expecting the checks to change the hash")
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	p := parse(t, variantFEN(KingOfTheHill), "4k3/8/8/8/8/4K3/8/8 w - - 0 1")
+	if o := outcome(t, p, "e3e4"); o != (Outcome{Result: WhiteWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting the king in the centre to win got %v", o)
+	}
+}
+
+func TestAtomic(t *testing.T) {
+	p := parse(t, variantFEN(Atomic), "4k3/8/8/3rn3/8/4N3/8/4K3 w - - 0 1")
+	if next := play(t, p, "e3d5"); next.FEN() != "4k3/8/8/8/8/8/8/4K3 b - - 0 1" {
+		t.Errorf("Expecting the capture to explode got %v", next.FEN())
+	}
+	if o := outcome(t, p, "e3d5"); o.Termination != InsufficientMaterial {
+		t.Errorf("Expecting a draw with only kings left got %v", o)
+	}
+
+	if legal(parse(t, variantFEN(Atomic), "4k3/8/8/8/8/8/3p4/4K3 w - - 0 1"), "e1d2") {
+		t.Error("Expecting a king capture to be illegal")
+	}
+	if parse(t, variantFEN(Atomic), "4r3/8/8/8/8/8/3k4/4K3 w - - 0 1").InCheck() {
+		t.Error("Expecting touching kings not to be in check")
+	}
+
+	blowUp := parse(t, variantFEN(Atomic), "3qk3/8/8/8/8/8/8/3RK3 w - - 0 1")
+	if o := outcome(t, blowUp, "d1d8"); o != (Outcome{Result: WhiteWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting exploding the king to win got %v", o)
+	}
+}
+
+func TestAntichess(t *testing.T) {
+	p := play(t, NewPosition(Antichess), "e2e3", "b7b5")
+	if moves := p.LegalMoves(); len(moves) != 1 || moves[0].String() != "f1b5" {
+		t.Errorf("Expecting the capture to be forced got %v", moves)
+	}
+
+	if o := outcome(t, parse(t, variantFEN(Antichess), "8/8/8/8/8/8/8/7r w - - 0 1")); o != (Outcome{Result: WhiteWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting losing every piece to win got %v", o)
+	}
+	if !legal(parse(t, variantFEN(Antichess), "8/P7/8/8/8/8/8/7r w - - 0 1"), "a7a8k") {
+		t.Error("Expecting a pawn to promote to a king")
+	}
+}
+
+func TestHorde(t *testing.T) {
+	p := parse(t, variantFEN(Horde), "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	if next := play(t, p, "a1a3"); next.FEN() != "4k3/8/8/8/8/P7/8/8 b - a2 0 1" {
+		t.Errorf("Expecting a pawn on the first rank to move two squares got %v", next.FEN())
+	}
+	if o := outcome(t, parse(t, variantFEN(Horde), "4k3/8/8/8/8/8/8/8 w - - 0 1")); o != (Outcome{Result: BlackWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting black to win once white has no pieces got %v", o)
+	}
+}
+
+func TestRacingKings(t *testing.T) {
+	p := parse(t, variantFEN(RacingKings), "8/8/8/8/8/8/k7/6RK w - - 0 1")
+	if legal(p, "g1g2") || !legal(p, "g1g3") {
+		t.Error("Expecting giving check to be illegal")
+	}
+
+	race := parse(t, variantFEN(RacingKings), "8/1k4K1/8/8/8/8/8/8 w - - 0 1")
+	if o := outcome(t, race, "g7g8"); o.Result != NoResult {
+		t.Errorf("Expecting black to get a move to draw got %v", o)
+	}
+	if o := outcome(t, race, "g7g8", "b7b8"); o != (Outcome{Result: Draw, Termination: VariantEnd}) {
+		t.Errorf("Expecting a draw when both kings reach the eighth rank got %v", o)
+	}
+	if o := outcome(t, race, "g7g8", "b7a6"); o != (Outcome{Result: WhiteWins, Termination: VariantEnd}) {
+		t.Errorf("Expecting white to win got %v", o)
+	}
+}
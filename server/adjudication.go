@@ -0,0 +1,89 @@
+package server
+
+import (
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// The terminations of games adjudicated from the engines' scores or their length
+const (
+	ResignAdjudication   rules.Termination = "resign adjudication"
+	DrawAdjudication     rules.Termination = "draw adjudication"
+	MaxMovesAdjudication rules.Termination = "max moves adjudication"
+)
+
+// mateScore is the score in centipawns given to a mate
+const mateScore = 100000
+
+// Adjudication ends games from the scores the engines report with their
+// moves, each rule is off while its number of moves is zero
+type Adjudication struct {
+	// A game is resigned once both engines agree for ResignMoves moves each
+	// that one side is ResignScore centipawns or more behind
+	ResignScore int
+	ResignMoves int
+	// A game is drawn once both engines score it within DrawScore
+	// centipawns for DrawMoves moves each after move DrawAfter
+	DrawScore int
+	DrawMoves int
+	DrawAfter int
+	// A game is drawn after MaxMoves moves
+	MaxMoves int
+}
+
+// adjudicator follows the scores of a game. The counts are the moves in a
+// row each engine scored as lost, won and drawn.
+type adjudicator struct {
+	rules   Adjudication
+	losing  [2]int
+	winning [2]int
+	drawn   [2]int
+}
+
+// score returns the score in centipawns for the engine and false when it is a bound
+func score(s *pb.UciRequest_Score) (int, bool) {
+	if s == nil || s.GetLower() != 0 || s.GetUpper() != 0 {
+		return 0, false
+	}
+	switch {
+	case s.GetMate() > 0:
+		return mateScore, true
+	case s.GetMate() < 0:
+		return -mateScore, true
+	}
+	return int(s.GetCp()), true
+}
+
+// record counts the score of the engine that just moved, nil when it gave
+// none eg. for a book move. plies is the number of moves played so far.
+func (a *adjudicator) record(c rules.Color, s *pb.UciRequest_Score, plies int) {
+	cp, ok := score(s)
+	count := func(n *int, counts bool) {
+		if ok && counts {
+			*n++
+		} else {
+			*n = 0
+		}
+	}
+	count(&a.losing[c], cp <= -a.rules.ResignScore)
+	count(&a.winning[c], cp >= a.rules.ResignScore)
+	count(&a.drawn[c], cp <= a.rules.DrawScore && cp >= -a.rules.DrawScore && (plies+1)/2 > a.rules.DrawAfter)
+}
+
+// outcome returns the result of the first rule that fires after plies moves
+func (a *adjudicator) outcome(plies int) rules.Outcome {
+	if n := a.rules.ResignMoves; n > 0 {
+		for _, c := range []rules.Color{rules.White, rules.Black} {
+			if a.losing[c] >= n && a.winning[c.Other()] >= n {
+				return rules.Outcome{Result: rules.Win(c.Other()), Termination: ResignAdjudication}
+			}
+		}
+	}
+	if n := a.rules.DrawMoves; n > 0 && a.drawn[rules.White] >= n && a.drawn[rules.Black] >= n {
+		return rules.Outcome{Result: rules.Draw, Termination: DrawAdjudication}
+	}
+	if n := a.rules.MaxMoves; n > 0 && plies >= 2*n {
+		return rules.Outcome{Result: rules.Draw, Termination: MaxMovesAdjudication}
+	}
+	return rules.Outcome{}
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// Analyze runs a search on an engine in the scheduler's pool and streams its
+// progress until the search limit is reached or the caller cancels. The
+// analysis waits in the scheduler's queue while every suitable engine is busy.
+func (cs *chessService) Analyze(req *pb.AnalysisRequest, stream pb.ChessApplication_AnalyzeServer) error {
+	logger := cs.l.WithField("request", "Analyze")
+	ctx := stream.Context()
+
+	position, err := analysisPosition(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	engine := slot{name: req.GetEngine()}
+	if !cs.scheduler.connected(engine) {
+		return status.Error(codes.Unavailable, "No engine is available for analysis")
+	}
+
+	var analysisErr error
+	err = cs.scheduler.do(ctx, int(req.GetPriority()), req.GetSubmitter(), []slot{engine}, func(players []*player) {
+		p := players[0]
+		p.logger.Infof("Analysing %v", position.FEN())
+
+		a := &analysis{player: p, request: req, position: position, send: stream.Send}
+		analysisErr = a.run(ctx, cs.scheduler.config.readyTimeout())
+		if analysisErr == errUnresponsive {
+			p.failed = true
+		}
+	})
+	if err == nil {
+		err = analysisErr
+	}
+
+	switch {
+	case err == nil || ctx.Err() != nil:
+		return nil
+	case err == ErrUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		logger.Warn("Analysis failed: ", err)
+		return status.Error(codes.Aborted, err.Error())
+	}
+}
+
+// analysisPosition returns the position an analysis request asks to analyse
+func analysisPosition(req *pb.AnalysisRequest) (*rules.Position, error) {
+	position := rules.StartingPosition()
+	if req.GetFen() != "" {
+		var err error
+		if position, err = rules.ParseFEN(req.GetFen()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range req.GetMoves() {
+		move, err := position.ParseMove(s)
+		if err != nil {
+			return nil, err
+		}
+		position = position.Play(move)
+	}
+
+	for _, s := range req.GetSearchmoves() {
+		if _, err := position.ParseMove(s); err != nil {
+			return nil, err
+		}
+	}
+	return position, nil
+}
+
+// analysis runs a single search on an engine and collects its output
+type analysis struct {
+	player   *player
+	request  *pb.AnalysisRequest
+	position *rules.Position
+	send     func(*pb.AnalysisUpdate) error
+
+	// the latest update and lines by multipv index
+	update pb.AnalysisUpdate
+	lines  map[uint32]*pb.AnalysisUpdate_Line
+}
+
+func (a *analysis) run(ctx context.Context, readyTimeout time.Duration) error {
+	p := a.player
+	a.update.Engine = p.name
+	a.lines = make(map[uint32]*pb.AnalysisUpdate_Line)
+
+	multiPV := a.request.GetMultipv() > 1 && p.hasOption("MultiPV")
+	if a.request.GetMultipv() > 1 && !multiPV {
+		p.logger.Warn("Engine does not support MultiPV, analysing a single line")
+	}
+	if multiPV {
+		if err := a.setMultiPV(a.request.GetMultipv()); err != nil {
+			return err
+		}
+		defer a.setMultiPV(1)
+	}
+
+	if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_UCINEWGAME}); err != nil {
+		return errDisconnected
+	}
+	if err := p.ready(readyTimeout); err != nil {
+		return err
+	}
+	if err := a.search(); err != nil {
+		return err
+	}
+
+	done := ctx.Done()
+	var timeout <-chan time.Time
+	for {
+		select {
+		case <-done:
+			// The caller is gone so the engine is stopped and its best move discarded
+			if err := a.stop(); err != nil {
+				return err
+			}
+			done = nil
+			timeout = time.After(readyTimeout)
+		case <-timeout:
+			return errUnresponsive
+		case <-p.resumed:
+			// The search ended with the old stream, a stopped search has
+			// nothing left to answer and a running one starts again
+			if done == nil {
+				return nil
+			}
+			p.owed, p.stale = 0, 0
+			p.logger.Info("Restarting the analysis after the engine reconnected")
+			if err := a.search(); err != nil {
+				return err
+			}
+		case msg, ok := <-p.in:
+			if !ok {
+				return errDisconnected
+			}
+			if p.owes(msg) {
+				continue
+			}
+
+			switch msg.GetMessageType() {
+			case pb.UciRequest_INFO:
+				info := p.checkInfo(a.position, msg.GetInfo(), a.position.Chess960())
+				if info == nil || !a.info(info) || done == nil {
+					continue
+				}
+				if err := a.send(a.snapshot()); err != nil {
+					if err := a.stop(); err != nil {
+						return err
+					}
+					done = nil
+					timeout = time.After(readyTimeout)
+				}
+			case pb.UciRequest_BESTMOVE:
+				if done == nil {
+					return nil
+				}
+				update := a.snapshot()
+				update.Bestmove = msg.GetBestMove().GetMove()
+				update.BestmoveSan = san(a.position, update.Bestmove)
+				return a.send(update)
+			}
+		}
+	}
+}
+
+// search sends the engine the position and starts the search
+func (a *analysis) search() error {
+	err := a.player.send(&pb.UciResponse{
+		MessageType: pb.UciResponse_POSITION,
+		Position: &pb.UciResponse_Position{
+			IsFen: a.request.GetFen() != "",
+			Fen:   a.request.GetFen(),
+			Moves: a.request.GetMoves(),
+		},
+	})
+	if err != nil {
+		return errDisconnected
+	}
+	err = a.player.send(&pb.UciResponse{
+		MessageType: pb.UciResponse_GO,
+		Go: &pb.UciResponse_Go{
+			Searchmoves: a.request.GetSearchmoves(),
+			Depth:       a.request.GetDepth(),
+			Nodes:       a.request.GetNodes(),
+			Movetime:    a.request.GetMovetime(),
+			IsInfinite:  a.request.GetDepth() == 0 && a.request.GetNodes() == 0 && a.request.GetMovetime() == 0,
+		},
+	})
+	if err != nil {
+		return errDisconnected
+	}
+	return nil
+}
+
+// setMultiPV sets the number of lines the engine searches
+func (a *analysis) setMultiPV(lines uint32) error {
+	err := a.player.send(&pb.UciResponse{
+		MessageType: pb.UciResponse_SETOPTION,
+		SetOption: &pb.UciResponse_SetOption{
+			Name:  "MultiPV",
+			Value: strconv.Itoa(int(lines)),
+		},
+	})
+	if err != nil {
+		return errDisconnected
+	}
+	return nil
+}
+
+func (a *analysis) stop() error {
+	if err := a.player.send(&pb.UciResponse{MessageType: pb.UciResponse_STOP}); err != nil {
+		return errDisconnected
+	}
+	return nil
+}
+
+// info records an info message returning true when it changed a line
+func (a *analysis) info(info *pb.UciRequest_Info) bool {
+	if info.GetDepth() > 0 {
+		a.update.Depth = info.GetDepth()
+	}
+	if info.GetSeldepth() > 0 {
+		a.update.Seldepth = info.GetSeldepth()
+	}
+	if info.GetNodes() > 0 {
+		a.update.Nodes = info.GetNodes()
+	}
+	if info.GetNps() > 0 {
+		a.update.Nps = info.GetNps()
+	}
+	if info.GetTime() > 0 {
+		a.update.Time = info.GetTime()
+	}
+
+	changed := false
+	if m := info.GetCurrmove(); m != "" && m != a.update.Currmove {
+		a.update.Currmove = m
+		a.update.CurrmoveSan = san(a.position, m)
+		changed = true
+	}
+
+	if len(info.GetPv()) == 0 && info.GetScore() == nil {
+		return changed
+	}
+
+	index := uint32(1)
+	if info.GetMultipv() > 1 {
+		index = uint32(info.GetMultipv())
+	}
+	line, ok := a.lines[index]
+	if !ok {
+		line = &pb.AnalysisUpdate_Line{Multipv: index}
+		a.lines[index] = line
+	}
+	if info.GetScore() != nil {
+		line.Score = whiteScore(info.GetScore(), a.position.Turn())
+	}
+	if len(info.GetPv()) > 0 {
+		line.San = a.position.SANLine(info.GetPv())
+		line.Pv = info.GetPv()[:len(line.San)]
+	}
+	return true
+}
+
+// san returns a move in UCI notation in standard algebraic notation or an
+// empty string when it is not legal in the position
+func san(position *rules.Position, move string) string {
+	m, err := position.ParseMove(move)
+	if err != nil {
+		return ""
+	}
+	return position.SAN(m)
+}
+
+// snapshot returns a copy of the current state of the analysis
+func (a *analysis) snapshot() *pb.AnalysisUpdate {
+	update := proto.Clone(&a.update).(*pb.AnalysisUpdate)
+	for _, line := range a.lines {
+		update.Lines = append(update.Lines, proto.Clone(line).(*pb.AnalysisUpdate_Line))
+	}
+	sort.Slice(update.Lines, func(i, j int) bool {
+		return update.Lines[i].GetMultipv() < update.Lines[j].GetMultipv()
+	})
+	return update
+}
+
+// whiteScore converts a score from the side to move's point of view to white's
+func whiteScore(score *pb.UciRequest_Score, turn rules.Color) *pb.UciRequest_Score {
+	if turn == rules.White {
+		return proto.Clone(score).(*pb.UciRequest_Score)
+	}
+	return &pb.UciRequest_Score{
+		Cp:    -score.GetCp(),
+		Mate:  -score.GetMate(),
+		Lower: score.GetUpper(),
+		Upper: score.GetLower(),
+	}
+}
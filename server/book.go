@@ -0,0 +1,90 @@
+package server
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// Order is the order a book hands out its openings
+type Order string
+
+// The orders of a book
+const (
+	// Sequential hands out the openings in the order they were read
+	Sequential Order = "sequential"
+	// Random shuffles the openings
+	Random Order = "random"
+)
+
+// Book hands out the openings games start from
+type Book struct {
+	mu       sync.Mutex
+	openings []Opening
+	order    Order
+	rand     *rand.Rand
+	next     int
+}
+
+// NewBook creates a book of openings, a random book is shuffled with the seed
+// so the same seed gives the same games
+func NewBook(openings []Opening, order Order, seed int64) *Book {
+	b := &Book{
+		openings: append([]Opening(nil), openings...),
+		order:    order,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+	b.shuffle()
+	return b
+}
+
+// Len returns the number of openings in the book
+func (b *Book) Len() int {
+	return len(b.openings)
+}
+
+// Next returns the opening for the next game. Once every opening has been
+// used the book starts again, a random book in a new order.
+func (b *Book) Next() Opening {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.openings) == 0 {
+		return Opening{}
+	}
+	if b.next == len(b.openings) {
+		b.next = 0
+		b.shuffle()
+	}
+	b.next++
+	return b.openings[b.next-1]
+}
+
+func (b *Book) shuffle() {
+	if b.order != Random {
+		return
+	}
+	b.rand.Shuffle(len(b.openings), func(i, j int) {
+		b.openings[i], b.openings[j] = b.openings[j], b.openings[i]
+	})
+}
+
+// Chess960Openings returns the Chess960 starting positions numbered from 0 to
+// 959, all 960 when no numbers are given
+func Chess960Openings(numbers ...int) ([]Opening, error) {
+	if len(numbers) == 0 {
+		for n := 0; n < 960; n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	openings := make([]Opening, len(numbers))
+	for i, n := range numbers {
+		p, err := rules.Chess960(n)
+		if err != nil {
+			return nil, err
+		}
+		openings[i] = Opening{FEN: p.FEN(), Chess960: true}
+	}
+	return openings, nil
+}
@@ -1,8 +1,8 @@
-package main
+package server
 
 import (
-	"context"
 	"fmt"
+	"time"
 
 	chess "github.com/schafer14/grpc-chess/service"
 	pb "github.com/schafer14/grpc-chess/service"
@@ -12,16 +12,20 @@ import (
 type chessService struct {
 	l logrus.Entry
 	// interface to store chess game state and such
+
+	// the pool of connected engines
+	scheduler *Scheduler
 }
 
-// NewChessService creates a new chess service given a logger and a data store
+// NewChessService creates a new chess service given a logger and the scheduler
+// connected engines join
 // note: datastore not yet implemented
-func NewChessService(l logrus.Entry) pb.ChessApplicationServer {
-	return &chessService{l}
+func NewChessService(l logrus.Entry, scheduler *Scheduler) pb.ChessApplicationServer {
+	return &chessService{l: l, scheduler: scheduler}
 }
 
 // UCI handles uci request from an egine. The service acts in the GUI role described in the UCI spec
-func (cs chessService) UCI(stream chess.ChessApplication_UCIServer) error {
+func (cs *chessService) UCI(stream chess.ChessApplication_UCIServer) error {
 	logger := cs.l.WithField("request", "UCI")
 
 	logger.Info("Got UCI game request")
@@ -34,6 +38,8 @@ func (cs chessService) UCI(stream chess.ChessApplication_UCIServer) error {
 		return err
 	}
 
+	p := &player{stream: stream, done: make(chan struct{}), grace: cs.scheduler.config.ReconnectGrace}
+
 	// At this point  the client can send a message of type: ID, Option, or UCIOK
 	// So the serve accepts any one of these until the UCIOK comes through
 Loop:
@@ -46,14 +52,25 @@ Loop:
 
 		switch message.GetMessageType() {
 		case pb.UciRequest_ID:
-			logger = logger.WithField("engine", message.GetId().GetName())
+			if name := message.GetId().GetName(); name != "" {
+				p.name = name
+				logger = logger.WithField("engine", name)
+			}
+			p.author = message.GetId().GetAuthor()
 		case pb.UciRequest_OPTION:
 			logger.Infof("Available option %v", message.GetOption().GetName())
+			p.options = append(p.options, message.GetOption())
 		case pb.UciRequest_UCIOK:
 			break Loop
 		}
 	}
 
+	p.logger = logger
+	p.onControl = func(msg *pb.ClientGameMessage) bool {
+		return cs.scheduler.correspondenceControl(p, msg)
+	}
+	source := receive(stream, logger)
+
 	// The server can send any options it wants and then sends a ISREADY
 	err = stream.Send(&pb.UciResponse{
 		MessageType: pb.UciResponse_SETOPTION,
@@ -62,6 +79,10 @@ Loop:
 			Value: "500",
 		},
 	})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
 
 	// Send is ready
 	err = stream.Send(&pb.UciResponse{
@@ -75,61 +96,92 @@ Loop:
 	logger.Info("Listening for a `readyok` message")
 
 	// Listen for ready ok message
-	readyOk, err := stream.Recv()
-	if err != nil {
-		logger.Error(err)
+	if err := cs.waitReady(p, source); err != nil {
+		logger.Warn(err)
 		return err
 	}
-	if readyOk.GetMessageType() != pb.UciRequest_READYOK {
-		logger.Warningf("Invalid message expecting readyok got %v", readyOk.GetMessageType().String())
-		return fmt.Errorf("Invalid message expecting readyok got %v", readyOk.GetMessageType().String())
-	}
 
 	logger.Info("Recieved `readyok` message")
 
-	return cs.handleGameLogic(stream, logger)
+	// An engine that reconnects carries on where its old stream left off
+	if token := sessionToken(stream.Context()); token != "" {
+		if resumed := cs.scheduler.resume(token, p.name, resumption{stream: stream, source: source}); resumed != nil {
+			return cs.serve(resumed, stream)
+		}
+		logger.Warn("Could not resume the session, joining as a new engine")
+	}
+
+	p.listen(source)
+	return cs.handleGameLogic(p, stream)
 }
 
-// handleGameLogic is responsible for the logic of adjudicating a game
-func (cs chessService) handleGameLogic(stream pb.ChessApplication_UCIServer, logger *logrus.Entry) error {
-	// Setup a new context
-	ctx, cancel := context.WithCancel(stream.Context())
-	defer cancel()
-
-	// Setup a channel to listen for messages on
-	inChan := make(chan pb.UciRequest)
-	go func(input chan pb.UciRequest) {
-		for {
-			in, err := stream.Recv()
-			if err != nil {
-				cancel()
-				return
+// waitReady waits for the engine to answer the isready of the handshake
+// applying the violation policy to an engine that does not answer or sends
+// something else first
+func (cs *chessService) waitReady(p *player, source <-chan *pb.UciRequest) error {
+	policy := cs.scheduler.config.Violations
+	timeout := time.NewTimer(cs.scheduler.config.readyTimeout())
+	defer timeout.Stop()
+
+	for retries := 0; ; {
+		select {
+		case <-timeout.C:
+			action := policy.action(NoReadyOK, retries)
+			p.logger.Warnf("%v during the handshake, %v", NoReadyOK, action)
+			switch action {
+			case Forfeit:
+				return errUnresponsive
+			case Warn:
+				p.owed++
+				return nil
+			}
+			retries++
+			if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_ISREADY}); err != nil {
+				return err
+			}
+			p.owed++
+			timeout.Reset(cs.scheduler.config.readyTimeout())
+		case msg, ok := <-source:
+			if !ok {
+				return errDisconnected
+			}
+			if msg.GetMessageType() == pb.UciRequest_READYOK {
+				return nil
+			}
+			v := UnexpectedMessage
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE {
+				v = BestMoveWithoutGo
+			}
+			action := policy.action(v, 0)
+			p.logger.Warnf("%v during the handshake: %v, %v", v, msg.GetMessageType(), action)
+			if action == Forfeit {
+				return fmt.Errorf("Invalid message expecting readyok got %v", msg.GetMessageType())
 			}
-			input <- *in
 		}
-	}(inChan)
+	}
+}
 
-	stream.Send(&pb.UciResponse{
-		MessageType: pb.UciResponse_UCINEWGAME,
-	})
+// handleGameLogic adds the engine to the scheduler's pool, asks for a game
+// against the next engine to connect unless pairing is off and blocks until
+// the engine leaves the pool
+func (cs *chessService) handleGameLogic(p *player, stream chess.ChessApplication_UCIServer) error {
+	cs.scheduler.register(p)
+	if !cs.scheduler.config.NoPairing {
+		cs.scheduler.seek(p)
+	}
+	return cs.serve(p, stream)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Println("Connection closed")
-			return fmt.Errorf("Context ended")
-		case msg := <-inChan:
-			switch msg.GetMessageType() {
-			case pb.UciRequest_INFO:
-				logger.Warn("Unimplemented uci info")
-				break
-			case pb.UciRequest_BESTMOVE:
-				logger.Warn("Unimplemented uci best move")
-				break
-			default:
-				logger.Errorf("Unknown uci message %v", msg.GetMessageType())
-				break
-			}
+// serve blocks until the engine leaves the pool or the stream ends. An engine
+// with a grace period is only removed once it has not reconnected in time.
+func (cs *chessService) serve(p *player, stream chess.ChessApplication_UCIServer) error {
+	select {
+	case <-p.done:
+		return nil
+	case <-stream.Context().Done():
+		if p.grace == 0 {
+			cs.scheduler.remove(p)
 		}
+		return fmt.Errorf("Context ended")
 	}
 }
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/schafer14/grpc-chess/polyglot"
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/syzygy"
+)
+
+// defaultReadyTimeout is how long an engine has to answer isready when the config does not say
+const defaultReadyTimeout = 10 * time.Second
+
+// Config configures the games run by the server
+type Config struct {
+	// The time each engine starts the game with
+	Time time.Duration
+	// The time added to an engine's clock after each move
+	Increment time.Duration
+	// Ponder lets engines that advertise the Ponder option think on the
+	// opponent's time, it is set for each match from the config the match starts with
+	Ponder bool
+	// How long an engine has to answer isready, 10 seconds when zero
+	ReadyTimeout time.Duration
+	// How often idle engines are checked with isready, never when zero
+	HealthInterval time.Duration
+	// How long an engine whose stream drops has to reconnect with its
+	// session token, the clock of its game keeps running in the meantime.
+	// Engines leave at once when zero.
+	ReconnectGrace time.Duration
+	// Called with the record of each game once it is over
+	OnGameOver func(GameRecord)
+	// NoPairing stops engines being paired as they connect, they only play
+	// the games asked for with Play eg. in a tournament
+	NoPairing bool
+	// The openings of games between engines paired as they connect, the
+	// standard starting position when nil
+	Book *Book
+	// RepeatOpenings plays each opening of a pair of engines paired as they
+	// connect a second time with colours reversed
+	RepeatOpenings bool
+	// MoveBook plays moves for the engines, while it has a move for the
+	// position within its depth the engine to move is not asked to search
+	MoveBook *polyglot.Book
+	// Tablebase adjudicates games once a position has TablebasePieces pieces
+	// or fewer including kings, or as many as its largest tables when zero
+	Tablebase       *syzygy.Tablebase
+	TablebasePieces int
+	// Adjudication ends games from the engines' scores and their length
+	Adjudication Adjudication
+	// Variant is the rules games are played by, standard chess when nil.
+	// Engines only play variants they list in their UCI_Variant option.
+	Variant rules.Variant
+	// Violations decides what happens when an engine breaks the UCI protocol
+	Violations ViolationPolicy
+
+	// black's starting time when it differs from white's, set for each match
+	blackTime time.Duration
+}
+
+func (c Config) readyTimeout() time.Duration {
+	if c.ReadyTimeout == 0 {
+		return defaultReadyTimeout
+	}
+	return c.ReadyTimeout
+}
+
+// Game asks the scheduler for a game between two engines
+type Game struct {
+	// The names of the engines to play, any idle engine plays when empty
+	White string
+	Black string
+	// The time control of the game, the scheduler's is used when Time is zero
+	Time      time.Duration
+	Increment time.Duration
+	// Black's starting time when it differs from white's eg. in an Armageddon game
+	BlackTime time.Duration
+	// Whether the engines may ponder during the game, the scheduler's when nil
+	Ponder *bool
+	// Games with a higher priority start first
+	Priority int
+	// Who asked for the game, jobs of different submitters with the same
+	// priority take turns
+	Submitter string
+	// The position the game starts from
+	Opening Opening
+	// The adjudication rules of the game, the scheduler's when nil
+	Adjudication *Adjudication
+	// The variant of the game, the scheduler's when nil
+	Variant rules.Variant
+}
+
+// Opening is the position a game starts from
+type Opening struct {
+	// The starting position in FEN, the standard starting position when empty
+	FEN string
+	// Moves in UCI notation played before the engines take over
+	Moves []string
+	// Chess960 starts a Chess960 game where castling is the king taking its
+	// own rook, FEN is in X-FEN or Shredder-FEN
+	Chess960 bool
+}
+
+// game returns a game of a variant with the opening moves played, standard
+// chess when the variant is nil
+func (o Opening) game(v rules.Variant) (*rules.Game, error) {
+	v = variantOf(v)
+	start := rules.NewPosition(v)
+	parse := func(fen string) (*rules.Position, error) {
+		return rules.ParseVariantFEN(v, fen)
+	}
+	if o.Chess960 {
+		if v != rules.Standard {
+			return nil, fmt.Errorf("Chess960 is not played with %v", v.Name())
+		}
+		parse = rules.ParseFEN960
+	}
+	if o.FEN != "" {
+		var err error
+		if start, err = parse(o.FEN); err != nil {
+			return nil, err
+		}
+	} else if o.Chess960 {
+		start, _ = parse(rules.StartFEN)
+	}
+
+	game := rules.NewGame(start)
+	for _, s := range o.Moves {
+		move, err := rules.ParseMove(s)
+		if err == nil {
+			err = game.Play(move)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid opening move %v: %v", s, err)
+		}
+	}
+	if game.Outcome().Result != rules.NoResult {
+		return nil, fmt.Errorf("The game is over after the opening")
+	}
+	return game, nil
+}
+
+// variantOf returns the variant or standard chess when it is nil
+func variantOf(v rules.Variant) rules.Variant {
+	if v == nil {
+		return rules.Standard
+	}
+	return v
+}
+
+// variantName returns the name of a variant recorded with a game, empty for standard chess
+func variantName(v rules.Variant) string {
+	if v == rules.Standard {
+		return ""
+	}
+	return v.Name()
+}
+
+// GameRecord is the record of a finished game
+type GameRecord struct {
+	// The name of the engine playing white
+	White string
+	// The name of the engine playing black
+	Black string
+	// The position the game started from, the standard starting position when empty
+	FEN string
+	// The moves played in UCI notation including the opening moves, castling
+	// is the king taking its own rook in Chess960
+	Moves []string
+	// Chess960 is set for Chess960 games
+	Chess960 bool
+	// The UCI name of the variant, empty for standard chess
+	Variant string
+	// The result of the game and why it ended
+	Outcome rules.Outcome
+	// The protocol violations of the engines in the order they happened
+	Violations []ViolationReport
+	// The time left on the clocks of white and black when the game ended
+	Clocks [2]time.Duration
+}
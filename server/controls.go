@@ -0,0 +1,275 @@
+package server
+
+import (
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// The terminations of games the players end themselves
+const (
+	Resignation   rules.Termination = "resignation"
+	DrawAgreement rules.Termination = "draw agreement"
+	// Aborted games end without a result
+	Aborted rules.Termination = "aborted"
+	// Adjourned games end without a result and are finished later from the game store
+	Adjourned rules.Termination = "adjourned"
+)
+
+// proposal is a draw offer or takeback request waiting for the opponent's answer
+type proposal struct {
+	kind pb.ClientGameMessage_MessageType
+	by   rules.Color
+	// the number of moves left once a takeback is accepted
+	ply int
+}
+
+// control is a game control that arrived while the match could not act on it
+type control struct {
+	side rules.Color
+	msg  *pb.ClientGameMessage
+}
+
+// over reports whether an outcome ends the game, aborted and adjourned games end without a result
+func over(o rules.Outcome) bool {
+	return o.Termination != ""
+}
+
+// control carries out a game control from a player. Controls the player can
+// not use in the game's state are rejected and only reported to the player,
+// the others are reported to both players. It returns the outcome when the
+// control ends the game and true when the searches must stop because the
+// game was paused or moves were taken back.
+func (m *match) control(side rules.Color, msg *pb.ClientGameMessage) (rules.Outcome, bool) {
+	if reason := m.invalid(side, msg); reason != "" {
+		m.logger.Infof("Rejected %v from %v: %v", msg.GetMessageType(), side, reason)
+		m.players[side].send(m.controlMessage(pb.ServerGameMessage_REJECTED, side, msg, reason))
+		return rules.Outcome{}, false
+	}
+
+	m.logger.Infof("%v from %v", msg.GetMessageType(), side)
+	for _, p := range m.players {
+		p.send(m.controlMessage(pb.ServerGameMessage_CONTROL, side, msg, ""))
+	}
+
+	switch msg.GetMessageType() {
+	case pb.ClientGameMessage_RESIGN:
+		return forfeit(side, Resignation), false
+	case pb.ClientGameMessage_OFFER_DRAW:
+		// Offering a draw the opponent offered agrees to it
+		if m.proposal != nil {
+			return rules.Outcome{Result: rules.Draw, Termination: DrawAgreement}, false
+		}
+		m.proposal = &proposal{kind: pb.ClientGameMessage_OFFER_DRAW, by: side}
+	case pb.ClientGameMessage_ACCEPT_DRAW:
+		return rules.Outcome{Result: rules.Draw, Termination: DrawAgreement}, false
+	case pb.ClientGameMessage_REQUEST_TAKEBACK:
+		m.proposal = &proposal{kind: pb.ClientGameMessage_REQUEST_TAKEBACK, by: side, ply: m.takebackPly(side)}
+	case pb.ClientGameMessage_ACCEPT_TAKEBACK:
+		m.takeBack(m.proposal.ply)
+		m.proposal = nil
+		return rules.Outcome{}, true
+	case pb.ClientGameMessage_DECLINE_DRAW, pb.ClientGameMessage_DECLINE_TAKEBACK:
+		m.proposal = nil
+	case pb.ClientGameMessage_ABORT:
+		return rules.Outcome{Result: rules.NoResult, Termination: Aborted}, false
+	case pb.ClientGameMessage_PAUSE:
+		m.paused = true
+		return rules.Outcome{}, true
+	case pb.ClientGameMessage_RESUME:
+		m.paused = false
+	case pb.ClientGameMessage_ADJOURN:
+		return rules.Outcome{Result: rules.NoResult, Termination: Adjourned}, false
+	}
+	return rules.Outcome{}, false
+}
+
+// invalid returns why a player can not use a control in the game's state, empty when it can
+func (m *match) invalid(side rules.Color, msg *pb.ClientGameMessage) string {
+	answers := func(kind pb.ClientGameMessage_MessageType) bool {
+		return m.proposal != nil && m.proposal.kind == kind && m.proposal.by == side.Other()
+	}
+
+	switch msg.GetMessageType() {
+	case pb.ClientGameMessage_RESIGN, pb.ClientGameMessage_ADJOURN:
+		return ""
+	case pb.ClientGameMessage_OFFER_DRAW:
+		if m.proposal != nil && !answers(pb.ClientGameMessage_OFFER_DRAW) {
+			return "A " + m.proposal.String() + " is waiting for an answer"
+		}
+	case pb.ClientGameMessage_ACCEPT_DRAW, pb.ClientGameMessage_DECLINE_DRAW:
+		if !answers(pb.ClientGameMessage_OFFER_DRAW) {
+			return "The opponent has not offered a draw"
+		}
+	case pb.ClientGameMessage_REQUEST_TAKEBACK:
+		if m.proposal != nil {
+			return "A " + m.proposal.String() + " is waiting for an answer"
+		}
+		if m.takebackPly(side) < m.first {
+			return "There is no move to take back"
+		}
+	case pb.ClientGameMessage_ACCEPT_TAKEBACK, pb.ClientGameMessage_DECLINE_TAKEBACK:
+		if !answers(pb.ClientGameMessage_REQUEST_TAKEBACK) {
+			return "The opponent has not asked for a takeback"
+		}
+	case pb.ClientGameMessage_ABORT:
+		if len(m.game.Moves())-m.first >= 2 {
+			return "A game can only be aborted before both sides have moved"
+		}
+	case pb.ClientGameMessage_PAUSE:
+		if m.paused {
+			return "The game is already paused"
+		}
+	case pb.ClientGameMessage_RESUME:
+		if !m.paused {
+			return "The game is not paused"
+		}
+	default:
+		return "Not a game control"
+	}
+	return ""
+}
+
+func (p *proposal) String() string {
+	if p.kind == pb.ClientGameMessage_OFFER_DRAW {
+		return "draw offer"
+	}
+	return "takeback request"
+}
+
+// controlMessage returns the message telling the players about a control
+func (m *match) controlMessage(t pb.ServerGameMessage_MessageType, side rules.Color, msg *pb.ClientGameMessage, reason string) *pb.UciResponse {
+	control := &pb.ServerGameMessage{
+		MessageType: t,
+		Control:     msg,
+		Side:        side.String(),
+		Reason:      reason,
+	}
+	setLastMove(control, m.game)
+	return &pb.UciResponse{MessageType: pb.UciResponse_GAME_CONTROL, GameControl: control}
+}
+
+// setLastMove sets the last move played in a game on a message about it,
+// the move is left empty before the first move
+func setLastMove(msg *pb.ServerGameMessage, game *rules.Game) {
+	if game == nil {
+		return
+	}
+	if move, from, ok := game.LastMove(); ok {
+		msg.Move = from.Format(move, rules.UCINotation)
+		msg.San = from.Format(move, rules.SANNotation)
+	}
+}
+
+// takebackPly returns the number of moves left once a player's last move and any reply to it are taken back
+func (m *match) takebackPly(side rules.Color) int {
+	if m.game.Position().Turn() == side {
+		return len(m.game.Moves()) - 2
+	}
+	return len(m.game.Moves()) - 1
+}
+
+// takeBack replays the game up to ply, the adjudication counts start again
+// as the scores of the moves taken back no longer count
+func (m *match) takeBack(ply int) {
+	game := rules.NewGame(m.game.Start())
+	for _, move := range m.game.Moves()[:ply] {
+		game.Play(move)
+	}
+	m.game = game
+	m.adjudicator = adjudicator{rules: m.adjudicator.rules}
+	m.scores = [2]*pb.UciRequest_Score{}
+}
+
+// moved lets a proposal lapse once a player moves, a draw offer stands
+// through the move of the player that offered it
+func (m *match) moved(side rules.Color) {
+	if m.proposal != nil && (m.proposal.kind == pb.ClientGameMessage_REQUEST_TAKEBACK || m.proposal.by != side) {
+		m.proposal = nil
+	}
+}
+
+// controls carries out the controls that arrived while the match could not
+// act on them and waits while the game is paused
+func (m *match) controls() rules.Outcome {
+	for len(m.queued) > 0 {
+		c := m.queued[0]
+		m.queued = m.queued[1:]
+		if outcome, _ := m.control(c.side, c.msg); over(outcome) {
+			return outcome
+		}
+	}
+	return m.whilePaused()
+}
+
+// turnControl carries out a control from either player during the turn of
+// side returning true when the turn is over
+func (m *match) turnControl(side, from rules.Color, msg *pb.ClientGameMessage, start time.Time) (rules.Outcome, bool) {
+	outcome, interrupted := m.control(from, msg)
+	switch {
+	case over(outcome):
+		if m.clocks[side] -= time.Since(start); m.clocks[side] < 0 {
+			m.clocks[side] = 0
+		}
+		return outcome, true
+	case interrupted:
+		return m.interrupt(side, start), true
+	}
+	return rules.Outcome{}, false
+}
+
+// interrupt stops the searches after a control paused the game or took moves
+// back, the time the engine to move spent since start is taken from its
+// clock. The turn starts again once the game is resumed.
+func (m *match) interrupt(side rules.Color, start time.Time) rules.Outcome {
+	if m.clocks[side] -= time.Since(start); m.clocks[side] < 0 {
+		m.clocks[side] = 0
+		return forfeit(side, TimeForfeit)
+	}
+	for _, c := range []rules.Color{side, side.Other()} {
+		if m.searching[c] {
+			m.ponders[c] = ""
+			if outcome := m.stopSearch(c); outcome.Result != rules.NoResult {
+				return outcome
+			}
+		}
+	}
+	return m.controls()
+}
+
+// whilePaused waits until a player resumes the game or ends it
+func (m *match) whilePaused() rules.Outcome {
+	for m.paused {
+		var side rules.Color
+		var msg *pb.UciRequest
+		var ok bool
+		select {
+		case msg, ok = <-m.players[rules.White].in:
+			side = rules.White
+		case msg, ok = <-m.players[rules.Black].in:
+			side = rules.Black
+		case <-m.players[rules.White].resumed:
+			m.resume(rules.White, m.clocks)
+			continue
+		case <-m.players[rules.Black].resumed:
+			m.resume(rules.Black, m.clocks)
+			continue
+		case <-m.abort:
+			m.logger.Info("Aborting the game as it is no longer wanted")
+			return rules.Outcome{Result: rules.NoResult, Termination: Aborted}
+		}
+		if !ok {
+			return forfeit(side, Disconnection)
+		}
+		if msg.GetMessageType() != pb.UciRequest_GAME_CONTROL {
+			m.owes(side, msg)
+			m.logger.Debugf("Ignoring %v from %v while the game is paused", msg.GetMessageType(), side)
+			continue
+		}
+		if outcome, _ := m.control(side, msg.GetGameControl()); over(outcome) {
+			return outcome
+		}
+	}
+	return rules.Outcome{}
+}
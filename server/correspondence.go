@@ -0,0 +1,648 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// correspondenceTournament is the tournament correspondence games are saved under in the game store
+const correspondenceTournament = "correspondence"
+
+// CorrespondenceConfig configures correspondence games
+type CorrespondenceConfig struct {
+	// The time each side has for a move, 3 days when zero
+	PerMove time.Duration
+	// How long an engine searches for a move, a minute when zero
+	MoveTime time.Duration
+	// Store keeps the games after each move, the games still being played are
+	// picked up from it when correspondence games start. Games are only kept
+	// in memory when nil.
+	Store store.Store
+}
+
+func (c CorrespondenceConfig) perMove() time.Duration {
+	if c.PerMove == 0 {
+		return 3 * 24 * time.Hour
+	}
+	return c.PerMove
+}
+
+func (c CorrespondenceConfig) moveTime() time.Duration {
+	if c.MoveTime == 0 {
+		return time.Minute
+	}
+	return c.MoveTime
+}
+
+// Correspondence runs games where each side has days for a move. A game does
+// not need its engines connected: the engine to move is asked for its move
+// whenever it is in the scheduler's pool and loses on time once its deadline
+// passes whether it is connected or not. An engine that reconnects is told
+// which games it is to move in and can ask for their state with
+// GAME_STATE_REQUEST.
+type Correspondence struct {
+	scheduler *Scheduler
+	config    CorrespondenceConfig
+	logger    *logrus.Entry
+
+	mu     sync.Mutex
+	games  map[string]*correspondenceGame
+	seq    int
+	closed bool
+}
+
+// correspondenceGame is a correspondence game and the turn in progress
+type correspondenceGame struct {
+	id           string
+	white, black string
+	// the position the game started from, the standard starting position when empty
+	fen     string
+	game    *rules.Game
+	outcome rules.Outcome
+	// when the side to move loses on time
+	deadline time.Time
+	// the premove lines of each side, each alternates between the opponent's move and the reply
+	premoves   [2][][]string
+	violations []ViolationReport
+	// the illegal moves in a row of the side to move
+	retries int
+
+	// the deadline of the turn and the context of the request for the engine's move
+	timer  *time.Timer
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewCorrespondence starts correspondence games for the engines in the
+// scheduler's pool picking up the games still being played in the store
+func NewCorrespondence(l *logrus.Entry, scheduler *Scheduler, config CorrespondenceConfig) (*Correspondence, error) {
+	c := &Correspondence{
+		scheduler: scheduler,
+		config:    config,
+		logger:    l.WithField("request", "correspondence"),
+		games:     make(map[string]*correspondenceGame),
+	}
+
+	if config.Store != nil {
+		saved, err := config.Store.Tournament(correspondenceTournament)
+		if err != nil {
+			return nil, err
+		}
+		c.seq = len(saved)
+		for _, s := range saved {
+			if s.Result != rules.NoResult.String() {
+				continue
+			}
+			g, err := loadCorrespondence(s)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load correspondence game %v: %v", s.ID, err)
+			}
+			c.games[g.id] = g
+		}
+		c.logger.Infof("Picked up %v correspondence games", len(c.games))
+	}
+
+	scheduler.mu.Lock()
+	scheduler.correspondence = c
+	scheduler.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, g := range c.games {
+		c.turn(g)
+	}
+	return c, nil
+}
+
+// loadCorrespondence rebuilds a correspondence game being played from the store
+func loadCorrespondence(s store.Game) (*correspondenceGame, error) {
+	game, err := Opening{FEN: s.FEN, Moves: s.Moves}.game(nil)
+	if err != nil {
+		return nil, err
+	}
+	g := &correspondenceGame{
+		id:       s.ID,
+		white:    s.White,
+		black:    s.Black,
+		fen:      s.FEN,
+		game:     game,
+		deadline: s.Deadline,
+		premoves: [2][][]string{s.WhitePremoves, s.BlackPremoves},
+	}
+	for _, v := range s.Violations {
+		color := rules.White
+		if v.Side == rules.Black.String() {
+			color = rules.Black
+		}
+		g.violations = append(g.violations, ViolationReport{Engine: v.Engine, Color: color, Violation: Violation(v.Violation), Action: Action(v.Action), Ply: v.Ply, Detail: v.Detail})
+	}
+	return g, nil
+}
+
+// Start starts a correspondence game returning its ID. White is asked for
+// its move as soon as it is in the scheduler's pool.
+func (c *Correspondence) Start(white, black string, opening Opening) (string, error) {
+	if opening.Chess960 {
+		return "", fmt.Errorf("Correspondence games are standard chess")
+	}
+	game, err := opening.game(nil)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", ErrUnavailable
+	}
+	c.seq++
+	g := &correspondenceGame{
+		id:       fmt.Sprintf("correspondence-%v", c.seq),
+		white:    white,
+		black:    black,
+		fen:      opening.FEN,
+		game:     game,
+		deadline: time.Now().Add(c.config.perMove()),
+	}
+	c.games[g.id] = g
+	c.logger.Infof("Starting correspondence game %v between %v and %v", g.id, white, black)
+
+	c.save(g)
+	c.turn(g)
+	return g.id, nil
+}
+
+// State returns the state of a correspondence game, false when there is none with the ID
+func (c *Correspondence) State(id string) (*pb.GameState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, ok := c.games[id]
+	if !ok {
+		return nil, false
+	}
+	return g.state(), true
+}
+
+// Close stops enforcing the deadlines and asking engines for moves, the games
+// still being played are picked up from the store by the next Correspondence
+func (c *Correspondence) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, g := range c.games {
+		g.endTurn()
+	}
+}
+
+// turn starts the turn of the side to move. A premove it has for the
+// opponent's last move is played at once, otherwise the side is told it is
+// its move and its engine asked for one.
+func (c *Correspondence) turn(g *correspondenceGame) {
+	if c.closed || over(g.outcome) {
+		return
+	}
+
+	side := g.game.Position().Turn()
+	if moves := g.game.Moves(); len(moves) > 0 {
+		if reply, ok := g.premove(side, moves[len(moves)-1].String()); ok {
+			c.logger.Infof("Game %v: playing the premove %v of %v", g.id, reply, side)
+			if err := c.move(g, reply); err == nil {
+				return
+			}
+		}
+	}
+
+	ply := len(g.game.Moves())
+	g.timer = time.AfterFunc(time.Until(g.deadline), func() { c.timeout(g, ply) })
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+
+	state := stateMessage(pb.ServerGameMessage_YOUR_MOVE, nil, g.state(), g.game, "")
+	for _, p := range c.scheduler.named(g.name(side)) {
+		p.send(state)
+	}
+	go c.ask(g, ply)
+}
+
+// endTurn stops the deadline and the request for the engine's move of the turn
+func (g *correspondenceGame) endTurn() {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	if g.cancel != nil {
+		g.cancel()
+	}
+}
+
+// premove returns the reply a side premoved to the opponent's move keeping
+// the lines that continue from it
+func (g *correspondenceGame) premove(side rules.Color, move string) (string, bool) {
+	var lines [][]string
+	for _, line := range g.premoves[side] {
+		if len(line) >= 2 && line[0] == move {
+			lines = append(lines, line[1:])
+		}
+	}
+	g.premoves[side] = nil
+	if len(lines) == 0 {
+		return "", false
+	}
+
+	reply := lines[0][0]
+	for _, line := range lines {
+		if line[0] == reply && len(line) > 1 {
+			g.premoves[side] = append(g.premoves[side], line[1:])
+		}
+	}
+	return reply, true
+}
+
+// ask waits in the scheduler's queue until the engine to move is connected
+// and idle and asks it for its move
+func (c *Correspondence) ask(g *correspondenceGame, ply int) {
+	c.mu.Lock()
+	ctx, side := g.ctx, g.game.Position().Turn()
+	name, moves := g.name(side), g.moves()
+	c.mu.Unlock()
+
+	err := c.scheduler.do(ctx, 0, "correspondence", []slot{{name: name}}, func(players []*player) {
+		p := players[0]
+		move, err := c.search(p, g.fen, moves)
+		if err != nil {
+			p.logger.Warnf("No move for correspondence game %v: %v", g.id, err)
+			p.failed = err == errUnresponsive
+			// The engine is asked again once it is back in the pool
+			c.mu.Lock()
+			if ctx.Err() == nil {
+				go c.ask(g, ply)
+			}
+			c.mu.Unlock()
+			return
+		}
+		c.bestMove(g, ply, p, move)
+	})
+	if err != nil && ctx.Err() == nil {
+		c.logger.Warnf("Could not ask %v for its move in game %v: %v", name, g.id, err)
+	}
+}
+
+// search asks an engine for its move in the position after the moves
+func (c *Correspondence) search(p *player, fen string, moves []string) (string, error) {
+	readyTimeout := c.scheduler.config.readyTimeout()
+
+	var msgs []*pb.UciResponse
+	if p.hasOption("UCI_Chess960") {
+		msgs = append(msgs, &pb.UciResponse{
+			MessageType: pb.UciResponse_SETOPTION,
+			SetOption:   &pb.UciResponse_SetOption{Name: "UCI_Chess960", Value: "false"},
+		})
+	}
+	msgs = append(msgs, &pb.UciResponse{MessageType: pb.UciResponse_UCINEWGAME})
+	for _, msg := range msgs {
+		if err := p.send(msg); err != nil {
+			return "", errDisconnected
+		}
+	}
+	if err := p.ready(readyTimeout); err != nil {
+		return "", err
+	}
+
+	msgs = []*pb.UciResponse{
+		{
+			MessageType: pb.UciResponse_POSITION,
+			Position:    &pb.UciResponse_Position{IsFen: fen != "", Fen: fen, Moves: moves},
+		},
+		{
+			MessageType: pb.UciResponse_GO,
+			Go:          &pb.UciResponse_Go{Movetime: milliseconds(c.config.moveTime())},
+		},
+	}
+	for _, msg := range msgs {
+		if err := p.send(msg); err != nil {
+			return "", errDisconnected
+		}
+	}
+
+	timeout := time.NewTimer(c.config.moveTime() + readyTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			return "", errUnresponsive
+		case <-p.resumed:
+			// The search ended with the old stream, the engine is asked again
+			return "", errDisconnected
+		case msg, ok := <-p.in:
+			if !ok {
+				return "", errDisconnected
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE && !p.owes(msg) {
+				return msg.GetBestMove().GetMove(), nil
+			}
+		}
+	}
+}
+
+// bestMove plays the move an engine found for a turn, an illegal move is
+// dealt with by the violation policy
+func (c *Correspondence) bestMove(g *correspondenceGame, ply int, p *player, s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if over(g.outcome) || len(g.game.Moves()) != ply {
+		return
+	}
+	side := g.game.Position().Turn()
+	if err := c.move(g, s); err == nil {
+		return
+	}
+
+	report := ViolationReport{
+		Engine:    p.name,
+		Color:     side,
+		Violation: IllegalBestMove,
+		Action:    c.scheduler.config.Violations.action(IllegalBestMove, g.retries),
+		Ply:       ply,
+		Detail:    "bestmove " + s,
+	}
+	c.logger.Warnf("Game %v: %v by %v: %v, %v", g.id, report.Violation, side, report.Detail, report.Action)
+	g.violations = append(g.violations, report)
+	p.send(report.message())
+
+	if report.Action == Forfeit {
+		c.finish(g, forfeit(side, IllegalMove))
+		return
+	}
+	g.retries++
+	c.save(g)
+	go c.ask(g, ply)
+}
+
+// move plays a move in UCI notation ending the turn and starting the next
+func (c *Correspondence) move(g *correspondenceGame, s string) error {
+	move, err := g.game.Position().ParseMove(s)
+	if err != nil {
+		return err
+	}
+	g.endTurn()
+	g.game.Play(move)
+	g.retries = 0
+	g.deadline = time.Now().Add(c.config.perMove())
+
+	if outcome := g.game.Outcome(); outcome.Result != rules.NoResult {
+		c.finish(g, outcome)
+		return nil
+	}
+	c.save(g)
+	c.turn(g)
+	return nil
+}
+
+// timeout ends the game once the side to move is still to move at its deadline
+func (c *Correspondence) timeout(g *correspondenceGame, ply int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || over(g.outcome) || len(g.game.Moves()) != ply {
+		return
+	}
+	c.finish(g, forfeit(g.game.Position().Turn(), TimeForfeit))
+}
+
+// finish ends a game saving it and passing its record to OnGameOver
+func (c *Correspondence) finish(g *correspondenceGame, outcome rules.Outcome) {
+	g.endTurn()
+	g.outcome = outcome
+	g.premoves = [2][][]string{}
+	c.logger.WithField("result", outcome.Result.String()).Infof("Game %v over by %v", g.id, outcome.Termination)
+	c.save(g)
+
+	if c.scheduler.config.OnGameOver != nil {
+		c.scheduler.config.OnGameOver(GameRecord{
+			White:      g.white,
+			Black:      g.black,
+			FEN:        g.fen,
+			Moves:      g.moves(),
+			Outcome:    outcome,
+			Violations: g.violations,
+		})
+	}
+}
+
+// save keeps a game in the store
+func (c *Correspondence) save(g *correspondenceGame) {
+	if c.config.Store == nil {
+		return
+	}
+
+	saved := store.Game{
+		ID:            g.id,
+		Tournament:    correspondenceTournament,
+		White:         g.white,
+		Black:         g.black,
+		FEN:           g.fen,
+		Moves:         g.moves(),
+		Result:        g.outcome.Result.String(),
+		Termination:   string(g.outcome.Termination),
+		WhitePremoves: g.premoves[rules.White],
+		BlackPremoves: g.premoves[rules.Black],
+	}
+	if over(g.outcome) {
+		saved.Finished = time.Now()
+	} else {
+		saved.Deadline = g.deadline
+	}
+	for _, v := range g.violations {
+		saved.Violations = append(saved.Violations, store.Violation{
+			Engine:    v.Engine,
+			Side:      v.Color.String(),
+			Violation: string(v.Violation),
+			Action:    string(v.Action),
+			Ply:       v.Ply,
+			Detail:    v.Detail,
+		})
+	}
+	if err := c.config.Store.Put(saved); err != nil {
+		c.logger.Errorf("Could not save correspondence game %v: %v", g.id, err)
+	}
+}
+
+// joined tells an engine that joined the pool which games it is to move in
+func (c *Correspondence) joined(p *player) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, g := range c.sorted() {
+		if !over(g.outcome) && g.name(g.game.Position().Turn()) == p.name {
+			p.send(stateMessage(pb.ServerGameMessage_YOUR_MOVE, nil, g.state(), g.game, ""))
+		}
+	}
+}
+
+// control answers game state requests and sets premoves returning false for
+// other controls, which are for the engine's live game
+func (c *Correspondence) control(p *player, msg *pb.ClientGameMessage) bool {
+	switch msg.GetMessageType() {
+	case pb.ClientGameMessage_GAME_STATE_REQUEST, pb.ClientGameMessage_PREMOVE:
+	default:
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var games []*correspondenceGame
+	for _, g := range c.sorted() {
+		if (g.white == p.name || g.black == p.name) && (msg.GetGame() == "" || msg.GetGame() == g.id) {
+			games = append(games, g)
+		}
+	}
+	if len(games) == 0 {
+		p.send(stateMessage(pb.ServerGameMessage_REJECTED, msg, nil, nil, "No correspondence game"))
+		return true
+	}
+
+	if msg.GetMessageType() == pb.ClientGameMessage_GAME_STATE_REQUEST {
+		for _, g := range games {
+			p.send(stateMessage(pb.ServerGameMessage_GAME_STATE_RESPONSE, msg, g.state(), g.game, ""))
+		}
+		return true
+	}
+
+	g := games[0]
+	side, reason := c.premoves(g, p.name, msg)
+	if reason != "" {
+		p.logger.Infof("Rejected premove in game %v: %v", g.id, reason)
+		p.send(stateMessage(pb.ServerGameMessage_REJECTED, msg, g.state(), g.game, reason))
+		return true
+	}
+	c.save(g)
+	reply := stateMessage(pb.ServerGameMessage_CONTROL, msg, g.state(), g.game, "")
+	reply.GameControl.Side = side.String()
+	p.send(reply)
+	return true
+}
+
+// premoves adds or clears the premove lines of an engine returning why they
+// were rejected, empty when they were not
+func (c *Correspondence) premoves(g *correspondenceGame, name string, msg *pb.ClientGameMessage) (rules.Color, string) {
+	if msg.GetGame() == "" {
+		return rules.White, "A premove needs a game"
+	}
+	if over(g.outcome) {
+		return rules.White, "The game is over"
+	}
+	side := g.game.Position().Turn().Other()
+	if g.name(side) != name {
+		return side.Other(), "It is your move"
+	}
+
+	line := msg.GetMoves()
+	if len(line) == 0 {
+		g.premoves[side] = nil
+		return side, ""
+	}
+	if len(line)%2 != 0 {
+		return side, "A premove line needs a reply to each move"
+	}
+	position := g.game.Position()
+	for _, s := range line {
+		move, err := position.ParseMove(s)
+		if err != nil {
+			return side, err.Error()
+		}
+		position = position.Play(move)
+	}
+	g.premoves[side] = append(g.premoves[side], line)
+	return side, ""
+}
+
+// sorted returns the games in the order of their IDs
+func (c *Correspondence) sorted() []*correspondenceGame {
+	games := make([]*correspondenceGame, 0, len(c.games))
+	for _, g := range c.games {
+		games = append(games, g)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].id < games[j].id })
+	return games
+}
+
+// name returns the engine playing a side
+func (g *correspondenceGame) name(side rules.Color) string {
+	if side == rules.White {
+		return g.white
+	}
+	return g.black
+}
+
+func (g *correspondenceGame) moves() []string {
+	moves := make([]string, len(g.game.Moves()))
+	for i, move := range g.game.Moves() {
+		moves[i] = move.String()
+	}
+	return moves
+}
+
+func (g *correspondenceGame) state() *pb.GameState {
+	state := &pb.GameState{
+		Fen:         g.game.Position().FEN(),
+		Id:          g.id,
+		White:       g.white,
+		Black:       g.black,
+		Moves:       g.moves(),
+		StartFen:    g.fen,
+		Turn:        g.game.Position().Turn().String(),
+		Result:      g.outcome.Result.String(),
+		Termination: string(g.outcome.Termination),
+	}
+	if !over(g.outcome) {
+		state.Deadline = g.deadline.Unix()
+	}
+	return state
+}
+
+// stateMessage returns a message about a game answering a control, which is
+// nil for a notification. The state and game are nil when there is no game.
+func stateMessage(t pb.ServerGameMessage_MessageType, msg *pb.ClientGameMessage, state *pb.GameState, game *rules.Game, reason string) *pb.UciResponse {
+	control := &pb.ServerGameMessage{
+		MessageType: t,
+		Control:     msg,
+		GameState:   state,
+		Reason:      reason,
+	}
+	setLastMove(control, game)
+	return &pb.UciResponse{MessageType: pb.UciResponse_GAME_CONTROL, GameControl: control}
+}
+
+// Correspondence starts a correspondence game
+func (cs *chessService) Correspondence(ctx context.Context, req *pb.CorrespondenceRequest) (*pb.GameState, error) {
+	cs.scheduler.mu.Lock()
+	c := cs.scheduler.correspondence
+	cs.scheduler.mu.Unlock()
+	if c == nil {
+		return nil, status.Error(codes.FailedPrecondition, "Correspondence games are not enabled")
+	}
+	if req.GetWhite() == "" || req.GetBlack() == "" {
+		return nil, status.Error(codes.InvalidArgument, "A correspondence game needs two engines")
+	}
+
+	id, err := c.Start(req.GetWhite(), req.GetBlack(), Opening{FEN: req.GetOpening().GetFen(), Moves: req.GetOpening().GetMoves()})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	state, _ := c.State(id)
+	return state, nil
+}
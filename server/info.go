@@ -0,0 +1,132 @@
+package server
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// Anomaly is something wrong with an info line an engine sent
+type Anomaly string
+
+// The anomalies found in info lines
+const (
+	// IllegalPV is a principal variation with an illegal move, it is cut before the move
+	IllegalPV Anomaly = "illegal pv"
+	// IllegalRefutation is a refutation with an illegal move, it is cut before the move
+	IllegalRefutation Anomaly = "illegal refutation"
+	// ImpossibleMate is a mate score that can not be right, the score is dropped
+	ImpossibleMate Anomaly = "impossible mate"
+	// MalformedInfo is an info line that makes no sense, it is dropped
+	MalformedInfo Anomaly = "malformed info"
+)
+
+// checkInfo checks an info line sent while the engine searches a position
+// and returns it with its lines cut at their first illegal move and an
+// impossible mate score removed, or nil when it should be dropped. The
+// anomalies found are logged and counted.
+func (p *player) checkInfo(position *rules.Position, info *pb.UciRequest_Info, chess960 bool) *pb.UciRequest_Info {
+	checked, anomalies := validateInfo(position, info, chess960)
+	for _, a := range anomalies {
+		p.logger.Warnf("Found %v in %v", a, info)
+		if p.onAnomaly != nil {
+			p.onAnomaly(a)
+		}
+	}
+	return checked
+}
+
+// validateInfo returns an info line with illegal moves cut from its lines and
+// an impossible mate score removed, or nil when it is malformed, with the
+// anomalies found. Moves are in the engine's notation, with chess960 set
+// castling is the king taking its own rook.
+func validateInfo(position *rules.Position, info *pb.UciRequest_Info, chess960 bool) (*pb.UciRequest_Info, []Anomaly) {
+	legal := position.LegalMoves()
+	if malformed(position, info, legal, chess960) {
+		return nil, []Anomaly{MalformedInfo}
+	}
+
+	var anomalies []Anomaly
+	checked := proto.Clone(info).(*pb.UciRequest_Info)
+	pv, end := replay(position, info.GetPv(), chess960)
+	if len(pv) < len(info.GetPv()) {
+		checked.Pv = pv
+		anomalies = append(anomalies, IllegalPV)
+	}
+	if refutation, _ := replay(position, info.GetRefutation(), chess960); len(refutation) < len(info.GetRefutation()) {
+		checked.Refutation = refutation
+		anomalies = append(anomalies, IllegalRefutation)
+	}
+	if mate := info.GetScore().GetMate(); mate != 0 && !possibleMate(position, legal, mate, len(pv), end) {
+		checked.Score = nil
+		anomalies = append(anomalies, ImpossibleMate)
+	}
+	return checked, anomalies
+}
+
+// malformed returns true when an info line contradicts itself or the position
+func malformed(position *rules.Position, info *pb.UciRequest_Info, legal []rules.Move, chess960 bool) bool {
+	score := info.GetScore()
+	switch {
+	case score.GetCp() != 0 && score.GetMate() != 0:
+		return true
+	case score.GetLower() != 0 && score.GetUpper() != 0:
+		return true
+	case info.GetHashfull() > 1000 || info.GetCpuload() > 1000:
+		return true
+	case info.GetMultipv() < 0 || int(info.GetMultipv()) > len(legal):
+		return true
+	case int(info.GetCurrmovenumber()) > len(legal):
+		return true
+	}
+	if currmove := info.GetCurrmove(); currmove != "" {
+		if _, err := position.ParseUCI(currmove, chess960); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// replay plays a line from a position returning the moves up to the first
+// illegal move and the position they reach
+func replay(position *rules.Position, line []string, chess960 bool) ([]string, *rules.Position) {
+	for i, s := range line {
+		move, err := position.ParseUCI(s, chess960)
+		if err != nil {
+			return line[:i:i], position
+		}
+		position = position.Play(move)
+	}
+	return line, position
+}
+
+// possibleMate returns false for a mate score, in moves from the side to
+// move's point of view, that can not be right. Mate scores are only checked
+// in standard chess where a mate ends the game.
+func possibleMate(position *rules.Position, legal []rules.Move, mate int32, plies int, end *rules.Position) bool {
+	if position.Variant() != rules.Standard {
+		return true
+	}
+	// the game is already over
+	if len(legal) == 0 {
+		return false
+	}
+	// a PV that ends in mate gives the distance
+	if plies > 0 && end.InCheck() && len(end.LegalMoves()) == 0 {
+		if plies%2 == 1 {
+			return mate == int32(plies+1)/2
+		}
+		return mate == -int32(plies)/2
+	}
+	// a mate in one needs a move that mates
+	if mate == 1 {
+		for _, m := range legal {
+			if next := position.Play(m); next.InCheck() && len(next.LegalMoves()) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,643 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/syzygy"
+	"github.com/sirupsen/logrus"
+)
+
+// The terminations decided by the referee rather than the rules
+const (
+	TimeForfeit   rules.Termination = "time forfeit"
+	IllegalMove   rules.Termination = "illegal move"
+	Disconnection rules.Termination = "disconnection"
+	Unresponsive  rules.Termination = "unresponsive engine"
+	// ProtocolViolation ends a game the violation policy forfeits for a message the engine should not have sent
+	ProtocolViolation rules.Termination = "protocol violation"
+	// TablebaseAdjudication ends a game with the result of its position in the tablebase
+	TablebaseAdjudication rules.Termination = "TB adjudication"
+)
+
+// match referees a game between two engines
+type match struct {
+	players [2]*player
+	game    *rules.Game
+	// the FEN of the starting position, empty for the standard starting position
+	fen    string
+	clocks [2]time.Duration
+	config Config
+	// abort is closed when the game is no longer wanted, it ends as aborted
+	abort <-chan struct{}
+	// canPonder is set for the engines that ponder in this match
+	canPonder [2]bool
+	// chess960 is set for the engines that write castling as the king taking its own rook
+	chess960 [2]bool
+	// ponders is the move each engine is pondering on, empty while it is not pondering
+	ponders [2]string
+	// searching is set while an engine has been sent go and has not answered with a best move
+	searching [2]bool
+	// scores is the last score of each engine's current search
+	scores [2]*pb.UciRequest_Score
+	// violations are the protocol violations of the engines so far
+	violations []ViolationReport
+	// first is the number of moves played before the engines took over, they can not be taken back
+	first int
+	// proposal is the draw offer or takeback request waiting for an answer, nil when there is none
+	proposal *proposal
+	// paused is set while a player has paused the game
+	paused bool
+	// queued are controls that arrived while the match waited for something else
+	queued      []control
+	adjudicator adjudicator
+	logger      *logrus.Entry
+}
+
+// newMatch creates a match continuing game, which starts from fen or the standard starting position when fen is empty
+func newMatch(white, black *player, game *rules.Game, fen string, config Config, logger *logrus.Entry) *match {
+	blackTime := config.Time
+	if config.blackTime > 0 {
+		blackTime = config.blackTime
+	}
+	return &match{
+		players:     [2]*player{white, black},
+		game:        game,
+		fen:         fen,
+		clocks:      [2]time.Duration{config.Time, blackTime},
+		config:      config,
+		first:       len(game.Moves()),
+		adjudicator: adjudicator{rules: config.Adjudication},
+		logger:      logger.WithField("white", white.name).WithField("black", black.name),
+	}
+}
+
+// play runs the game until it is over and stops any search still running
+func (m *match) play() GameRecord {
+	m.logger.Info("Starting game")
+
+	outcome := m.run()
+	m.logger.WithField("result", outcome.Result.String()).Infof("Game over by %v", outcome.Termination)
+
+	// The best move of a search still running is read here so the engine's
+	// next job does not take it for an answer
+	for _, c := range []rules.Color{rules.White, rules.Black} {
+		if m.searching[c] {
+			m.stopSearch(c)
+		}
+	}
+	if outcome.Termination == Unresponsive {
+		m.players[loser(outcome.Result)].failed = true
+	}
+
+	return GameRecord{
+		White:      m.players[rules.White].name,
+		Black:      m.players[rules.Black].name,
+		FEN:        m.fen,
+		Moves:      m.moves(),
+		Chess960:   m.game.Start().Chess960(),
+		Variant:    variantName(m.game.Start().Variant()),
+		Outcome:    outcome,
+		Violations: m.violations,
+		Clocks:     m.clocks,
+	}
+}
+
+func (m *match) run() rules.Outcome {
+	for _, c := range []rules.Color{rules.White, rules.Black} {
+		p := m.players[c]
+		// A reconnection before the game has nothing to catch up on
+		select {
+		case <-p.resumed:
+		default:
+		}
+		// Engines that can ponder are told whether they may in this match
+		if p.hasOption("Ponder") {
+			m.canPonder[c] = m.config.Ponder
+			err := p.send(&pb.UciResponse{
+				MessageType: pb.UciResponse_SETOPTION,
+				SetOption: &pb.UciResponse_SetOption{
+					Name:  "Ponder",
+					Value: strconv.FormatBool(m.config.Ponder),
+				},
+			})
+			if err != nil {
+				return forfeit(c, Disconnection)
+			}
+		}
+		// Engines that play Chess960 are told whether this game is one,
+		// others are sent castling as the king moving two squares
+		chess960 := m.game.Start().Chess960()
+		if p.hasOption("UCI_Chess960") {
+			m.chess960[c] = chess960
+			err := p.send(&pb.UciResponse{
+				MessageType: pb.UciResponse_SETOPTION,
+				SetOption: &pb.UciResponse_SetOption{
+					Name:  "UCI_Chess960",
+					Value: strconv.FormatBool(chess960),
+				},
+			})
+			if err != nil {
+				return forfeit(c, Disconnection)
+			}
+		} else if chess960 {
+			m.logger.Warnf("Playing Chess960 with %v which does not advertise UCI_Chess960", c)
+		}
+		// Engines that play variants are told the variant of the game, only
+		// engines that list it are given the game
+		if variant := m.game.Start().Variant(); p.listsVariant(variant.Name()) {
+			err := p.send(&pb.UciResponse{
+				MessageType: pb.UciResponse_SETOPTION,
+				SetOption: &pb.UciResponse_SetOption{
+					Name:  "UCI_Variant",
+					Value: variant.Name(),
+				},
+			})
+			if err != nil {
+				return forfeit(c, Disconnection)
+			}
+		}
+		if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_UCINEWGAME}); err != nil {
+			return forfeit(c, Disconnection)
+		}
+		if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_ISREADY}); err != nil {
+			return forfeit(c, Disconnection)
+		}
+	}
+	for _, c := range []rules.Color{rules.White, rules.Black} {
+		if outcome := m.waitReady(c); outcome.Result != rules.NoResult {
+			return outcome
+		}
+	}
+
+	for {
+		if outcome := m.game.Outcome(); outcome.Result != rules.NoResult {
+			return outcome
+		}
+		if outcome := m.adjudicate(); outcome.Result != rules.NoResult {
+			return outcome
+		}
+		if outcome := m.adjudicator.outcome(len(m.game.Moves())); outcome.Result != rules.NoResult {
+			return outcome
+		}
+		if outcome := m.turn(); over(outcome) {
+			return outcome
+		}
+	}
+}
+
+// waitReady waits for an engine to answer isready
+func (m *match) waitReady(c rules.Color) rules.Outcome {
+	timeout := time.NewTimer(m.config.readyTimeout())
+	defer timeout.Stop()
+
+	for retries := 0; ; {
+		select {
+		case <-timeout.C:
+			switch m.violation(c, NoReadyOK, retries, "no answer in %v", m.config.readyTimeout()) {
+			case Forfeit:
+				return forfeit(c, Unresponsive)
+			case Warn:
+				m.players[c].owed++
+				return rules.Outcome{}
+			}
+			retries++
+			if err := m.players[c].send(&pb.UciResponse{MessageType: pb.UciResponse_ISREADY}); err != nil {
+				return forfeit(c, Disconnection)
+			}
+			m.players[c].owed++
+			timeout.Reset(m.config.readyTimeout())
+		case <-m.players[c].resumed:
+			// The engine answered isready when it reconnected
+			m.resume(c, m.clocks)
+			return rules.Outcome{}
+		case msg, ok := <-m.players[c].in:
+			if !ok {
+				return forfeit(c, Disconnection)
+			}
+			if msg.GetMessageType() == pb.UciRequest_READYOK {
+				return rules.Outcome{}
+			}
+			if msg.GetMessageType() == pb.UciRequest_GAME_CONTROL {
+				m.queued = append(m.queued, control{side: c, msg: msg.GetGameControl()})
+				continue
+			}
+			if m.unexpected(c, msg, "while waiting for readyok") == Forfeit {
+				return forfeit(c, ProtocolViolation)
+			}
+		}
+	}
+}
+
+// turn asks the engine to move and plays its move. It returns without a
+// move when a control from either player ended the game or interrupted the
+// search eg. to take moves back.
+func (m *match) turn() rules.Outcome {
+	if outcome := m.controls(); over(outcome) {
+		return outcome
+	}
+
+	side := m.game.Position().Turn()
+	p := m.players[side]
+	opponent := m.players[side.Other()]
+
+	if played, outcome := m.bookMove(side); played || outcome.Result != rules.NoResult {
+		return outcome
+	}
+	if outcome := m.startSearch(side); outcome.Result != rules.NoResult {
+		return outcome
+	}
+
+	start := time.Now()
+	flag := time.NewTimer(m.clocks[side])
+	defer func() { flag.Stop() }()
+
+	for retries := 0; ; {
+		select {
+		case <-flag.C:
+			m.clocks[side] = 0
+			return forfeit(side, TimeForfeit)
+		case <-m.abort:
+			if m.clocks[side] -= time.Since(start); m.clocks[side] < 0 {
+				m.clocks[side] = 0
+			}
+			m.logger.Info("Aborting the game as it is no longer wanted")
+			return rules.Outcome{Result: rules.NoResult, Termination: Aborted}
+		case <-opponent.resumed:
+			clocks := m.clocks
+			clocks[side] -= time.Since(start)
+			m.resume(side.Other(), clocks)
+		case <-p.resumed:
+			// The engine lost its search, it is asked again with its clock still running
+			if m.clocks[side] -= time.Since(start); m.clocks[side] < 0 {
+				m.clocks[side] = 0
+				return forfeit(side, TimeForfeit)
+			}
+			m.resume(side, m.clocks)
+			if outcome := m.search(side, m.movesFor(side), false); outcome.Result != rules.NoResult {
+				return outcome
+			}
+			start = time.Now()
+			flag.Stop()
+			flag = time.NewTimer(m.clocks[side])
+		case msg, ok := <-opponent.in:
+			if !ok {
+				return forfeit(side.Other(), Disconnection)
+			}
+			if msg.GetMessageType() == pb.UciRequest_GAME_CONTROL {
+				if outcome, done := m.turnControl(side, side.Other(), msg.GetGameControl(), start); done {
+					return outcome
+				}
+				continue
+			}
+			if msg.GetMessageType() == pb.UciRequest_INFO && m.ponders[side.Other()] != "" {
+				if info := opponent.checkInfo(m.searchPosition(side.Other()), msg.GetInfo(), m.chess960[side.Other()]); info != nil {
+					m.scored(side.Other(), info)
+				}
+				continue
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE && m.ponders[side.Other()] != "" {
+				m.logger.Warnf("Best move from %v while it is pondering", side.Other())
+				m.ponders[side.Other()] = ""
+				m.searching[side.Other()] = false
+				continue
+			}
+			if m.owes(side.Other(), msg) {
+				continue
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE && !m.searching[side.Other()] {
+				if m.unexpected(side.Other(), msg, "while it is not its turn") == Forfeit {
+					return forfeit(side.Other(), ProtocolViolation)
+				}
+				continue
+			}
+			m.logger.Debugf("Ignoring %v from %v while it is not its turn", msg.GetMessageType(), side.Other())
+		case msg, ok := <-p.in:
+			if !ok {
+				return forfeit(side, Disconnection)
+			}
+
+			if m.owes(side, msg) {
+				continue
+			}
+
+			switch msg.GetMessageType() {
+			case pb.UciRequest_GAME_CONTROL:
+				if outcome, done := m.turnControl(side, side, msg.GetGameControl(), start); done {
+					return outcome
+				}
+			case pb.UciRequest_INFO:
+				m.logger.Debugf("Info from %v: %v", side, msg.GetInfo())
+				if info := p.checkInfo(m.searchPosition(side), msg.GetInfo(), m.chess960[side]); info != nil {
+					m.scored(side, info)
+				}
+			case pb.UciRequest_BESTMOVE:
+				m.searching[side] = false
+				m.clocks[side] -= time.Since(start)
+				if m.clocks[side] < 0 {
+					m.clocks[side] = 0
+					return forfeit(side, TimeForfeit)
+				}
+
+				bestMove := msg.GetBestMove().GetMove()
+				move, err := m.game.Position().ParseUCI(bestMove, m.chess960[side])
+				if err != nil {
+					v := IllegalBestMove
+					meant, ok := otherNotation(m.game.Position(), bestMove, m.chess960[side])
+					if ok {
+						v = WrongNotation
+					}
+					action := m.violation(side, v, retries, "bestmove %v", bestMove)
+					switch {
+					case action == Forfeit:
+						return forfeit(side, IllegalMove)
+					case action == Warn && ok:
+						move = meant
+					default:
+						// The engine is asked again with its clock still running
+						if action == Retry {
+							retries++
+						}
+						if outcome := m.search(side, m.movesFor(side), false); outcome.Result != rules.NoResult {
+							return outcome
+						}
+						start = time.Now()
+						flag.Stop()
+						flag = time.NewTimer(m.clocks[side])
+						continue
+					}
+				}
+				if err := m.game.Play(move); err != nil {
+					m.logger.Warnf("Illegal move from %v: %v", side, err)
+					return forfeit(side, IllegalMove)
+				}
+				m.moved(side)
+
+				m.adjudicator.record(side, m.scores[side], len(m.game.Moves()))
+				m.clocks[side] += m.config.Increment
+				return m.ponder(side, msg.GetBestMove().GetPonder())
+			default:
+				if m.unexpected(side, msg, "while it is searching") == Forfeit {
+					return forfeit(side, ProtocolViolation)
+				}
+			}
+		}
+	}
+}
+
+// bookMove plays a move from the move book for the engine to move, its clock
+// does not run and a ponder search it started is stopped
+func (m *match) bookMove(side rules.Color) (bool, rules.Outcome) {
+	if m.config.MoveBook == nil || m.game.Start().Variant() != rules.Standard {
+		return false, rules.Outcome{}
+	}
+	move, ok := m.config.MoveBook.Choose(m.game.Position(), len(m.game.Moves()))
+	if !ok {
+		return false, rules.Outcome{}
+	}
+
+	if m.ponders[side] != "" {
+		m.ponders[side] = ""
+		if outcome := m.stopSearch(side); outcome.Result != rules.NoResult {
+			return true, outcome
+		}
+	}
+	if err := m.game.Play(move); err != nil {
+		m.logger.Warnf("Illegal book move %v for %v: %v", move, side, err)
+		return false, rules.Outcome{}
+	}
+	m.moved(side)
+	m.adjudicator.record(side, nil, len(m.game.Moves()))
+	m.logger.Debugf("Book move %v for %v", move, side)
+	return true, rules.Outcome{}
+}
+
+// adjudicate ends the game with the result of the tablebase once there are
+// few enough pieces. The tables assume the fifty move count starts from zero,
+// which it does after the capture that brings the game into them.
+func (m *match) adjudicate() rules.Outcome {
+	tb := m.config.Tablebase
+	if tb == nil || m.game.Start().Variant() != rules.Standard {
+		return rules.Outcome{}
+	}
+	limit := m.config.TablebasePieces
+	if limit == 0 || limit > tb.MaxPieces() {
+		limit = tb.MaxPieces()
+	}
+	position := m.game.Position()
+	if syzygy.Pieces(position) > limit {
+		return rules.Outcome{}
+	}
+
+	wdl, err := tb.ProbeWDL(position)
+	if err != nil {
+		m.logger.Debugf("Could not probe the tablebase: %v", err)
+		return rules.Outcome{}
+	}
+	m.logger.Debugf("The tablebase has a %v for %v", wdl, position.Turn())
+	switch wdl {
+	case syzygy.Win:
+		return rules.Outcome{Result: rules.Win(position.Turn()), Termination: TablebaseAdjudication}
+	case syzygy.Loss:
+		return rules.Outcome{Result: rules.Win(position.Turn().Other()), Termination: TablebaseAdjudication}
+	}
+	return rules.Outcome{Result: rules.Draw, Termination: TablebaseAdjudication}
+}
+
+// searchPosition returns the position an engine is searching, the position
+// after the move it ponders on while it ponders
+func (m *match) searchPosition(side rules.Color) *rules.Position {
+	position := m.game.Position()
+	if m.ponders[side] == "" {
+		return position
+	}
+	move, err := position.ParseMove(m.ponders[side])
+	if err != nil {
+		return position
+	}
+	return position.Play(move)
+}
+
+// scored keeps the score of the main line of an engine's search
+func (m *match) scored(side rules.Color, info *pb.UciRequest_Info) {
+	if info.GetScore() != nil && info.GetMultipv() <= 1 {
+		m.scores[side] = info.GetScore()
+	}
+}
+
+// startSearch starts the engine's search in the current position. An engine
+// pondering on the move that was played is sent ponderhit, otherwise its ponder
+// search is stopped and a new search is started.
+func (m *match) startSearch(side rules.Color) rules.Outcome {
+	p := m.players[side]
+	predicted := m.ponders[side]
+	m.ponders[side] = ""
+
+	if predicted != "" {
+		moves := m.game.Moves()
+		if moves[len(moves)-1].String() == predicted {
+			m.logger.Debugf("Ponder hit for %v on %v", side, predicted)
+			if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_PONDERHIT}); err != nil {
+				return forfeit(side, Disconnection)
+			}
+			return rules.Outcome{}
+		}
+
+		m.logger.Debugf("Ponder miss for %v expecting %v", side, predicted)
+		if outcome := m.stopSearch(side); outcome.Result != rules.NoResult {
+			return outcome
+		}
+	}
+
+	return m.search(side, m.movesFor(side), false)
+}
+
+// search sends the position after moves and a go command with the current clocks
+func (m *match) search(side rules.Color, moves []string, ponder bool) rules.Outcome {
+	p := m.players[side]
+	m.scores[side] = nil
+
+	err := p.send(&pb.UciResponse{
+		MessageType: pb.UciResponse_POSITION,
+		Position:    &pb.UciResponse_Position{IsFen: m.fen != "", Fen: m.fen, Moves: moves},
+	})
+	if err != nil {
+		return forfeit(side, Disconnection)
+	}
+	err = p.send(&pb.UciResponse{
+		MessageType: pb.UciResponse_GO,
+		Go: &pb.UciResponse_Go{
+			IsPonder: ponder,
+			Wtime:    milliseconds(m.clocks[rules.White]),
+			Btime:    milliseconds(m.clocks[rules.Black]),
+			Winc:     milliseconds(m.config.Increment),
+			Binc:     milliseconds(m.config.Increment),
+		},
+	})
+	if err != nil {
+		return forfeit(side, Disconnection)
+	}
+	m.searching[side] = true
+	return rules.Outcome{}
+}
+
+// ponder starts the engine pondering on the move it expects the opponent to
+// play. Pondering is skipped when the engine does not ponder in this match,
+// did not suggest a legal move or the game is already over.
+func (m *match) ponder(side rules.Color, suggested []string) rules.Outcome {
+	if !m.canPonder[side] || len(suggested) == 0 {
+		return rules.Outcome{}
+	}
+	if outcome := m.game.Outcome(); outcome.Result != rules.NoResult {
+		return rules.Outcome{}
+	}
+
+	position := m.game.Position()
+	move, err := position.ParseUCI(suggested[0], m.chess960[side])
+	if err != nil {
+		m.logger.Debugf("Not pondering on %v from %v: %v", suggested[0], side, err)
+		return rules.Outcome{}
+	}
+
+	if outcome := m.search(side, append(m.movesFor(side), position.UCI(move, m.chess960[side])), true); outcome.Result != rules.NoResult {
+		return outcome
+	}
+	m.ponders[side] = move.String()
+	return rules.Outcome{}
+}
+
+// stopSearch stops a search and discards the best move it returns
+func (m *match) stopSearch(side rules.Color) rules.Outcome {
+	p := m.players[side]
+	if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_STOP}); err != nil {
+		return forfeit(side, Disconnection)
+	}
+
+	timeout := time.NewTimer(m.config.readyTimeout())
+	defer timeout.Stop()
+
+	for retries := 0; ; {
+		select {
+		case <-timeout.C:
+			switch m.violation(side, NoBestMoveAfterStop, retries, "no answer in %v", m.config.readyTimeout()) {
+			case Forfeit:
+				return forfeit(side, Unresponsive)
+			case Warn:
+				m.searching[side] = false
+				m.players[side].stale++
+				return rules.Outcome{}
+			}
+			retries++
+			if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_STOP}); err != nil {
+				return forfeit(side, Disconnection)
+			}
+			timeout.Reset(m.config.readyTimeout())
+		case <-p.resumed:
+			// The search ended with the old stream
+			m.resume(side, m.clocks)
+			return rules.Outcome{}
+		case msg, ok := <-p.in:
+			if !ok {
+				return forfeit(side, Disconnection)
+			}
+			if m.owes(side, msg) {
+				continue
+			}
+			if msg.GetMessageType() == pb.UciRequest_GAME_CONTROL {
+				m.queued = append(m.queued, control{side: side, msg: msg.GetGameControl()})
+				continue
+			}
+			if msg.GetMessageType() == pb.UciRequest_BESTMOVE {
+				m.searching[side] = false
+				return rules.Outcome{}
+			}
+		}
+	}
+}
+
+// owes returns true for a readyok or best move the engine owed after a
+// violation, they come too late to be used and are discarded
+func (m *match) owes(side rules.Color, msg *pb.UciRequest) bool {
+	return m.players[side].owes(msg)
+}
+
+func (m *match) moves() []string {
+	moves := make([]string, len(m.game.Moves()))
+	for i, move := range m.game.Moves() {
+		moves[i] = move.String()
+	}
+	return moves
+}
+
+// movesFor returns the moves played in the notation of an engine
+func (m *match) movesFor(side rules.Color) []string {
+	position := m.game.Start()
+	moves := make([]string, len(m.game.Moves()))
+	for i, move := range m.game.Moves() {
+		moves[i] = position.UCI(move, m.chess960[side])
+		position = position.Play(move)
+	}
+	return moves
+}
+
+// loser returns the color that lost a decisive result
+func loser(r rules.Result) rules.Color {
+	if r == rules.WhiteWins {
+		return rules.Black
+	}
+	return rules.White
+}
+
+// forfeit returns an outcome where c loses
+func forfeit(c rules.Color, termination rules.Termination) rules.Outcome {
+	return rules.Outcome{Result: rules.Win(c.Other()), Termination: termination}
+}
+
+func milliseconds(d time.Duration) uint32 {
+	if d < 0 {
+		return 0
+	}
+	return uint32(d / time.Millisecond)
+}
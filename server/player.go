@@ -0,0 +1,244 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/sirupsen/logrus"
+)
+
+// errDisconnected is returned when the engine's stream ends during a job
+var errDisconnected = fmt.Errorf("Engine disconnected")
+
+// errUnresponsive is returned when the engine does not answer during a job
+var errUnresponsive = fmt.Errorf("Engine is unresponsive")
+
+// player is an engine connected over a UCI stream that has completed the handshake
+type player struct {
+	name    string
+	author  string
+	options []*pb.UciRequest_Option
+	stream  pb.ChessApplication_UCIServer
+	logger  *logrus.Entry
+
+	// messages from the engine, closed when the stream ends and the engine
+	// does not reconnect within its grace period
+	in chan *pb.UciRequest
+	// closed just before in
+	lost chan struct{}
+	// closed once the engine leaves the scheduler
+	done chan struct{}
+	// how long the engine has to reconnect once its stream ends, none when zero
+	grace time.Duration
+	// the session an engine reconnects to with a new stream
+	session string
+	resumes chan resumption
+	// signalled when the engine reconnected, it lost any search it was
+	// running and has answered isready again
+	resumed chan struct{}
+	// set by a job when the engine stopped responding so it is not given another
+	failed bool
+	// owed counts the readyoks the engine still owes after it did not answer
+	// isready in time and stale the best moves it owes after it did not
+	// answer stop in time, they are discarded when they arrive even in a
+	// later job
+	owed  int
+	stale int
+	// counts an anomaly in the engine's info lines, set when it joins the scheduler
+	onAnomaly func(Anomaly)
+	// carries out a control that is not for the engine's current game eg. a
+	// correspondence game state request, returning false for other controls.
+	// It is set before the engine is listened to.
+	onControl func(*pb.ClientGameMessage) bool
+
+	// sends come from the job and from controls answered as they arrive
+	sendMu sync.Mutex
+}
+
+// resumption is a new stream of an engine that reconnected and completed the handshake again
+type resumption struct {
+	stream pb.ChessApplication_UCIServer
+	// the messages of the stream after the handshake
+	source <-chan *pb.UciRequest
+}
+
+// receive reads messages from a stream into a channel that is closed when the stream ends
+func receive(stream pb.ChessApplication_UCIServer, logger *logrus.Entry) <-chan *pb.UciRequest {
+	source := make(chan *pb.UciRequest)
+	go func() {
+		defer close(source)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				logger.Info("Stream closed: ", err)
+				return
+			}
+			select {
+			case source <- msg:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+	return source
+}
+
+// listen starts passing the messages from the stream's source to the in
+// channel. Once the stream ends the engine has its grace period to reconnect,
+// the messages of the new stream are passed on from then on.
+func (p *player) listen(source <-chan *pb.UciRequest) {
+	p.in = make(chan *pb.UciRequest)
+	p.lost = make(chan struct{})
+	p.resumes = make(chan resumption, 1)
+	p.resumed = make(chan struct{}, 1)
+	go func() {
+		defer close(p.in)
+		defer close(p.lost)
+
+		ctx := p.stream.Context()
+		var grace <-chan time.Time
+		for {
+			select {
+			case msg, ok := <-source:
+				if !ok {
+					if p.grace == 0 {
+						return
+					}
+					p.logger.Infof("Waiting %v for the engine to reconnect", p.grace)
+					source, grace = nil, time.After(p.grace)
+					continue
+				}
+				if msg.GetMessageType() == pb.UciRequest_GAME_CONTROL && p.onControl != nil && p.onControl(msg.GetGameControl()) {
+					continue
+				}
+				select {
+				case p.in <- msg:
+				case <-ctx.Done():
+				}
+			case r := <-p.resumes:
+				p.logger.Info("Engine reconnected")
+				p.sendMu.Lock()
+				p.stream = r.stream
+				p.sendMu.Unlock()
+				ctx, source, grace = r.stream.Context(), r.source, nil
+				select {
+				case p.resumed <- struct{}{}:
+				default:
+				}
+			case <-grace:
+				p.logger.Warn("Engine did not reconnect")
+				return
+			}
+		}
+	}()
+}
+
+// send sends a message to the engine. Messages to an engine that may still
+// reconnect are dropped, it is brought up to date once it does.
+func (p *player) send(msg *pb.UciResponse) error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	err := p.stream.Send(msg)
+	if err != nil && p.grace > 0 && p.connected() {
+		return nil
+	}
+	return err
+}
+
+// ready sends isready and waits for readyok. A reconnection before then has
+// nothing to catch up on, an engine that reconnects while it is waited for
+// lost the isready with its old stream so it is asked again.
+func (p *player) ready(timeout time.Duration) error {
+	select {
+	case <-p.resumed:
+		p.owed, p.stale = 0, 0
+	default:
+	}
+	if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_ISREADY}); err != nil {
+		return errDisconnected
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return errUnresponsive
+		case <-p.resumed:
+			p.owed, p.stale = 0, 0
+			if err := p.send(&pb.UciResponse{MessageType: pb.UciResponse_ISREADY}); err != nil {
+				return errDisconnected
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case msg, ok := <-p.in:
+			if !ok {
+				return errDisconnected
+			}
+			if msg.GetMessageType() == pb.UciRequest_READYOK && !p.owes(msg) {
+				return nil
+			}
+		}
+	}
+}
+
+// owes returns true for a readyok or best move the engine owed after a
+// violation, they come too late to be used and are discarded
+func (p *player) owes(msg *pb.UciRequest) bool {
+	switch {
+	case msg.GetMessageType() == pb.UciRequest_READYOK && p.owed > 0:
+		p.owed--
+		return true
+	case msg.GetMessageType() == pb.UciRequest_BESTMOVE && p.stale > 0:
+		p.stale--
+		return true
+	}
+	return false
+}
+
+// connected returns false once the stream has ended and the engine did not reconnect
+func (p *player) connected() bool {
+	select {
+	case <-p.lost:
+		return false
+	default:
+		return true
+	}
+}
+
+// hasOption reports whether the engine advertised an option, option names are case insensitive
+func (p *player) hasOption(name string) bool {
+	for _, option := range p.options {
+		if strings.EqualFold(option.GetName(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// listsVariant reports whether the engine lists a variant in its UCI_Variant option
+func (p *player) listsVariant(name string) bool {
+	for _, option := range p.options {
+		if !strings.EqualFold(option.GetName(), "UCI_Variant") {
+			continue
+		}
+		for _, v := range option.GetVar() {
+			if strings.EqualFold(v, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// plays reports whether the engine can play a variant, every engine plays standard chess
+func (p *player) plays(v rules.Variant) bool {
+	return v == rules.Standard || p.listsVariant(v.Name())
+}
@@ -0,0 +1,686 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnavailable is returned for a job that can not run because an engine it needs has left
+var ErrUnavailable = fmt.Errorf("Engine is no longer available")
+
+// EngineInfo describes an engine connected to the scheduler
+type EngineInfo struct {
+	// The name and author the engine sent in its id
+	Name   string
+	Author string
+	// The options the engine advertised
+	Options []*pb.UciRequest_Option
+	// Capacity is the number of connections of the engine, each runs one job at a time
+	Capacity int
+	// Idle is the number of connections without a job
+	Idle int
+	// Anomalies counts each anomaly found in the info lines of engines with
+	// the name since the scheduler started
+	Anomalies map[Anomaly]int
+}
+
+// Scheduler keeps a pool of connected engines and hands out games and
+// analysis jobs to them. Jobs with a higher priority are started first. Jobs
+// with the same priority take turns between their submitters, so a submitter
+// with many jobs queued does not hold up the others, and the jobs of a
+// submitter start in the order they were submitted. A job that is waiting for a particular engine does not hold up jobs behind it that
+// can run on other engines. Engines are given jobs least recently used first
+// and are reused across jobs for as long as they stay connected.
+type Scheduler struct {
+	config Config
+	logger *logrus.Entry
+
+	mu      sync.Mutex
+	engines []*poolEngine
+	jobs    []*job
+	seq     uint64
+	// when a job of each submitter last started, in jobs started
+	served map[string]uint64
+	turns  uint64
+	// an engine that asked for a game and is waiting for an opponent to ask too
+	seeking *player
+	closed  bool
+	stop    chan struct{}
+	// the anomalies in the info lines of each engine by name
+	anomalies map[string]map[Anomaly]int
+	// the correspondence games engines in the pool play, nil until they are enabled
+	correspondence *Correspondence
+	// the engines in the pool by session token when engines may reconnect
+	sessions map[string]*player
+}
+
+// poolEngine is an engine in the scheduler's pool
+type poolEngine struct {
+	player *player
+	busy   bool
+	// when the engine last finished a job or health check
+	idleSince time.Time
+}
+
+// slot is an engine a job needs
+type slot struct {
+	// a particular connection, any connection when nil
+	player *player
+	// the name of the engine, any engine when empty
+	name string
+	// the variant the engine must play, any when nil
+	variant rules.Variant
+}
+
+func (s slot) accepts(p *player) bool {
+	if s.variant != nil && !p.plays(s.variant) {
+		return false
+	}
+	if s.player != nil {
+		return s.player == p
+	}
+	return s.name == "" || s.name == p.name
+}
+
+// job is work waiting for engines
+type job struct {
+	priority  int
+	submitter string
+	seq       uint64
+	slots     []slot
+	run       func(players []*player)
+
+	// closed when the job starts or is dropped
+	started chan struct{}
+	// set when the job is dropped because it can never start
+	err error
+	// closed once the job has run and its engines are released
+	finished chan struct{}
+}
+
+// NewScheduler creates a scheduler with an empty pool
+func NewScheduler(l *logrus.Entry, config Config) *Scheduler {
+	s := &Scheduler{config: config, logger: l.WithField("request", "scheduler"), stop: make(chan struct{}), served: make(map[string]uint64)}
+	if config.HealthInterval > 0 {
+		go s.checkHealth()
+	}
+	return s
+}
+
+// Engines returns the engines in the pool grouped by name
+func (s *Scheduler) Engines() []EngineInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var infos []EngineInfo
+	index := make(map[string]int)
+	for _, e := range s.engines {
+		i, ok := index[e.player.name]
+		if !ok {
+			i = len(infos)
+			index[e.player.name] = i
+			infos = append(infos, EngineInfo{Name: e.player.name, Author: e.player.author, Options: e.player.options, Anomalies: make(map[Anomaly]int)})
+			for a, n := range s.anomalies[e.player.name] {
+				infos[i].Anomalies[a] = n
+			}
+		}
+		infos[i].Capacity++
+		if !e.busy {
+			infos[i].Idle++
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Queued returns the number of jobs waiting for engines
+func (s *Scheduler) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// Play queues a game and blocks until it is over. Cancelling the context
+// takes a game that has not started off the queue and aborts a game that is
+// being played, stopping the engines' searches.
+func (s *Scheduler) Play(ctx context.Context, g Game) (GameRecord, error) {
+	config := s.config
+	if g.Time > 0 {
+		config.Time = g.Time
+		config.Increment = g.Increment
+	}
+	if g.Ponder != nil {
+		config.Ponder = *g.Ponder
+	}
+	config.blackTime = g.BlackTime
+	if g.Adjudication != nil {
+		config.Adjudication = *g.Adjudication
+	}
+	if g.Variant != nil {
+		config.Variant = g.Variant
+	}
+
+	game, err := g.Opening.game(config.Variant)
+	if err != nil {
+		return GameRecord{}, err
+	}
+
+	// A game of a variant an engine does not play is refused rather than
+	// left waiting for a connection that plays it
+	variant := game.Start().Variant()
+	slots := []slot{{name: g.White, variant: variant}, {name: g.Black, variant: variant}}
+	for _, sl := range slots {
+		if sl.name != "" && s.connected(slot{name: sl.name}) && !s.connected(sl) {
+			return GameRecord{}, fmt.Errorf("Engine %v does not play %v", sl.name, variant.Name())
+		}
+	}
+
+	var record GameRecord
+	err = s.do(ctx, g.Priority, g.Submitter, slots, func(players []*player) {
+		record = s.play(ctx, players[0], players[1], game, g.Opening.FEN, config)
+	})
+	return record, err
+}
+
+// play runs a match and reports the game, the match is aborted once ctx is done
+func (s *Scheduler) play(ctx context.Context, white, black *player, game *rules.Game, fen string, config Config) GameRecord {
+	m := newMatch(white, black, game, fen, config, s.logger.WithField("request", "match"))
+	m.abort = ctx.Done()
+	record := m.play()
+	if config.OnGameOver != nil {
+		config.OnGameOver(record)
+	}
+	return record
+}
+
+// do submits a job and blocks until it has run
+func (s *Scheduler) do(ctx context.Context, priority int, submitter string, slots []slot, run func(players []*player)) error {
+	j := s.submit(priority, submitter, slots, run)
+
+	select {
+	case <-j.started:
+	case <-ctx.Done():
+		if s.cancel(j) {
+			return ctx.Err()
+		}
+		<-j.started
+	}
+	if j.err != nil {
+		return j.err
+	}
+
+	<-j.finished
+	return nil
+}
+
+// submit queues a job and starts any jobs that can run
+func (s *Scheduler) submit(priority int, submitter string, slots []slot, run func(players []*player)) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	j := &job{
+		priority:  priority,
+		submitter: submitter,
+		seq:       s.seq,
+		slots:     slots,
+		run:       run,
+		started:   make(chan struct{}),
+		finished:  make(chan struct{}),
+	}
+	if s.closed {
+		j.err = ErrUnavailable
+		close(j.started)
+		return j
+	}
+
+	s.jobs = append(s.jobs, j)
+	s.sortJobs()
+	s.dispatch()
+	return j
+}
+
+// cancel removes a job that has not started returning false when it already has
+func (s *Scheduler) cancel(j *job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, queued := range s.jobs {
+		if queued == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch starts every queued job that has idle engines for all its slots, s.mu must be held
+func (s *Scheduler) dispatch() {
+	idle := make([]*poolEngine, 0, len(s.engines))
+	for _, e := range s.engines {
+		if !e.busy {
+			idle = append(idle, e)
+		}
+	}
+	sort.SliceStable(idle, func(a, b int) bool { return idle[a].idleSince.Before(idle[b].idleSince) })
+
+	var waiting []*job
+	for _, j := range s.fair() {
+		engines := assign(j, idle)
+		if engines == nil {
+			waiting = append(waiting, j)
+			continue
+		}
+
+		players := make([]*player, len(engines))
+		for i, e := range engines {
+			e.busy = true
+			players[i] = e.player
+		}
+		idle = removeEngines(idle, engines)
+		s.turns++
+		s.served[j.submitter] = s.turns
+
+		close(j.started)
+		go s.runJob(j, players)
+	}
+	s.jobs = waiting
+	s.sortJobs()
+}
+
+// sortJobs orders the queue by priority then in the order jobs were submitted, s.mu must be held
+func (s *Scheduler) sortJobs() {
+	sort.SliceStable(s.jobs, func(a, b int) bool {
+		if s.jobs[a].priority != s.jobs[b].priority {
+			return s.jobs[a].priority > s.jobs[b].priority
+		}
+		return s.jobs[a].seq < s.jobs[b].seq
+	})
+}
+
+// fair orders the queued jobs by priority and within a priority takes one job
+// from each submitter in turn, starting with the submitter that has waited
+// longest since one of its jobs started, s.mu must be held
+func (s *Scheduler) fair() []*job {
+	var order []*job
+	for start := 0; start < len(s.jobs); {
+		end := start
+		for end < len(s.jobs) && s.jobs[end].priority == s.jobs[start].priority {
+			end++
+		}
+
+		// the jobs of each submitter in the order they were submitted
+		var submitters []string
+		queues := make(map[string][]*job)
+		for _, j := range s.jobs[start:end] {
+			if _, ok := queues[j.submitter]; !ok {
+				submitters = append(submitters, j.submitter)
+			}
+			queues[j.submitter] = append(queues[j.submitter], j)
+		}
+		sort.SliceStable(submitters, func(a, b int) bool {
+			return s.served[submitters[a]] < s.served[submitters[b]]
+		})
+
+		for n := end - start; n > 0; {
+			for _, submitter := range submitters {
+				if queue := queues[submitter]; len(queue) > 0 {
+					order = append(order, queue[0])
+					queues[submitter] = queue[1:]
+					n--
+				}
+			}
+		}
+		start = end
+	}
+	return order
+}
+
+// assign picks an idle engine for each slot of the job returning nil when a slot can not be filled
+func assign(j *job, idle []*poolEngine) []*poolEngine {
+	var engines []*poolEngine
+	for _, sl := range j.slots {
+		var found *poolEngine
+		for _, e := range idle {
+			if sl.accepts(e.player) && !containsEngine(engines, e) {
+				found = e
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		engines = append(engines, found)
+	}
+	return engines
+}
+
+func containsEngine(engines []*poolEngine, e *poolEngine) bool {
+	for _, other := range engines {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+func removeEngines(engines, remove []*poolEngine) []*poolEngine {
+	var kept []*poolEngine
+	for _, e := range engines {
+		if !containsEngine(remove, e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func (s *Scheduler) runJob(j *job, players []*player) {
+	j.run(players)
+	for _, p := range players {
+		s.release(p)
+	}
+	close(j.finished)
+}
+
+// register adds an engine that completed the handshake to the pool, it is
+// told to quit when the scheduler is closed
+func (s *Scheduler) register(p *player) {
+	if !s.add(p) {
+		p.send(&pb.UciResponse{MessageType: pb.UciResponse_QUIT})
+		close(p.done)
+		return
+	}
+	if p.session != "" {
+		p.send(&pb.UciResponse{
+			MessageType: pb.UciResponse_SESSION,
+			Session:     &pb.UciResponse_Session{Token: p.session, Grace: milliseconds(p.grace)},
+		})
+	}
+
+	s.mu.Lock()
+	s.dispatch()
+	s.mu.Unlock()
+}
+
+// add puts an engine in the pool returning false once the scheduler is closed,
+// it is not given a job until the caller dispatches
+func (s *Scheduler) add(p *player) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	p.onAnomaly = func(a Anomaly) { s.countAnomaly(p.name, a) }
+	s.engines = append(s.engines, &poolEngine{player: p, idleSince: time.Now()})
+	if s.correspondence != nil {
+		go s.correspondence.joined(p)
+	}
+	if p.grace > 0 {
+		s.startSession(p)
+	}
+	return true
+}
+
+// correspondenceControl passes a control to the correspondence games returning false when it is not for them
+func (s *Scheduler) correspondenceControl(p *player, msg *pb.ClientGameMessage) bool {
+	s.mu.Lock()
+	c := s.correspondence
+	s.mu.Unlock()
+	return c != nil && c.control(p, msg)
+}
+
+// named returns the connections of an engine in the pool
+func (s *Scheduler) named(name string) []*player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var players []*player
+	for _, e := range s.engines {
+		if e.player.name == name {
+			players = append(players, e.player)
+		}
+	}
+	return players
+}
+
+// countAnomaly counts an anomaly in the info lines of an engine
+func (s *Scheduler) countAnomaly(name string, a Anomaly) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.anomalies == nil {
+		s.anomalies = make(map[string]map[Anomaly]int)
+	}
+	if s.anomalies[name] == nil {
+		s.anomalies[name] = make(map[Anomaly]int)
+	}
+	s.anomalies[name][a]++
+}
+
+// seek asks for a game between the engine and the next engine to seek one
+// starting from the next opening of the book. The engine that asked first
+// plays white, with RepeatOpenings they then play the opening again with
+// colours reversed.
+func (s *Scheduler) seek(p *player) {
+	variant := s.config.Variant
+	if variant != nil && !p.plays(variant) {
+		p.logger.Warnf("Not pairing the engine, it does not play %v", variant.Name())
+		return
+	}
+
+	s.mu.Lock()
+	opponent := s.seeking
+	if opponent == nil {
+		s.seeking = p
+	} else {
+		s.seeking = nil
+	}
+	s.mu.Unlock()
+
+	if opponent == nil {
+		p.logger.Info("Waiting for an opponent")
+		return
+	}
+
+	config := s.config
+	opening := Opening{}
+	if config.Book != nil {
+		opening = config.Book.Next()
+	}
+
+	go func() {
+		colours := [][]slot{{{player: opponent}, {player: p}}}
+		if config.RepeatOpenings {
+			colours = append(colours, []slot{{player: p}, {player: opponent}})
+		}
+		for _, slots := range colours {
+			game, err := opening.game(variant)
+			if err != nil {
+				s.logger.Warnf("Playing from the starting position: %v", err)
+				game, opening = rules.NewGame(rules.NewPosition(variantOf(variant))), Opening{}
+			}
+			err = s.do(context.Background(), 0, "", slots, func(players []*player) {
+				s.play(context.Background(), players[0], players[1], game, opening.FEN, config)
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// release returns an engine to the pool once a job is done with it. Engines
+// that disconnected or stopped responding are removed instead.
+func (s *Scheduler) release(p *player) {
+	if !p.connected() {
+		s.remove(p)
+		return
+	}
+	if p.failed {
+		p.logger.Warn("Removing unresponsive engine")
+		s.quit(p)
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.quit(p)
+		return
+	}
+	for _, e := range s.engines {
+		if e.player == p {
+			e.busy = false
+			e.idleSince = time.Now()
+		}
+	}
+	s.dispatch()
+	s.mu.Unlock()
+}
+
+// remove takes an engine out of the pool and drops jobs that needed it
+func (s *Scheduler) remove(p *player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(p)
+}
+
+// removeLocked removes an engine returning false when it was not in the pool, s.mu must be held
+func (s *Scheduler) removeLocked(p *player) bool {
+	if s.seeking == p {
+		s.seeking = nil
+	}
+
+	found := false
+	for i, e := range s.engines {
+		if e.player == p {
+			s.engines = append(s.engines[:i], s.engines[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	close(p.done)
+	delete(s.sessions, p.session)
+
+	var jobs []*job
+	for _, j := range s.jobs {
+		dropped := false
+		for _, sl := range j.slots {
+			dropped = dropped || sl.player == p
+		}
+		if dropped {
+			j.err = ErrUnavailable
+			close(j.started)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	s.jobs = jobs
+	return true
+}
+
+// quit removes an engine from the pool and tells it to quit
+func (s *Scheduler) quit(p *player) {
+	s.mu.Lock()
+	removed := s.removeLocked(p)
+	s.mu.Unlock()
+
+	if removed {
+		p.send(&pb.UciResponse{MessageType: pb.UciResponse_QUIT})
+	}
+}
+
+// connected reports whether an engine that can fill the slot is in the pool
+func (s *Scheduler) connected(sl slot) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.engines {
+		if sl.accepts(e.player) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHealth periodically checks engines that have been idle for a while answer isready
+func (s *Scheduler) checkHealth() {
+	ticker := time.NewTicker(s.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		var due []*player
+		for _, e := range s.engines {
+			if !e.busy && time.Since(e.idleSince) >= s.config.HealthInterval {
+				e.busy = true
+				due = append(due, e.player)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, p := range due {
+			go s.check(p)
+		}
+	}
+}
+
+// check sends isready to a busy engine and releases it once it answers
+func (s *Scheduler) check(p *player) {
+	if err := p.ready(s.config.readyTimeout()); err != nil {
+		p.logger.Warn("Health check failed: ", err)
+		p.failed = err == errUnresponsive
+	}
+	s.release(p)
+}
+
+// Close tells idle engines to quit and stops handing out jobs. Busy engines
+// are told to quit once their job is over.
+func (s *Scheduler) Close() {
+	for _, p := range s.close() {
+		p.send(&pb.UciResponse{MessageType: pb.UciResponse_QUIT})
+	}
+}
+
+// close marks the scheduler closed returning the idle engines it took out of the pool
+func (s *Scheduler) close() []*player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+
+	var idle []*player
+	for _, e := range append([]*poolEngine(nil), s.engines...) {
+		if !e.busy && s.removeLocked(e.player) {
+			idle = append(idle, e.player)
+		}
+	}
+	for _, j := range s.jobs {
+		j.err = ErrUnavailable
+		close(j.started)
+	}
+	s.jobs = nil
+	return idle
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// sessionMetadata is the key of the gRPC metadata a reconnecting engine sends its session token in
+const sessionMetadata = "session"
+
+// sessionToken returns the session token a stream was opened with, empty when there is none
+func sessionToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(sessionMetadata)) == 0 {
+		return ""
+	}
+	return md.Get(sessionMetadata)[0]
+}
+
+// startSession gives an engine that joined the pool a session token it can
+// reconnect with and removes it once its grace period ends without it
+// reconnecting, s.mu must be held. The engine is sent the token by register.
+func (s *Scheduler) startSession(p *player) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		p.logger.Warn("Could not create a session: ", err)
+		return
+	}
+	p.session = hex.EncodeToString(token)
+	if s.sessions == nil {
+		s.sessions = make(map[string]*player)
+	}
+	s.sessions[p.session] = p
+
+	go func() {
+		select {
+		case <-p.lost:
+			s.remove(p)
+		case <-p.done:
+		}
+	}()
+}
+
+// resume hands the new stream of an engine that reconnected to the engine
+// of the session, it returns nil when there is no such session
+func (s *Scheduler) resume(token, name string, r resumption) *player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.sessions[token]
+	if !ok || p.name != name {
+		return nil
+	}
+	select {
+	case p.resumes <- r:
+		return p
+	default:
+		return nil
+	}
+}
+
+// resume brings an engine that reconnected during the game up to date with
+// the position and the clocks, it lost any search it was running
+func (m *match) resume(side rules.Color, clocks [2]time.Duration) {
+	m.logger.Infof("%v reconnected", side)
+	m.players[side].owed, m.players[side].stale = 0, 0
+	m.ponders[side] = ""
+	m.searching[side] = false
+
+	m.players[side].send(stateMessage(pb.ServerGameMessage_GAME_STATE_RESPONSE, nil, m.state(clocks), m.game, ""))
+}
+
+// state returns the state of the game with the time left on the clocks
+func (m *match) state(clocks [2]time.Duration) *pb.GameState {
+	return &pb.GameState{
+		Fen: m.game.Position().FEN(),
+		TimeControl: &pb.TimeControl{
+			Time:     int32(milliseconds(m.config.Time)),
+			Incremet: int32(milliseconds(m.config.Increment)),
+		},
+		TimeState: &pb.TimeState{
+			WhiteTimeRemaining: int32(milliseconds(clocks[rules.White])),
+			BlackTimeRemaining: int32(milliseconds(clocks[rules.Black])),
+		},
+		White:    m.players[rules.White].name,
+		Black:    m.players[rules.Black].name,
+		Moves:    m.moves(),
+		StartFen: m.fen,
+		Turn:     m.game.Position().Turn().String(),
+		Result:   rules.NoResult.String(),
+	}
+}
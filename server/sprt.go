@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/sprt"
+)
+
+// defaultErrorRate is the false positive and false negative rate of an SPRT when the request does not say
+const defaultErrorRate = 0.05
+
+// SPRT plays pairs of games between a candidate and a baseline engine, each
+// pair plays an opening with both colours, and streams the test's progress
+// after each pair until the test accepts a hypothesis or reaches its limit
+func (cs *chessService) SPRT(req *pb.SPRTRequest, stream pb.ChessApplication_SPRTServer) error {
+	logger := cs.l.WithField("request", "SPRT").WithField("candidate", req.GetCandidate()).WithField("baseline", req.GetBaseline())
+	ctx := stream.Context()
+
+	test, openings, err := sprtTest(req, cs.scheduler.config.Variant)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, name := range []string{req.GetCandidate(), req.GetBaseline()} {
+		if !cs.scheduler.connected(slot{name: name}) {
+			return status.Error(codes.Unavailable, fmt.Sprintf("Engine %v is not connected", name))
+		}
+	}
+
+	concurrency := int(req.GetConcurrency())
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		pairs   int
+		result  = pb.SPRTUpdate_RUNNING
+		testErr error
+		wg      sync.WaitGroup
+	)
+
+	// next returns the index of the next pair to play, ok is false once the test is over
+	next := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result != pb.SPRTUpdate_RUNNING || testErr != nil {
+			return 0, false
+		}
+		if req.GetMaxPairs() > 0 && pairs >= int(req.GetMaxPairs()) {
+			return 0, false
+		}
+		pairs++
+		return pairs - 1, true
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				pair, ok := next()
+				if !ok {
+					return
+				}
+
+				points, err := cs.playPair(ctx, req, openings[pair%len(openings)])
+
+				mu.Lock()
+				switch {
+				case result != pb.SPRTUpdate_RUNNING || testErr != nil:
+					// the test ended while the pair was played
+				case err != nil:
+					testErr = err
+					cancel()
+				default:
+					test.Add(points[0], points[1])
+					switch test.Result() {
+					case sprt.AcceptH0:
+						result = pb.SPRTUpdate_H0
+					case sprt.AcceptH1:
+						result = pb.SPRTUpdate_H1
+					case sprt.Continue:
+						if req.GetMaxPairs() > 0 && test.Pairs.Pairs() >= int(req.GetMaxPairs()) {
+							result = pb.SPRTUpdate_INCONCLUSIVE
+						}
+					}
+					if err := stream.Send(sprtUpdate(test, result)); err != nil {
+						testErr = err
+					}
+					if result != pb.SPRTUpdate_RUNNING || testErr != nil {
+						cancel()
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case result != pb.SPRTUpdate_RUNNING:
+		logger.Infof("SPRT finished with %v after %v games", result, test.Games())
+		return nil
+	case stream.Context().Err() != nil:
+		return nil
+	case testErr == ErrUnavailable:
+		return status.Error(codes.Unavailable, testErr.Error())
+	default:
+		logger.Warn("SPRT failed: ", testErr)
+		return status.Error(codes.Aborted, testErr.Error())
+	}
+}
+
+// sprtTest checks an SPRT request and returns the test it asks for and its openings
+func sprtTest(req *pb.SPRTRequest, variant rules.Variant) (*sprt.Test, []Opening, error) {
+	if req.GetCandidate() == "" || req.GetBaseline() == "" || req.GetCandidate() == req.GetBaseline() {
+		return nil, nil, fmt.Errorf("An SPRT needs two different engines")
+	}
+	if req.GetElo0() >= req.GetElo1() {
+		return nil, nil, fmt.Errorf("Expecting elo0 to be less than elo1 got %v and %v", req.GetElo0(), req.GetElo1())
+	}
+
+	test := &sprt.Test{Elo0: req.GetElo0(), Elo1: req.GetElo1(), Alpha: req.GetAlpha(), Beta: req.GetBeta()}
+	if test.Alpha == 0 {
+		test.Alpha = defaultErrorRate
+	}
+	if test.Beta == 0 {
+		test.Beta = defaultErrorRate
+	}
+	if test.Alpha <= 0 || test.Alpha >= 0.5 || test.Beta <= 0 || test.Beta >= 0.5 {
+		return nil, nil, fmt.Errorf("Expecting alpha and beta between 0 and 0.5 got %v and %v", test.Alpha, test.Beta)
+	}
+
+	var openings []Opening
+	for _, o := range req.GetOpenings() {
+		opening := Opening{FEN: o.GetFen(), Moves: o.GetMoves()}
+		if _, err := opening.game(variant); err != nil {
+			return nil, nil, err
+		}
+		openings = append(openings, opening)
+	}
+	if len(openings) == 0 {
+		openings = []Opening{{}}
+	}
+	return test, openings, nil
+}
+
+// playPair plays an opening with the candidate as white and then as black
+// and returns the candidate's points in each game
+func (cs *chessService) playPair(ctx context.Context, req *pb.SPRTRequest, opening Opening) ([2]float64, error) {
+	var points [2]float64
+	players := [2][2]string{
+		{req.GetCandidate(), req.GetBaseline()},
+		{req.GetBaseline(), req.GetCandidate()},
+	}
+	for i, p := range players {
+		record, err := cs.scheduler.Play(ctx, Game{
+			White:     p[0],
+			Black:     p[1],
+			Time:      time.Duration(req.GetTime()) * time.Millisecond,
+			Increment: time.Duration(req.GetIncrement()) * time.Millisecond,
+			Priority:  int(req.GetPriority()),
+			Submitter: req.GetSubmitter(),
+			Opening:   opening,
+		})
+		if err != nil {
+			return points, err
+		}
+
+		switch record.Outcome.Result {
+		case rules.NoResult:
+			return points, fmt.Errorf("Game between %v and %v was %v", p[0], p[1], record.Outcome.Termination)
+		case rules.WhiteWins:
+			if record.White == req.GetCandidate() {
+				points[i] = 1
+			}
+		case rules.BlackWins:
+			if record.Black == req.GetCandidate() {
+				points[i] = 1
+			}
+		default:
+			points[i] = 0.5
+		}
+	}
+	return points, nil
+}
+
+func sprtUpdate(test *sprt.Test, result pb.SPRTUpdate_Result) *pb.SPRTUpdate {
+	lower, upper := test.Bounds()
+	elo, margin := test.Elo()
+	update := &pb.SPRTUpdate{
+		Result:    result,
+		Llr:       test.LLR(),
+		Lower:     lower,
+		Upper:     upper,
+		Elo:       elo,
+		EloMargin: margin,
+		Games:     uint32(test.Games()),
+		Wins:      uint32(test.Wins),
+		Draws:     uint32(test.Draws),
+		Losses:    uint32(test.Losses),
+	}
+	for _, n := range test.Pairs {
+		update.Pentanomial = append(update.Pentanomial, uint32(n))
+	}
+	return update
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/schafer14/grpc-chess/rules"
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+// Violation is a way an engine broke the UCI protocol
+type Violation string
+
+// The violations the server looks for
+const (
+	// IllegalBestMove is a best move that is not legal in the position
+	IllegalBestMove Violation = "illegal bestmove"
+	// WrongNotation is a best move that is legal once read in another
+	// notation eg. SAN or castling written the Chess960 way in a standard game
+	WrongNotation Violation = "wrong notation"
+	// BestMoveWithoutGo is a best move the engine was not asked for
+	BestMoveWithoutGo Violation = "bestmove without go"
+	// NoBestMoveAfterStop is an engine that does not end its search with a best move once stopped
+	NoBestMoveAfterStop Violation = "no bestmove after stop"
+	// NoReadyOK is an engine that does not answer isready
+	NoReadyOK Violation = "no readyok"
+	// UnexpectedMessage is a message the engine should not send at that point
+	UnexpectedMessage Violation = "unexpected message"
+)
+
+// Action is what the server does about a violation
+type Action string
+
+// The actions taken on a violation
+const (
+	// Forfeit ends the game as a loss for the engine
+	Forfeit Action = "forfeit"
+	// Warn reports the violation and carries on as if the engine had answered,
+	// a move in the wrong notation is played as it was meant and an illegal
+	// move is asked for again while the engine's clock runs
+	Warn Action = "warn"
+	// Retry asks the engine again, the engine forfeits once it has been asked
+	// again as many times as the policy allows
+	Retry Action = "retry"
+)
+
+// defaultActions keep the referee strict about the moves and answers a game
+// needs and lenient about messages it can ignore
+var defaultActions = map[Violation]Action{
+	IllegalBestMove:     Forfeit,
+	WrongNotation:       Forfeit,
+	BestMoveWithoutGo:   Warn,
+	NoBestMoveAfterStop: Forfeit,
+	NoReadyOK:           Forfeit,
+	UnexpectedMessage:   Warn,
+}
+
+// ViolationPolicy decides what the server does when an engine breaks the protocol
+type ViolationPolicy struct {
+	// Actions overrides the action taken on a violation
+	Actions map[Violation]Action
+	// How many times an engine is asked again in a row, 1 when zero
+	Retries int
+}
+
+// action returns the action taken on the nth violation of a kind in a row
+// counting from 0, a retry turns into a forfeit once the retries are used up
+func (vp ViolationPolicy) action(v Violation, n int) Action {
+	action, ok := vp.Actions[v]
+	if !ok {
+		action = defaultActions[v]
+	}
+	retries := vp.Retries
+	if retries == 0 {
+		retries = 1
+	}
+	if action == Retry && n >= retries {
+		return Forfeit
+	}
+	return action
+}
+
+// ViolationReport is a violation recorded with a game
+type ViolationReport struct {
+	// The engine that broke the protocol and its colour
+	Engine string
+	Color  rules.Color
+	// What it did and what the server did about it
+	Violation Violation
+	Action    Action
+	// The number of moves played when it happened
+	Ply int
+	// The message or move in question
+	Detail string
+}
+
+var violationCodes = map[Violation]pb.UciResponse_Violation_Code{
+	IllegalBestMove:     pb.UciResponse_Violation_ILLEGAL_BESTMOVE,
+	WrongNotation:       pb.UciResponse_Violation_WRONG_NOTATION,
+	BestMoveWithoutGo:   pb.UciResponse_Violation_BESTMOVE_WITHOUT_GO,
+	NoBestMoveAfterStop: pb.UciResponse_Violation_NO_BESTMOVE_AFTER_STOP,
+	NoReadyOK:           pb.UciResponse_Violation_NO_READYOK,
+	UnexpectedMessage:   pb.UciResponse_Violation_UNEXPECTED_MESSAGE,
+}
+
+var actionCodes = map[Action]pb.UciResponse_Violation_Action{
+	Forfeit: pb.UciResponse_Violation_FORFEIT,
+	Warn:    pb.UciResponse_Violation_WARN,
+	Retry:   pb.UciResponse_Violation_RETRY,
+}
+
+// message returns the message reporting a violation to the engines
+func (r ViolationReport) message() *pb.UciResponse {
+	return &pb.UciResponse{
+		MessageType: pb.UciResponse_VIOLATION,
+		Violation: &pb.UciResponse_Violation{
+			Code:   violationCodes[r.Violation],
+			Action: actionCodes[r.Action],
+			Engine: r.Engine,
+			Side:   r.Color.String(),
+			Ply:    uint32(r.Ply),
+			Detail: r.Detail,
+		},
+	}
+}
+
+// violation records a violation by an engine, reports it to both engines and
+// returns the action to take. n counts the violations of the kind in a row
+// the engine was asked again after.
+func (m *match) violation(side rules.Color, v Violation, n int, format string, args ...interface{}) Action {
+	report := ViolationReport{
+		Engine:    m.players[side].name,
+		Color:     side,
+		Violation: v,
+		Action:    m.config.Violations.action(v, n),
+		Ply:       len(m.game.Moves()),
+		Detail:    fmt.Sprintf(format, args...),
+	}
+	m.logger.Warnf("%v by %v: %v, %v", v, side, report.Detail, report.Action)
+	m.violations = append(m.violations, report)
+
+	for _, p := range m.players {
+		// A player that is gone is found out when the game next waits on it
+		p.send(report.message())
+	}
+	return report.Action
+}
+
+// unexpected records a message the engine should not have sent, a best move
+// is one it was not asked for
+func (m *match) unexpected(side rules.Color, msg *pb.UciRequest, while string) Action {
+	if msg.GetMessageType() == pb.UciRequest_BESTMOVE {
+		return m.violation(side, BestMoveWithoutGo, 0, "bestmove %v %v", msg.GetBestMove().GetMove(), while)
+	}
+	return m.violation(side, UnexpectedMessage, 0, "%v %v", msg.GetMessageType(), while)
+}
+
+// otherNotation reads a move the engine wrote in the wrong notation returning
+// false when it is not legal in any notation
+func otherNotation(position *rules.Position, s string, chess960 bool) (rules.Move, bool) {
+	if m, err := position.ParseUCI(s, !chess960); err == nil {
+		return m, true
+	}
+	for _, n := range []rules.Notation{rules.SANNotation, rules.LANNotation} {
+		if m, err := position.Parse(s, n); err == nil {
+			return m, true
+		}
+	}
+	return rules.Move{}, false
+}
@@ -35,6 +35,8 @@ const (
 	UciRequest_COPYPROTECTION UciRequest_MessageType = 5
 	UciRequest_REGISTRATION   UciRequest_MessageType = 6
 	UciRequest_INFO           UciRequest_MessageType = 7
+	// Not a UCI command, a lifecycle control of the game eg. a resignation
+	UciRequest_GAME_CONTROL UciRequest_MessageType = 8
 )
 
 var UciRequest_MessageType_name = map[int32]string{
@@ -46,6 +48,7 @@ var UciRequest_MessageType_name = map[int32]string{
 	5: "COPYPROTECTION",
 	6: "REGISTRATION",
 	7: "INFO",
+	8: "GAME_CONTROL",
 }
 
 var UciRequest_MessageType_value = map[string]int32{
@@ -57,6 +60,7 @@ var UciRequest_MessageType_value = map[string]int32{
 	"COPYPROTECTION": 5,
 	"REGISTRATION":   6,
 	"INFO":           7,
+	"GAME_CONTROL":   8,
 }
 
 func (x UciRequest_MessageType) String() string {
@@ -81,6 +85,13 @@ const (
 	UciResponse_STOP       UciResponse_MessageType = 8
 	UciResponse_PONDERHIT  UciResponse_MessageType = 9
 	UciResponse_QUIT       UciResponse_MessageType = 10
+	// Not a UCI command, reports a protocol violation by either engine in the game
+	UciResponse_VIOLATION UciResponse_MessageType = 11
+	// Not a UCI command, reports a lifecycle control of the game
+	UciResponse_GAME_CONTROL UciResponse_MessageType = 12
+	// Not a UCI command, gives the engine the session it reconnects to
+	// by sending the token in the session metadata of a new stream
+	UciResponse_SESSION UciResponse_MessageType = 13
 )
 
 var UciResponse_MessageType_name = map[int32]string{
@@ -95,20 +106,26 @@ var UciResponse_MessageType_name = map[int32]string{
 	8:  "STOP",
 	9:  "PONDERHIT",
 	10: "QUIT",
+	11: "VIOLATION",
+	12: "GAME_CONTROL",
+	13: "SESSION",
 }
 
 var UciResponse_MessageType_value = map[string]int32{
-	"UCI":        0,
-	"DEBUG":      1,
-	"ISREADY":    2,
-	"SETOPTION":  3,
-	"REGISTER":   4,
-	"UCINEWGAME": 5,
-	"POSITION":   6,
-	"GO":         7,
-	"STOP":       8,
-	"PONDERHIT":  9,
-	"QUIT":       10,
+	"UCI":          0,
+	"DEBUG":        1,
+	"ISREADY":      2,
+	"SETOPTION":    3,
+	"REGISTER":     4,
+	"UCINEWGAME":   5,
+	"POSITION":     6,
+	"GO":           7,
+	"STOP":         8,
+	"PONDERHIT":    9,
+	"QUIT":         10,
+	"VIOLATION":    11,
+	"GAME_CONTROL": 12,
+	"SESSION":      13,
 }
 
 func (x UciResponse_MessageType) String() string {
@@ -119,6 +136,102 @@ func (UciResponse_MessageType) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_cdc17040449aa6b8, []int{1, 0}
 }
 
+type UciResponse_Violation_Code int32
+
+const (
+	UciResponse_Violation_ILLEGAL_BESTMOVE       UciResponse_Violation_Code = 0
+	UciResponse_Violation_WRONG_NOTATION         UciResponse_Violation_Code = 1
+	UciResponse_Violation_BESTMOVE_WITHOUT_GO    UciResponse_Violation_Code = 2
+	UciResponse_Violation_NO_BESTMOVE_AFTER_STOP UciResponse_Violation_Code = 3
+	UciResponse_Violation_NO_READYOK             UciResponse_Violation_Code = 4
+	UciResponse_Violation_UNEXPECTED_MESSAGE     UciResponse_Violation_Code = 5
+)
+
+var UciResponse_Violation_Code_name = map[int32]string{
+	0: "ILLEGAL_BESTMOVE",
+	1: "WRONG_NOTATION",
+	2: "BESTMOVE_WITHOUT_GO",
+	3: "NO_BESTMOVE_AFTER_STOP",
+	4: "NO_READYOK",
+	5: "UNEXPECTED_MESSAGE",
+}
+
+var UciResponse_Violation_Code_value = map[string]int32{
+	"ILLEGAL_BESTMOVE":       0,
+	"WRONG_NOTATION":         1,
+	"BESTMOVE_WITHOUT_GO":    2,
+	"NO_BESTMOVE_AFTER_STOP": 3,
+	"NO_READYOK":             4,
+	"UNEXPECTED_MESSAGE":     5,
+}
+
+func (x UciResponse_Violation_Code) String() string {
+	return proto.EnumName(UciResponse_Violation_Code_name, int32(x))
+}
+
+func (UciResponse_Violation_Code) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{1, 3, 0}
+}
+
+type UciResponse_Violation_Action int32
+
+const (
+	UciResponse_Violation_FORFEIT UciResponse_Violation_Action = 0
+	UciResponse_Violation_WARN    UciResponse_Violation_Action = 1
+	UciResponse_Violation_RETRY   UciResponse_Violation_Action = 2
+)
+
+var UciResponse_Violation_Action_name = map[int32]string{
+	0: "FORFEIT",
+	1: "WARN",
+	2: "RETRY",
+}
+
+var UciResponse_Violation_Action_value = map[string]int32{
+	"FORFEIT": 0,
+	"WARN":    1,
+	"RETRY":   2,
+}
+
+func (x UciResponse_Violation_Action) String() string {
+	return proto.EnumName(UciResponse_Violation_Action_name, int32(x))
+}
+
+func (UciResponse_Violation_Action) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{1, 3, 1}
+}
+
+type SPRTUpdate_Result int32
+
+const (
+	SPRTUpdate_RUNNING      SPRTUpdate_Result = 0
+	SPRTUpdate_H0           SPRTUpdate_Result = 1
+	SPRTUpdate_H1           SPRTUpdate_Result = 2
+	SPRTUpdate_INCONCLUSIVE SPRTUpdate_Result = 3
+)
+
+var SPRTUpdate_Result_name = map[int32]string{
+	0: "RUNNING",
+	1: "H0",
+	2: "H1",
+	3: "INCONCLUSIVE",
+}
+
+var SPRTUpdate_Result_value = map[string]int32{
+	"RUNNING":      0,
+	"H0":           1,
+	"H1":           2,
+	"INCONCLUSIVE": 3,
+}
+
+func (x SPRTUpdate_Result) String() string {
+	return proto.EnumName(SPRTUpdate_Result_name, int32(x))
+}
+
+func (SPRTUpdate_Result) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{6, 0}
+}
+
 type GameMessageResponse_GameMessageResponseTypes int32
 
 const (
@@ -141,7 +254,7 @@ func (x GameMessageResponse_GameMessageResponseTypes) String() string {
 }
 
 func (GameMessageResponse_GameMessageResponseTypes) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{10, 0}
+	return fileDescriptor_cdc17040449aa6b8, []int{15, 0}
 }
 
 type ClientGameMessage_MessageType int32
@@ -149,16 +262,62 @@ type ClientGameMessage_MessageType int32
 const (
 	ClientGameMessage_UCI                ClientGameMessage_MessageType = 0
 	ClientGameMessage_GAME_STATE_REQUEST ClientGameMessage_MessageType = 1
+	ClientGameMessage_RESIGN             ClientGameMessage_MessageType = 2
+	// A draw offer stands until the opponent answers it or moves
+	ClientGameMessage_OFFER_DRAW   ClientGameMessage_MessageType = 3
+	ClientGameMessage_ACCEPT_DRAW  ClientGameMessage_MessageType = 4
+	ClientGameMessage_DECLINE_DRAW ClientGameMessage_MessageType = 5
+	// A takeback request takes back the sender's last move and any reply
+	// to it, it stands until the opponent answers it or either side moves
+	ClientGameMessage_REQUEST_TAKEBACK ClientGameMessage_MessageType = 6
+	ClientGameMessage_ACCEPT_TAKEBACK  ClientGameMessage_MessageType = 7
+	ClientGameMessage_DECLINE_TAKEBACK ClientGameMessage_MessageType = 8
+	// Ends the game without a result, only before each side has made its first move
+	ClientGameMessage_ABORT ClientGameMessage_MessageType = 9
+	// Stops the clocks and any search until either player resumes the game
+	ClientGameMessage_PAUSE  ClientGameMessage_MessageType = 10
+	ClientGameMessage_RESUME ClientGameMessage_MessageType = 11
+	// Ends the game without a result so it can be finished later from the game store
+	ClientGameMessage_ADJOURN ClientGameMessage_MessageType = 12
+	// Conditional premoves of a correspondence game, each line alternates
+	// between a move the opponent may play and the reply to it. Each
+	// message adds a line and one without moves clears them, lines lapse
+	// once the opponent plays something else.
+	ClientGameMessage_PREMOVE ClientGameMessage_MessageType = 13
 )
 
 var ClientGameMessage_MessageType_name = map[int32]string{
-	0: "UCI",
-	1: "GAME_STATE_REQUEST",
+	0:  "UCI",
+	1:  "GAME_STATE_REQUEST",
+	2:  "RESIGN",
+	3:  "OFFER_DRAW",
+	4:  "ACCEPT_DRAW",
+	5:  "DECLINE_DRAW",
+	6:  "REQUEST_TAKEBACK",
+	7:  "ACCEPT_TAKEBACK",
+	8:  "DECLINE_TAKEBACK",
+	9:  "ABORT",
+	10: "PAUSE",
+	11: "RESUME",
+	12: "ADJOURN",
+	13: "PREMOVE",
 }
 
 var ClientGameMessage_MessageType_value = map[string]int32{
 	"UCI":                0,
 	"GAME_STATE_REQUEST": 1,
+	"RESIGN":             2,
+	"OFFER_DRAW":         3,
+	"ACCEPT_DRAW":        4,
+	"DECLINE_DRAW":       5,
+	"REQUEST_TAKEBACK":   6,
+	"ACCEPT_TAKEBACK":    7,
+	"DECLINE_TAKEBACK":   8,
+	"ABORT":              9,
+	"PAUSE":              10,
+	"RESUME":             11,
+	"ADJOURN":            12,
+	"PREMOVE":            13,
 }
 
 func (x ClientGameMessage_MessageType) String() string {
@@ -166,7 +325,7 @@ func (x ClientGameMessage_MessageType) String() string {
 }
 
 func (ClientGameMessage_MessageType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{11, 0}
+	return fileDescriptor_cdc17040449aa6b8, []int{16, 0}
 }
 
 type ServerGameMessage_MessageType int32
@@ -174,16 +333,28 @@ type ServerGameMessage_MessageType int32
 const (
 	ServerGameMessage_UCI                 ServerGameMessage_MessageType = 0
 	ServerGameMessage_GAME_STATE_RESPONSE ServerGameMessage_MessageType = 1
+	// A control either player sent that the referee carried out
+	ServerGameMessage_CONTROL ServerGameMessage_MessageType = 2
+	// A control the sender can not use in the game's state
+	ServerGameMessage_REJECTED ServerGameMessage_MessageType = 3
+	// Tells the side to move of a correspondence game it is its move
+	ServerGameMessage_YOUR_MOVE ServerGameMessage_MessageType = 4
 )
 
 var ServerGameMessage_MessageType_name = map[int32]string{
 	0: "UCI",
 	1: "GAME_STATE_RESPONSE",
+	2: "CONTROL",
+	3: "REJECTED",
+	4: "YOUR_MOVE",
 }
 
 var ServerGameMessage_MessageType_value = map[string]int32{
 	"UCI":                 0,
 	"GAME_STATE_RESPONSE": 1,
+	"CONTROL":             2,
+	"REJECTED":            3,
+	"YOUR_MOVE":           4,
 }
 
 func (x ServerGameMessage_MessageType) String() string {
@@ -191,7 +362,7 @@ func (x ServerGameMessage_MessageType) String() string {
 }
 
 func (ServerGameMessage_MessageType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{15, 0}
+	return fileDescriptor_cdc17040449aa6b8, []int{21, 0}
 }
 
 type UciRequest struct {
@@ -200,6 +371,9 @@ type UciRequest struct {
 	BestMove             *UciRequest_BestMove   `protobuf:"bytes,3,opt,name=bestMove,proto3" json:"bestMove,omitempty"`
 	Info                 *UciRequest_Info       `protobuf:"bytes,4,opt,name=info,proto3" json:"info,omitempty"`
 	Option               *UciRequest_Option     `protobuf:"bytes,5,opt,name=option,proto3" json:"option,omitempty"`
+	Copyprotection       string                 `protobuf:"bytes,6,opt,name=copyprotection,proto3" json:"copyprotection,omitempty"`
+	Registration         string                 `protobuf:"bytes,7,opt,name=registration,proto3" json:"registration,omitempty"`
+	GameControl          *ClientGameMessage     `protobuf:"bytes,8,opt,name=gameControl,proto3" json:"gameControl,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
 	XXX_unrecognized     []byte                 `json:"-"`
 	XXX_sizecache        int32                  `json:"-"`
@@ -265,6 +439,27 @@ func (m *UciRequest) GetOption() *UciRequest_Option {
 	return nil
 }
 
+func (m *UciRequest) GetCopyprotection() string {
+	if m != nil {
+		return m.Copyprotection
+	}
+	return ""
+}
+
+func (m *UciRequest) GetRegistration() string {
+	if m != nil {
+		return m.Registration
+	}
+	return ""
+}
+
+func (m *UciRequest) GetGameControl() *ClientGameMessage {
+	if m != nil {
+		return m.GameControl
+	}
+	return nil
+}
+
 type UciRequest_Option struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
@@ -393,6 +588,7 @@ func (m *UciRequest_Id) GetAuthor() string {
 
 type UciRequest_BestMove struct {
 	Ponder               []string `protobuf:"bytes,1,rep,name=ponder,proto3" json:"ponder,omitempty"`
+	Move                 string   `protobuf:"bytes,2,opt,name=move,proto3" json:"move,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -430,6 +626,13 @@ func (m *UciRequest_BestMove) GetPonder() []string {
 	return nil
 }
 
+func (m *UciRequest_BestMove) GetMove() string {
+	if m != nil {
+		return m.Move
+	}
+	return ""
+}
+
 type UciRequest_Score struct {
 	Cp                   int32    `protobuf:"varint,1,opt,name=cp,proto3" json:"cp,omitempty"`
 	Mate                 int32    `protobuf:"varint,2,opt,name=mate,proto3" json:"mate,omitempty"`
@@ -496,20 +699,21 @@ func (m *UciRequest_Score) GetUpper() int32 {
 type UciRequest_Info struct {
 	Depth                uint32            `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`
 	Seldepth             uint32            `protobuf:"varint,2,opt,name=seldepth,proto3" json:"seldepth,omitempty"`
-	Time                 uint32            `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
-	Nodes                uint32            `protobuf:"varint,4,opt,name=nodes,proto3" json:"nodes,omitempty"`
-	Pv                   int32             `protobuf:"varint,5,opt,name=pv,proto3" json:"pv,omitempty"`
+	Time                 uint64            `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	Nodes                uint64            `protobuf:"varint,4,opt,name=nodes,proto3" json:"nodes,omitempty"`
 	Multipv              int32             `protobuf:"varint,6,opt,name=multipv,proto3" json:"multipv,omitempty"`
 	Score                *UciRequest_Score `protobuf:"bytes,7,opt,name=score,proto3" json:"score,omitempty"`
 	Currmove             string            `protobuf:"bytes,8,opt,name=currmove,proto3" json:"currmove,omitempty"`
 	Currmovenumber       uint32            `protobuf:"varint,9,opt,name=currmovenumber,proto3" json:"currmovenumber,omitempty"`
 	Hashfull             uint32            `protobuf:"varint,10,opt,name=hashfull,proto3" json:"hashfull,omitempty"`
-	Nps                  uint32            `protobuf:"varint,11,opt,name=nps,proto3" json:"nps,omitempty"`
-	Tbhits               uint32            `protobuf:"varint,12,opt,name=tbhits,proto3" json:"tbhits,omitempty"`
+	Nps                  uint64            `protobuf:"varint,11,opt,name=nps,proto3" json:"nps,omitempty"`
+	Tbhits               uint64            `protobuf:"varint,12,opt,name=tbhits,proto3" json:"tbhits,omitempty"`
 	Cpuload              uint32            `protobuf:"varint,13,opt,name=cpuload,proto3" json:"cpuload,omitempty"`
 	String_              string            `protobuf:"bytes,14,opt,name=string,proto3" json:"string,omitempty"`
 	Refutation           []string          `protobuf:"bytes,15,rep,name=refutation,proto3" json:"refutation,omitempty"`
 	Currline             string            `protobuf:"bytes,16,opt,name=currline,proto3" json:"currline,omitempty"`
+	Sbhits               uint64            `protobuf:"varint,17,opt,name=sbhits,proto3" json:"sbhits,omitempty"`
+	Pv                   []string          `protobuf:"bytes,18,rep,name=pv,proto3" json:"pv,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -554,27 +758,20 @@ func (m *UciRequest_Info) GetSeldepth() uint32 {
 	return 0
 }
 
-func (m *UciRequest_Info) GetTime() uint32 {
+func (m *UciRequest_Info) GetTime() uint64 {
 	if m != nil {
 		return m.Time
 	}
 	return 0
 }
 
-func (m *UciRequest_Info) GetNodes() uint32 {
+func (m *UciRequest_Info) GetNodes() uint64 {
 	if m != nil {
 		return m.Nodes
 	}
 	return 0
 }
 
-func (m *UciRequest_Info) GetPv() int32 {
-	if m != nil {
-		return m.Pv
-	}
-	return 0
-}
-
 func (m *UciRequest_Info) GetMultipv() int32 {
 	if m != nil {
 		return m.Multipv
@@ -610,14 +807,14 @@ func (m *UciRequest_Info) GetHashfull() uint32 {
 	return 0
 }
 
-func (m *UciRequest_Info) GetNps() uint32 {
+func (m *UciRequest_Info) GetNps() uint64 {
 	if m != nil {
 		return m.Nps
 	}
 	return 0
 }
 
-func (m *UciRequest_Info) GetTbhits() uint32 {
+func (m *UciRequest_Info) GetTbhits() uint64 {
 	if m != nil {
 		return m.Tbhits
 	}
@@ -652,11 +849,29 @@ func (m *UciRequest_Info) GetCurrline() string {
 	return ""
 }
 
+func (m *UciRequest_Info) GetSbhits() uint64 {
+	if m != nil {
+		return m.Sbhits
+	}
+	return 0
+}
+
+func (m *UciRequest_Info) GetPv() []string {
+	if m != nil {
+		return m.Pv
+	}
+	return nil
+}
+
 type UciResponse struct {
 	MessageType          UciResponse_MessageType `protobuf:"varint,1,opt,name=messageType,proto3,enum=UciResponse_MessageType" json:"messageType,omitempty"`
 	Debug                bool                    `protobuf:"varint,2,opt,name=debug,proto3" json:"debug,omitempty"`
 	SetOption            *UciResponse_SetOption  `protobuf:"bytes,3,opt,name=setOption,proto3" json:"setOption,omitempty"`
 	Position             *UciResponse_Position   `protobuf:"bytes,4,opt,name=position,proto3" json:"position,omitempty"`
+	Go                   *UciResponse_Go         `protobuf:"bytes,5,opt,name=go,proto3" json:"go,omitempty"`
+	Violation            *UciResponse_Violation  `protobuf:"bytes,6,opt,name=violation,proto3" json:"violation,omitempty"`
+	GameControl          *ServerGameMessage      `protobuf:"bytes,7,opt,name=gameControl,proto3" json:"gameControl,omitempty"`
+	Session              *UciResponse_Session    `protobuf:"bytes,8,opt,name=session,proto3" json:"session,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
 	XXX_unrecognized     []byte                  `json:"-"`
 	XXX_sizecache        int32                   `json:"-"`
@@ -715,6 +930,34 @@ func (m *UciResponse) GetPosition() *UciResponse_Position {
 	return nil
 }
 
+func (m *UciResponse) GetGo() *UciResponse_Go {
+	if m != nil {
+		return m.Go
+	}
+	return nil
+}
+
+func (m *UciResponse) GetViolation() *UciResponse_Violation {
+	if m != nil {
+		return m.Violation
+	}
+	return nil
+}
+
+func (m *UciResponse) GetGameControl() *ServerGameMessage {
+	if m != nil {
+		return m.GameControl
+	}
+	return nil
+}
+
+func (m *UciResponse) GetSession() *UciResponse_Session {
+	if m != nil {
+		return m.Session
+	}
+	return nil
+}
+
 type UciResponse_SetOption struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -765,6 +1008,7 @@ func (m *UciResponse_SetOption) GetValue() string {
 type UciResponse_Position struct {
 	IsFen                bool     `protobuf:"varint,1,opt,name=isFen,proto3" json:"isFen,omitempty"`
 	Moves                []string `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	Fen                  string   `protobuf:"bytes,3,opt,name=fen,proto3" json:"fen,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -809,6 +1053,13 @@ func (m *UciResponse_Position) GetMoves() []string {
 	return nil
 }
 
+func (m *UciResponse_Position) GetFen() string {
+	if m != nil {
+		return m.Fen
+	}
+	return ""
+}
+
 type UciResponse_Go struct {
 	Searchmoves          []string `protobuf:"bytes,1,rep,name=searchmoves,proto3" json:"searchmoves,omitempty"`
 	IsPonder             bool     `protobuf:"varint,2,opt,name=isPonder,proto3" json:"isPonder,omitempty"`
@@ -928,189 +1179,946 @@ func (m *UciResponse_Go) GetIsInfinite() bool {
 	return false
 }
 
-type Person struct {
-	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Rating               int32    `protobuf:"varint,3,opt,name=rating,proto3" json:"rating,omitempty"`
+type UciResponse_Violation struct {
+	Code UciResponse_Violation_Code `protobuf:"varint,1,opt,name=code,proto3,enum=UciResponse_Violation_Code" json:"code,omitempty"`
+	// What the server did about the violation
+	Action UciResponse_Violation_Action `protobuf:"varint,2,opt,name=action,proto3,enum=UciResponse_Violation_Action" json:"action,omitempty"`
+	// The engine that broke the protocol and its colour, white or black
+	Engine string `protobuf:"bytes,3,opt,name=engine,proto3" json:"engine,omitempty"`
+	Side   string `protobuf:"bytes,4,opt,name=side,proto3" json:"side,omitempty"`
+	// The number of moves played when it happened
+	Ply                  uint32   `protobuf:"varint,5,opt,name=ply,proto3" json:"ply,omitempty"`
+	Detail               string   `protobuf:"bytes,6,opt,name=detail,proto3" json:"detail,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Person) Reset()         { *m = Person{} }
-func (m *Person) String() string { return proto.CompactTextString(m) }
-func (*Person) ProtoMessage()    {}
-func (*Person) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{2}
+func (m *UciResponse_Violation) Reset()         { *m = UciResponse_Violation{} }
+func (m *UciResponse_Violation) String() string { return proto.CompactTextString(m) }
+func (*UciResponse_Violation) ProtoMessage()    {}
+func (*UciResponse_Violation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{1, 3}
 }
 
-func (m *Person) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Person.Unmarshal(m, b)
+func (m *UciResponse_Violation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UciResponse_Violation.Unmarshal(m, b)
 }
-func (m *Person) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Person.Marshal(b, m, deterministic)
+func (m *UciResponse_Violation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UciResponse_Violation.Marshal(b, m, deterministic)
 }
-func (m *Person) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Person.Merge(m, src)
+func (m *UciResponse_Violation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UciResponse_Violation.Merge(m, src)
 }
-func (m *Person) XXX_Size() int {
-	return xxx_messageInfo_Person.Size(m)
+func (m *UciResponse_Violation) XXX_Size() int {
+	return xxx_messageInfo_UciResponse_Violation.Size(m)
 }
-func (m *Person) XXX_DiscardUnknown() {
-	xxx_messageInfo_Person.DiscardUnknown(m)
+func (m *UciResponse_Violation) XXX_DiscardUnknown() {
+	xxx_messageInfo_UciResponse_Violation.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Person proto.InternalMessageInfo
+var xxx_messageInfo_UciResponse_Violation proto.InternalMessageInfo
 
-func (m *Person) GetId() string {
+func (m *UciResponse_Violation) GetCode() UciResponse_Violation_Code {
 	if m != nil {
-		return m.Id
+		return m.Code
+	}
+	return UciResponse_Violation_ILLEGAL_BESTMOVE
+}
+
+func (m *UciResponse_Violation) GetAction() UciResponse_Violation_Action {
+	if m != nil {
+		return m.Action
+	}
+	return UciResponse_Violation_FORFEIT
+}
+
+func (m *UciResponse_Violation) GetEngine() string {
+	if m != nil {
+		return m.Engine
 	}
 	return ""
 }
 
-func (m *Person) GetName() string {
+func (m *UciResponse_Violation) GetSide() string {
 	if m != nil {
-		return m.Name
+		return m.Side
 	}
 	return ""
 }
 
-func (m *Person) GetRating() int32 {
+func (m *UciResponse_Violation) GetPly() uint32 {
 	if m != nil {
-		return m.Rating
+		return m.Ply
 	}
 	return 0
 }
 
-type RatingFilter struct {
+func (m *UciResponse_Violation) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
+type UciResponse_Session struct {
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// How long in milliseconds the engine has to reconnect once its stream drops
+	Grace                uint32   `protobuf:"varint,2,opt,name=grace,proto3" json:"grace,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RatingFilter) Reset()         { *m = RatingFilter{} }
-func (m *RatingFilter) String() string { return proto.CompactTextString(m) }
-func (*RatingFilter) ProtoMessage()    {}
-func (*RatingFilter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{3}
+func (m *UciResponse_Session) Reset()         { *m = UciResponse_Session{} }
+func (m *UciResponse_Session) String() string { return proto.CompactTextString(m) }
+func (*UciResponse_Session) ProtoMessage()    {}
+func (*UciResponse_Session) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{1, 4}
 }
 
-func (m *RatingFilter) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RatingFilter.Unmarshal(m, b)
+func (m *UciResponse_Session) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UciResponse_Session.Unmarshal(m, b)
 }
-func (m *RatingFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RatingFilter.Marshal(b, m, deterministic)
+func (m *UciResponse_Session) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UciResponse_Session.Marshal(b, m, deterministic)
 }
-func (m *RatingFilter) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RatingFilter.Merge(m, src)
+func (m *UciResponse_Session) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UciResponse_Session.Merge(m, src)
 }
-func (m *RatingFilter) XXX_Size() int {
-	return xxx_messageInfo_RatingFilter.Size(m)
+func (m *UciResponse_Session) XXX_Size() int {
+	return xxx_messageInfo_UciResponse_Session.Size(m)
 }
-func (m *RatingFilter) XXX_DiscardUnknown() {
-	xxx_messageInfo_RatingFilter.DiscardUnknown(m)
+func (m *UciResponse_Session) XXX_DiscardUnknown() {
+	xxx_messageInfo_UciResponse_Session.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RatingFilter proto.InternalMessageInfo
+var xxx_messageInfo_UciResponse_Session proto.InternalMessageInfo
 
-type GameProposals struct {
-	TimeControl          *TimeControl `protobuf:"bytes,1,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
-	Opponent             *Person      `protobuf:"bytes,2,opt,name=opponent,proto3" json:"opponent,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+func (m *UciResponse_Session) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
 }
 
-func (m *GameProposals) Reset()         { *m = GameProposals{} }
-func (m *GameProposals) String() string { return proto.CompactTextString(m) }
-func (*GameProposals) ProtoMessage()    {}
-func (*GameProposals) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{4}
+func (m *UciResponse_Session) GetGrace() uint32 {
+	if m != nil {
+		return m.Grace
+	}
+	return 0
 }
 
-func (m *GameProposals) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GameProposals.Unmarshal(m, b)
+type AnalysisRequest struct {
+	// The position to analyse, the starting position when empty
+	Fen string `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
+	// Moves in UCI notation played from the position
+	Moves []string `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	// Limits of the search, the search runs until it is cancelled when none are set
+	Depth    uint32 `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	Nodes    uint32 `protobuf:"varint,4,opt,name=nodes,proto3" json:"nodes,omitempty"`
+	Movetime uint32 `protobuf:"varint,5,opt,name=movetime,proto3" json:"movetime,omitempty"`
+	// The number of lines to analyse, 1 when unset
+	Multipv uint32 `protobuf:"varint,6,opt,name=multipv,proto3" json:"multipv,omitempty"`
+	// Only search these moves in UCI notation
+	Searchmoves []string `protobuf:"bytes,7,rep,name=searchmoves,proto3" json:"searchmoves,omitempty"`
+	// The name of the engine to run the analysis on, any engine when empty
+	Engine string `protobuf:"bytes,8,opt,name=engine,proto3" json:"engine,omitempty"`
+	// Analyses with a higher priority start first when engines are busy
+	Priority int32 `protobuf:"varint,9,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Who asked for the analysis, jobs of different submitters with the same
+	// priority take turns when engines are busy
+	Submitter            string   `protobuf:"bytes,10,opt,name=submitter,proto3" json:"submitter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
-func (m *GameProposals) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GameProposals.Marshal(b, m, deterministic)
+
+func (m *AnalysisRequest) Reset()         { *m = AnalysisRequest{} }
+func (m *AnalysisRequest) String() string { return proto.CompactTextString(m) }
+func (*AnalysisRequest) ProtoMessage()    {}
+func (*AnalysisRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{2}
 }
-func (m *GameProposals) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GameProposals.Merge(m, src)
+
+func (m *AnalysisRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnalysisRequest.Unmarshal(m, b)
 }
-func (m *GameProposals) XXX_Size() int {
-	return xxx_messageInfo_GameProposals.Size(m)
+func (m *AnalysisRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnalysisRequest.Marshal(b, m, deterministic)
 }
-func (m *GameProposals) XXX_DiscardUnknown() {
-	xxx_messageInfo_GameProposals.DiscardUnknown(m)
+func (m *AnalysisRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnalysisRequest.Merge(m, src)
+}
+func (m *AnalysisRequest) XXX_Size() int {
+	return xxx_messageInfo_AnalysisRequest.Size(m)
+}
+func (m *AnalysisRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnalysisRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GameProposals proto.InternalMessageInfo
+var xxx_messageInfo_AnalysisRequest proto.InternalMessageInfo
 
-func (m *GameProposals) GetTimeControl() *TimeControl {
+func (m *AnalysisRequest) GetFen() string {
 	if m != nil {
-		return m.TimeControl
+		return m.Fen
 	}
-	return nil
+	return ""
 }
 
-func (m *GameProposals) GetOpponent() *Person {
+func (m *AnalysisRequest) GetMoves() []string {
 	if m != nil {
-		return m.Opponent
+		return m.Moves
 	}
 	return nil
 }
 
-type GameControls struct {
-	TimeControl          *TimeControl  `protobuf:"bytes,1,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
-	RatingFilter         *RatingFilter `protobuf:"bytes,2,opt,name=ratingFilter,proto3" json:"ratingFilter,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+func (m *AnalysisRequest) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
 }
 
-func (m *GameControls) Reset()         { *m = GameControls{} }
-func (m *GameControls) String() string { return proto.CompactTextString(m) }
-func (*GameControls) ProtoMessage()    {}
-func (*GameControls) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{5}
+func (m *AnalysisRequest) GetNodes() uint32 {
+	if m != nil {
+		return m.Nodes
+	}
+	return 0
 }
 
-func (m *GameControls) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GameControls.Unmarshal(m, b)
-}
-func (m *GameControls) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GameControls.Marshal(b, m, deterministic)
-}
-func (m *GameControls) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GameControls.Merge(m, src)
-}
-func (m *GameControls) XXX_Size() int {
-	return xxx_messageInfo_GameControls.Size(m)
-}
-func (m *GameControls) XXX_DiscardUnknown() {
-	xxx_messageInfo_GameControls.DiscardUnknown(m)
+func (m *AnalysisRequest) GetMovetime() uint32 {
+	if m != nil {
+		return m.Movetime
+	}
+	return 0
 }
 
-var xxx_messageInfo_GameControls proto.InternalMessageInfo
+func (m *AnalysisRequest) GetMultipv() uint32 {
+	if m != nil {
+		return m.Multipv
+	}
+	return 0
+}
 
-func (m *GameControls) GetTimeControl() *TimeControl {
+func (m *AnalysisRequest) GetSearchmoves() []string {
 	if m != nil {
-		return m.TimeControl
+		return m.Searchmoves
 	}
 	return nil
 }
 
-func (m *GameControls) GetRatingFilter() *RatingFilter {
+func (m *AnalysisRequest) GetEngine() string {
 	if m != nil {
-		return m.RatingFilter
+		return m.Engine
 	}
-	return nil
+	return ""
 }
 
-type Confimation struct {
-	ConfimationCode      string   `protobuf:"bytes,1,opt,name=confimationCode,proto3" json:"confimationCode,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+func (m *AnalysisRequest) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *AnalysisRequest) GetSubmitter() string {
+	if m != nil {
+		return m.Submitter
+	}
+	return ""
+}
+
+type AnalysisUpdate struct {
+	// The name of the engine running the analysis
+	Engine   string `protobuf:"bytes,1,opt,name=engine,proto3" json:"engine,omitempty"`
+	Depth    uint32 `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	Seldepth uint32 `protobuf:"varint,3,opt,name=seldepth,proto3" json:"seldepth,omitempty"`
+	Nodes    uint64 `protobuf:"varint,4,opt,name=nodes,proto3" json:"nodes,omitempty"`
+	Nps      uint64 `protobuf:"varint,5,opt,name=nps,proto3" json:"nps,omitempty"`
+	Time     uint64 `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`
+	// The latest line for each multipv index ordered by index
+	Lines []*AnalysisUpdate_Line `protobuf:"bytes,7,rep,name=lines,proto3" json:"lines,omitempty"`
+	// The move the engine settled on, set on the last update
+	Bestmove string `protobuf:"bytes,8,opt,name=bestmove,proto3" json:"bestmove,omitempty"`
+	// The best move in standard algebraic notation
+	BestmoveSan string `protobuf:"bytes,9,opt,name=bestmoveSan,proto3" json:"bestmoveSan,omitempty"`
+	// The move the engine is searching in UCI and standard algebraic notation
+	Currmove             string   `protobuf:"bytes,10,opt,name=currmove,proto3" json:"currmove,omitempty"`
+	CurrmoveSan          string   `protobuf:"bytes,11,opt,name=currmoveSan,proto3" json:"currmoveSan,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnalysisUpdate) Reset()         { *m = AnalysisUpdate{} }
+func (m *AnalysisUpdate) String() string { return proto.CompactTextString(m) }
+func (*AnalysisUpdate) ProtoMessage()    {}
+func (*AnalysisUpdate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{3}
+}
+
+func (m *AnalysisUpdate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnalysisUpdate.Unmarshal(m, b)
+}
+func (m *AnalysisUpdate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnalysisUpdate.Marshal(b, m, deterministic)
+}
+func (m *AnalysisUpdate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnalysisUpdate.Merge(m, src)
+}
+func (m *AnalysisUpdate) XXX_Size() int {
+	return xxx_messageInfo_AnalysisUpdate.Size(m)
+}
+func (m *AnalysisUpdate) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnalysisUpdate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AnalysisUpdate proto.InternalMessageInfo
+
+func (m *AnalysisUpdate) GetEngine() string {
+	if m != nil {
+		return m.Engine
+	}
+	return ""
+}
+
+func (m *AnalysisUpdate) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate) GetSeldepth() uint32 {
+	if m != nil {
+		return m.Seldepth
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate) GetNodes() uint64 {
+	if m != nil {
+		return m.Nodes
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate) GetNps() uint64 {
+	if m != nil {
+		return m.Nps
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate) GetTime() uint64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate) GetLines() []*AnalysisUpdate_Line {
+	if m != nil {
+		return m.Lines
+	}
+	return nil
+}
+
+func (m *AnalysisUpdate) GetBestmove() string {
+	if m != nil {
+		return m.Bestmove
+	}
+	return ""
+}
+
+func (m *AnalysisUpdate) GetBestmoveSan() string {
+	if m != nil {
+		return m.BestmoveSan
+	}
+	return ""
+}
+
+func (m *AnalysisUpdate) GetCurrmove() string {
+	if m != nil {
+		return m.Currmove
+	}
+	return ""
+}
+
+func (m *AnalysisUpdate) GetCurrmoveSan() string {
+	if m != nil {
+		return m.CurrmoveSan
+	}
+	return ""
+}
+
+type AnalysisUpdate_Line struct {
+	Multipv uint32 `protobuf:"varint,1,opt,name=multipv,proto3" json:"multipv,omitempty"`
+	// The score from white's point of view
+	Score *UciRequest_Score `protobuf:"bytes,2,opt,name=score,proto3" json:"score,omitempty"`
+	// The principal variation in UCI notation
+	Pv []string `protobuf:"bytes,3,rep,name=pv,proto3" json:"pv,omitempty"`
+	// The principal variation in standard algebraic notation
+	San                  []string `protobuf:"bytes,4,rep,name=san,proto3" json:"san,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AnalysisUpdate_Line) Reset()         { *m = AnalysisUpdate_Line{} }
+func (m *AnalysisUpdate_Line) String() string { return proto.CompactTextString(m) }
+func (*AnalysisUpdate_Line) ProtoMessage()    {}
+func (*AnalysisUpdate_Line) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{3, 0}
+}
+
+func (m *AnalysisUpdate_Line) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnalysisUpdate_Line.Unmarshal(m, b)
+}
+func (m *AnalysisUpdate_Line) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnalysisUpdate_Line.Marshal(b, m, deterministic)
+}
+func (m *AnalysisUpdate_Line) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnalysisUpdate_Line.Merge(m, src)
+}
+func (m *AnalysisUpdate_Line) XXX_Size() int {
+	return xxx_messageInfo_AnalysisUpdate_Line.Size(m)
+}
+func (m *AnalysisUpdate_Line) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnalysisUpdate_Line.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AnalysisUpdate_Line proto.InternalMessageInfo
+
+func (m *AnalysisUpdate_Line) GetMultipv() uint32 {
+	if m != nil {
+		return m.Multipv
+	}
+	return 0
+}
+
+func (m *AnalysisUpdate_Line) GetScore() *UciRequest_Score {
+	if m != nil {
+		return m.Score
+	}
+	return nil
+}
+
+func (m *AnalysisUpdate_Line) GetPv() []string {
+	if m != nil {
+		return m.Pv
+	}
+	return nil
+}
+
+func (m *AnalysisUpdate_Line) GetSan() []string {
+	if m != nil {
+		return m.San
+	}
+	return nil
+}
+
+type Opening struct {
+	// The starting position, the standard starting position when empty
+	Fen string `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
+	// Moves in UCI notation played before the engines take over
+	Moves                []string `protobuf:"bytes,2,rep,name=moves,proto3" json:"moves,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Opening) Reset()         { *m = Opening{} }
+func (m *Opening) String() string { return proto.CompactTextString(m) }
+func (*Opening) ProtoMessage()    {}
+func (*Opening) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{4}
+}
+
+func (m *Opening) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Opening.Unmarshal(m, b)
+}
+func (m *Opening) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Opening.Marshal(b, m, deterministic)
+}
+func (m *Opening) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Opening.Merge(m, src)
+}
+func (m *Opening) XXX_Size() int {
+	return xxx_messageInfo_Opening.Size(m)
+}
+func (m *Opening) XXX_DiscardUnknown() {
+	xxx_messageInfo_Opening.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Opening proto.InternalMessageInfo
+
+func (m *Opening) GetFen() string {
+	if m != nil {
+		return m.Fen
+	}
+	return ""
+}
+
+func (m *Opening) GetMoves() []string {
+	if m != nil {
+		return m.Moves
+	}
+	return nil
+}
+
+type SPRTRequest struct {
+	// The names of the engines under test
+	Candidate string `protobuf:"bytes,1,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	Baseline  string `protobuf:"bytes,2,opt,name=baseline,proto3" json:"baseline,omitempty"`
+	// The Elo differences of the hypotheses H0 and H1
+	Elo0 float64 `protobuf:"fixed64,3,opt,name=elo0,proto3" json:"elo0,omitempty"`
+	Elo1 float64 `protobuf:"fixed64,4,opt,name=elo1,proto3" json:"elo1,omitempty"`
+	// The false positive and false negative rates, 0.05 when unset
+	Alpha float64 `protobuf:"fixed64,5,opt,name=alpha,proto3" json:"alpha,omitempty"`
+	Beta  float64 `protobuf:"fixed64,6,opt,name=beta,proto3" json:"beta,omitempty"`
+	// Openings played in turn, each pair of games plays one with both colours
+	Openings []*Opening `protobuf:"bytes,7,rep,name=openings,proto3" json:"openings,omitempty"`
+	// The time control in milliseconds, the server's when unset
+	Time      uint32 `protobuf:"varint,8,opt,name=time,proto3" json:"time,omitempty"`
+	Increment uint32 `protobuf:"varint,9,opt,name=increment,proto3" json:"increment,omitempty"`
+	// How many pairs of games are played at once, 1 when unset
+	Concurrency uint32 `protobuf:"varint,10,opt,name=concurrency,proto3" json:"concurrency,omitempty"`
+	// The test stops without a result after this many pairs, never when unset
+	MaxPairs uint32 `protobuf:"varint,11,opt,name=maxPairs,proto3" json:"maxPairs,omitempty"`
+	// Games with a higher priority start first when engines are busy
+	Priority int32 `protobuf:"varint,12,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Who asked for the test, jobs of different submitters with the same
+	// priority take turns when engines are busy
+	Submitter            string   `protobuf:"bytes,13,opt,name=submitter,proto3" json:"submitter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SPRTRequest) Reset()         { *m = SPRTRequest{} }
+func (m *SPRTRequest) String() string { return proto.CompactTextString(m) }
+func (*SPRTRequest) ProtoMessage()    {}
+func (*SPRTRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{5}
+}
+
+func (m *SPRTRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SPRTRequest.Unmarshal(m, b)
+}
+func (m *SPRTRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SPRTRequest.Marshal(b, m, deterministic)
+}
+func (m *SPRTRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SPRTRequest.Merge(m, src)
+}
+func (m *SPRTRequest) XXX_Size() int {
+	return xxx_messageInfo_SPRTRequest.Size(m)
+}
+func (m *SPRTRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SPRTRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SPRTRequest proto.InternalMessageInfo
+
+func (m *SPRTRequest) GetCandidate() string {
+	if m != nil {
+		return m.Candidate
+	}
+	return ""
+}
+
+func (m *SPRTRequest) GetBaseline() string {
+	if m != nil {
+		return m.Baseline
+	}
+	return ""
+}
+
+func (m *SPRTRequest) GetElo0() float64 {
+	if m != nil {
+		return m.Elo0
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetElo1() float64 {
+	if m != nil {
+		return m.Elo1
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetAlpha() float64 {
+	if m != nil {
+		return m.Alpha
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetBeta() float64 {
+	if m != nil {
+		return m.Beta
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetOpenings() []*Opening {
+	if m != nil {
+		return m.Openings
+	}
+	return nil
+}
+
+func (m *SPRTRequest) GetTime() uint32 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetIncrement() uint32 {
+	if m != nil {
+		return m.Increment
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetConcurrency() uint32 {
+	if m != nil {
+		return m.Concurrency
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetMaxPairs() uint32 {
+	if m != nil {
+		return m.MaxPairs
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *SPRTRequest) GetSubmitter() string {
+	if m != nil {
+		return m.Submitter
+	}
+	return ""
+}
+
+type SPRTUpdate struct {
+	Result SPRTUpdate_Result `protobuf:"varint,1,opt,name=result,proto3,enum=SPRTUpdate_Result" json:"result,omitempty"`
+	// The log-likelihood ratio and the bounds at which H0 and H1 are accepted
+	Llr   float64 `protobuf:"fixed64,2,opt,name=llr,proto3" json:"llr,omitempty"`
+	Lower float64 `protobuf:"fixed64,3,opt,name=lower,proto3" json:"lower,omitempty"`
+	Upper float64 `protobuf:"fixed64,4,opt,name=upper,proto3" json:"upper,omitempty"`
+	// The candidate's Elo difference to the baseline and the margin of its 95% confidence interval
+	Elo       float64 `protobuf:"fixed64,5,opt,name=elo,proto3" json:"elo,omitempty"`
+	EloMargin float64 `protobuf:"fixed64,6,opt,name=eloMargin,proto3" json:"eloMargin,omitempty"`
+	// The candidate's results
+	Games  uint32 `protobuf:"varint,7,opt,name=games,proto3" json:"games,omitempty"`
+	Wins   uint32 `protobuf:"varint,8,opt,name=wins,proto3" json:"wins,omitempty"`
+	Draws  uint32 `protobuf:"varint,9,opt,name=draws,proto3" json:"draws,omitempty"`
+	Losses uint32 `protobuf:"varint,10,opt,name=losses,proto3" json:"losses,omitempty"`
+	// Pairs of games by the candidate's points in the pair from 0 to 2 in halves
+	Pentanomial          []uint32 `protobuf:"varint,11,rep,packed,name=pentanomial,proto3" json:"pentanomial,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SPRTUpdate) Reset()         { *m = SPRTUpdate{} }
+func (m *SPRTUpdate) String() string { return proto.CompactTextString(m) }
+func (*SPRTUpdate) ProtoMessage()    {}
+func (*SPRTUpdate) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{6}
+}
+
+func (m *SPRTUpdate) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SPRTUpdate.Unmarshal(m, b)
+}
+func (m *SPRTUpdate) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SPRTUpdate.Marshal(b, m, deterministic)
+}
+func (m *SPRTUpdate) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SPRTUpdate.Merge(m, src)
+}
+func (m *SPRTUpdate) XXX_Size() int {
+	return xxx_messageInfo_SPRTUpdate.Size(m)
+}
+func (m *SPRTUpdate) XXX_DiscardUnknown() {
+	xxx_messageInfo_SPRTUpdate.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SPRTUpdate proto.InternalMessageInfo
+
+func (m *SPRTUpdate) GetResult() SPRTUpdate_Result {
+	if m != nil {
+		return m.Result
+	}
+	return SPRTUpdate_RUNNING
+}
+
+func (m *SPRTUpdate) GetLlr() float64 {
+	if m != nil {
+		return m.Llr
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetLower() float64 {
+	if m != nil {
+		return m.Lower
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetUpper() float64 {
+	if m != nil {
+		return m.Upper
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetElo() float64 {
+	if m != nil {
+		return m.Elo
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetEloMargin() float64 {
+	if m != nil {
+		return m.EloMargin
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetGames() uint32 {
+	if m != nil {
+		return m.Games
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetWins() uint32 {
+	if m != nil {
+		return m.Wins
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetDraws() uint32 {
+	if m != nil {
+		return m.Draws
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetLosses() uint32 {
+	if m != nil {
+		return m.Losses
+	}
+	return 0
+}
+
+func (m *SPRTUpdate) GetPentanomial() []uint32 {
+	if m != nil {
+		return m.Pentanomial
+	}
+	return nil
+}
+
+type Person struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Rating               int32    `protobuf:"varint,3,opt,name=rating,proto3" json:"rating,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Person) Reset()         { *m = Person{} }
+func (m *Person) String() string { return proto.CompactTextString(m) }
+func (*Person) ProtoMessage()    {}
+func (*Person) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{7}
+}
+
+func (m *Person) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Person.Unmarshal(m, b)
+}
+func (m *Person) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Person.Marshal(b, m, deterministic)
+}
+func (m *Person) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Person.Merge(m, src)
+}
+func (m *Person) XXX_Size() int {
+	return xxx_messageInfo_Person.Size(m)
+}
+func (m *Person) XXX_DiscardUnknown() {
+	xxx_messageInfo_Person.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Person proto.InternalMessageInfo
+
+func (m *Person) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Person) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Person) GetRating() int32 {
+	if m != nil {
+		return m.Rating
+	}
+	return 0
+}
+
+type RatingFilter struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RatingFilter) Reset()         { *m = RatingFilter{} }
+func (m *RatingFilter) String() string { return proto.CompactTextString(m) }
+func (*RatingFilter) ProtoMessage()    {}
+func (*RatingFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{8}
+}
+
+func (m *RatingFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RatingFilter.Unmarshal(m, b)
+}
+func (m *RatingFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RatingFilter.Marshal(b, m, deterministic)
+}
+func (m *RatingFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RatingFilter.Merge(m, src)
+}
+func (m *RatingFilter) XXX_Size() int {
+	return xxx_messageInfo_RatingFilter.Size(m)
+}
+func (m *RatingFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_RatingFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RatingFilter proto.InternalMessageInfo
+
+type GameProposals struct {
+	TimeControl          *TimeControl `protobuf:"bytes,1,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
+	Opponent             *Person      `protobuf:"bytes,2,opt,name=opponent,proto3" json:"opponent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *GameProposals) Reset()         { *m = GameProposals{} }
+func (m *GameProposals) String() string { return proto.CompactTextString(m) }
+func (*GameProposals) ProtoMessage()    {}
+func (*GameProposals) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{9}
+}
+
+func (m *GameProposals) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameProposals.Unmarshal(m, b)
+}
+func (m *GameProposals) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameProposals.Marshal(b, m, deterministic)
+}
+func (m *GameProposals) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameProposals.Merge(m, src)
+}
+func (m *GameProposals) XXX_Size() int {
+	return xxx_messageInfo_GameProposals.Size(m)
+}
+func (m *GameProposals) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameProposals.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameProposals proto.InternalMessageInfo
+
+func (m *GameProposals) GetTimeControl() *TimeControl {
+	if m != nil {
+		return m.TimeControl
+	}
+	return nil
+}
+
+func (m *GameProposals) GetOpponent() *Person {
+	if m != nil {
+		return m.Opponent
+	}
+	return nil
+}
+
+type GameControls struct {
+	TimeControl          *TimeControl  `protobuf:"bytes,1,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
+	RatingFilter         *RatingFilter `protobuf:"bytes,2,opt,name=ratingFilter,proto3" json:"ratingFilter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *GameControls) Reset()         { *m = GameControls{} }
+func (m *GameControls) String() string { return proto.CompactTextString(m) }
+func (*GameControls) ProtoMessage()    {}
+func (*GameControls) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{10}
+}
+
+func (m *GameControls) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameControls.Unmarshal(m, b)
+}
+func (m *GameControls) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameControls.Marshal(b, m, deterministic)
+}
+func (m *GameControls) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameControls.Merge(m, src)
+}
+func (m *GameControls) XXX_Size() int {
+	return xxx_messageInfo_GameControls.Size(m)
+}
+func (m *GameControls) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameControls.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameControls proto.InternalMessageInfo
+
+func (m *GameControls) GetTimeControl() *TimeControl {
+	if m != nil {
+		return m.TimeControl
+	}
+	return nil
+}
+
+func (m *GameControls) GetRatingFilter() *RatingFilter {
+	if m != nil {
+		return m.RatingFilter
+	}
+	return nil
+}
+
+type Confimation struct {
+	ConfimationCode      string   `protobuf:"bytes,1,opt,name=confimationCode,proto3" json:"confimationCode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
@@ -1119,7 +2127,7 @@ func (m *Confimation) Reset()         { *m = Confimation{} }
 func (m *Confimation) String() string { return proto.CompactTextString(m) }
 func (*Confimation) ProtoMessage()    {}
 func (*Confimation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{6}
+	return fileDescriptor_cdc17040449aa6b8, []int{11}
 }
 
 func (m *Confimation) XXX_Unmarshal(b []byte) error {
@@ -1157,7 +2165,7 @@ func (m *RoomRequest) Reset()         { *m = RoomRequest{} }
 func (m *RoomRequest) String() string { return proto.CompactTextString(m) }
 func (*RoomRequest) ProtoMessage()    {}
 func (*RoomRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{7}
+	return fileDescriptor_cdc17040449aa6b8, []int{12}
 }
 
 func (m *RoomRequest) XXX_Unmarshal(b []byte) error {
@@ -1189,7 +2197,7 @@ func (m *RoomMessage) Reset()         { *m = RoomMessage{} }
 func (m *RoomMessage) String() string { return proto.CompactTextString(m) }
 func (*RoomMessage) ProtoMessage()    {}
 func (*RoomMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{8}
+	return fileDescriptor_cdc17040449aa6b8, []int{13}
 }
 
 func (m *RoomMessage) XXX_Unmarshal(b []byte) error {
@@ -1228,7 +2236,7 @@ func (m *GameRequestMessage) Reset()         { *m = GameRequestMessage{} }
 func (m *GameRequestMessage) String() string { return proto.CompactTextString(m) }
 func (*GameRequestMessage) ProtoMessage()    {}
 func (*GameRequestMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{9}
+	return fileDescriptor_cdc17040449aa6b8, []int{14}
 }
 
 func (m *GameRequestMessage) XXX_Unmarshal(b []byte) error {
@@ -1267,7 +2275,7 @@ func (m *GameMessageResponse) Reset()         { *m = GameMessageResponse{} }
 func (m *GameMessageResponse) String() string { return proto.CompactTextString(m) }
 func (*GameMessageResponse) ProtoMessage()    {}
 func (*GameMessageResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{10}
+	return fileDescriptor_cdc17040449aa6b8, []int{15}
 }
 
 func (m *GameMessageResponse) XXX_Unmarshal(b []byte) error {
@@ -1296,18 +2304,23 @@ func (m *GameMessageResponse) GetType() GameMessageResponse_GameMessageResponseT
 }
 
 type ClientGameMessage struct {
-	MessageType          ClientGameMessage_MessageType `protobuf:"varint,1,opt,name=messageType,proto3,enum=ClientGameMessage_MessageType" json:"messageType,omitempty"`
-	UciMessage           string                        `protobuf:"bytes,2,opt,name=uciMessage,proto3" json:"uciMessage,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
-	XXX_unrecognized     []byte                        `json:"-"`
-	XXX_sizecache        int32                         `json:"-"`
+	MessageType ClientGameMessage_MessageType `protobuf:"varint,1,opt,name=messageType,proto3,enum=ClientGameMessage_MessageType" json:"messageType,omitempty"`
+	UciMessage  string                        `protobuf:"bytes,2,opt,name=uciMessage,proto3" json:"uciMessage,omitempty"`
+	// The correspondence game a state request or premove is for, a state
+	// request without one asks for every game of the engine
+	Game string `protobuf:"bytes,3,opt,name=game,proto3" json:"game,omitempty"`
+	// The premove line in UCI notation
+	Moves                []string `protobuf:"bytes,4,rep,name=moves,proto3" json:"moves,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ClientGameMessage) Reset()         { *m = ClientGameMessage{} }
 func (m *ClientGameMessage) String() string { return proto.CompactTextString(m) }
 func (*ClientGameMessage) ProtoMessage()    {}
 func (*ClientGameMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{11}
+	return fileDescriptor_cdc17040449aa6b8, []int{16}
 }
 
 func (m *ClientGameMessage) XXX_Unmarshal(b []byte) error {
@@ -1342,20 +2355,49 @@ func (m *ClientGameMessage) GetUciMessage() string {
 	return ""
 }
 
+func (m *ClientGameMessage) GetGame() string {
+	if m != nil {
+		return m.Game
+	}
+	return ""
+}
+
+func (m *ClientGameMessage) GetMoves() []string {
+	if m != nil {
+		return m.Moves
+	}
+	return nil
+}
+
 type GameState struct {
-	Fen                  string       `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
-	TimeControl          *TimeControl `protobuf:"bytes,2,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
-	TimeState            *TimeState   `protobuf:"bytes,3,opt,name=timeState,proto3" json:"timeState,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+	// The current position
+	Fen         string       `protobuf:"bytes,1,opt,name=fen,proto3" json:"fen,omitempty"`
+	TimeControl *TimeControl `protobuf:"bytes,2,opt,name=timeControl,proto3" json:"timeControl,omitempty"`
+	TimeState   *TimeState   `protobuf:"bytes,3,opt,name=timeState,proto3" json:"timeState,omitempty"`
+	// The correspondence game and its engines
+	Id    string `protobuf:"bytes,4,opt,name=id,proto3" json:"id,omitempty"`
+	White string `protobuf:"bytes,5,opt,name=white,proto3" json:"white,omitempty"`
+	Black string `protobuf:"bytes,6,opt,name=black,proto3" json:"black,omitempty"`
+	// The moves played in UCI notation from startFen, the standard starting position when empty
+	Moves    []string `protobuf:"bytes,7,rep,name=moves,proto3" json:"moves,omitempty"`
+	StartFen string   `protobuf:"bytes,8,opt,name=startFen,proto3" json:"startFen,omitempty"`
+	// The side to move, white or black
+	Turn string `protobuf:"bytes,9,opt,name=turn,proto3" json:"turn,omitempty"`
+	// When the side to move loses on time in seconds since the Unix epoch
+	Deadline int64 `protobuf:"varint,10,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	// The result in PGN notation eg. 1-0 and why the game ended, * while it is played
+	Result               string   `protobuf:"bytes,11,opt,name=result,proto3" json:"result,omitempty"`
+	Termination          string   `protobuf:"bytes,12,opt,name=termination,proto3" json:"termination,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *GameState) Reset()         { *m = GameState{} }
 func (m *GameState) String() string { return proto.CompactTextString(m) }
 func (*GameState) ProtoMessage()    {}
 func (*GameState) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{12}
+	return fileDescriptor_cdc17040449aa6b8, []int{17}
 }
 
 func (m *GameState) XXX_Unmarshal(b []byte) error {
@@ -1397,6 +2439,126 @@ func (m *GameState) GetTimeState() *TimeState {
 	return nil
 }
 
+func (m *GameState) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *GameState) GetWhite() string {
+	if m != nil {
+		return m.White
+	}
+	return ""
+}
+
+func (m *GameState) GetBlack() string {
+	if m != nil {
+		return m.Black
+	}
+	return ""
+}
+
+func (m *GameState) GetMoves() []string {
+	if m != nil {
+		return m.Moves
+	}
+	return nil
+}
+
+func (m *GameState) GetStartFen() string {
+	if m != nil {
+		return m.StartFen
+	}
+	return ""
+}
+
+func (m *GameState) GetTurn() string {
+	if m != nil {
+		return m.Turn
+	}
+	return ""
+}
+
+func (m *GameState) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
+}
+
+func (m *GameState) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+func (m *GameState) GetTermination() string {
+	if m != nil {
+		return m.Termination
+	}
+	return ""
+}
+
+type CorrespondenceRequest struct {
+	// The names of the engines
+	White string `protobuf:"bytes,1,opt,name=white,proto3" json:"white,omitempty"`
+	Black string `protobuf:"bytes,2,opt,name=black,proto3" json:"black,omitempty"`
+	// The position the game starts from
+	Opening              *Opening `protobuf:"bytes,3,opt,name=opening,proto3" json:"opening,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CorrespondenceRequest) Reset()         { *m = CorrespondenceRequest{} }
+func (m *CorrespondenceRequest) String() string { return proto.CompactTextString(m) }
+func (*CorrespondenceRequest) ProtoMessage()    {}
+func (*CorrespondenceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_cdc17040449aa6b8, []int{18}
+}
+
+func (m *CorrespondenceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CorrespondenceRequest.Unmarshal(m, b)
+}
+func (m *CorrespondenceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CorrespondenceRequest.Marshal(b, m, deterministic)
+}
+func (m *CorrespondenceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CorrespondenceRequest.Merge(m, src)
+}
+func (m *CorrespondenceRequest) XXX_Size() int {
+	return xxx_messageInfo_CorrespondenceRequest.Size(m)
+}
+func (m *CorrespondenceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CorrespondenceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CorrespondenceRequest proto.InternalMessageInfo
+
+func (m *CorrespondenceRequest) GetWhite() string {
+	if m != nil {
+		return m.White
+	}
+	return ""
+}
+
+func (m *CorrespondenceRequest) GetBlack() string {
+	if m != nil {
+		return m.Black
+	}
+	return ""
+}
+
+func (m *CorrespondenceRequest) GetOpening() *Opening {
+	if m != nil {
+		return m.Opening
+	}
+	return nil
+}
+
 type TimeControl struct {
 	Time                 int32    `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
 	Incremet             int32    `protobuf:"varint,2,opt,name=incremet,proto3" json:"incremet,omitempty"`
@@ -1409,7 +2571,7 @@ func (m *TimeControl) Reset()         { *m = TimeControl{} }
 func (m *TimeControl) String() string { return proto.CompactTextString(m) }
 func (*TimeControl) ProtoMessage()    {}
 func (*TimeControl) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{13}
+	return fileDescriptor_cdc17040449aa6b8, []int{19}
 }
 
 func (m *TimeControl) XXX_Unmarshal(b []byte) error {
@@ -1456,7 +2618,7 @@ func (m *TimeState) Reset()         { *m = TimeState{} }
 func (m *TimeState) String() string { return proto.CompactTextString(m) }
 func (*TimeState) ProtoMessage()    {}
 func (*TimeState) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{14}
+	return fileDescriptor_cdc17040449aa6b8, []int{20}
 }
 
 func (m *TimeState) XXX_Unmarshal(b []byte) error {
@@ -1492,18 +2654,28 @@ func (m *TimeState) GetBlackTimeRemaining() int32 {
 }
 
 type ServerGameMessage struct {
-	MessageType          ServerGameMessage_MessageType `protobuf:"varint,1,opt,name=messageType,proto3,enum=ServerGameMessage_MessageType" json:"messageType,omitempty"`
-	UciMessage           string                        `protobuf:"bytes,2,opt,name=uciMessage,proto3" json:"uciMessage,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
-	XXX_unrecognized     []byte                        `json:"-"`
-	XXX_sizecache        int32                         `json:"-"`
+	MessageType ServerGameMessage_MessageType `protobuf:"varint,1,opt,name=messageType,proto3,enum=ServerGameMessage_MessageType" json:"messageType,omitempty"`
+	UciMessage  string                        `protobuf:"bytes,2,opt,name=uciMessage,proto3" json:"uciMessage,omitempty"`
+	// The last move played in the game in UCI and standard algebraic
+	// notation, empty before the first move
+	Move string `protobuf:"bytes,3,opt,name=move,proto3" json:"move,omitempty"`
+	San  string `protobuf:"bytes,4,opt,name=san,proto3" json:"san,omitempty"`
+	// The control, the colour of the player that sent it and why it was rejected
+	Control *ClientGameMessage `protobuf:"bytes,5,opt,name=control,proto3" json:"control,omitempty"`
+	Side    string             `protobuf:"bytes,6,opt,name=side,proto3" json:"side,omitempty"`
+	Reason  string             `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	// The correspondence game a state response or move notification is about
+	GameState            *GameState `protobuf:"bytes,8,opt,name=gameState,proto3" json:"gameState,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *ServerGameMessage) Reset()         { *m = ServerGameMessage{} }
 func (m *ServerGameMessage) String() string { return proto.CompactTextString(m) }
 func (*ServerGameMessage) ProtoMessage()    {}
 func (*ServerGameMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_cdc17040449aa6b8, []int{15}
+	return fileDescriptor_cdc17040449aa6b8, []int{21}
 }
 
 func (m *ServerGameMessage) XXX_Unmarshal(b []byte) error {
@@ -1538,9 +2710,54 @@ func (m *ServerGameMessage) GetUciMessage() string {
 	return ""
 }
 
+func (m *ServerGameMessage) GetMove() string {
+	if m != nil {
+		return m.Move
+	}
+	return ""
+}
+
+func (m *ServerGameMessage) GetSan() string {
+	if m != nil {
+		return m.San
+	}
+	return ""
+}
+
+func (m *ServerGameMessage) GetControl() *ClientGameMessage {
+	if m != nil {
+		return m.Control
+	}
+	return nil
+}
+
+func (m *ServerGameMessage) GetSide() string {
+	if m != nil {
+		return m.Side
+	}
+	return ""
+}
+
+func (m *ServerGameMessage) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *ServerGameMessage) GetGameState() *GameState {
+	if m != nil {
+		return m.GameState
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("UciRequest_MessageType", UciRequest_MessageType_name, UciRequest_MessageType_value)
 	proto.RegisterEnum("UciResponse_MessageType", UciResponse_MessageType_name, UciResponse_MessageType_value)
+	proto.RegisterEnum("UciResponse_Violation_Code", UciResponse_Violation_Code_name, UciResponse_Violation_Code_value)
+	proto.RegisterEnum("UciResponse_Violation_Action", UciResponse_Violation_Action_name, UciResponse_Violation_Action_value)
+	proto.RegisterEnum("SPRTUpdate_Result", SPRTUpdate_Result_name, SPRTUpdate_Result_value)
 	proto.RegisterEnum("GameMessageResponse_GameMessageResponseTypes", GameMessageResponse_GameMessageResponseTypes_name, GameMessageResponse_GameMessageResponseTypes_value)
 	proto.RegisterEnum("ClientGameMessage_MessageType", ClientGameMessage_MessageType_name, ClientGameMessage_MessageType_value)
 	proto.RegisterEnum("ServerGameMessage_MessageType", ServerGameMessage_MessageType_name, ServerGameMessage_MessageType_value)
@@ -1554,6 +2771,14 @@ func init() {
 	proto.RegisterType((*UciResponse_SetOption)(nil), "UciResponse.SetOption")
 	proto.RegisterType((*UciResponse_Position)(nil), "UciResponse.Position")
 	proto.RegisterType((*UciResponse_Go)(nil), "UciResponse.Go")
+	proto.RegisterType((*UciResponse_Violation)(nil), "UciResponse.Violation")
+	proto.RegisterType((*UciResponse_Session)(nil), "UciResponse.Session")
+	proto.RegisterType((*AnalysisRequest)(nil), "AnalysisRequest")
+	proto.RegisterType((*AnalysisUpdate)(nil), "AnalysisUpdate")
+	proto.RegisterType((*AnalysisUpdate_Line)(nil), "AnalysisUpdate.Line")
+	proto.RegisterType((*Opening)(nil), "Opening")
+	proto.RegisterType((*SPRTRequest)(nil), "SPRTRequest")
+	proto.RegisterType((*SPRTUpdate)(nil), "SPRTUpdate")
 	proto.RegisterType((*Person)(nil), "Person")
 	proto.RegisterType((*RatingFilter)(nil), "RatingFilter")
 	proto.RegisterType((*GameProposals)(nil), "GameProposals")
@@ -1565,6 +2790,7 @@ func init() {
 	proto.RegisterType((*GameMessageResponse)(nil), "GameMessageResponse")
 	proto.RegisterType((*ClientGameMessage)(nil), "ClientGameMessage")
 	proto.RegisterType((*GameState)(nil), "GameState")
+	proto.RegisterType((*CorrespondenceRequest)(nil), "CorrespondenceRequest")
 	proto.RegisterType((*TimeControl)(nil), "TimeControl")
 	proto.RegisterType((*TimeState)(nil), "TimeState")
 	proto.RegisterType((*ServerGameMessage)(nil), "ServerGameMessage")
@@ -1573,95 +2799,174 @@ func init() {
 func init() { proto.RegisterFile("service/chess.proto", fileDescriptor_cdc17040449aa6b8) }
 
 var fileDescriptor_cdc17040449aa6b8 = []byte{
-	// 1396 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xcb, 0x6e, 0xdb, 0x46,
-	0x14, 0x35, 0x69, 0x4b, 0x26, 0xaf, 0x1e, 0x61, 0x26, 0x69, 0x42, 0x08, 0x45, 0x6a, 0xb0, 0x41,
-	0x6a, 0x14, 0xa8, 0x92, 0xb8, 0x69, 0x8b, 0x06, 0x28, 0x50, 0x47, 0x66, 0x5c, 0x22, 0xb1, 0xa9,
-	0x8c, 0xe4, 0x06, 0x59, 0x05, 0x14, 0x35, 0xb2, 0x88, 0x50, 0x1c, 0x86, 0xa4, 0xe4, 0x74, 0xd7,
-	0x2f, 0xe8, 0xaa, 0xcb, 0x6e, 0xdb, 0x5d, 0x7f, 0xa3, 0x7f, 0xd3, 0x7f, 0x28, 0xee, 0xcc, 0x50,
-	0xa2, 0x1c, 0xb9, 0xaf, 0xdd, 0x9c, 0x73, 0xcf, 0xdc, 0x79, 0xdc, 0x07, 0x87, 0x70, 0x23, 0x67,
-	0xd9, 0x22, 0x0a, 0xd9, 0xfd, 0x70, 0xca, 0xf2, 0xbc, 0x9b, 0x66, 0xbc, 0xe0, 0xce, 0x1f, 0x06,
-	0xc0, 0x59, 0x18, 0x51, 0xf6, 0x76, 0xce, 0xf2, 0x82, 0x7c, 0x0d, 0x8d, 0x19, 0xcb, 0xf3, 0xe0,
-	0x9c, 0x0d, 0x7f, 0x48, 0x99, 0xad, 0xed, 0x69, 0xfb, 0xed, 0x83, 0xdb, 0xdd, 0x95, 0xa2, 0x7b,
-	0xb2, 0x32, 0xd3, 0xaa, 0x96, 0xdc, 0x01, 0x3d, 0x1a, 0xdb, 0xfa, 0x9e, 0xb6, 0xdf, 0x38, 0x68,
-	0x57, 0x67, 0x78, 0x63, 0xaa, 0x47, 0x63, 0xf2, 0x00, 0x8c, 0x11, 0xcb, 0x8b, 0x13, 0xbe, 0x60,
-	0xf6, 0xb6, 0x50, 0xdd, 0xac, 0xaa, 0x9e, 0x28, 0x1b, 0x5d, 0xaa, 0xc8, 0x5d, 0xd8, 0x89, 0x92,
-	0x09, 0xb7, 0x77, 0x84, 0xda, 0x5a, 0xf3, 0x99, 0x4c, 0x38, 0x15, 0x56, 0xf2, 0x29, 0xd4, 0x79,
-	0x5a, 0x44, 0x3c, 0xb1, 0x6b, 0x42, 0x47, 0xaa, 0x3a, 0x5f, 0x58, 0xa8, 0x52, 0x74, 0x7e, 0xd4,
-	0xa0, 0x2e, 0x29, 0x42, 0x60, 0x27, 0x09, 0x66, 0xf2, 0x88, 0x26, 0x15, 0x63, 0xe4, 0x0a, 0x3c,
-	0xb6, 0x2e, 0x39, 0x1c, 0x13, 0x1b, 0x76, 0xc7, 0x6c, 0x12, 0xcc, 0xe3, 0x42, 0xec, 0xda, 0xa4,
-	0x25, 0x24, 0x16, 0x6c, 0xcf, 0xa2, 0x44, 0xec, 0xae, 0x46, 0x71, 0x28, 0x98, 0xe0, 0x9d, 0xd8,
-	0x07, 0x32, 0xc1, 0x3b, 0x64, 0x16, 0x41, 0x66, 0xd7, 0xf7, 0xb6, 0xf7, 0x4d, 0x8a, 0xc3, 0xce,
-	0x03, 0xd0, 0xbd, 0xf1, 0xc6, 0xd5, 0x6f, 0x41, 0x3d, 0x98, 0x17, 0x53, 0x9e, 0xa9, 0xf5, 0x15,
-	0xea, 0x38, 0x60, 0x94, 0x97, 0x83, 0x9a, 0x94, 0x27, 0x63, 0x96, 0xd9, 0x9a, 0x70, 0xa9, 0x50,
-	0xe7, 0x25, 0xd4, 0x06, 0x21, 0xcf, 0x18, 0x69, 0x83, 0x1e, 0xa6, 0xc2, 0x6d, 0x8d, 0xea, 0x61,
-	0x8a, 0x0b, 0xcd, 0x82, 0x42, 0x1e, 0xa9, 0x46, 0xc5, 0x98, 0xdc, 0x84, 0x5a, 0xcc, 0x2f, 0x58,
-	0x26, 0x0e, 0x54, 0xa3, 0x12, 0x20, 0x3b, 0x4f, 0x53, 0x96, 0xa9, 0x03, 0x49, 0xd0, 0xf9, 0x7d,
-	0x1b, 0x76, 0xf0, 0xb2, 0xd1, 0x3c, 0x66, 0x69, 0x31, 0x15, 0xbe, 0x5b, 0x54, 0x02, 0xd2, 0x01,
-	0x23, 0x67, 0xb1, 0x34, 0xe8, 0xc2, 0xb0, 0xc4, 0xe2, 0x36, 0xa3, 0x99, 0x0c, 0x76, 0x8b, 0x8a,
-	0x31, 0x7a, 0x49, 0xf8, 0x98, 0xe5, 0x62, 0x91, 0x16, 0x95, 0x00, 0x37, 0x9d, 0x2e, 0xd4, 0xb5,
-	0xe9, 0xe9, 0x02, 0xef, 0x7c, 0x36, 0x8f, 0x8b, 0x28, 0x5d, 0xd8, 0x75, 0x41, 0x96, 0x90, 0x7c,
-	0x02, 0xb5, 0x1c, 0xcf, 0x69, 0xef, 0x8a, 0x58, 0x5f, 0xaf, 0xc6, 0x5a, 0x5c, 0x00, 0x95, 0x76,
-	0xdc, 0x58, 0x38, 0xcf, 0xb2, 0x19, 0x66, 0x9b, 0x21, 0xae, 0x73, 0x89, 0xc9, 0x3d, 0x68, 0x97,
-	0xe3, 0x64, 0x3e, 0x1b, 0xb1, 0xcc, 0x36, 0xc5, 0x6e, 0x2e, 0xb1, 0xe8, 0x63, 0x1a, 0xe4, 0xd3,
-	0xc9, 0x3c, 0x8e, 0x6d, 0x90, 0x87, 0x2b, 0x31, 0x06, 0x36, 0x49, 0x73, 0xbb, 0x21, 0x68, 0x1c,
-	0x62, 0x68, 0x8a, 0xd1, 0x34, 0x2a, 0x72, 0xbb, 0x29, 0x48, 0x85, 0xf0, 0x30, 0x61, 0x3a, 0x8f,
-	0x79, 0x30, 0xb6, 0x5b, 0xc2, 0x50, 0x42, 0x9c, 0x91, 0x17, 0x59, 0x94, 0x9c, 0xdb, 0x6d, 0x19,
-	0x70, 0x89, 0xc8, 0x1d, 0x80, 0x8c, 0x4d, 0xe6, 0x45, 0x20, 0xb2, 0xfa, 0x9a, 0x08, 0x74, 0x85,
-	0x29, 0xcf, 0x16, 0x47, 0x09, 0xb3, 0xad, 0xd5, 0xd9, 0x10, 0x3b, 0x17, 0xd0, 0xa8, 0x54, 0x28,
-	0xa9, 0x83, 0xee, 0x1d, 0x59, 0x5b, 0x04, 0xa0, 0xee, 0xf7, 0x87, 0x9e, 0x7f, 0x6a, 0x69, 0xc4,
-	0x84, 0xda, 0x59, 0xcf, 0xf3, 0x9f, 0x59, 0x3a, 0x69, 0xc0, 0x2e, 0x75, 0x0f, 0x8f, 0x5e, 0xf9,
-	0xcf, 0xac, 0x6d, 0xd2, 0x04, 0xe3, 0x89, 0x3b, 0x18, 0x9e, 0xf8, 0xdf, 0xbb, 0xd6, 0x0e, 0x21,
-	0xd0, 0xee, 0xf9, 0xfd, 0x57, 0x7d, 0xea, 0x0f, 0xdd, 0x9e, 0x98, 0x59, 0x23, 0x16, 0x34, 0xa9,
-	0x7b, 0xec, 0x0d, 0x86, 0xf4, 0x50, 0x30, 0x75, 0x62, 0xc0, 0x8e, 0x77, 0xfa, 0xd4, 0xb7, 0x76,
-	0x9d, 0x3f, 0x6b, 0xd0, 0x10, 0xc1, 0xc8, 0x53, 0x9e, 0xe4, 0x8c, 0x3c, 0xde, 0xd4, 0x49, 0xec,
-	0x6e, 0x45, 0x72, 0x75, 0x2b, 0x11, 0xb9, 0x36, 0x9a, 0x9f, 0x8b, 0x94, 0x32, 0xa8, 0x04, 0xe4,
-	0x11, 0x98, 0x39, 0x2b, 0x64, 0xf9, 0xaa, 0x0e, 0x72, 0x6b, 0xcd, 0xdf, 0xa0, 0xb4, 0xd2, 0x95,
-	0x90, 0x3c, 0x04, 0x23, 0xe5, 0x79, 0x24, 0x26, 0xc9, 0x46, 0xf2, 0xc1, 0xda, 0xa4, 0xbe, 0x32,
-	0xd2, 0xa5, 0xac, 0xf3, 0x05, 0x98, 0x4b, 0x57, 0x1b, 0x2b, 0xf5, 0x26, 0xd4, 0x16, 0x41, 0x3c,
-	0x2f, 0x1b, 0x85, 0x04, 0x9d, 0x2f, 0xc1, 0x28, 0x9d, 0xa1, 0x22, 0xca, 0x9f, 0xb2, 0x44, 0x4c,
-	0x33, 0xa8, 0x04, 0xc8, 0x62, 0x7a, 0xe5, 0xb6, 0x2e, 0x62, 0x2a, 0x41, 0xe7, 0x17, 0x1d, 0xf4,
-	0x63, 0x4e, 0xf6, 0xa0, 0x91, 0xb3, 0x20, 0x0b, 0xa7, 0x52, 0x22, 0xeb, 0xbb, 0x4a, 0x61, 0xdc,
-	0xa3, 0xbc, 0x2f, 0xcb, 0x5f, 0xde, 0xcc, 0x12, 0xa3, 0xeb, 0x8b, 0x4a, 0xb5, 0x49, 0x80, 0xec,
-	0x48, 0xb0, 0xaa, 0xdc, 0x04, 0xc0, 0x23, 0x5d, 0x44, 0x49, 0x28, 0x0a, 0xae, 0x45, 0xc5, 0x18,
-	0xb9, 0x11, 0x72, 0x75, 0xc9, 0xe1, 0x98, 0x7c, 0x08, 0xa6, 0x58, 0xb8, 0xe0, 0xe7, 0x5c, 0x14,
-	0x5c, 0x8b, 0xae, 0x88, 0x55, 0x43, 0x30, 0xaa, 0x0d, 0x61, 0x59, 0xe0, 0x66, 0xb5, 0xc0, 0x3b,
-	0x60, 0xe0, 0x44, 0xb1, 0x15, 0x55, 0x49, 0x25, 0xc6, 0x6c, 0x8f, 0x72, 0x2f, 0x99, 0x44, 0x49,
-	0x54, 0x30, 0x51, 0x50, 0x06, 0xad, 0x30, 0xce, 0xcf, 0xda, 0x7a, 0x4a, 0xef, 0xc2, 0xf6, 0x59,
-	0xcf, 0xb3, 0xb6, 0x30, 0x8f, 0x8f, 0xdc, 0x27, 0x67, 0xc7, 0x96, 0x86, 0x79, 0xec, 0x0d, 0x44,
-	0x26, 0x5b, 0x3a, 0x69, 0x81, 0x39, 0x70, 0x87, 0x2a, 0xdd, 0x45, 0x5a, 0xcb, 0xa4, 0x75, 0xa9,
-	0xb5, 0x43, 0xda, 0x00, 0x67, 0x3d, 0xef, 0xd4, 0x7d, 0x79, 0x7c, 0x78, 0xe2, 0x5a, 0x35, 0xb4,
-	0xf6, 0xfd, 0x81, 0xa7, 0xd2, 0xb9, 0x0e, 0xfa, 0xb1, 0x6f, 0xed, 0x62, 0x5a, 0x0f, 0x86, 0x7e,
-	0xdf, 0x32, 0xd0, 0x59, 0xdf, 0x3f, 0x3d, 0x72, 0xe9, 0x77, 0xde, 0xd0, 0x32, 0xd1, 0xf0, 0xe2,
-	0xcc, 0x1b, 0x5a, 0xe0, 0x1c, 0x41, 0xbd, 0xcf, 0xb2, 0x9c, 0x27, 0xd8, 0xbd, 0xa2, 0xb1, 0xca,
-	0x0f, 0xfc, 0xd0, 0x95, 0x19, 0xa3, 0xaf, 0xf7, 0xf6, 0x2c, 0x28, 0xb0, 0xd4, 0x65, 0xcf, 0x55,
-	0xc8, 0x69, 0x43, 0x93, 0x8a, 0xd1, 0xd3, 0x28, 0x2e, 0x58, 0xe6, 0x8c, 0xa1, 0x75, 0x1c, 0xcc,
-	0x58, 0x3f, 0xe3, 0x29, 0xcf, 0x83, 0x38, 0x27, 0x5d, 0x68, 0xe0, 0x2d, 0xf5, 0x78, 0x52, 0x64,
-	0x3c, 0x16, 0xab, 0x34, 0x0e, 0x9a, 0xdd, 0xe1, 0x8a, 0xa3, 0x55, 0x01, 0xf9, 0x18, 0x0c, 0x9e,
-	0xa6, 0x3c, 0x61, 0x49, 0xa1, 0xbe, 0xc5, 0xbb, 0x5d, 0xb9, 0x4f, 0xba, 0x34, 0x38, 0x6f, 0xa1,
-	0x89, 0xab, 0xa8, 0x39, 0xff, 0x7d, 0x91, 0x87, 0xd0, 0xcc, 0x2a, 0xbb, 0x56, 0x0b, 0xb5, 0xba,
-	0xd5, 0xa3, 0xd0, 0x35, 0x89, 0xf3, 0x15, 0x34, 0x7a, 0x3c, 0x99, 0x44, 0x33, 0xd9, 0xc2, 0xf6,
-	0xe1, 0x5a, 0xb8, 0x82, 0x3d, 0x3e, 0x2e, 0x0b, 0xec, 0x32, 0xed, 0xb4, 0xa0, 0x41, 0x39, 0x9f,
-	0xa9, 0x26, 0xef, 0x7c, 0x24, 0xa1, 0x4a, 0x08, 0xf1, 0xc5, 0xcd, 0xcf, 0xd5, 0x5c, 0x1c, 0x3a,
-	0x77, 0x81, 0xe0, 0xd9, 0x94, 0xbe, 0xd4, 0x5d, 0x8a, 0x91, 0xf3, 0x93, 0x06, 0x37, 0x50, 0xa6,
-	0xec, 0xcb, 0xae, 0x75, 0xa8, 0x5e, 0x00, 0xb2, 0x5d, 0x7d, 0xd6, 0xdd, 0xa0, 0xd9, 0xc4, 0x61,
-	0x62, 0xe6, 0xf2, 0xc1, 0xe0, 0x3c, 0x02, 0xfb, 0x2a, 0x05, 0xe6, 0x97, 0xff, 0xcc, 0xda, 0xc2,
-	0x46, 0xea, 0x3d, 0x7f, 0xee, 0x1e, 0x1f, 0x3e, 0x7f, 0x2d, 0xda, 0xad, 0xe6, 0xfc, 0xaa, 0xc1,
-	0xf5, 0x5e, 0x1c, 0xb1, 0xa4, 0xa8, 0x4c, 0x26, 0xdf, 0x6e, 0x6a, 0xa2, 0x77, 0xba, 0xef, 0x09,
-	0xff, 0xee, 0x55, 0x06, 0xf3, 0x30, 0x52, 0x66, 0x95, 0x92, 0x15, 0xc6, 0xe9, 0x5e, 0x51, 0x5c,
-	0xb7, 0x80, 0x60, 0x85, 0xbc, 0x1e, 0x0c, 0x0f, 0x87, 0xee, 0x6b, 0xea, 0xbe, 0x38, 0x73, 0x07,
-	0x43, 0x4b, 0x73, 0x2e, 0xc0, 0xc4, 0x75, 0x07, 0x05, 0x3e, 0x24, 0x2c, 0xd8, 0x9e, 0xa8, 0x1e,
-	0x67, 0x52, 0x1c, 0x5e, 0xce, 0x24, 0xfd, 0x9f, 0x32, 0x69, 0x1f, 0x4c, 0x84, 0xc2, 0x9d, 0xea,
-	0xe9, 0x20, 0xd4, 0x82, 0xa1, 0x2b, 0xa3, 0xf3, 0x0d, 0x34, 0x2a, 0x5e, 0x96, 0x8f, 0x0b, 0xf9,
-	0xd2, 0x91, 0x8f, 0x0b, 0xec, 0x8f, 0x49, 0x98, 0xb1, 0x19, 0x2b, 0xd4, 0x7b, 0x67, 0x89, 0x9d,
-	0x37, 0x60, 0x2e, 0xdd, 0x92, 0x2e, 0x90, 0x8b, 0x69, 0x54, 0x30, 0x64, 0x28, 0x9b, 0x05, 0x51,
-	0x82, 0x95, 0x29, 0x5d, 0x6d, 0xb0, 0xa0, 0x7e, 0x14, 0x07, 0xe1, 0x9b, 0x75, 0xbd, 0x5c, 0x62,
-	0x83, 0xc5, 0xf9, 0x4d, 0x83, 0xeb, 0x03, 0x96, 0x2d, 0x58, 0xf6, 0x2f, 0x82, 0xf9, 0x9e, 0xf0,
-	0xff, 0x07, 0xf3, 0xfe, 0x15, 0xc1, 0xbc, 0x0d, 0x37, 0xd6, 0x82, 0x39, 0xe8, 0xfb, 0xa7, 0x03,
-	0xd7, 0xd2, 0x0e, 0x1e, 0x83, 0xd5, 0xc3, 0x9f, 0x81, 0xc3, 0x34, 0x8d, 0xa3, 0x50, 0x96, 0xe6,
-	0x3d, 0x31, 0x8b, 0x34, 0x2a, 0x4f, 0xab, 0x4e, 0xb3, 0xfa, 0xc9, 0x74, 0xb6, 0xf6, 0xb5, 0x07,
-	0xda, 0xa8, 0x2e, 0x7e, 0x20, 0x3e, 0xff, 0x2b, 0x00, 0x00, 0xff, 0xff, 0xde, 0x74, 0xa7, 0xfc,
-	0x57, 0x0c, 0x00, 0x00,
+	// 2671 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x59, 0xcd, 0x92, 0xe3, 0x48,
+	0x11, 0x6e, 0xc9, 0xff, 0x69, 0xbb, 0x5b, 0x53, 0x33, 0x3b, 0xeb, 0x30, 0xcb, 0x6e, 0x87, 0x58,
+	0xa0, 0x63, 0x02, 0xbc, 0x3d, 0xc3, 0xfe, 0x04, 0x44, 0x10, 0x81, 0xc7, 0xad, 0xf6, 0x78, 0xa7,
+	0xdb, 0xf2, 0x96, 0xed, 0x19, 0xe6, 0xd4, 0xa1, 0xb6, 0xab, 0xdd, 0x8a, 0x91, 0x25, 0xad, 0x24,
+	0xf7, 0xec, 0x70, 0xe2, 0xc2, 0x81, 0x08, 0x16, 0x5e, 0x80, 0x17, 0x20, 0x78, 0x0b, 0xae, 0x7b,
+	0xe0, 0xcc, 0x23, 0xc0, 0x23, 0x70, 0x22, 0x32, 0xab, 0x24, 0xcb, 0x6e, 0xf7, 0x2e, 0x04, 0x27,
+	0x57, 0x66, 0x65, 0xa9, 0xaa, 0xb2, 0x32, 0xbf, 0xfa, 0xb2, 0x0c, 0xf7, 0x63, 0x11, 0xdd, 0xb8,
+	0x33, 0xf1, 0xd1, 0xec, 0x5a, 0xc4, 0x71, 0x27, 0x8c, 0x82, 0x24, 0x30, 0xbf, 0x06, 0x80, 0xe9,
+	0xcc, 0xe5, 0xe2, 0xcb, 0x95, 0x88, 0x13, 0xf6, 0x73, 0xa8, 0x2f, 0x45, 0x1c, 0x3b, 0x0b, 0x31,
+	0x79, 0x1b, 0x8a, 0x96, 0x76, 0xa8, 0x1d, 0xed, 0x3f, 0x79, 0xb7, 0xb3, 0xb6, 0xe8, 0x9c, 0xaf,
+	0xbb, 0x79, 0xde, 0x96, 0xbd, 0x0f, 0xba, 0x3b, 0x6f, 0xe9, 0x87, 0xda, 0x51, 0xfd, 0xc9, 0x7e,
+	0x7e, 0xc4, 0x60, 0xce, 0x75, 0x77, 0xce, 0x8e, 0xa1, 0x7a, 0x29, 0xe2, 0xe4, 0x3c, 0xb8, 0x11,
+	0xad, 0x02, 0x59, 0x3d, 0xc8, 0x5b, 0x3d, 0x55, 0x7d, 0x3c, 0xb3, 0x62, 0x1f, 0x42, 0xd1, 0xf5,
+	0xaf, 0x82, 0x56, 0x91, 0xac, 0x8d, 0x8d, 0x6f, 0xfa, 0x57, 0x01, 0xa7, 0x5e, 0xf6, 0x08, 0xca,
+	0x41, 0x98, 0xb8, 0x81, 0xdf, 0x2a, 0x91, 0x1d, 0xcb, 0xdb, 0xd9, 0xd4, 0xc3, 0x95, 0x05, 0xfb,
+	0x11, 0xec, 0xcf, 0x82, 0xf0, 0x2d, 0x6e, 0x5d, 0xcc, 0x68, 0x4c, 0xf9, 0x50, 0x3b, 0xaa, 0xf1,
+	0x2d, 0x2d, 0x33, 0xa1, 0x11, 0x89, 0x85, 0x1b, 0x27, 0x91, 0x43, 0x56, 0x15, 0xb2, 0xda, 0xd0,
+	0xb1, 0x8f, 0xa1, 0xbe, 0x70, 0x96, 0xa2, 0x17, 0xf8, 0x49, 0x14, 0x78, 0xad, 0xaa, 0x9a, 0xbc,
+	0xe7, 0xb9, 0xc2, 0x4f, 0xfa, 0xce, 0x52, 0x28, 0x4f, 0xf1, 0xbc, 0x59, 0xfb, 0xb7, 0x1a, 0x94,
+	0xe5, 0xa2, 0x18, 0x83, 0xa2, 0xef, 0x2c, 0xa5, 0x93, 0x6b, 0x9c, 0xda, 0xa8, 0x4b, 0xd0, 0xf1,
+	0xba, 0xd4, 0x61, 0x9b, 0xb5, 0xa0, 0x32, 0x17, 0x57, 0xce, 0xca, 0x4b, 0xc8, 0x6f, 0x35, 0x9e,
+	0x8a, 0xcc, 0x80, 0xc2, 0xd2, 0xf5, 0xc9, 0x3f, 0x25, 0x8e, 0x4d, 0xd2, 0x38, 0x5f, 0xb5, 0x4a,
+	0x4a, 0xe3, 0x7c, 0x85, 0x9a, 0x1b, 0x27, 0x6a, 0x95, 0x0f, 0x0b, 0x47, 0x35, 0x8e, 0xcd, 0xf6,
+	0x31, 0xe8, 0x83, 0xf9, 0xce, 0xd9, 0x1f, 0x42, 0xd9, 0x59, 0x25, 0xd7, 0x41, 0xa4, 0xe6, 0x57,
+	0x52, 0xfb, 0x53, 0xa8, 0xa6, 0xc7, 0x83, 0x36, 0x61, 0xe0, 0xcf, 0x45, 0xd4, 0xd2, 0xe8, 0x93,
+	0x4a, 0xc2, 0xef, 0x2d, 0xf1, 0x68, 0xd5, 0xca, 0xb1, 0xdd, 0x7e, 0x09, 0xa5, 0xf1, 0x2c, 0x88,
+	0x04, 0xdb, 0x07, 0x7d, 0x16, 0xd2, 0x54, 0x25, 0xae, 0xcf, 0x42, 0x32, 0x76, 0x12, 0x69, 0x5c,
+	0xe2, 0xd4, 0x66, 0x0f, 0xa0, 0xe4, 0x05, 0x6f, 0x44, 0x44, 0x9b, 0x2c, 0x71, 0x29, 0xa0, 0x76,
+	0x15, 0x86, 0x22, 0x52, 0x9b, 0x94, 0x42, 0xfb, 0x9b, 0x02, 0x14, 0x31, 0x04, 0xb0, 0x7b, 0x2e,
+	0xc2, 0xe4, 0x9a, 0xbe, 0xdd, 0xe4, 0x52, 0x60, 0x6d, 0xa8, 0xc6, 0xc2, 0x93, 0x1d, 0x3a, 0x75,
+	0x64, 0x32, 0x79, 0xd8, 0x5d, 0xca, 0x10, 0x2c, 0x72, 0x6a, 0xe3, 0x57, 0xfc, 0x60, 0x2e, 0x62,
+	0x9a, 0xa4, 0xc8, 0xa5, 0x80, 0x7e, 0x5f, 0xae, 0xbc, 0xc4, 0x0d, 0x6f, 0x28, 0x4a, 0x4a, 0x3c,
+	0x15, 0xd9, 0x8f, 0xa1, 0x14, 0xe3, 0xbe, 0x28, 0x2e, 0xea, 0x4f, 0xee, 0xe5, 0x23, 0x8e, 0x36,
+	0xcc, 0x65, 0x3f, 0x2e, 0x64, 0xb6, 0x8a, 0x22, 0x72, 0x4c, 0x95, 0x1c, 0x93, 0xc9, 0x14, 0x8b,
+	0xaa, 0xed, 0xaf, 0x96, 0x97, 0x22, 0x6a, 0xd5, 0x68, 0xa9, 0x5b, 0x5a, 0xfc, 0xc6, 0xb5, 0x13,
+	0x5f, 0x5f, 0xad, 0x3c, 0xaf, 0x05, 0x72, 0x33, 0xa9, 0x8c, 0x87, 0xeb, 0x87, 0x71, 0xab, 0x4e,
+	0xcb, 0xc6, 0x26, 0x1e, 0x4f, 0x72, 0x79, 0xed, 0x26, 0x71, 0xab, 0x41, 0x4a, 0x25, 0xe1, 0x66,
+	0x66, 0xe1, 0xca, 0x0b, 0x9c, 0x79, 0xab, 0x49, 0x1f, 0x49, 0x45, 0x1c, 0x11, 0x27, 0x91, 0xeb,
+	0x2f, 0x5a, 0xfb, 0xf2, 0xd0, 0xa5, 0xc4, 0xde, 0x07, 0x88, 0xc4, 0xd5, 0x2a, 0x91, 0x19, 0x70,
+	0x40, 0x87, 0x9d, 0xd3, 0xa4, 0x7b, 0xf3, 0x5c, 0x5f, 0xb4, 0x8c, 0xf5, 0xde, 0x50, 0xa6, 0x6f,
+	0xca, 0x55, 0xdc, 0x93, 0xab, 0x90, 0x12, 0xc6, 0x41, 0x78, 0xd3, 0x62, 0xf4, 0x2d, 0x3d, 0xbc,
+	0xf9, 0xbc, 0x58, 0x2d, 0x19, 0x65, 0xf3, 0xf7, 0x1a, 0xd4, 0x73, 0xb0, 0xc2, 0xca, 0xa0, 0x0f,
+	0x4e, 0x8c, 0x3d, 0x06, 0x50, 0xb6, 0x47, 0x93, 0x81, 0x3d, 0x34, 0x34, 0x56, 0x83, 0xd2, 0xb4,
+	0x37, 0xb0, 0x9f, 0x1b, 0x3a, 0xab, 0x43, 0x85, 0x5b, 0xdd, 0x93, 0x57, 0xf6, 0x73, 0xa3, 0xc0,
+	0x1a, 0x50, 0x7d, 0x6a, 0x8d, 0x27, 0xe7, 0xf6, 0x0b, 0xcb, 0x28, 0x32, 0x06, 0xfb, 0x3d, 0x7b,
+	0xf4, 0x6a, 0xc4, 0xed, 0x89, 0xd5, 0xa3, 0x91, 0x25, 0x66, 0x40, 0x83, 0x5b, 0xfd, 0xc1, 0x78,
+	0xc2, 0xbb, 0xa4, 0x29, 0xb3, 0x2a, 0x14, 0x07, 0xc3, 0x53, 0xdb, 0xa8, 0x60, 0x5f, 0xbf, 0x7b,
+	0x6e, 0x5d, 0xf4, 0xec, 0xe1, 0x84, 0xdb, 0x67, 0x46, 0xd5, 0xfc, 0x4b, 0x1d, 0xea, 0x74, 0x9a,
+	0x71, 0x18, 0xf8, 0xb1, 0x60, 0xbf, 0xd8, 0x05, 0x88, 0xad, 0x4e, 0xce, 0xe4, 0x6e, 0x44, 0xa4,
+	0xe0, 0xbc, 0x5c, 0x2d, 0x28, 0x06, 0xab, 0x5c, 0x0a, 0xec, 0x63, 0xa8, 0xc5, 0x22, 0x91, 0x18,
+	0xa0, 0x80, 0xf0, 0xe1, 0xc6, 0xf7, 0xc6, 0x69, 0x2f, 0x5f, 0x1b, 0xb2, 0xc7, 0x50, 0x0d, 0x83,
+	0xd8, 0xa5, 0x41, 0x12, 0x0f, 0xdf, 0xd9, 0x18, 0x34, 0x52, 0x9d, 0x3c, 0x33, 0x63, 0x1f, 0x80,
+	0xbe, 0x08, 0x14, 0x28, 0x1e, 0x6c, 0x18, 0xf7, 0x03, 0xae, 0x2f, 0x02, 0x5c, 0xc9, 0x8d, 0x1b,
+	0x78, 0x4e, 0x06, 0x84, 0xdb, 0x2b, 0x79, 0x91, 0xf6, 0xf2, 0xb5, 0xe1, 0x36, 0xee, 0x55, 0x14,
+	0xee, 0x8d, 0x45, 0x74, 0x23, 0xa2, 0xbb, 0x70, 0x8f, 0x75, 0xa0, 0x12, 0x8b, 0x38, 0xc6, 0x99,
+	0xaa, 0x79, 0xf0, 0xcf, 0xf6, 0x4c, 0x7d, 0x3c, 0x35, 0x6a, 0x7f, 0x02, 0xb5, 0xcc, 0x0f, 0x3b,
+	0xb1, 0xea, 0x01, 0x94, 0x6e, 0x1c, 0x6f, 0x95, 0x02, 0x8e, 0x14, 0xda, 0xcf, 0xa0, 0x9a, 0x7a,
+	0x02, 0x2d, 0xdc, 0xf8, 0x54, 0xf8, 0x34, 0xac, 0xca, 0xa5, 0x80, 0x5a, 0x4c, 0xae, 0xb8, 0xa5,
+	0x53, 0x14, 0x4a, 0x01, 0x13, 0xe9, 0x4a, 0xf8, 0x0a, 0x5f, 0xb1, 0xd9, 0xfe, 0xb3, 0x0e, 0x7a,
+	0x3f, 0x60, 0x87, 0x50, 0x8f, 0x85, 0x13, 0xcd, 0xae, 0xe5, 0x20, 0x89, 0x79, 0x79, 0x15, 0xe6,
+	0x81, 0x1b, 0x8f, 0x24, 0x24, 0xca, 0x83, 0xce, 0x64, 0x9c, 0xec, 0x4d, 0x86, 0x36, 0x4d, 0x2e,
+	0x05, 0xd4, 0x5e, 0x92, 0xb6, 0x28, 0xb5, 0x24, 0xe0, 0x26, 0xdf, 0xb8, 0xfe, 0x8c, 0x0e, 0xac,
+	0xc9, 0xa9, 0x8d, 0xba, 0x4b, 0xd4, 0x95, 0xa5, 0x0e, 0xdb, 0xec, 0x3d, 0xa8, 0xd1, 0xc4, 0x49,
+	0xb0, 0x08, 0xc8, 0xfb, 0x4d, 0xbe, 0x56, 0xac, 0x01, 0xb1, 0x9a, 0x07, 0xc4, 0x0c, 0xe0, 0x24,
+	0xc4, 0x48, 0x01, 0x57, 0x8e, 0x03, 0x69, 0x29, 0x0a, 0x59, 0x52, 0x19, 0xb3, 0xdf, 0x8d, 0x07,
+	0xfe, 0x95, 0xeb, 0xbb, 0x89, 0x20, 0x80, 0xa9, 0xf2, 0x9c, 0xa6, 0xfd, 0xbb, 0x02, 0xd4, 0xb2,
+	0xf0, 0x60, 0x1f, 0x41, 0x71, 0x16, 0xcc, 0xd3, 0xf4, 0xf8, 0xde, 0xee, 0x20, 0xea, 0xf4, 0x82,
+	0xb9, 0xe0, 0x64, 0xc8, 0x3e, 0x81, 0xb2, 0x23, 0x2f, 0x60, 0x9d, 0x86, 0x7c, 0xff, 0x8e, 0x21,
+	0xdd, 0x99, 0xbc, 0xbf, 0xa5, 0x31, 0xe2, 0x8a, 0xf0, 0x17, 0xae, 0x2f, 0x1d, 0x5a, 0xe3, 0x4a,
+	0x42, 0x3f, 0xc5, 0xee, 0x5c, 0x3a, 0xb4, 0xc6, 0xa9, 0x8d, 0x47, 0x1a, 0x7a, 0x6f, 0x95, 0x3b,
+	0xb1, 0x89, 0xa3, 0xe7, 0x22, 0x71, 0x5c, 0x4f, 0xdd, 0xfa, 0x4a, 0x32, 0xbf, 0xd6, 0xa0, 0x88,
+	0x6b, 0x63, 0x0f, 0xc0, 0x18, 0x9c, 0x9d, 0x59, 0xfd, 0xee, 0xd9, 0x45, 0x06, 0x2a, 0x7b, 0x08,
+	0x2a, 0x2f, 0xb9, 0x3d, 0xec, 0x5f, 0x0c, 0xed, 0x49, 0x57, 0xc1, 0xd1, 0xbb, 0x70, 0x3f, 0xb5,
+	0xb8, 0x78, 0x39, 0x98, 0x3c, 0xb3, 0xa7, 0x93, 0x8b, 0xbe, 0x6d, 0xe8, 0xac, 0x0d, 0x0f, 0x87,
+	0x76, 0x36, 0xfa, 0xa2, 0x7b, 0x3a, 0xb1, 0xf8, 0xc5, 0x78, 0x62, 0x8f, 0x8c, 0x02, 0xdb, 0x07,
+	0x18, 0xda, 0x17, 0x29, 0x76, 0x15, 0xd9, 0x43, 0x60, 0xd3, 0xa1, 0xf5, 0xeb, 0x91, 0xd5, 0x9b,
+	0x58, 0x27, 0x17, 0xe7, 0xd6, 0x78, 0xdc, 0xed, 0x5b, 0x46, 0xc9, 0x7c, 0x04, 0x65, 0xb9, 0x6f,
+	0x84, 0xba, 0x53, 0x9b, 0x9f, 0x5a, 0x83, 0x89, 0xb1, 0x87, 0xb0, 0xf5, 0xb2, 0xcb, 0x15, 0x18,
+	0x72, 0x6b, 0xc2, 0x5f, 0x19, 0x7a, 0xfb, 0x13, 0xa8, 0xa8, 0xdc, 0xc1, 0x43, 0x4e, 0x82, 0xd7,
+	0x2a, 0xde, 0x6b, 0x5c, 0x0a, 0xa8, 0x5d, 0x44, 0xce, 0x4c, 0xa8, 0x8b, 0x50, 0x0a, 0xe6, 0xdf,
+	0xb6, 0x20, 0xb7, 0x02, 0x85, 0x69, 0x6f, 0x60, 0xec, 0xe1, 0xa7, 0x4f, 0xac, 0xa7, 0xd3, 0xbe,
+	0xa1, 0xe1, 0xe4, 0x83, 0x31, 0xad, 0xd6, 0xd0, 0x59, 0x13, 0x6a, 0x63, 0x6b, 0xa2, 0xe0, 0x98,
+	0x60, 0x57, 0x82, 0xaa, 0xc5, 0x8d, 0x22, 0x6e, 0x6c, 0xda, 0x1b, 0x0c, 0xad, 0x97, 0x08, 0xa6,
+	0x46, 0x09, 0x7b, 0x47, 0xf6, 0x78, 0xa0, 0xe0, 0xb6, 0x0c, 0x7a, 0x1f, 0xc1, 0xb6, 0x0a, 0x45,
+	0x72, 0x44, 0x15, 0x3f, 0x36, 0xb2, 0x87, 0x27, 0x16, 0x7f, 0x36, 0x98, 0x18, 0x35, 0xec, 0xf8,
+	0x62, 0x3a, 0x98, 0x18, 0x80, 0x1d, 0x2f, 0x06, 0xf6, 0x99, 0xf4, 0x72, 0xfd, 0x16, 0x3c, 0x37,
+	0x70, 0x4d, 0x63, 0x6b, 0x3c, 0xc6, 0xee, 0xa6, 0xf9, 0x27, 0x1d, 0x0e, 0xba, 0xbe, 0xe3, 0xbd,
+	0x8d, 0xdd, 0x38, 0x25, 0xb0, 0x2a, 0x91, 0xb5, 0x2c, 0x91, 0xef, 0x48, 0xf8, 0x2c, 0x4f, 0x0a,
+	0x3b, 0xf3, 0xa4, 0x78, 0x57, 0x9e, 0x94, 0xb6, 0xf2, 0x64, 0x8b, 0x24, 0x34, 0xd7, 0x24, 0x61,
+	0x0b, 0x39, 0x2a, 0xb7, 0x91, 0x63, 0x1d, 0xcd, 0xd5, 0x8d, 0x68, 0x6e, 0x43, 0x35, 0x8c, 0xdc,
+	0x20, 0x72, 0x93, 0xb7, 0x94, 0xb0, 0x25, 0x9e, 0xc9, 0x98, 0xfd, 0xf1, 0xea, 0x72, 0xe9, 0x26,
+	0x89, 0x88, 0x28, 0x69, 0x6b, 0x7c, 0xad, 0x30, 0xff, 0x5a, 0x80, 0xfd, 0xd4, 0x23, 0xd3, 0x70,
+	0x8e, 0xb4, 0x6a, 0x3d, 0x89, 0xb6, 0x31, 0x49, 0xe6, 0x00, 0xfd, 0x2e, 0xe6, 0x54, 0xd8, 0x62,
+	0x4e, 0xbb, 0x59, 0x92, 0xa2, 0x20, 0xa5, 0x35, 0x05, 0x49, 0x19, 0x56, 0x39, 0xc7, 0xb0, 0x1e,
+	0x41, 0x09, 0x89, 0x81, 0x74, 0x03, 0x82, 0xff, 0xe6, 0x2a, 0x3b, 0x67, 0xae, 0x2f, 0xb8, 0x34,
+	0xc1, 0x35, 0x60, 0x09, 0x90, 0x27, 0x4d, 0xa9, 0x8c, 0x4e, 0x4d, 0xdb, 0x63, 0xc7, 0x27, 0xef,
+	0xd4, 0x78, 0x5e, 0xb5, 0x41, 0xb9, 0x60, 0x8b, 0x72, 0x1d, 0x42, 0x3d, 0x6d, 0xe3, 0xe8, 0xba,
+	0x1c, 0x9d, 0x53, 0xb5, 0x5f, 0x43, 0x11, 0x97, 0x92, 0x3f, 0x56, 0x6d, 0xf3, 0x58, 0x33, 0xee,
+	0xa7, 0x7f, 0x07, 0xf7, 0x93, 0x5c, 0xa7, 0x90, 0x72, 0x1d, 0x74, 0x54, 0xec, 0xe0, 0xe5, 0x8d,
+	0x0a, 0x6c, 0x9a, 0x8f, 0xa1, 0x62, 0x87, 0xc2, 0x47, 0xb2, 0xf5, 0x5f, 0x86, 0xad, 0xf9, 0x4f,
+	0x1d, 0xea, 0xe3, 0x11, 0x9f, 0xa4, 0xe1, 0xfe, 0x1e, 0xd4, 0x66, 0x8e, 0x3f, 0x77, 0xd1, 0x89,
+	0x6a, 0xf4, 0x5a, 0x41, 0x9e, 0x74, 0x62, 0x41, 0x14, 0x4d, 0x57, 0x9e, 0x54, 0x32, 0x9e, 0x92,
+	0xf0, 0x82, 0x63, 0x3a, 0x65, 0x8d, 0x53, 0x5b, 0xe9, 0x1e, 0xb7, 0x8a, 0x99, 0xee, 0x31, 0xae,
+	0xc3, 0xf1, 0xc2, 0x6b, 0x87, 0x4e, 0x58, 0xe3, 0x52, 0xa0, 0x8b, 0x49, 0x24, 0x0e, 0x9d, 0xb1,
+	0xc6, 0xa9, 0xcd, 0x3e, 0x84, 0x6a, 0x20, 0xb7, 0x93, 0x1e, 0x73, 0xb5, 0xa3, 0xf6, 0xc7, 0xb3,
+	0x9e, 0x2c, 0x3a, 0xe4, 0xfd, 0x44, 0x6d, 0xdc, 0x85, 0xeb, 0xcf, 0x22, 0xb1, 0x14, 0x7e, 0xa2,
+	0xae, 0xa8, 0xb5, 0x82, 0x4e, 0x2d, 0xf0, 0xf1, 0x94, 0x84, 0x3f, 0x7b, 0xab, 0x6e, 0xaa, 0xbc,
+	0x8a, 0x12, 0xd4, 0xf9, 0x6a, 0xe4, 0xb8, 0x91, 0xe4, 0xc2, 0x4d, 0x9e, 0xc9, 0x1b, 0xc9, 0xd4,
+	0xf8, 0xb6, 0x64, 0x6a, 0x6e, 0x27, 0xd3, 0x3f, 0x74, 0x00, 0xf4, 0xb5, 0x4a, 0xa4, 0x47, 0x50,
+	0x8e, 0x44, 0x8c, 0x55, 0x98, 0xbc, 0xe5, 0x58, 0x67, 0xdd, 0xd9, 0xe1, 0xd4, 0xc3, 0x95, 0x05,
+	0x1e, 0xa7, 0xe7, 0x49, 0x3a, 0xa0, 0x71, 0x6c, 0x6e, 0x56, 0x37, 0xda, 0xce, 0xea, 0x46, 0x53,
+	0xd5, 0x0d, 0x8e, 0x16, 0x5e, 0xa0, 0x1c, 0x8e, 0x4d, 0x5c, 0xa8, 0xf0, 0x82, 0x73, 0x27, 0x5a,
+	0xb8, 0xbe, 0xf2, 0xf9, 0x5a, 0x41, 0x10, 0xef, 0x2c, 0x29, 0xb9, 0x24, 0xc4, 0xa3, 0xa0, 0xf8,
+	0x44, 0x9c, 0x3a, 0x1a, 0xdb, 0x94, 0xf4, 0x91, 0xf3, 0x26, 0xe3, 0x01, 0x24, 0x20, 0x44, 0x78,
+	0x41, 0x1c, 0x8b, 0x58, 0xf9, 0x56, 0x49, 0xe8, 0xf8, 0x50, 0xf8, 0x89, 0xe3, 0x07, 0x4b, 0xd7,
+	0xf1, 0x5a, 0xf5, 0xc3, 0x02, 0x3a, 0x3e, 0xa7, 0x32, 0x3f, 0x83, 0xb2, 0xdc, 0x39, 0x91, 0xf2,
+	0xe9, 0x70, 0x38, 0x18, 0xf6, 0x8d, 0x3d, 0x44, 0xfc, 0x67, 0xc7, 0x86, 0x46, 0xbf, 0x8f, 0x0d,
+	0x1d, 0x71, 0x7c, 0x30, 0xec, 0xd9, 0xc3, 0xde, 0xd9, 0x74, 0x3c, 0x78, 0x61, 0x19, 0x05, 0xf3,
+	0x04, 0xca, 0x23, 0x11, 0xc5, 0x81, 0x8f, 0x69, 0xe2, 0xce, 0x55, 0xe8, 0xe2, 0x33, 0x41, 0xca,
+	0xf5, 0xf4, 0xcd, 0xba, 0x14, 0x8b, 0x6e, 0x7f, 0xa1, 0x6a, 0x43, 0x25, 0x99, 0xfb, 0xd0, 0xe0,
+	0xd4, 0x3a, 0x75, 0x3d, 0x3c, 0xb1, 0x39, 0x34, 0x91, 0x80, 0x8e, 0xa2, 0x20, 0x0c, 0x62, 0xc7,
+	0x8b, 0x59, 0x07, 0xea, 0x89, 0x9b, 0x91, 0x50, 0x9a, 0xa5, 0xfe, 0xa4, 0xd1, 0x99, 0xac, 0x75,
+	0x3c, 0x6f, 0xc0, 0x7e, 0x80, 0x21, 0x1c, 0x06, 0x3e, 0xc6, 0xa1, 0xcc, 0xef, 0x4a, 0x47, 0xae,
+	0x93, 0x67, 0x1d, 0xe6, 0x97, 0xd0, 0xe8, 0xaf, 0x99, 0xed, 0xff, 0x3e, 0xc9, 0x63, 0x68, 0x44,
+	0xb9, 0x55, 0xab, 0x89, 0x9a, 0x9d, 0xfc, 0x56, 0xf8, 0x86, 0x89, 0xf9, 0x19, 0xd4, 0x7b, 0x81,
+	0x7f, 0xe5, 0x2e, 0x25, 0xdd, 0x3a, 0x82, 0x83, 0xd9, 0x5a, 0xec, 0xa5, 0xcc, 0xab, 0xc6, 0xb7,
+	0xd5, 0x66, 0x13, 0xea, 0x3c, 0x08, 0x96, 0x0a, 0x2e, 0xcc, 0x0f, 0xa4, 0xa8, 0x6e, 0x7e, 0x7a,
+	0x2d, 0x88, 0x17, 0x29, 0xea, 0x2c, 0xe3, 0x85, 0xf9, 0x21, 0x30, 0xdc, 0x9b, 0xb2, 0x4f, 0xed,
+	0xb6, 0xce, 0xc8, 0xfc, 0xa3, 0x06, 0xf7, 0xf3, 0x4c, 0x3f, 0x2d, 0x96, 0xba, 0xea, 0xf5, 0x42,
+	0x26, 0xc8, 0x4f, 0x3b, 0x3b, 0x6c, 0x76, 0xe9, 0x90, 0x81, 0xc4, 0xf2, 0xb1, 0xc3, 0xfc, 0x18,
+	0x5a, 0x77, 0x59, 0x60, 0x38, 0xd9, 0xcf, 0x8d, 0x3d, 0x0a, 0x27, 0x45, 0xd3, 0x88, 0xa2, 0x69,
+	0xe6, 0x1f, 0x0a, 0x70, 0xef, 0xd6, 0xc3, 0x0b, 0xfb, 0xd5, 0xae, 0xda, 0xed, 0xfd, 0xdb, 0x2f,
+	0x34, 0xdf, 0xf6, 0xa6, 0x05, 0xab, 0x99, 0xab, 0xba, 0x55, 0x48, 0xe6, 0x34, 0x18, 0xac, 0x98,
+	0x6c, 0x8a, 0x8d, 0x52, 0x7b, 0x0d, 0xdc, 0xc5, 0x3c, 0x70, 0xff, 0xeb, 0x2e, 0xc2, 0xf5, 0x10,
+	0x18, 0x71, 0x9c, 0xf1, 0xa4, 0x3b, 0xb1, 0x2e, 0xb8, 0xf5, 0xc5, 0xd4, 0x1a, 0x4f, 0x0c, 0x0d,
+	0x8b, 0x5f, 0x6e, 0x8d, 0x07, 0xfd, 0xa1, 0xa1, 0x23, 0xbf, 0xb2, 0x4f, 0x4f, 0x2d, 0x7e, 0x71,
+	0xc2, 0xbb, 0x2f, 0x8d, 0x02, 0x3b, 0x80, 0x7a, 0xb7, 0xd7, 0xb3, 0x46, 0x13, 0xa9, 0x28, 0xa2,
+	0x47, 0x4e, 0xac, 0xde, 0xd9, 0x60, 0x68, 0x49, 0x4d, 0x09, 0xa9, 0xac, 0xfa, 0xd6, 0xc5, 0xa4,
+	0xfb, 0xdc, 0x7a, 0xda, 0xed, 0x3d, 0x37, 0xca, 0xec, 0x3e, 0x1c, 0xa8, 0x81, 0x99, 0xb2, 0x82,
+	0xa6, 0xe9, 0xe0, 0x4c, 0x5b, 0x45, 0x22, 0xd8, 0x7d, 0x6a, 0x73, 0xe4, 0x67, 0x35, 0x28, 0x8d,
+	0xba, 0xd3, 0xb1, 0x65, 0x80, 0x5a, 0xd5, 0xf4, 0xdc, 0x32, 0xea, 0x98, 0xf2, 0xdd, 0x93, 0xcf,
+	0xed, 0x29, 0x1f, 0x4a, 0x62, 0x36, 0xe2, 0x16, 0x1d, 0x47, 0xd3, 0xfc, 0xbb, 0x0e, 0x35, 0xf4,
+	0xef, 0x38, 0x41, 0xe0, 0xbc, 0x7d, 0xb7, 0x6d, 0x65, 0x8c, 0xfe, 0x5d, 0x19, 0x73, 0x04, 0xb5,
+	0xc4, 0x55, 0x9f, 0x53, 0x25, 0x33, 0x74, 0x26, 0xa9, 0x86, 0xaf, 0x3b, 0x55, 0xa4, 0x16, 0x33,
+	0x34, 0xc1, 0x02, 0xec, 0x1a, 0x2b, 0x98, 0x12, 0xa9, 0xa4, 0x40, 0x05, 0x98, 0xe7, 0xcc, 0x5e,
+	0xab, 0x3a, 0x40, 0x0a, 0xeb, 0x83, 0xab, 0xe4, 0x89, 0x22, 0x32, 0xa2, 0xc4, 0x89, 0x12, 0x2c,
+	0x24, 0x15, 0x1b, 0x49, 0x65, 0xba, 0xcb, 0x56, 0x51, 0x4a, 0x43, 0xa8, 0x8d, 0xf6, 0x73, 0xe1,
+	0xcc, 0xe9, 0xce, 0x45, 0x38, 0x2d, 0xf0, 0x4c, 0x26, 0x1c, 0x93, 0x57, 0x88, 0xa4, 0x1e, 0x4a,
+	0x42, 0xa0, 0x4d, 0x44, 0xb4, 0x74, 0x7d, 0x59, 0x8a, 0x37, 0xa8, 0x33, 0xaf, 0x32, 0x17, 0xf0,
+	0x4e, 0x2f, 0x88, 0x22, 0x4a, 0x87, 0xb9, 0xf0, 0x67, 0x69, 0x86, 0xae, 0x37, 0xa8, 0xed, 0xdc,
+	0xa0, 0x9e, 0xdf, 0xa0, 0x09, 0x15, 0x75, 0x0d, 0x2b, 0x27, 0xae, 0xef, 0xe7, 0xb4, 0xc3, 0xfc,
+	0x25, 0xd4, 0x73, 0xc7, 0x90, 0xdd, 0xd6, 0xf2, 0xe9, 0x8e, 0xda, 0x54, 0xf0, 0xca, 0xcb, 0x39,
+	0x51, 0x0f, 0x78, 0x99, 0x6c, 0xbe, 0x86, 0x5a, 0x76, 0x2e, 0xac, 0x03, 0x8c, 0x96, 0x83, 0x1a,
+	0x2e, 0x96, 0x8e, 0x4b, 0x53, 0xcb, 0x4f, 0xed, 0xe8, 0x41, 0x7b, 0x5a, 0xe8, 0xa6, 0xbd, 0x9c,
+	0x62, 0x47, 0x8f, 0xf9, 0x6f, 0x1d, 0xee, 0xdd, 0x7a, 0x76, 0xb8, 0x2b, 0xeb, 0x6f, 0x19, 0xfe,
+	0x5f, 0x59, 0xbf, 0x4c, 0x5f, 0xb1, 0xd5, 0x53, 0xe7, 0x9a, 0xdd, 0x69, 0x8a, 0xdd, 0xb1, 0x9f,
+	0x40, 0x65, 0xa6, 0x02, 0xbc, 0x74, 0xe7, 0xdb, 0x70, 0x6a, 0x92, 0x55, 0xb0, 0xe5, 0x5c, 0x05,
+	0x4b, 0xe1, 0xe2, 0xc4, 0xd9, 0xfb, 0xb3, 0x92, 0x30, 0x1d, 0x16, 0x69, 0x76, 0xa9, 0xd7, 0x14,
+	0xe8, 0x64, 0xf9, 0xc6, 0xd7, 0x9d, 0xe6, 0xab, 0x3b, 0x40, 0xe7, 0x5d, 0xb8, 0xbf, 0x01, 0x3a,
+	0xe3, 0x91, 0x3d, 0x1c, 0x5b, 0xb2, 0xe6, 0x4b, 0x8b, 0x2d, 0x5d, 0x16, 0x79, 0x9f, 0x53, 0x75,
+	0x6a, 0x14, 0xb0, 0x36, 0x7b, 0x65, 0x4f, 0xb9, 0x84, 0xdc, 0xe2, 0x93, 0x6f, 0x34, 0x30, 0x7a,
+	0xf8, 0x47, 0x42, 0x37, 0x0c, 0x3d, 0x77, 0xe6, 0xa8, 0xf7, 0x75, 0x9c, 0x80, 0xd5, 0x73, 0xa4,
+	0xb8, 0xdd, 0xc8, 0x97, 0xf6, 0xe6, 0xde, 0x91, 0x76, 0xac, 0xb1, 0x63, 0xa8, 0x50, 0x01, 0xf0,
+	0x1b, 0xc1, 0x8c, 0xce, 0x56, 0x09, 0xd7, 0x3e, 0xd8, 0x2a, 0x0e, 0xcc, 0xbd, 0x63, 0x8d, 0xfd,
+	0x10, 0x8a, 0x48, 0xb7, 0x58, 0xa3, 0x93, 0xa3, 0xbf, 0xed, 0x7a, 0x8e, 0x83, 0x91, 0xd9, 0xa7,
+	0xb0, 0xbf, 0x99, 0x27, 0xec, 0x61, 0x67, 0x67, 0xe2, 0xb4, 0x73, 0x1e, 0x33, 0xf7, 0x2e, 0xcb,
+	0xf4, 0x6f, 0xc8, 0xcf, 0xfe, 0x33, 0x00, 0x13, 0x98, 0x2f, 0xda, 0x24, 0x19, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1677,6 +2982,14 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ChessApplicationClient interface {
 	UCI(ctx context.Context, opts ...grpc.CallOption) (ChessApplication_UCIClient, error)
+	// Analyze runs a search on an engine connected over UCI and streams its progress
+	Analyze(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (ChessApplication_AnalyzeClient, error)
+	// SPRT plays a candidate engine against a baseline until a sequential
+	// probability ratio test accepts a hypothesis and streams its progress
+	SPRT(ctx context.Context, in *SPRTRequest, opts ...grpc.CallOption) (ChessApplication_SPRTClient, error)
+	// Correspondence starts a correspondence game between two engines and
+	// returns its state, the engines are asked for their moves over UCI
+	Correspondence(ctx context.Context, in *CorrespondenceRequest, opts ...grpc.CallOption) (*GameState, error)
 }
 
 type chessApplicationClient struct {
@@ -1718,9 +3031,90 @@ func (x *chessApplicationUCIClient) Recv() (*UciResponse, error) {
 	return m, nil
 }
 
+func (c *chessApplicationClient) Analyze(ctx context.Context, in *AnalysisRequest, opts ...grpc.CallOption) (ChessApplication_AnalyzeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChessApplication_serviceDesc.Streams[1], "/ChessApplication/Analyze", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chessApplicationAnalyzeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChessApplication_AnalyzeClient interface {
+	Recv() (*AnalysisUpdate, error)
+	grpc.ClientStream
+}
+
+type chessApplicationAnalyzeClient struct {
+	grpc.ClientStream
+}
+
+func (x *chessApplicationAnalyzeClient) Recv() (*AnalysisUpdate, error) {
+	m := new(AnalysisUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chessApplicationClient) SPRT(ctx context.Context, in *SPRTRequest, opts ...grpc.CallOption) (ChessApplication_SPRTClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChessApplication_serviceDesc.Streams[2], "/ChessApplication/SPRT", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chessApplicationSPRTClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChessApplication_SPRTClient interface {
+	Recv() (*SPRTUpdate, error)
+	grpc.ClientStream
+}
+
+type chessApplicationSPRTClient struct {
+	grpc.ClientStream
+}
+
+func (x *chessApplicationSPRTClient) Recv() (*SPRTUpdate, error) {
+	m := new(SPRTUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chessApplicationClient) Correspondence(ctx context.Context, in *CorrespondenceRequest, opts ...grpc.CallOption) (*GameState, error) {
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, "/ChessApplication/Correspondence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChessApplicationServer is the server API for ChessApplication service.
 type ChessApplicationServer interface {
 	UCI(ChessApplication_UCIServer) error
+	// Analyze runs a search on an engine connected over UCI and streams its progress
+	Analyze(*AnalysisRequest, ChessApplication_AnalyzeServer) error
+	// SPRT plays a candidate engine against a baseline until a sequential
+	// probability ratio test accepts a hypothesis and streams its progress
+	SPRT(*SPRTRequest, ChessApplication_SPRTServer) error
+	// Correspondence starts a correspondence game between two engines and
+	// returns its state, the engines are asked for their moves over UCI
+	Correspondence(context.Context, *CorrespondenceRequest) (*GameState, error)
 }
 
 // UnimplementedChessApplicationServer can be embedded to have forward compatible implementations.
@@ -1730,6 +3124,15 @@ type UnimplementedChessApplicationServer struct {
 func (*UnimplementedChessApplicationServer) UCI(srv ChessApplication_UCIServer) error {
 	return status.Errorf(codes.Unimplemented, "method UCI not implemented")
 }
+func (*UnimplementedChessApplicationServer) Analyze(req *AnalysisRequest, srv ChessApplication_AnalyzeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Analyze not implemented")
+}
+func (*UnimplementedChessApplicationServer) SPRT(req *SPRTRequest, srv ChessApplication_SPRTServer) error {
+	return status.Errorf(codes.Unimplemented, "method SPRT not implemented")
+}
+func (*UnimplementedChessApplicationServer) Correspondence(ctx context.Context, req *CorrespondenceRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Correspondence not implemented")
+}
 
 func RegisterChessApplicationServer(s *grpc.Server, srv ChessApplicationServer) {
 	s.RegisterService(&_ChessApplication_serviceDesc, srv)
@@ -1761,10 +3164,75 @@ func (x *chessApplicationUCIServer) Recv() (*UciRequest, error) {
 	return m, nil
 }
 
+func _ChessApplication_Analyze_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AnalysisRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChessApplicationServer).Analyze(m, &chessApplicationAnalyzeServer{stream})
+}
+
+type ChessApplication_AnalyzeServer interface {
+	Send(*AnalysisUpdate) error
+	grpc.ServerStream
+}
+
+type chessApplicationAnalyzeServer struct {
+	grpc.ServerStream
+}
+
+func (x *chessApplicationAnalyzeServer) Send(m *AnalysisUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChessApplication_SPRT_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SPRTRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChessApplicationServer).SPRT(m, &chessApplicationSPRTServer{stream})
+}
+
+type ChessApplication_SPRTServer interface {
+	Send(*SPRTUpdate) error
+	grpc.ServerStream
+}
+
+type chessApplicationSPRTServer struct {
+	grpc.ServerStream
+}
+
+func (x *chessApplicationSPRTServer) Send(m *SPRTUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChessApplication_Correspondence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CorrespondenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChessApplicationServer).Correspondence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ChessApplication/Correspondence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChessApplicationServer).Correspondence(ctx, req.(*CorrespondenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ChessApplication_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "ChessApplication",
 	HandlerType: (*ChessApplicationServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Correspondence",
+			Handler:    _ChessApplication_Correspondence_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "UCI",
@@ -1772,6 +3240,16 @@ var _ChessApplication_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Analyze",
+			Handler:       _ChessApplication_Analyze_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SPRT",
+			Handler:       _ChessApplication_SPRT_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "service/chess.proto",
 }
@@ -0,0 +1,186 @@
+// Package sprt implements the sequential probability ratio test used to
+// decide whether a candidate engine is stronger than a baseline. Games are
+// played in pairs with the same opening and each engine having white once,
+// the pairs are counted with the pentanomial model.
+package sprt
+
+import (
+	"math"
+)
+
+// z95 is the z-score of a two sided 95% confidence interval
+const z95 = 1.959964
+
+// Result is the state of a test
+type Result int
+
+// The results of a test
+const (
+	// Continue means neither hypothesis can be accepted yet
+	Continue Result = iota
+	// AcceptH0 means the candidate is at most elo0 stronger than the baseline
+	AcceptH0
+	// AcceptH1 means the candidate is at least elo1 stronger than the baseline
+	AcceptH1
+)
+
+func (r Result) String() string {
+	switch r {
+	case AcceptH0:
+		return "H0"
+	case AcceptH1:
+		return "H1"
+	}
+	return "continue"
+}
+
+// Pentanomial counts game pairs by the candidate's points in the pair, index
+// i counts the pairs where it scored i/2 points
+type Pentanomial [5]int
+
+// Add counts a pair where the candidate scored between 0 and 2 points
+func (p *Pentanomial) Add(points float64) {
+	p[int(math.Round(points*2))]++
+}
+
+// Pairs returns the number of pairs counted
+func (p Pentanomial) Pairs() int {
+	n := 0
+	for _, c := range p {
+		n += c
+	}
+	return n
+}
+
+// stats returns the number of pairs and the mean and variance of the
+// candidate's score per pair as a fraction of the points available. Empty
+// counts are given a tiny weight so the variance of a one sided result is
+// not zero.
+func (p Pentanomial) stats() (n, mean, variance float64) {
+	var counts [5]float64
+	for i, c := range p {
+		counts[i] = float64(c)
+		if c == 0 {
+			counts[i] = 1e-3
+		}
+		n += counts[i]
+	}
+	for i, c := range counts {
+		mean += c * float64(i) / 4
+	}
+	mean /= n
+	for i, c := range counts {
+		variance += c * math.Pow(float64(i)/4-mean, 2)
+	}
+	return n, mean, variance / n
+}
+
+// Score returns the expected score of an engine rated elo above its opponent
+func Score(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// EloDifference returns the Elo difference that gives an expected score
+func EloDifference(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+// Bounds returns the log-likelihood ratios at which H0 and H1 are accepted
+// with false positive rate alpha and false negative rate beta
+func Bounds(alpha, beta float64) (lower, upper float64) {
+	return math.Log(beta / (1 - alpha)), math.Log((1 - beta) / alpha)
+}
+
+// LLR returns the log-likelihood ratio of H1, the candidate is elo1
+// stronger, over H0, it is elo0 stronger, using the normal approximation of
+// the generalised SPRT
+func LLR(p Pentanomial, elo0, elo1 float64) float64 {
+	if p.Pairs() == 0 {
+		return 0
+	}
+	n, mean, variance := p.stats()
+	s0, s1 := Score(elo0), Score(elo1)
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// Elo estimates how much stronger the candidate is along with the margin of
+// a 95% confidence interval
+func Elo(p Pentanomial) (elo, margin float64) {
+	if p.Pairs() == 0 {
+		return 0, 0
+	}
+	n, mean, variance := p.stats()
+	deviation := math.Sqrt(variance / n)
+	low := EloDifference(mean - z95*deviation)
+	high := EloDifference(mean + z95*deviation)
+	return EloDifference(mean), (high - low) / 2
+}
+
+// Test is a running SPRT
+type Test struct {
+	// The Elo differences of H0 and H1 and the error rates of the test
+	Elo0  float64
+	Elo1  float64
+	Alpha float64
+	Beta  float64
+
+	Pairs Pentanomial
+	// The candidate's game results
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Add counts a pair of games with the candidate's points in each
+func (t *Test) Add(first, second float64) {
+	for _, points := range []float64{first, second} {
+		switch points {
+		case 1:
+			t.Wins++
+		case 0:
+			t.Losses++
+		default:
+			t.Draws++
+		}
+	}
+	t.Pairs.Add(first + second)
+}
+
+// Games returns the number of games counted
+func (t *Test) Games() int {
+	return t.Wins + t.Draws + t.Losses
+}
+
+// LLR returns the test's log-likelihood ratio
+func (t *Test) LLR() float64 {
+	return LLR(t.Pairs, t.Elo0, t.Elo1)
+}
+
+// Bounds returns the test's log-likelihood ratio bounds
+func (t *Test) Bounds() (lower, upper float64) {
+	return Bounds(t.Alpha, t.Beta)
+}
+
+// Elo estimates the candidate's strength relative to the baseline
+func (t *Test) Elo() (elo, margin float64) {
+	return Elo(t.Pairs)
+}
+
+// Result returns whether the test has accepted a hypothesis
+func (t *Test) Result() Result {
+	llr := t.LLR()
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	}
+	return Continue
+}
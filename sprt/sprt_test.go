@@ -0,0 +1,63 @@
+package sprt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLLR(t *testing.T) {
+	p := Pentanomial{5, 20, 40, 25, 10}
+	if llr := LLR(p, 0, 10); math.Abs(llr-0.67897) > 1e-4 {
+		t.Errorf("Expecting an LLR of 0.67897 got %v", llr)
+	}
+
+	elo, margin := Elo(p)
+	if math.Abs(elo-26.107) > 1e-3 || math.Abs(margin-34.826) > 1e-3 {
+		t.Errorf("Expecting 26.107 ± 34.826 got %v ± %v", elo, margin)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	lower, upper := Bounds(0.05, 0.05)
+	if math.Abs(lower+2.944) > 1e-3 || math.Abs(upper-2.944) > 1e-3 {
+		t.Errorf("Expecting bounds of ±2.944 got %v and %v", lower, upper)
+	}
+}
+
+func TestResult(t *testing.T) {
+	test := &Test{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	if test.Result() != Continue || test.LLR() != 0 {
+		t.Errorf("Expecting an empty test to continue got %v with LLR %v", test.Result(), test.LLR())
+	}
+
+	// A candidate that wins every other pair and splits the rest is stronger
+	for i := 0; test.Result() == Continue && i < 10000; i++ {
+		if i%2 == 0 {
+			test.Add(1, 0.5)
+		} else {
+			test.Add(1, 0)
+		}
+	}
+	if test.Result() != AcceptH1 {
+		t.Errorf("Expecting H1 got %v", test.Result())
+	}
+	if pairs := test.Pairs.Pairs(); test.Games() != 2*pairs || test.Wins != pairs || test.Draws != (pairs+1)/2 {
+		t.Errorf("Unexpected results %v wins %v draws %v losses", test.Wins, test.Draws, test.Losses)
+	}
+
+	// A candidate that only splits pairs is no stronger
+	test = &Test{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	for i := 0; test.Result() == Continue && i < 10000; i++ {
+		switch i % 3 {
+		case 0:
+			test.Add(1, 0)
+		case 1:
+			test.Add(0.5, 0.5)
+		default:
+			test.Add(0.5, 0)
+		}
+	}
+	if test.Result() != AcceptH0 {
+		t.Errorf("Expecting H0 got %v", test.Result())
+	}
+}
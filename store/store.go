@@ -0,0 +1,188 @@
+// Package store keeps finished games so results survive a restart of the server
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Game is a finished game, or an adjourned or correspondence game that is
+// still being played with the result *
+type Game struct {
+	// ID identifies the game in the store
+	ID string `json:"id"`
+	// The tournament the game was played in, empty for games outside a tournament
+	Tournament string `json:"tournament,omitempty"`
+	// The round of the tournament the game was played in
+	Round int `json:"round,omitempty"`
+	// The names of the engines, Black is empty when White had a bye
+	White string `json:"white"`
+	Black string `json:"black"`
+	// The position the game started from, the standard starting position when empty
+	FEN string `json:"fen,omitempty"`
+	// The moves played in UCI notation, castling is the king taking its own rook in Chess960
+	Moves []string `json:"moves"`
+	// Chess960 is set for Chess960 games
+	Chess960 bool `json:"chess960,omitempty"`
+	// The UCI name of the variant, empty for standard chess
+	Variant string `json:"variant,omitempty"`
+	// The result in PGN notation eg. 1-0
+	Result string `json:"result"`
+	// Why the game ended
+	Termination string `json:"termination"`
+	// The protocol violations of the engines in the order they happened
+	Violations []Violation `json:"violations,omitempty"`
+	// The time left on the clocks of white and black, kept to resume adjourned games
+	WhiteClock time.Duration `json:"whiteClock,omitempty"`
+	BlackClock time.Duration `json:"blackClock,omitempty"`
+	// When the side to move of a correspondence game loses on time and the
+	// conditional premove lines of each side
+	Deadline      time.Time  `json:"deadline"`
+	WhitePremoves [][]string `json:"whitePremoves,omitempty"`
+	BlackPremoves [][]string `json:"blackPremoves,omitempty"`
+	// When the game finished
+	Finished time.Time `json:"finished"`
+}
+
+// Violation is a way an engine broke the UCI protocol during a game
+type Violation struct {
+	// The engine and its colour, white or black
+	Engine string `json:"engine"`
+	Side   string `json:"side"`
+	// The violation eg. illegal bestmove and what was done about it eg. forfeit
+	Violation string `json:"violation"`
+	Action    string `json:"action"`
+	// The number of moves played when it happened
+	Ply    int    `json:"ply"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Store keeps finished games
+type Store interface {
+	// Put saves a game replacing any game with the same ID
+	Put(game Game) error
+	// Get returns the game with an ID, ok is false when there is none
+	Get(id string) (game Game, ok bool, err error)
+	// Tournament returns the games of a tournament in the order they were first saved
+	Tournament(name string) ([]Game, error)
+}
+
+// Memory is a Store that keeps games in memory
+type Memory struct {
+	mu    sync.Mutex
+	games []Game
+	index map[string]int
+}
+
+// NewMemory creates an empty in memory store
+func NewMemory() *Memory {
+	return &Memory{index: make(map[string]int)}
+}
+
+// Put implements Store
+func (m *Memory) Put(game Game) error {
+	if game.ID == "" {
+		return fmt.Errorf("Game has no ID")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, ok := m.index[game.ID]; ok {
+		m.games[i] = game
+		return nil
+	}
+	m.index[game.ID] = len(m.games)
+	m.games = append(m.games, game)
+	return nil
+}
+
+// Get implements Store
+func (m *Memory) Get(id string) (Game, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, ok := m.index[id]
+	if !ok {
+		return Game{}, false, nil
+	}
+	return m.games[i], true, nil
+}
+
+// Tournament implements Store
+func (m *Memory) Tournament(name string) ([]Game, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var games []Game
+	for _, game := range m.games {
+		if game.Tournament == name {
+			games = append(games, game)
+		}
+	}
+	return games, nil
+}
+
+// File is a Store that appends each game to a file of JSON lines. The file
+// is read back into memory when it is opened, a game saved more than once
+// takes the last value written.
+type File struct {
+	*Memory
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// Open opens or creates a file store
+func Open(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := NewMemory()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var game Game
+		if err := json.Unmarshal(scanner.Bytes(), &game); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Invalid game on line %v of %v: %v", line, path, err)
+		}
+		memory.Put(game)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{Memory: memory, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Put implements Store, the game is written to the file before it is kept in memory
+func (f *File) Put(game Game) error {
+	if game.ID == "" {
+		return fmt.Errorf("Game has no ID")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.enc.Encode(game); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	return f.Memory.Put(game)
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.jsonl")
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range []Game{
+		{ID: "t/0", Tournament: "t", White: "a", Black: "b", Result: "*"},
+		{ID: "t/1", Tournament: "t", White: "b", Black: "a", Result: "0-1"},
+		{ID: "other", White: "a", Black: "b", Result: "1-0"},
+		{ID: "t/0", Tournament: "t", White: "a", Black: "b", Result: "1-0", Moves: []string{"e2e4"}},
+	} {
+		if err := f.Put(game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	game, ok, err := f.Get("t/0")
+	if err != nil || !ok {
+		t.Fatalf("Expecting game t/0 got %v %v", ok, err)
+	}
+	if game.Result != "1-0" || len(game.Moves) != 1 {
+		t.Errorf("Expecting the last write to win got %+v", game)
+	}
+
+	games, err := f.Tournament("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].ID != "t/0" || games[1].ID != "t/1" {
+		t.Errorf("Unexpected tournament games %+v", games)
+	}
+}
@@ -0,0 +1,313 @@
+package syzygy
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// The tables used to turn a position into an index of a table, they follow
+// the encoding of the Syzygy generator
+var (
+	// mapPawns maps the squares a2 to h7 to 0..47 with the square nearest the
+	// edge and lowest rank highest, that pawn leads
+	mapPawns [64]int
+	// mapB1H1H7 maps the squares below the a1-h8 diagonal to 0..27
+	mapB1H1H7 [64]int
+	// mapA1D1D4 maps the a1-d1-d4 triangle to 0..9 with the diagonal last
+	mapA1D1D4 [64]int
+	// mapKK maps the 462 placements of two kings with the first in the
+	// a1-d1-d4 triangle
+	mapKK [10][64]int
+	// binomial[k][n] is the number of ways to choose k of n
+	binomial [6][64]uint64
+	// leadPawnIdx is the index of the leading pawn on a square by the number of leading pawns
+	leadPawnIdx [6][64]uint64
+	// leadPawnsSize is the number of placements of the leading pawns by file
+	leadPawnsSize [6][4]uint64
+)
+
+func init() {
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if offA1H8(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	var diagonal []int
+	code = 0
+	for sq := 0; sq <= 27; sq++ {
+		if offA1H8(sq) < 0 && file(sq) <= 3 {
+			mapA1D1D4[sq] = code
+			code++
+		} else if offA1H8(sq) == 0 && file(sq) <= 3 {
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	type pair struct{ idx, sq int }
+	var bothOnDiagonal []pair
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case distance(s1, s2) <= 1:
+					// the kings are next to each other
+				case offA1H8(s1) == 0 && offA1H8(s2) > 0:
+					// the first king is on the diagonal and the second above it
+				case offA1H8(s1) == 0 && offA1H8(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{idx, s2})
+				default:
+					mapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	available := 47
+	for leadPawns := 1; leadPawns <= 5; leadPawns++ {
+		for f := 0; f <= 3; f++ {
+			var idx uint64
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if leadPawns == 1 {
+					mapPawns[sq] = available
+					available--
+					mapPawns[sq^7] = available
+					available--
+				}
+				leadPawnIdx[leadPawns][sq] = idx
+				idx += binomial[leadPawns-1][mapPawns[sq]]
+			}
+			leadPawnsSize[leadPawns][f] = idx
+		}
+	}
+}
+
+func file(sq int) int { return sq & 7 }
+
+func rank(sq int) int { return sq >> 3 }
+
+// offA1H8 is positive above the a1-h8 diagonal, negative below it and zero on it
+func offA1H8(sq int) int { return rank(sq) - file(sq) }
+
+func distance(a, b int) int {
+	df, dr := file(a)-file(b), rank(a)-rank(b)
+	if df < 0 {
+		df = -df
+	}
+	if dr < 0 {
+		dr = -dr
+	}
+	if df > dr {
+		return df
+	}
+	return dr
+}
+
+// pieceOrder is the order pieces are written in table names
+const pieceOrder = "KQRBNP"
+
+// material returns the name of the pieces of each side eg. KRP
+func material(p *rules.Position) (white, black string) {
+	var counts [2][7]int
+	for sq := rules.Square(0); sq < 64; sq++ {
+		if piece := p.Piece(sq); piece != rules.NoPiece {
+			counts[piece.Color()][piece.Type()]++
+		}
+	}
+	name := func(c rules.Color) string {
+		var b strings.Builder
+		for _, t := range []rules.PieceType{rules.King, rules.Queen, rules.Rook, rules.Bishop, rules.Knight, rules.Pawn} {
+			b.WriteString(strings.Repeat(pieceOrder[6-int(t):7-int(t)], counts[c][t]))
+		}
+		return b.String()
+	}
+	return name(rules.White), name(rules.Black)
+}
+
+// index encodes a position as an index of the table. stm is the side to move
+// of the table, the position's side to move unless colours were swapped.
+func (t *table) index(p *rules.Position, flip bool) (d *pairsData, idx uint64, changeSTM bool) {
+	var squares, pieces [7]int
+	size, leadPawnsCnt := 0, 0
+	var leadPawns uint64
+	tbFile := 0
+
+	flipColor, flipSquares, stm := 0, 0, int(p.Turn())
+	if flip {
+		flipColor, flipSquares, stm = 8, 56, stm^1
+	}
+
+	if t.hasPawns {
+		lead := t.get(0, 0).pieces[0] ^ flipColor
+		for sq := 0; sq < 64; sq++ {
+			if int(p.Piece(rules.Square(sq))) == lead {
+				leadPawns |= 1 << uint(sq)
+				squares[size] = sq ^ flipSquares
+				size++
+			}
+		}
+		leadPawnsCnt = size
+		best := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+		tbFile = file(squares[0])
+		if tbFile > 3 {
+			tbFile = 7 - tbFile
+		}
+	}
+
+	if t.dtz {
+		flags := t.get(stm, tbFile).flags
+		if int(flags&flagSTM) != stm && (t.key != t.key2 || t.hasPawns) {
+			return nil, 0, true
+		}
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		piece := p.Piece(rules.Square(sq))
+		if piece == rules.NoPiece || leadPawns&(1<<uint(sq)) != 0 {
+			continue
+		}
+		squares[size] = sq ^ flipSquares
+		pieces[size] = int(piece) ^ flipColor
+		size++
+	}
+
+	d = t.get(stm, tbFile)
+
+	// order the pieces the way the table lists them
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// the leading piece goes in the a1-d1-d4 triangle
+	if file(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawnsCnt][squares[0]]
+		others := squares[1:leadPawnsCnt]
+		sort.SliceStable(others, func(i, j int) bool {
+			return mapPawns[others[i]] < mapPawns[others[j]]
+		})
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		if rank(squares[0]) > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] ^= 56
+			}
+		}
+
+		// the first piece of the leading group off the diagonal goes below it
+		for i := 0; i < d.groupLen[0]; i++ {
+			if offA1H8(squares[i]) == 0 {
+				continue
+			}
+			if offA1H8(squares[i]) > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+				}
+			}
+			break
+		}
+
+		if t.hasUniquePieces {
+			adjust1 := 0
+			if squares[1] > squares[0] {
+				adjust1 = 1
+			}
+			adjust2 := 0
+			if squares[2] > squares[0] {
+				adjust2++
+			}
+			if squares[2] > squares[1] {
+				adjust2++
+			}
+
+			switch {
+			case offA1H8(squares[0]) != 0:
+				idx = uint64((mapA1D1D4[squares[0]]*63+(squares[1]-adjust1))*62 + squares[2] - adjust2)
+			case offA1H8(squares[1]) != 0:
+				idx = uint64((6*63+rank(squares[0])*28+mapB1H1H7[squares[1]])*62 + squares[2] - adjust2)
+			case offA1H8(squares[2]) != 0:
+				idx = uint64(6*63*62 + 4*28*62 + rank(squares[0])*7*28 + (rank(squares[1])-adjust1)*28 + mapB1H1H7[squares[2]])
+			default:
+				idx = uint64(6*63*62 + 4*28*62 + 4*7*28 + rank(squares[0])*7*6 + (rank(squares[1])-adjust1)*6 + (rank(squares[2]) - adjust2))
+			}
+		} else {
+			idx = uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+		}
+	}
+
+	// the remaining groups in ascending order of square
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+		var n uint64
+		for i, sq := range group {
+			adjust := 0
+			for _, s := range squares[:start] {
+				if sq > s {
+					adjust++
+				}
+			}
+			pawnRank := 0
+			if remainingPawns {
+				pawnRank = 8
+			}
+			n += binomial[i+1][sq-adjust-pawnRank]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+	return d, idx, false
+}
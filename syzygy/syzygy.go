@@ -0,0 +1,308 @@
+// Package syzygy probes Syzygy endgame tablebases read from local .rtbw
+// (win/draw/loss) and .rtbz (distance to zeroing) files
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+// WDL is the result of a position for the side to move assuming the fifty move rule
+type WDL int
+
+// The results of a position
+const (
+	// Loss is lost for the side to move
+	Loss WDL = -2
+	// BlessedLoss is lost but drawn by the fifty move rule
+	BlessedLoss WDL = -1
+	// Draw is a draw
+	Draw WDL = 0
+	// CursedWin is won but drawn by the fifty move rule
+	CursedWin WDL = 1
+	// Win is won for the side to move
+	Win WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed loss"
+	case Draw:
+		return "draw"
+	case CursedWin:
+		return "cursed win"
+	case Win:
+		return "win"
+	}
+	return fmt.Sprintf("WDL(%d)", int(w))
+}
+
+// tableName matches the name of a table file eg. KRPvKR
+var tableName = regexp.MustCompile(`^K[QRBNP]*vK[QRBNP]*$`)
+
+// Tablebase is a set of Syzygy tables, each file is read the first time a
+// position is probed in it
+type Tablebase struct {
+	wdl       map[string]*table
+	dtz       map[string]*table
+	maxPieces int
+}
+
+// Open finds the tables in directories
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{wdl: map[string]*table{}, dtz: map[string]*table{}}
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			ext := filepath.Ext(f.Name())
+			name := strings.TrimSuffix(f.Name(), ext)
+			if f.IsDir() || !tableName.MatchString(name) || len(name)-1 > 7 {
+				continue
+			}
+			path := filepath.Join(dir, f.Name())
+			switch ext {
+			case ".rtbw":
+				tb.wdl[name] = newTable(path, name, false)
+				if len(name)-1 > tb.maxPieces {
+					tb.maxPieces = len(name) - 1
+				}
+			case ".rtbz":
+				tb.dtz[name] = newTable(path, name, true)
+			}
+		}
+	}
+	return tb, nil
+}
+
+// MaxPieces returns the number of pieces including kings of the largest WDL table
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// Pieces returns the number of pieces on the board including kings
+func Pieces(p *rules.Position) int {
+	n := 0
+	for sq := rules.Square(0); sq < 64; sq++ {
+		if p.Piece(sq) != rules.NoPiece {
+			n++
+		}
+	}
+	return n
+}
+
+// lookup finds the table of a position and whether its colours are swapped
+// in the table. Tables are named with the stronger side first and when both
+// sides have the same pieces only white to move is stored.
+func (tb *Tablebase) lookup(tables map[string]*table, p *rules.Position) (*table, bool, error) {
+	if p.Castling() != 0 {
+		return nil, false, fmt.Errorf("Tables do not include positions with castling rights")
+	}
+	white, black := material(p)
+	if t, ok := tables[white+"v"+black]; ok {
+		return t, white == black && p.Turn() == rules.Black, t.load()
+	}
+	if t, ok := tables[black+"v"+white]; ok {
+		return t, true, t.load()
+	}
+	return nil, false, fmt.Errorf("No table for %vv%v", white, black)
+}
+
+// probeWDL looks up the result of a position in its WDL table
+func (tb *Tablebase) probeWDL(p *rules.Position) (WDL, error) {
+	if white, black := material(p); white == "K" && black == "K" {
+		return Draw, nil
+	}
+	t, flip, err := tb.lookup(tb.wdl, p)
+	if err != nil {
+		return Draw, err
+	}
+	d, idx, _ := t.index(p, flip)
+	v, err := d.value(idx)
+	if err != nil {
+		return Draw, fmt.Errorf("Invalid table %v: %v", t.path, err)
+	}
+	return WDL(v - 2), nil
+}
+
+// isCapture returns true for moves taking a piece including en passant
+func isCapture(p *rules.Position, m rules.Move) bool {
+	if p.Piece(m.To) != rules.NoPiece {
+		return true
+	}
+	return p.Piece(m.From).Type() == rules.Pawn && m.From.File() != m.To.File()
+}
+
+// isZeroing returns true for captures and pawn moves which reset the fifty move count
+func isZeroing(p *rules.Position, m rules.Move) bool {
+	return isCapture(p, m) || p.Piece(m.From).Type() == rules.Pawn
+}
+
+// search resolves the captures of a position, or with zeroing set all its
+// zeroing moves, as the tables do not store positions where a capture is
+// best. zeroingBest is set when a zeroing move is at least as good as the
+// stored result.
+func (tb *Tablebase) search(p *rules.Position, zeroing bool) (wdl WDL, zeroingBest bool, err error) {
+	best := Loss
+	moves := p.LegalMoves()
+	searched := 0
+	for _, m := range moves {
+		if !isCapture(p, m) && !(zeroing && p.Piece(m.From).Type() == rules.Pawn) {
+			continue
+		}
+		searched++
+		v, _, err := tb.search(p.Play(m), false)
+		if err != nil {
+			return Draw, false, err
+		}
+		if -v > best {
+			best = -v
+			if best >= Win {
+				return best, true, nil
+			}
+		}
+	}
+
+	// Every move has been searched so the table does not need to be probed
+	all := searched > 0 && searched == len(moves)
+	value := best
+	if !all {
+		if value, err = tb.probeWDL(p); err != nil {
+			return Draw, false, err
+		}
+	}
+	if best >= value {
+		return best, best > Draw || all, nil
+	}
+	return value, false, nil
+}
+
+// ProbeWDL returns the result of a position for the side to move. The
+// position must not have castling rights and the tables of the position and
+// of the positions after any captures must be available.
+func (tb *Tablebase) ProbeWDL(p *rules.Position) (WDL, error) {
+	wdl, _, err := tb.search(p, false)
+	return wdl, err
+}
+
+// beforeZeroing returns the distance to zeroing of a position where the best
+// move zeroes with the result
+func beforeZeroing(wdl WDL) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	}
+	return 0
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// ProbeDTZ returns the distance to zeroing of a position in plies, the
+// number of plies until a capture, a pawn move or mate, positive when the side
+// to move wins and negative when it loses. A win with a distance of more than
+// 100 plies is a cursed win. Draws are 0. The distance may be a ply more than
+// the shortest when the table stores moves rather than plies.
+func (tb *Tablebase) ProbeDTZ(p *rules.Position) (int, error) {
+	wdl, zeroingBest, err := tb.search(p, true)
+	if err != nil || wdl == Draw {
+		return 0, err
+	}
+	if zeroingBest {
+		return beforeZeroing(wdl), nil
+	}
+
+	t, flip, err := tb.lookup(tb.dtz, p)
+	if err != nil {
+		return 0, err
+	}
+	d, idx, changeSTM := t.index(p, flip)
+	if !changeSTM {
+		v, err := d.value(idx)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid table %v: %v", t.path, err)
+		}
+		dtz := t.mapDTZ(d, v, wdl)
+		if wdl == BlessedLoss || wdl == CursedWin {
+			dtz += 100
+		}
+		return dtz * sign(int(wdl)), nil
+	}
+
+	// The table stores the other side to move so the best move is found
+	// with a search of one ply
+	best := 0xffff
+	for _, m := range p.LegalMoves() {
+		next := p.Play(m)
+		var dtz int
+		if isZeroing(p, m) {
+			v, _, err := tb.search(next, false)
+			if err != nil {
+				return 0, err
+			}
+			dtz = -beforeZeroing(v)
+		} else {
+			if dtz, err = tb.ProbeDTZ(next); err != nil {
+				return 0, err
+			}
+			dtz = -dtz
+			// a move that mates is one ply from the end
+			if dtz == 1 && next.InCheck() && len(next.LegalMoves()) == 0 {
+				best = 1
+			}
+			dtz += sign(dtz)
+		}
+		if dtz < best && sign(dtz) == sign(int(wdl)) {
+			best = dtz
+		}
+	}
+	if best == 0xffff {
+		return -1, nil
+	}
+	return best, nil
+}
+
+// mapDTZ converts a value of a DTZ table to plies
+func (t *table) mapDTZ(d *pairsData, value int, wdl WDL) int {
+	// the maps are stored in the order win, loss, cursed win, blessed loss
+	m := map[WDL]int{Win: 0, Loss: 1, CursedWin: 2, BlessedLoss: 3}[wdl]
+	if d.flags&flagMapped != 0 {
+		if d.flags&flagWide != 0 {
+			if i := d.mapIdx[m] + 2*value; i+2 <= len(t.dtzMap) {
+				value = int(binary.LittleEndian.Uint16(t.dtzMap[i:]))
+			}
+		} else if i := d.mapIdx[m] + value; i < len(t.dtzMap) {
+			value = int(t.dtzMap[i])
+		}
+	}
+
+	if (wdl == Win && d.flags&flagWinPlies == 0) || (wdl == Loss && d.flags&flagLossPlies == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
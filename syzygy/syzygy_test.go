@@ -0,0 +1,288 @@
+package syzygy
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+var tables = flag.String("tables", "", "A directory of tables made by the Syzygy generator to probe known positions in")
+
+var (
+	solvedOnce sync.Once
+	solved     map[string]*endgame
+	// largeDir holds the tables of the endgames solved with -large
+	largeDir string
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *large {
+		dir, err := ioutil.TempDir("", "syzygy")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		largeDir = dir
+	}
+
+	code := m.Run()
+	if largeDir != "" {
+		os.RemoveAll(largeDir)
+	}
+	os.Exit(code)
+}
+
+// fixtureEndgames solves the fixtures once for all the tests
+func fixtureEndgames(t *testing.T) map[string]*endgame {
+	t.Helper()
+
+	solvedOnce.Do(func() {
+		solved = solveFixtures(t)
+	})
+	if solved == nil {
+		t.Fatal("The fixtures could not be solved")
+	}
+	return solved
+}
+
+// openFixtures opens the tables in testdata and those of the endgames solved with -large
+func openFixtures(t *testing.T) *Tablebase {
+	t.Helper()
+
+	dirs := []string{"testdata"}
+	if *large {
+		fixtureEndgames(t)
+		dirs = append(dirs, largeDir)
+	}
+	tb, err := Open(dirs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func parse(t *testing.T, fen string) *rules.Position {
+	t.Helper()
+
+	p, err := rules.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// swap returns a position with the colours of the pieces and the side to move swapped
+func swap(t *testing.T, p *rules.Position) *rules.Position {
+	t.Helper()
+
+	fields := strings.Fields(p.FEN())
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	board := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return r
+	}, strings.Join(ranks, "/"))
+	turn := "w"
+	if fields[1] == "w" {
+		turn = "b"
+	}
+	return parse(t, board+" "+turn+" - - 0 1")
+}
+
+func TestMapKK(t *testing.T) {
+	codes := map[int]bool{}
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				if distance(s1, s2) > 1 && !(offA1H8(s1) == 0 && offA1H8(s2) > 0) {
+					codes[mapKK[idx][s2]] = true
+				}
+			}
+		}
+	}
+	if len(codes) != 462 {
+		t.Errorf("Expecting 462 placements of the kings got %v", len(codes))
+	}
+}
+
+// stride is how many positions of an endgame are probed, every position
+// of the small ones and a sample of the larger ones
+func stride(e *endgame) int {
+	if e.layout.pieceCount > quickPieces {
+		return 97
+	}
+	return 1
+}
+
+func TestProbeWDL(t *testing.T) {
+	tb := openFixtures(t)
+	pieces := quickPieces
+	if *large {
+		pieces = 4
+	}
+	if tb.MaxPieces() != pieces {
+		t.Errorf("Expecting tables of up to %v pieces got %v", pieces, tb.MaxPieces())
+	}
+
+	for name, e := range fixtureEndgames(t) {
+		probed := 0
+		for i := 0; i < len(e.nodes); i += stride(e) {
+			n := &e.nodes[i]
+			if !n.used {
+				continue
+			}
+			pos := e.pos(n)
+			wdl, err := tb.ProbeWDL(pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wdl != n.wdl {
+				t.Fatalf("Expecting %v for %v got %v", n.wdl, pos.FEN(), wdl)
+			}
+			// The same position with the colours swapped is in the same table
+			if probed%13 == 0 {
+				swapped := swap(t, pos)
+				if wdl, err := tb.ProbeWDL(swapped); err != nil || wdl != n.wdl {
+					t.Fatalf("Expecting %v for %v got %v %v", n.wdl, swapped.FEN(), wdl, err)
+				}
+			}
+			probed++
+		}
+		t.Logf("Probed %v positions of %v", probed, name)
+	}
+}
+
+func TestProbeDTZ(t *testing.T) {
+	tb := openFixtures(t)
+
+	for _, e := range fixtureEndgames(t) {
+		for i := 0; i < len(e.nodes); i += 7 * stride(e) {
+			n := &e.nodes[i]
+			if !n.used {
+				continue
+			}
+			pos := e.pos(n)
+			dtz, err := tb.ProbeDTZ(pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.wdl == Draw && dtz != 0 || n.wdl != Draw && dtz != n.dtz {
+				t.Fatalf("Expecting a distance of %v for %v got %v", n.dtz, pos.FEN(), dtz)
+			}
+		}
+	}
+}
+
+// known are positions with results from endgame theory, dtz is the
+// distance to a zeroing move in plies
+var known = []struct {
+	fen string
+	wdl WDL
+	dtz int
+}{
+	{"4k3/8/4K3/8/4P3/8/8/8 w - - 0 1", Win, 1},
+	{"1k6/8/K7/P7/8/8/8/8 w - - 0 1", Draw, 0},
+	{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Draw, 0},
+	{"k7/8/1K6/8/8/8/7Q/8 w - - 0 1", Win, 1},
+	{"k6Q/8/1K6/8/8/8/8/8 b - - 0 1", Loss, -1},
+	{"k7/8/1K6/8/8/8/8/7R w - - 0 1", Win, 1},
+	{"k7/1R6/2K5/8/8/8/8/8 b - - 0 1", Draw, 0},
+	{"k7/1R6/8/8/8/8/8/7K b - - 0 1", Draw, 0},
+	{"8/8/8/8/8/8/8/4kn1K b - - 0 1", Draw, 0},
+	{"7K/8/8/8/8/8/8/2k5 b - - 0 1", Draw, 0},
+	// Two knights mate but can not force it
+	{"k7/2NN4/1K6/8/8/8/8/8 b - - 0 1", Loss, -1},
+	{"k7/3N4/1K6/3N4/8/8/8/8 w - - 0 1", Win, 1},
+	{"8/8/8/4k3/8/8/8/1NN1K3 w - - 0 1", Draw, 0},
+	// Bishop and knight mate in at most 33 moves
+	{"k7/3N4/1K6/3B4/8/8/8/8 b - - 0 1", Loss, -1},
+	{"k7/3N4/1K2B3/8/8/8/8/8 w - - 0 1", Win, 1},
+	{"8/8/8/8/8/8/3B4/K2k3N w - - 0 1", Win, 65},
+	{"8/8/8/8/8/2k5/2B5/K6N b - - 0 1", Draw, 0},
+}
+
+// probeKnown probes the known positions with no more pieces than the
+// largest table. A distance may be a ply longer than the exact one by up
+// to slack as tables can store it in moves.
+func probeKnown(t *testing.T, tb *Tablebase, slack int) {
+	t.Helper()
+
+	for _, test := range known {
+		p := parse(t, test.fen)
+		if Pieces(p) > tb.MaxPieces() {
+			continue
+		}
+		// The same position with the colours swapped has the same result
+		for _, p := range []*rules.Position{p, swap(t, p)} {
+			wdl, err := tb.ProbeWDL(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wdl != test.wdl {
+				t.Errorf("Expecting %v for %v got %v", test.wdl, p.FEN(), wdl)
+			}
+			dtz, err := tb.ProbeDTZ(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			longer := dtz - test.dtz
+			if test.dtz < 0 {
+				longer = -longer
+			}
+			if longer < 0 || longer > slack || test.dtz == 0 && dtz != 0 {
+				t.Errorf("Expecting a distance of %v for %v got %v", test.dtz, p.FEN(), dtz)
+			}
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	probeKnown(t, openFixtures(t), 0)
+}
+
+// TestGeneratorTables probes the known positions in tables made by the
+// Syzygy generator, which are too large to check in
+func TestGeneratorTables(t *testing.T) {
+	if *tables == "" {
+		t.Skip("Run with -tables set to a directory of Syzygy tables")
+	}
+	tb, err := Open(*tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.MaxPieces() < 3 {
+		t.Fatalf("Expecting tables in %v", *tables)
+	}
+	probeKnown(t, tb, 1)
+}
+
+func TestErrors(t *testing.T) {
+	tb := openFixtures(t)
+
+	if _, err := tb.ProbeWDL(parse(t, "4k3/8/8/8/8/8/8/R3K3 w Q - 0 1")); err == nil {
+		t.Error("Expecting an error for a position with castling rights")
+	}
+	if _, err := tb.ProbeWDL(parse(t, "4k3/8/8/8/8/8/8/R3K2R w - - 0 1")); err == nil {
+		t.Error("Expecting an error for a position without a table")
+	}
+	if _, err := Open("missing"); err == nil {
+		t.Error("Expecting an error for a missing directory")
+	}
+}
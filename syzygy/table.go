@@ -0,0 +1,502 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// The magic numbers at the start of WDL and DTZ files
+var (
+	wdlMagic = []byte{0x71, 0xe8, 0x23, 0x5d}
+	dtzMagic = []byte{0xd7, 0x66, 0x0c, 0xa5}
+)
+
+// The flags of the values of a table
+const (
+	// flagSTM is the side to move of a DTZ table
+	flagSTM = 1
+	// flagMapped is set when DTZ values are looked up in a map
+	flagMapped = 2
+	// flagWinPlies is set when DTZ values of wins are in plies rather than moves
+	flagWinPlies = 4
+	// flagLossPlies is set when DTZ values of losses are in plies rather than moves
+	flagLossPlies = 8
+	// flagWide is set when the DTZ map holds 16 bit values
+	flagWide = 16
+	// flagSingleValue is set when every position of a table has the same value
+	flagSingleValue = 128
+)
+
+// pairsData is the compressed values of a table for a side to move and file
+// of the leading pawn
+type pairsData struct {
+	flags     byte
+	maxSymLen int
+	minSymLen int
+	// the values are stored in blocks of blockSize bytes with an entry in
+	// the sparse index about every span values
+	blockSize       int
+	span            uint64
+	numBlocks       int
+	blockLengthSize int
+	sparseIndexSize int
+	// the canonical Huffman code, base64[l] is the lowest code of length
+	// l+minSymLen padded to 64 bits and lowestSym[l] its symbol
+	lowestSym []byte
+	base64    []uint64
+	// btree[sym] holds the two symbols a symbol expands to
+	btree []byte
+	// symlen[sym] is the number of values less one a symbol expands to
+	symlen []int
+
+	sparseIndex []byte
+	blockLength []byte
+	data        []byte
+
+	// the pieces in the order they are encoded and the groups they form
+	pieces   [7]int
+	groupIdx [8]uint64
+	groupLen [8]int
+	// the offsets of the values of wins, losses, cursed wins and blessed
+	// losses in the DTZ map
+	mapIdx [4]int
+}
+
+// table is a WDL or DTZ table file, it is read when first probed
+type table struct {
+	path string
+	dtz  bool
+	// the names of the table eg. KRvK and KvKR
+	key, key2 string
+
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	// the number of pawns of the leading colour and of the other colour
+	pawnCount [2]int
+
+	once   sync.Once
+	err    error
+	items  [2][4]pairsData
+	dtzMap []byte
+}
+
+// newTable creates a table from the name of its file eg. KRvK
+func newTable(path, name string, dtz bool) *table {
+	sides := strings.SplitN(name, "v", 2)
+	t := &table{
+		path:       path,
+		dtz:        dtz,
+		key:        name,
+		key2:       sides[1] + "v" + sides[0],
+		pieceCount: len(name) - 1,
+	}
+
+	white, black := strings.Count(sides[0], "P"), strings.Count(sides[1], "P")
+	t.hasPawns = white+black > 0
+	for _, side := range sides {
+		for _, piece := range "QRBNP" {
+			if strings.Count(side, string(piece)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+	// The leading colour is the side with fewer pawns
+	if black == 0 || (white > 0 && black >= white) {
+		t.pawnCount = [2]int{white, black}
+	} else {
+		t.pawnCount = [2]int{black, white}
+	}
+	return t
+}
+
+func (t *table) get(stm, f int) *pairsData {
+	sides := 1
+	if !t.dtz && t.key != t.key2 {
+		sides = 2
+	}
+	if !t.hasPawns {
+		f = 0
+	}
+	return &t.items[stm%sides][f]
+}
+
+// load reads the table file the first time it is needed
+func (t *table) load() error {
+	t.once.Do(func() {
+		data, err := ioutil.ReadFile(t.path)
+		if err != nil {
+			t.err = err
+			return
+		}
+		if err := t.parse(data); err != nil {
+			t.err = fmt.Errorf("Invalid table %v: %v", t.path, err)
+		}
+	})
+	return t.err
+}
+
+// reader reads the little endian values of a table file recording the first read past the end
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("Unexpected end of file at %v", r.pos)
+		}
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) byte() byte { return r.next(1)[0] }
+
+func (r *reader) uint16() int { return int(binary.LittleEndian.Uint16(r.next(2))) }
+
+func (r *reader) uint32() int { return int(binary.LittleEndian.Uint32(r.next(4))) }
+
+// align moves to the next multiple of n bytes from the start of the file
+func (r *reader) align(n int) {
+	if rem := r.pos % n; rem != 0 {
+		r.next(n - rem)
+	}
+}
+
+// parse reads the layout of a table file
+func (t *table) parse(data []byte) error {
+	magic := wdlMagic
+	if t.dtz {
+		magic = dtzMagic
+	}
+	if len(data) < 4 || string(data[:4]) != string(magic) {
+		return fmt.Errorf("Unexpected magic number")
+	}
+	r := &reader{data: data, pos: 4}
+
+	const split, hasPawns = 1, 2
+	flags := r.byte()
+	if (flags&hasPawns != 0) != t.hasPawns || (flags&split != 0) != (t.key != t.key2) {
+		return fmt.Errorf("The table does not match its name")
+	}
+
+	sides := 1
+	if !t.dtz && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	for f := 0; f <= maxFile; f++ {
+		b := r.byte()
+		order := [2][2]int{{int(b & 0xf), 0xf}, {int(b >> 4), 0xf}}
+		if pp {
+			b := r.byte()
+			order[0][1], order[1][1] = int(b&0xf), int(b>>4)
+		}
+		for k := 0; k < t.pieceCount; k++ {
+			b := r.byte()
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.get(i, f).pieces[k] = int(b & 0xf)
+				} else {
+					t.get(i, f).pieces[k] = int(b >> 4)
+				}
+			}
+		}
+		for i := 0; i < sides; i++ {
+			if err := t.setGroups(t.get(i, f), order[i], f); err != nil {
+				return err
+			}
+		}
+	}
+	r.align(2)
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.get(i, f).setSizes(r)
+		}
+	}
+	if t.dtz {
+		t.setDTZMap(r, maxFile)
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.sparseIndex = r.next(6 * d.sparseIndexSize)
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.blockLength = r.next(2 * d.blockLengthSize)
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			r.align(64)
+			d.data = r.next(d.numBlocks * d.blockSize)
+		}
+	}
+	return r.err
+}
+
+// setGroups splits the pieces into the groups they are encoded in and sets
+// the index each group starts from. The order of the groups is stored per
+// table, order[0] is the leading group and order[1] the remaining pawns.
+func (t *table) setGroups(d *pairsData, order [2]int, f int) error {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	if pp {
+		next = 2
+	}
+	free := 64 - d.groupLen[0]
+	if pp {
+		free -= d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k > 16 {
+			return fmt.Errorf("Invalid group order")
+		}
+		switch {
+		case k == order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= leadPawnsSize[d.groupLen[0]][f]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][free]
+			free -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+	return nil
+}
+
+// size returns the number of indices of the table
+func (d *pairsData) size() uint64 {
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	return d.groupIdx[n]
+}
+
+// setSizes reads the block sizes and Huffman code of the values
+func (d *pairsData) setSizes(r *reader) {
+	d.flags = r.byte()
+	if d.flags&flagSingleValue != 0 {
+		d.minSymLen = int(r.byte())
+		return
+	}
+
+	d.blockSize = 1 << r.byte()
+	d.span = 1 << r.byte()
+	d.sparseIndexSize = int((d.size() + d.span - 1) / d.span)
+	padding := int(r.byte())
+	d.numBlocks = r.uint32()
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(r.byte())
+	d.minSymLen = int(r.byte())
+	if d.maxSymLen < d.minSymLen || d.maxSymLen > 64 {
+		r.err = fmt.Errorf("Invalid symbol lengths %v to %v", d.minSymLen, d.maxSymLen)
+		return
+	}
+
+	lengths := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = r.next(2 * lengths)
+	lowest := func(i int) uint64 { return uint64(binary.LittleEndian.Uint16(d.lowestSym[2*i:])) }
+	d.base64 = make([]uint64, lengths)
+	for i := lengths - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + lowest(i) - lowest(i+1)) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+
+	symbols := r.uint16()
+	d.btree = r.next(3 * symbols)
+	d.symlen = make([]int, symbols)
+	visited := make([]bool, symbols)
+	for sym := 0; sym < symbols; sym++ {
+		if !visited[sym] {
+			d.symlen[sym] = d.setSymlen(sym, visited)
+		}
+	}
+	r.next(symbols & 1)
+}
+
+// left and right return the symbols a symbol expands to
+func (d *pairsData) left(sym int) int {
+	return int(d.btree[3*sym+1]&0xf)<<8 | int(d.btree[3*sym])
+}
+
+func (d *pairsData) right(sym int) int {
+	return int(d.btree[3*sym+2])<<4 | int(d.btree[3*sym+1]>>4)
+}
+
+func (d *pairsData) setSymlen(sym int, visited []bool) int {
+	visited[sym] = true
+	right := d.right(sym)
+	if right == 0xfff {
+		return 0
+	}
+	left := d.left(sym)
+	if left >= len(d.symlen) || right >= len(d.symlen) {
+		return 0
+	}
+	if !visited[left] {
+		d.symlen[left] = d.setSymlen(left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = d.setSymlen(right, visited)
+	}
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+// setDTZMap reads the maps of DTZ values
+func (t *table) setDTZMap(r *reader, maxFile int) {
+	start := r.pos
+	for f := 0; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&flagMapped == 0 {
+			continue
+		}
+		if d.flags&flagWide != 0 {
+			r.align(2)
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = r.pos - start + 2
+				r.next(2 * r.uint16())
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = r.pos - start + 1
+				r.next(int(r.byte()))
+			}
+		}
+	}
+	t.dtzMap = r.data[start:r.pos]
+	r.align(2)
+}
+
+// value returns the value stored for an index
+func (d *pairsData) value(idx uint64) (int, error) {
+	if d.flags&flagSingleValue != 0 {
+		return d.minSymLen, nil
+	}
+	if idx >= d.size() {
+		return 0, fmt.Errorf("Index %v out of range", idx)
+	}
+
+	k := int(idx / d.span)
+	block := int(binary.LittleEndian.Uint32(d.sparseIndex[6*k:]))
+	offset := int(binary.LittleEndian.Uint16(d.sparseIndex[6*k+4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	length := func(block int) int {
+		return int(binary.LittleEndian.Uint16(d.blockLength[2*block:]))
+	}
+	for offset < 0 {
+		block--
+		if block < 0 {
+			return 0, fmt.Errorf("Index %v before the first block", idx)
+		}
+		offset += length(block) + 1
+	}
+	for offset > length(block) {
+		offset -= length(block) + 1
+		block++
+		if block >= d.numBlocks {
+			return 0, fmt.Errorf("Index %v after the last block", idx)
+		}
+	}
+
+	data := d.data[block*d.blockSize:]
+	word := func(i int) uint64 {
+		if i+4 > len(data) {
+			return 0
+		}
+		return uint64(binary.BigEndian.Uint32(data[i:]))
+	}
+	buf := word(0)<<32 | word(4)
+	pos, bits := 8, 64
+
+	var sym int
+	for {
+		l := 0
+		for l < len(d.base64)-1 && buf < d.base64[l] {
+			l++
+		}
+		sym = int((buf - d.base64[l]) >> uint(64-l-d.minSymLen))
+		sym += int(binary.LittleEndian.Uint16(d.lowestSym[2*l:]))
+		if sym >= len(d.symlen) {
+			return 0, fmt.Errorf("Invalid symbol %v", sym)
+		}
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		l += d.minSymLen
+		buf <<= uint(l)
+		bits -= l
+		if bits <= 32 {
+			bits += 32
+			buf |= word(pos) << uint(64-bits)
+			pos += 4
+		}
+	}
+
+	for d.symlen[sym] != 0 {
+		left := d.left(sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = d.right(sym)
+		}
+	}
+	return d.left(sym), nil
+}
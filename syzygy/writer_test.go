@@ -0,0 +1,930 @@
+package syzygy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/schafer14/grpc-chess/rules"
+)
+
+var (
+	update = flag.Bool("update", false, "Rewrite the tables in testdata")
+	large  = flag.Bool("large", false, "Also solve the 4 piece endgames, which takes minutes")
+)
+
+// The tables in testdata are written by these tests from a retrograde
+// analysis of each endgame, the prober is checked against the analysis and
+// TestGeneratorTables checks it against tables made by the Syzygy generator.
+// dtzSide is the side to move the DTZ table stores and dtzFlags how its
+// values are stored. longest is the longest win in plies known from endgame
+// theory, which checks the analysis, or 0 when it is not checked.
+var fixtures = []struct {
+	name     string
+	dtzSide  int
+	dtzFlags byte
+	longest  int
+}{
+	{"KNvK", 0, 0, 0},
+	{"KBvK", 0, 0, 0},
+	// mate in 10
+	{"KQvK", 0, flagWinPlies | flagLossPlies, 19},
+	// mate in 16
+	{"KRvK", 1, flagMapped | flagWide | flagWinPlies | flagLossPlies, 31},
+	{"KPvK", 1, flagMapped | flagWinPlies | flagLossPlies, 0},
+	{"KNNvK", 0, flagWinPlies | flagLossPlies, 0},
+	// mate in 33
+	{"KBNvK", 0, flagMapped | flagWinPlies | flagLossPlies, 65},
+}
+
+// quickPieces is the most pieces of the endgames solved on every run and
+// kept in testdata, the larger ones are only solved with -large and their
+// tables are written to largeDir
+const quickPieces = 3
+
+// edge is a move from a position of an endgame
+type edge struct {
+	// the node after the move in the same endgame, -1 when the move
+	// changes the endgame
+	next int32
+	// the result after a move changing the endgame for the side to move then
+	wdl     int8
+	zeroing bool
+}
+
+// node is a position of an endgame standing for all the positions with its index
+type node struct {
+	// the squares of the pieces in the order of the layout
+	squares [7]int8
+	stm     int8
+	file    int8
+	// used is set when a legal position has the index of the node
+	used   bool
+	mated  bool
+	solved bool
+	hasDTZ bool
+	wdl    WDL
+	dtz    int
+	moves  []edge
+}
+
+// endgame is the solution of each position of an endgame. The nodes of the
+// values of each side to move and file follow each other in order of index.
+type endgame struct {
+	layout *table
+	first  map[*pairsData]int
+	nodes  []node
+}
+
+// layout creates a table with pieces in the order of the name and groups
+// following the generator
+func layout(name string, dtz bool) *table {
+	t := newTable(name, name, dtz)
+	sides := strings.SplitN(name, "v", 2)
+	var pieces []int
+	add := func(side string, c rules.Color) {
+		for _, char := range side {
+			pt := rules.PieceType(6 - strings.IndexRune(pieceOrder, char))
+			pieces = append(pieces, int(rules.NewPiece(c, pt)))
+		}
+	}
+	add(sides[0], rules.White)
+	add(sides[1], rules.Black)
+	// Pawns lead, otherwise a unique piece or without one the kings
+	lead := []int{}
+	rest := []int{}
+	for _, p := range pieces {
+		if p&7 == int(rules.Pawn) && p>>3 == 0 {
+			lead = append(lead, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	if !t.hasPawns {
+		lead, rest = nil, nil
+		for _, p := range pieces {
+			switch {
+			case t.hasUniquePieces && p&7 != int(rules.King) && len(lead) == 0:
+				lead = append(lead, p)
+			case !t.hasUniquePieces && p&7 == int(rules.King):
+				lead = append(lead, p)
+			default:
+				rest = append(rest, p)
+			}
+		}
+	}
+	pieces = append(lead, rest...)
+
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < 2; i++ {
+			d := t.get(i, f)
+			copy(d.pieces[:], pieces)
+			if err := t.setGroups(d, [2]int{0, 0xf}, f); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return t
+}
+
+// place calls f with every legal placement of the pieces with the leading
+// piece in the part of the board the encoding maps positions to
+func place(t *table, f func(p *rules.Position)) {
+	pieces := t.get(0, 0).pieces[:t.pieceCount]
+	var leads []int
+	for sq := 0; sq < 64; sq++ {
+		if t.hasPawns && rank(sq) >= 1 && rank(sq) <= 6 && file(sq) <= 3 {
+			leads = append(leads, sq)
+		}
+		if !t.hasPawns && sq <= 27 && file(sq) <= 3 && offA1H8(sq) <= 0 {
+			leads = append(leads, sq)
+		}
+	}
+
+	var board [64]rules.Piece
+	var put func(i int)
+	put = func(i int) {
+		if i == len(pieces) {
+			for _, turn := range []string{"w", "b"} {
+				if p, err := rules.ParseFEN(fen(board) + " " + turn + " - - 0 1"); err == nil {
+					f(p)
+				}
+			}
+			return
+		}
+		squares := leads
+		if i > 0 {
+			squares = nil
+			for sq := 0; sq < 64; sq++ {
+				squares = append(squares, sq)
+			}
+		}
+		for _, sq := range squares {
+			if board[sq] != rules.NoPiece {
+				continue
+			}
+			board[sq] = rules.Piece(pieces[i])
+			put(i + 1)
+			board[sq] = rules.NoPiece
+		}
+	}
+	put(0)
+}
+
+func fen(board [64]rules.Piece) string {
+	var b strings.Builder
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < 8; f++ {
+			piece := board[r*8+f]
+			if piece == rules.NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprint(&b, empty)
+				empty = 0
+			}
+			b.WriteString(piece.String())
+		}
+		if empty > 0 {
+			fmt.Fprint(&b, empty)
+		}
+		if r > 0 {
+			b.WriteByte('/')
+		}
+	}
+	return b.String()
+}
+
+// solve finds the result and distance to zeroing of every position of an
+// endgame, the endgames its captures and promotions lead to must be solved
+func solve(name string, solved map[string]*endgame) (*endgame, error) {
+	e := &endgame{layout: layout(name, false), first: map[*pairsData]int{}}
+	maxFile := 0
+	if e.layout.hasPawns {
+		maxFile = 3
+	}
+	size := 0
+	for f := 0; f <= maxFile; f++ {
+		for stm := 0; stm < 2; stm++ {
+			if d := e.layout.get(stm, f); d.size() > 0 {
+				if _, ok := e.first[d]; !ok {
+					e.first[d] = size
+					size += int(d.size())
+				}
+			}
+		}
+	}
+	e.nodes = make([]node, size)
+	pieces := e.layout.get(0, 0).pieces[:e.layout.pieceCount]
+
+	var err error
+	place(e.layout, func(p *rules.Position) {
+		i, d := e.number(p)
+		n := &e.nodes[i]
+		if n.used || err != nil {
+			return
+		}
+		n.used, n.stm = true, int8(p.Turn())
+		for e.layout.get(int(n.stm), int(n.file)) != d {
+			n.file++
+		}
+		var taken uint64
+		for k, piece := range pieces {
+			for sq := 0; sq < 64; sq++ {
+				if int(p.Piece(rules.Square(sq))) == piece && taken&(1<<uint(sq)) == 0 {
+					n.squares[k] = int8(sq)
+					taken |= 1 << uint(sq)
+					break
+				}
+			}
+		}
+
+		moves := p.LegalMoves()
+		n.moves = make([]edge, 0, len(moves))
+		for _, m := range moves {
+			next := p.Play(m)
+			ed := edge{next: -1, zeroing: isZeroing(p, m)}
+			white, black := material(next)
+			switch {
+			case white+"v"+black == name:
+				j, _ := e.number(next)
+				ed.next = int32(j)
+			case black == "K" && (white == "K" || white == "KN" || white == "KB"):
+				ed.wdl = int8(Draw)
+			default:
+				other, ok := solved[white+"v"+black]
+				if !ok {
+					err = fmt.Errorf("Expecting %vv%v to be solved", white, black)
+					return
+				}
+				ed.wdl = int8(other.result(next))
+			}
+			n.moves = append(n.moves, ed)
+		}
+		if len(moves) == 0 {
+			n.solved = true
+			n.mated = p.InCheck()
+			if n.mated {
+				n.wdl, n.dtz = Loss, -1
+			}
+			n.hasDTZ = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range e.nodes {
+		for _, ed := range e.nodes[i].moves {
+			if ed.next >= 0 && !e.nodes[ed.next].used {
+				return nil, fmt.Errorf("No position with the index of a move from %v", e.pos(&e.nodes[i]).FEN())
+			}
+		}
+	}
+
+	// The result of each position
+	value := func(ed edge) (WDL, bool) {
+		if ed.next < 0 {
+			return WDL(ed.wdl), true
+		}
+		return e.nodes[ed.next].wdl, e.nodes[ed.next].solved
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := range e.nodes {
+			n := &e.nodes[i]
+			if !n.used || n.solved {
+				continue
+			}
+			lost := true
+			for _, ed := range n.moves {
+				v, ok := value(ed)
+				if ok && v == Loss {
+					n.wdl, n.solved, changed = Win, true, true
+					break
+				}
+				lost = lost && ok && v == Win
+			}
+			if !n.solved && lost {
+				n.wdl, n.solved, changed = Loss, true, true
+			}
+		}
+	}
+	pending := 0
+	for i := range e.nodes {
+		n := &e.nodes[i]
+		if !n.used {
+			continue
+		}
+		if !n.solved {
+			n.wdl, n.solved = Draw, true
+		}
+		if n.wdl == Draw {
+			n.hasDTZ = true
+		}
+		if !n.hasDTZ {
+			pending++
+		}
+	}
+
+	// The distance to zeroing of each position by increasing distance
+	for k := 1; pending > 0; k++ {
+		if k > 200 {
+			return nil, fmt.Errorf("No distance to zeroing for %v positions", pending)
+		}
+		for i := range e.nodes {
+			n := &e.nodes[i]
+			if !n.used || n.hasDTZ || n.wdl != Win {
+				continue
+			}
+			for _, ed := range n.moves {
+				if v, _ := value(ed); v != Loss {
+					continue
+				}
+				quick := ed.zeroing || e.nodes[ed.next].mated
+				if (quick && k == 1) || (!quick && e.nodes[ed.next].hasDTZ && -e.nodes[ed.next].dtz == k-1) {
+					n.dtz, n.hasDTZ = k, true
+					pending--
+					break
+				}
+			}
+		}
+		for i := range e.nodes {
+			n := &e.nodes[i]
+			if !n.used || n.hasDTZ || n.wdl != Loss {
+				continue
+			}
+			worst, all := 0, true
+			for _, ed := range n.moves {
+				c := 1
+				if !ed.zeroing {
+					if !e.nodes[ed.next].hasDTZ {
+						all = false
+						break
+					}
+					c = e.nodes[ed.next].dtz + 1
+				}
+				if c > worst {
+					worst = c
+				}
+			}
+			if all {
+				n.dtz, n.hasDTZ = -worst, true
+				pending--
+			}
+		}
+	}
+	return e, nil
+}
+
+// number returns the number of the node standing for a position and the
+// values it is stored in
+func (e *endgame) number(p *rules.Position) (int, *pairsData) {
+	d, idx, _ := e.layout.index(p, false)
+	return e.first[d] + int(idx), d
+}
+
+// node returns the solved position standing for a position of the endgame
+func (e *endgame) node(p *rules.Position) *node {
+	i, _ := e.number(p)
+	return &e.nodes[i]
+}
+
+func (e *endgame) result(p *rules.Position) WDL {
+	return e.node(p).wdl
+}
+
+// pos returns the position a node was solved from
+func (e *endgame) pos(n *node) *rules.Position {
+	var board [64]rules.Piece
+	for k, piece := range e.layout.get(0, 0).pieces[:e.layout.pieceCount] {
+		board[n.squares[k]] = rules.Piece(piece)
+	}
+	turn := "w"
+	if n.stm == int8(rules.Black) {
+		turn = "b"
+	}
+	p, err := rules.ParseFEN(fen(board) + " " + turn + " - - 0 1")
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// longest returns the longest distance to zeroing of the wins of an endgame
+func (e *endgame) longest() (int, *node) {
+	var best *node
+	for i := range e.nodes {
+		if n := &e.nodes[i]; n.used && n.wdl == Win && (best == nil || n.dtz > best.dtz) {
+			best = n
+		}
+	}
+	if best == nil {
+		return 0, nil
+	}
+	return best.dtz, best
+}
+
+// The limits of the symbols compress makes
+const (
+	// maxSymbols keeps counting the pairs of symbols quick
+	maxSymbols = 512
+	// maxExpansion is the most values a symbol stands for
+	maxExpansion = 1024
+	// minPairs is how often a pair is seen before it gets a symbol
+	minPairs = 8
+)
+
+// symbol is a value, with 0xfff on the right, or a pair of symbols
+type symbol struct {
+	left, right int
+	values      int
+}
+
+// compress stores values the way the generator does. The most frequent pair
+// of adjacent symbols is replaced by a new symbol until pairs are rare and
+// the symbols are written with a canonical Huffman code in blocks.
+func compress(flags byte, values []int) (header, sparse, lengths, data []byte) {
+	max, single := 0, true
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+		single = single && v == values[0]
+	}
+	if single {
+		return []byte{flags | flagSingleValue, byte(values[0])}, nil, nil, nil
+	}
+
+	symbols := make([]symbol, max+1)
+	for v := range symbols {
+		symbols[v] = symbol{v, 0xfff, 1}
+	}
+	seq := append([]int(nil), values...)
+	counts := make([]int, maxSymbols*maxSymbols)
+	for len(symbols) < maxSymbols {
+		for i := range counts {
+			counts[i] = 0
+		}
+		best := -1
+		for i := 0; i+1 < len(seq); i++ {
+			a, b := seq[i], seq[i+1]
+			k := a*maxSymbols + b
+			counts[k]++
+			if (best < 0 || counts[k] > counts[best]) && symbols[a].values+symbols[b].values <= maxExpansion {
+				best = k
+			}
+		}
+		if best < 0 || counts[best] < minPairs {
+			break
+		}
+		a, b := best/maxSymbols, best%maxSymbols
+		symbols = append(symbols, symbol{a, b, symbols[a].values + symbols[b].values})
+		pair := len(symbols) - 1
+		out := seq[:0]
+		for i := 0; i < len(seq); i++ {
+			if i+1 < len(seq) && seq[i] == a && seq[i+1] == b {
+				out = append(out, pair)
+				i++
+			} else {
+				out = append(out, seq[i])
+			}
+		}
+		seq = out
+	}
+
+	// Symbols are numbered from the longest code to the shortest, the
+	// symbols without a code come last
+	freq := make([]int, len(symbols))
+	for _, s := range seq {
+		freq[s]++
+	}
+	codeLen := huffman(freq)
+	order := make([]int, len(symbols))
+	for s := range order {
+		order[s] = s
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := codeLen[order[i]], codeLen[order[j]]
+		return a != 0 && (b == 0 || a > b)
+	})
+	number := make([]int, len(symbols))
+	minLen, maxLen := 64, 0
+	for i, s := range order {
+		number[s] = i
+		if l := codeLen[s]; l > 0 {
+			if l < minLen {
+				minLen = l
+			}
+			if l > maxLen {
+				maxLen = l
+			}
+		}
+	}
+	if maxLen > 32 {
+		panic(fmt.Sprintf("A code of %v bits is too long", maxLen))
+	}
+
+	// lowest[i] is the number of the first symbol with a code of minLen+i
+	// bits and base[i] its code
+	lowest := make([]int, maxLen-minLen+1)
+	base := make([]uint64, len(lowest))
+	for i := range lowest {
+		for _, l := range codeLen {
+			if l > minLen+i {
+				lowest[i]++
+			}
+		}
+	}
+	for i := len(base) - 2; i >= 0; i-- {
+		base[i] = (base[i+1] + uint64(lowest[i]-lowest[i+1])) / 2
+	}
+	code := func(s int) uint64 {
+		i := codeLen[s] - minLen
+		return base[i] + uint64(number[s]-lowest[i])
+	}
+
+	// Each block holds whole symbols
+	const blockBits, spanBits, padding = 6, 10, 1
+	blockSize, span := 1<<blockBits, 1<<spanBits
+	var blockValues []int
+	block, bits, n := make([]byte, blockSize), 0, 0
+	flush := func() {
+		data = append(data, block...)
+		blockValues = append(blockValues, n)
+		block, bits, n = make([]byte, blockSize), 0, 0
+	}
+	for _, s := range seq {
+		l := codeLen[s]
+		if bits+l > blockSize*8 || n+symbols[s].values > 1<<16 {
+			flush()
+		}
+		c := code(s)
+		for j := 0; j < l; j++ {
+			if c&(1<<uint(l-1-j)) != 0 {
+				block[(bits+j)/8] |= 0x80 >> uint((bits+j)%8)
+			}
+		}
+		bits += l
+		n += symbols[s].values
+	}
+	flush()
+
+	var h bytes.Buffer
+	h.Write([]byte{flags, blockBits, spanBits, padding})
+	binary.Write(&h, binary.LittleEndian, uint32(len(blockValues)))
+	h.Write([]byte{byte(maxLen), byte(minLen)})
+	for _, l := range lowest {
+		binary.Write(&h, binary.LittleEndian, uint16(l))
+	}
+	binary.Write(&h, binary.LittleEndian, uint16(len(symbols)))
+	for _, s := range order {
+		left, right := symbols[s].left, symbols[s].right
+		if right != 0xfff {
+			left, right = number[left], number[right]
+		}
+		h.Write([]byte{byte(left), byte(left>>8)&0xf | byte(right&0xf)<<4, byte(right >> 4)})
+	}
+	if len(symbols)&1 != 0 {
+		h.WriteByte(0)
+	}
+
+	var s bytes.Buffer
+	for k, b, start := 0, 0, 0; k*span < len(values); k++ {
+		q := k*span + span/2
+		for b < len(blockValues)-1 && q >= start+blockValues[b] {
+			start += blockValues[b]
+			b++
+		}
+		binary.Write(&s, binary.LittleEndian, uint32(b))
+		binary.Write(&s, binary.LittleEndian, uint16(q-start))
+	}
+
+	var l bytes.Buffer
+	for b := 0; b < len(blockValues)+padding; b++ {
+		n := 1
+		if b < len(blockValues) {
+			n = blockValues[b]
+		}
+		binary.Write(&l, binary.LittleEndian, uint16(n-1))
+	}
+	return h.Bytes(), s.Bytes(), l.Bytes(), data
+}
+
+// huffman returns the length of the code of each symbol, 0 for symbols
+// that are not used
+func huffman(freq []int) []int {
+	type tree struct {
+		weight  int
+		symbols []int
+	}
+	var trees []tree
+	for s, f := range freq {
+		if f > 0 {
+			trees = append(trees, tree{f, []int{s}})
+		}
+	}
+	// a code has at least two symbols
+	if len(trees) == 1 {
+		other := 0
+		if trees[0].symbols[0] == 0 {
+			other = 1
+		}
+		trees = append(trees, tree{0, []int{other}})
+	}
+
+	lengths := make([]int, len(freq))
+	for len(trees) > 1 {
+		sort.SliceStable(trees, func(i, j int) bool { return trees[i].weight < trees[j].weight })
+		merged := tree{trees[0].weight + trees[1].weight, append(append([]int(nil), trees[0].symbols...), trees[1].symbols...)}
+		for _, s := range merged.symbols {
+			lengths[s]++
+		}
+		trees = append(trees[2:], merged)
+	}
+	return lengths
+}
+
+// writeTable lays out the compressed values of each side to move and file
+func writeTable(t *table, values func(stm, f int) []int, flags func(f int) byte, dtzMap func(f int) [4][]int) []byte {
+	var b bytes.Buffer
+	if t.dtz {
+		b.Write(dtzMagic)
+	} else {
+		b.Write(wdlMagic)
+	}
+	var tableFlags byte
+	if t.key != t.key2 {
+		tableFlags |= 1
+	}
+	if t.hasPawns {
+		tableFlags |= 2
+	}
+	b.WriteByte(tableFlags)
+
+	sides := 1
+	if !t.dtz && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	for f := 0; f <= maxFile; f++ {
+		b.WriteByte(0)
+		if pp {
+			b.WriteByte(0x11)
+		}
+		for k := 0; k < t.pieceCount; k++ {
+			p := byte(t.get(0, f).pieces[k])
+			b.WriteByte(p | p<<4)
+		}
+	}
+	align := func(n int) {
+		for b.Len()%n != 0 {
+			b.WriteByte(0)
+		}
+	}
+	align(2)
+
+	type part struct{ header, sparse, lengths, data []byte }
+	var parts []part
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			h, s, l, d := compress(flags(f), values(i, f))
+			parts = append(parts, part{h, s, l, d})
+			b.Write(h)
+		}
+	}
+	if t.dtz {
+		for f := 0; f <= maxFile; f++ {
+			if flags(f)&flagMapped == 0 {
+				continue
+			}
+			wide := flags(f)&flagWide != 0
+			if wide {
+				align(2)
+			}
+			for _, list := range dtzMap(f) {
+				if wide {
+					binary.Write(&b, binary.LittleEndian, uint16(len(list)))
+					for _, v := range list {
+						binary.Write(&b, binary.LittleEndian, uint16(v))
+					}
+				} else {
+					b.WriteByte(byte(len(list)))
+					for _, v := range list {
+						b.WriteByte(byte(v))
+					}
+				}
+			}
+		}
+		align(2)
+	}
+	for _, p := range parts {
+		b.Write(p.sparse)
+	}
+	for _, p := range parts {
+		b.Write(p.lengths)
+	}
+	for _, p := range parts {
+		align(64)
+		b.Write(p.data)
+	}
+	return b.Bytes()
+}
+
+// write returns the WDL and DTZ files of a solved endgame
+func (e *endgame) write(dtzSide int, dtzFlags byte) (wdl, dtz []byte) {
+	sizes := func(t *table, stm, f int) []int {
+		return make([]int, t.get(stm, f).size())
+	}
+
+	// idx returns the index of a node and the values it is stored in
+	idx := func(i int) (*pairsData, int) {
+		n := &e.nodes[i]
+		d := e.layout.get(int(n.stm), int(n.file))
+		return d, i - e.first[d]
+	}
+
+	wdlValues := map[*pairsData][]int{}
+	for i, n := range e.nodes {
+		if !n.used {
+			continue
+		}
+		d, idx := idx(i)
+		if wdlValues[d] == nil {
+			wdlValues[d] = sizes(e.layout, int(n.stm), int(n.file))
+			for i := range wdlValues[d] {
+				wdlValues[d][i] = int(Draw) + 2
+			}
+		}
+		wdlValues[d][idx] = int(n.wdl) + 2
+	}
+	wdl = writeTable(e.layout, func(stm, f int) []int {
+		if v := wdlValues[e.layout.get(stm, f)]; v != nil {
+			return v
+		}
+		return sizes(e.layout, stm, f)
+	}, func(int) byte { return 0 }, nil)
+
+	// DTZ values are stored as the distance less one or as a symbol of the
+	// maps of wins and losses
+	dtzLayout := layout(e.layout.key, true)
+	mapped := dtzFlags&flagMapped != 0
+	var maps [4][4][]int
+	dtzValues := [4][]int{}
+	for i, n := range e.nodes {
+		if !n.used || int(n.stm) != dtzSide || n.wdl == Draw {
+			continue
+		}
+		_, idx := idx(i)
+		if dtzValues[n.file] == nil {
+			dtzValues[n.file] = sizes(dtzLayout, 0, int(n.file))
+		}
+		v := n.dtz - 1
+		category := 0
+		if n.wdl == Loss {
+			v, category = -n.dtz-1, 1
+		}
+		if mapped {
+			list := &maps[n.file][category]
+			sym := -1
+			for i, m := range *list {
+				if m == v {
+					sym = i
+				}
+			}
+			if sym < 0 {
+				sym = len(*list)
+				*list = append(*list, v)
+			}
+			v = sym
+		}
+		dtzValues[n.file][idx] = v
+	}
+	dtz = writeTable(dtzLayout, func(_, f int) []int {
+		if dtzValues[f] != nil {
+			return dtzValues[f]
+		}
+		return sizes(dtzLayout, 0, f)
+	}, func(int) byte { return dtzFlags | byte(dtzSide) }, func(f int) [4][]int {
+		return maps[f]
+	})
+	return wdl, dtz
+}
+
+// solveFixtures solves the endgames of the fixtures
+func solveFixtures(t *testing.T) map[string]*endgame {
+	t.Helper()
+
+	solved := map[string]*endgame{}
+	for _, f := range fixtures {
+		if len(f.name)-1 > quickPieces && !*large {
+			continue
+		}
+		e, err := solve(f.name, solved)
+		if err != nil {
+			t.Fatalf("Could not solve %v: %v", f.name, err)
+		}
+		solved[f.name] = e
+		if len(f.name)-1 <= quickPieces {
+			continue
+		}
+
+		wdl, dtz := e.write(f.dtzSide, f.dtzFlags)
+		for ext, data := range map[string][]byte{".rtbw": wdl, ".rtbz": dtz} {
+			if err := ioutil.WriteFile(filepath.Join(largeDir, f.name+ext), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return solved
+}
+
+func TestFixtures(t *testing.T) {
+	solved := fixtureEndgames(t)
+	for _, f := range fixtures {
+		e, ok := solved[f.name]
+		if !ok {
+			continue
+		}
+		if longest, n := e.longest(); f.longest != 0 && longest != f.longest {
+			t.Errorf("Expecting the longest win of %v to be %v plies got %v in %v", f.name, f.longest, longest, e.pos(n).FEN())
+		}
+		if len(f.name)-1 > quickPieces {
+			continue
+		}
+
+		wdl, dtz := e.write(f.dtzSide, f.dtzFlags)
+		for ext, data := range map[string][]byte{".rtbw": wdl, ".rtbz": dtz} {
+			path := filepath.Join("testdata", f.name+ext)
+			if *update {
+				if err := ioutil.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			existing, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(existing, data) {
+				t.Errorf("Expecting %v to match the solved endgame, run the tests with -update", path)
+			}
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	values := make([]int, 5000)
+	for i := range values {
+		// long runs of draws broken by a few wins and losses
+		switch {
+		case i%97 == 0:
+			values[i] = 4
+		case i%41 < 3:
+			values[i] = i % 5
+		default:
+			values[i] = 2
+		}
+	}
+
+	header, sparse, lengths, data := compress(0, values)
+	d := &pairsData{sparseIndex: sparse, blockLength: lengths, data: data}
+	d.groupLen[0], d.groupIdx[1] = 1, uint64(len(values))
+	r := &reader{data: header}
+	d.setSizes(r)
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if d.maxSymLen == d.minSymLen {
+		t.Errorf("Expecting codes of several lengths got %v bits", d.minSymLen)
+	}
+	pairs := 0
+	for _, n := range d.symlen {
+		if n > 0 {
+			pairs++
+		}
+	}
+	if pairs == 0 || d.numBlocks < 2 {
+		t.Errorf("Expecting pairs of symbols in several blocks got %v pairs in %v blocks", pairs, d.numBlocks)
+	}
+	for i, v := range values {
+		if got, err := d.value(uint64(i)); err != nil || got != v {
+			t.Fatalf("Expecting %v at %v got %v %v", v, i, got, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package tournament
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// Standing is an engine's results in a tournament
+type Standing struct {
+	Engine string
+	Games  int
+	Wins   int
+	Draws  int
+	Losses int
+	// Byes counts the rounds the engine sat out, each is worth a point
+	Byes int
+	// Points counts a win as 1 and a draw as 0.5
+	Points float64
+	// Buchholz is the sum of the points of the engine's opponents
+	Buchholz float64
+	// SonnebornBerger is the sum of the points of the opponents the engine
+	// beat and half the points of the opponents it drew with
+	SonnebornBerger float64
+	// The Elo difference to the engine's opponents and the margin of its 95% confidence interval
+	Elo       float64
+	EloMargin float64
+}
+
+// Crosstable holds the results of a tournament
+type Crosstable struct {
+	// Standings ordered by points then Buchholz then Sonneborn-Berger, a
+	// knockout orders engines by how far they got first
+	Standings []Standing
+	// The matches of a knockout in the order they were played
+	Bracket []Match
+
+	engines []string
+	points  map[[2]string]float64
+	games   map[[2]string]int
+}
+
+// NewCrosstable tallies the results of finished games between the engines,
+// games without a decisive or drawn result are ignored
+func NewCrosstable(engines []string, games []store.Game) *Crosstable {
+	c := &Crosstable{
+		engines: engines,
+		points:  make(map[[2]string]float64),
+		games:   make(map[[2]string]int),
+	}
+
+	standings := make(map[string]*Standing)
+	for _, name := range engines {
+		standings[name] = &Standing{Engine: name}
+	}
+
+	type result struct {
+		white, black string
+		points       float64
+	}
+	var results []result
+
+	for _, game := range games {
+		if game.Black == "" {
+			if s := standings[game.White]; s != nil {
+				s.Byes++
+				s.Points++
+			}
+			continue
+		}
+
+		white, ok := whitePoints(game.Result)
+		if !ok {
+			continue
+		}
+
+		w, b := standings[game.White], standings[game.Black]
+		if w == nil || b == nil {
+			continue
+		}
+		w.record(white)
+		b.record(1 - white)
+		results = append(results, result{game.White, game.Black, white})
+
+		c.points[[2]string{game.White, game.Black}] += white
+		c.points[[2]string{game.Black, game.White}] += 1 - white
+		c.games[[2]string{game.White, game.Black}]++
+		c.games[[2]string{game.Black, game.White}]++
+	}
+
+	for _, r := range results {
+		w, b := standings[r.white], standings[r.black]
+		w.Buchholz += b.Points
+		b.Buchholz += w.Points
+		w.SonnebornBerger += r.points * b.Points
+		b.SonnebornBerger += (1 - r.points) * w.Points
+	}
+
+	for _, name := range engines {
+		s := standings[name]
+		s.Elo, s.EloMargin = Elo(s.Wins, s.Draws, s.Losses)
+		c.Standings = append(c.Standings, *s)
+	}
+	sort.SliceStable(c.Standings, func(i, j int) bool {
+		a, b := c.Standings[i], c.Standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		return a.SonnebornBerger > b.SonnebornBerger
+	})
+	return c
+}
+
+// whitePoints returns the points white scored in a game with a PGN result
+func whitePoints(result string) (float64, bool) {
+	switch result {
+	case "1-0":
+		return 1, true
+	case "0-1":
+		return 0, true
+	case "1/2-1/2":
+		return 0.5, true
+	}
+	return 0, false
+}
+
+func (s *Standing) record(points float64) {
+	s.Games++
+	s.Points += points
+	switch points {
+	case 1:
+		s.Wins++
+	case 0:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+}
+
+// Score returns the points engine a scored against engine b and the number of games they played
+func (c *Crosstable) Score(a, b string) (float64, int) {
+	key := [2]string{a, b}
+	return c.points[key], c.games[key]
+}
+
+// String formats the crosstable with a row for each engine in the order of
+// the standings followed by the bracket of a knockout
+func (c *Crosstable) String() string {
+	width := len("Engine")
+	for _, name := range c.engines {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-4v %-*v %5v %6v %6v %6v %6v %14v", "#", width, "Engine", "Games", "Points", "Score", "Buch", "SB", "Elo")
+	for i := range c.Standings {
+		fmt.Fprintf(&b, " %7v", i+1)
+	}
+	b.WriteString("\n")
+
+	for i, s := range c.Standings {
+		score := 0.0
+		if s.Games > 0 {
+			score = 100 * (s.Points - float64(s.Byes)) / float64(s.Games)
+		}
+		fmt.Fprintf(&b, "%-4v %-*v %5v %6.1f %5.1f%% %6.1f %6.2f %14v", i+1, width, s.Engine, s.Games, s.Points, score,
+			s.Buchholz, s.SonnebornBerger, formatElo(s.Elo, s.EloMargin))
+
+		for _, opponent := range c.Standings {
+			points, games := c.Score(s.Engine, opponent.Engine)
+			switch {
+			case s.Engine == opponent.Engine:
+				fmt.Fprintf(&b, " %7v", "-")
+			case games == 0:
+				fmt.Fprintf(&b, " %7v", "")
+			default:
+				fmt.Fprintf(&b, " %7v", fmt.Sprintf("%v/%v", points, games))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if len(c.Bracket) > 0 {
+		b.WriteString("\n")
+	}
+	for _, m := range c.Bracket {
+		b.WriteString(m.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func formatElo(elo, margin float64) string {
+	if math.IsInf(elo, 0) || math.IsInf(margin, 0) || math.IsNaN(margin) {
+		if math.IsInf(elo, 0) {
+			return fmt.Sprintf("%+v", elo)
+		}
+		return fmt.Sprintf("%+.0f", elo)
+	}
+	return fmt.Sprintf("%+.0f ± %.0f", elo, margin)
+}
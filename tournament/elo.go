@@ -0,0 +1,41 @@
+package tournament
+
+import (
+	"math"
+)
+
+// z95 is the z-score of a two sided 95% confidence interval
+const z95 = 1.959964
+
+// Elo estimates the Elo difference a score implies along with the margin of
+// a 95% confidence interval. The estimate is infinite when every game was
+// won or lost.
+func Elo(wins, draws, losses int) (elo, margin float64) {
+	n := float64(wins + draws + losses)
+	if n == 0 {
+		return 0, 0
+	}
+
+	score := (float64(wins) + float64(draws)/2) / n
+
+	// the standard deviation of the score of a single game
+	variance := (float64(wins)*math.Pow(1-score, 2) +
+		float64(draws)*math.Pow(0.5-score, 2) +
+		float64(losses)*math.Pow(0-score, 2)) / n
+	deviation := math.Sqrt(variance / n)
+
+	low := eloDifference(score - z95*deviation)
+	high := eloDifference(score + z95*deviation)
+	return eloDifference(score), (high - low) / 2
+}
+
+// eloDifference returns the Elo difference that gives an expected score
+func eloDifference(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
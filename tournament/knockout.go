@@ -0,0 +1,264 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Match is a knockout match between two engines
+type Match struct {
+	// Stage counts the rounds of the bracket from 1
+	Stage int
+	// The higher seed first, the second engine is empty when the first has a bye
+	Engines [2]string
+	// The points each engine scored including tiebreak games
+	Points [2]float64
+	// The number of games played including tiebreaks
+	Games int
+	// Armageddon is set when the match was decided by an Armageddon game,
+	// the higher seed has black and wins a draw
+	Armageddon bool
+	Winner     string
+}
+
+func (m Match) String() string {
+	if m.Engines[1] == "" {
+		return fmt.Sprintf("Stage %v: %v has a bye", m.Stage, m.Engines[0])
+	}
+	s := fmt.Sprintf("Stage %v: %v %v-%v %v", m.Stage, m.Engines[0], m.Points[0], m.Points[1], m.Engines[1])
+	if m.Armageddon {
+		s += " after Armageddon"
+	}
+	return s + ", " + m.Winner + " advances"
+}
+
+// level reports whether neither engine leads the match
+func (m *Match) level() bool {
+	return m.Points[0] == m.Points[1]
+}
+
+// bracket returns the seeds in the order they appear in a bracket big enough
+// for n engines so the top seeds only meet in the last stages. Seeds of n or
+// more are byes and are always paired with one of the top seeds.
+func bracket(n int) []int {
+	seeds := []int{0}
+	for len(seeds) < n {
+		size := 2 * len(seeds)
+		var next []int
+		for _, s := range seeds {
+			next = append(next, s, size-1-s)
+		}
+		seeds = next
+	}
+	return seeds
+}
+
+// knockout plays a knockout tournament stage by stage. Each match is played
+// over the configured number of games, a level match goes to tiebreak
+// mini-matches and then to an Armageddon game.
+func (r *runner) knockout(ctx context.Context) (*Crosstable, error) {
+	engines := r.config.Engines
+	seeds := make(map[string]int)
+	var field []string
+	for _, seed := range bracket(len(engines)) {
+		if seed < len(engines) {
+			seeds[engines[seed]] = seed
+			field = append(field, engines[seed])
+		} else {
+			field = append(field, "")
+		}
+	}
+
+	// the stage each engine was knocked out in
+	reached := make(map[string]int)
+	var played []Match
+	stage := 1
+	for ; len(field) > 1; stage++ {
+		var matches []*Match
+		for i := 0; i < len(field); i += 2 {
+			a, b := field[i], field[i+1]
+			if a == "" || (b != "" && seeds[b] < seeds[a]) {
+				a, b = b, a
+			}
+			matches = append(matches, &Match{Stage: stage, Engines: [2]string{a, b}})
+		}
+
+		if err := r.stage(ctx, matches); err != nil {
+			return nil, err
+		}
+
+		field = nil
+		for _, m := range matches {
+			field = append(field, m.Winner)
+			if m.Engines[1] != "" {
+				loser := m.Engines[0]
+				if m.Winner == loser {
+					loser = m.Engines[1]
+				}
+				reached[loser] = stage
+			}
+			played = append(played, *m)
+		}
+	}
+	reached[field[0]] = stage
+
+	table, err := r.crosstable()
+	if err != nil {
+		return nil, err
+	}
+	table.Bracket = played
+	sort.SliceStable(table.Standings, func(i, j int) bool {
+		return reached[table.Standings[i].Engine] > reached[table.Standings[j].Engine]
+	})
+	return table, nil
+}
+
+// stage plays the matches of a stage of a knockout at the same time and sets their winners
+func (r *runner) stage(ctx context.Context, matches []*Match) error {
+	var regular []*Match
+	for _, m := range matches {
+		if m.Engines[1] == "" {
+			m.Winner = m.Engines[0]
+		} else {
+			regular = append(regular, m)
+		}
+	}
+	if err := r.matchGames(ctx, regular, r.config.rounds(), 0, 0); err != nil {
+		return err
+	}
+
+	tiebreaks, tiebreakGames := r.config.Tiebreaks, r.config.TiebreakGames
+	if tiebreaks < 1 {
+		tiebreaks = 1
+	}
+	if tiebreakGames < 1 {
+		tiebreakGames = 2
+	}
+	for i := 0; i < tiebreaks; i++ {
+		if err := r.matchGames(ctx, levelMatches(regular), tiebreakGames, r.config.TiebreakTime, r.config.TiebreakIncrement); err != nil {
+			return err
+		}
+	}
+
+	var armageddon []game
+	level := levelMatches(regular)
+	for _, m := range level {
+		m.Armageddon = true
+		armageddon = append(armageddon, r.armageddon(m))
+	}
+	results, err := r.all(ctx, armageddon)
+	if err != nil {
+		return err
+	}
+	for i, m := range level {
+		r.tally(m, results[i].White, results[i].Result)
+	}
+
+	for _, m := range regular {
+		switch {
+		case m.Armageddon:
+			// the Armageddon game was the last game of the match
+		case m.Points[0] > m.Points[1]:
+			m.Winner = m.Engines[0]
+		default:
+			m.Winner = m.Engines[1]
+		}
+	}
+	return nil
+}
+
+// matchGames plays a number of games of each match at a time control, the
+// tournament's when clock is zero
+func (r *runner) matchGames(ctx context.Context, matches []*Match, n int, clock, increment time.Duration) error {
+	var (
+		games []game
+		of    []*Match
+	)
+	for _, m := range matches {
+		for i := 0; i < n; i++ {
+			g := r.gameOf(m)
+			g.time, g.increment = clock, increment
+			games = append(games, g)
+			of = append(of, m)
+		}
+	}
+
+	results, err := r.all(ctx, games)
+	if err != nil {
+		return err
+	}
+	for i, result := range results {
+		r.tally(of[i], result.White, result.Result)
+	}
+	return nil
+}
+
+func levelMatches(matches []*Match) []*Match {
+	var level []*Match
+	for _, m := range matches {
+		if m.level() {
+			level = append(level, m)
+		}
+	}
+	return level
+}
+
+// gameOf returns the next game of a match, colours alternate with the higher
+// seed having white first and each opening is played with both colours
+func (r *runner) gameOf(m *Match) game {
+	g := game{
+		id:    fmt.Sprintf("%v/%v.%v.%v", r.config.Name, m.Stage, m.Engines[0], m.Games+1),
+		round: m.Stage,
+		white: m.Engines[0],
+		black: m.Engines[1],
+	}
+	if m.Games%2 == 1 {
+		g.white, g.black = g.black, g.white
+	}
+	if len(r.config.Openings) > 0 {
+		g.opening = r.config.Openings[(m.Games/2)%len(r.config.Openings)]
+	}
+	m.Games++
+	return g
+}
+
+// armageddon returns the Armageddon game of a match
+func (r *runner) armageddon(m *Match) game {
+	g := r.gameOf(m)
+	g.white, g.black = m.Engines[1], m.Engines[0]
+
+	g.time, g.increment = r.config.TiebreakTime, r.config.TiebreakIncrement
+	if g.time == 0 {
+		g.time, g.increment = r.config.Time, r.config.Increment
+	}
+	if r.config.ArmageddonWhite > 0 {
+		g.time = r.config.ArmageddonWhite
+	}
+	g.blackTime = r.config.ArmageddonBlack
+	if g.blackTime == 0 {
+		g.blackTime = g.time * 4 / 5
+	}
+	return g
+}
+
+// tally adds the result of a game to a match, white wins an Armageddon game
+// only with a win and black wins it otherwise
+func (r *runner) tally(m *Match, white, result string) {
+	side := 0
+	if white != m.Engines[0] {
+		side = 1
+	}
+	if points, ok := whitePoints(result); ok {
+		m.Points[side] += points
+		m.Points[1-side] += 1 - points
+	}
+
+	if m.Armageddon {
+		m.Winner = m.Engines[1-side]
+		if result == "1-0" {
+			m.Winner = white
+		}
+	}
+}
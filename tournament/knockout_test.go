@@ -0,0 +1,113 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+func TestBracket(t *testing.T) {
+	if got := fmt.Sprint(bracket(8)); got != "[0 7 3 4 1 6 2 5]" {
+		t.Errorf("Unexpected bracket for 8 engines %v", got)
+	}
+	if got := fmt.Sprint(bracket(5)); got != "[0 7 3 4 1 6 2 5]" {
+		t.Errorf("Unexpected bracket for 5 engines %v", got)
+	}
+	if got := fmt.Sprint(bracket(2)); got != "[0 1]" {
+		t.Errorf("Unexpected bracket for 2 engines %v", got)
+	}
+}
+
+func TestKnockout(t *testing.T) {
+	engines := names(5)
+	c := Config{Name: "ko", Kind: Knockout, Engines: engines, Concurrency: 2}
+	table, err := Run(context.Background(), c, &scheduler{result: stronger(engines)}, store.NewMemory(), logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bracket []string
+	for _, m := range table.Bracket {
+		bracket = append(bracket, m.String())
+	}
+	expected := []string{
+		"Stage 1: engine0 has a bye",
+		"Stage 1: engine3 2-0 engine4, engine3 advances",
+		"Stage 1: engine1 has a bye",
+		"Stage 1: engine2 has a bye",
+		"Stage 2: engine0 2-0 engine3, engine0 advances",
+		"Stage 2: engine1 2-0 engine2, engine1 advances",
+		"Stage 3: engine0 2-0 engine1, engine0 advances",
+	}
+	if fmt.Sprint(bracket) != fmt.Sprint(expected) {
+		t.Errorf("Expecting bracket\n%v\ngot\n%v", expected, bracket)
+	}
+
+	order := ""
+	for _, s := range table.Standings {
+		order += s.Engine[len("engine"):]
+	}
+	if order != "01234" && order != "01324" {
+		t.Errorf("Expecting the standings in order of elimination got %v", order)
+	}
+}
+
+func TestArmageddon(t *testing.T) {
+	engines := names(2)
+	c := Config{
+		Name:          "armageddon",
+		Kind:          Knockout,
+		Engines:       engines,
+		Time:          time.Minute,
+		TiebreakTime:  10 * time.Second,
+		TiebreakGames: 2,
+		Tiebreaks:     2,
+	}
+
+	// Every game is drawn
+	s := &scheduler{result: func(server.Game) rules.Result { return rules.Draw }}
+	games := store.NewMemory()
+	table, err := Run(context.Background(), c, s, games, logrus.NewEntry(logrus.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := table.Bracket[0]
+	if !m.Armageddon || m.Games != 7 || m.Winner != "engine0" {
+		t.Errorf("Expecting engine0 to win on draw odds after 7 games got %+v", m)
+	}
+
+	if len(s.games) != 7 {
+		t.Fatalf("Expecting 7 games got %v", len(s.games))
+	}
+	for i, g := range s.games[:2] {
+		if g.Time != time.Minute {
+			t.Errorf("Expecting game %v at the main time control got %v", i+1, g.Time)
+		}
+	}
+	for i, g := range s.games[2:6] {
+		if g.Time != 10*time.Second {
+			t.Errorf("Expecting tiebreak game %v at the tiebreak time control got %v", i+1, g.Time)
+		}
+	}
+	armageddon := s.games[6]
+	if armageddon.White != "engine1" || armageddon.Time != 10*time.Second || armageddon.BlackTime != 8*time.Second {
+		t.Errorf("Expecting engine1 to have white with more time in the Armageddon game got %+v", armageddon)
+	}
+
+	// Resuming plays nothing
+	s = &scheduler{}
+	if _, err := Run(context.Background(), c, s, games, logrus.NewEntry(logrus.New())); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.games) != 0 {
+		t.Errorf("Expecting a finished knockout not to play again got %v games", len(s.games))
+	}
+}
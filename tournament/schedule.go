@@ -0,0 +1,164 @@
+package tournament
+
+import (
+	"fmt"
+
+	"github.com/schafer14/grpc-chess/server"
+)
+
+// Pairing is a game of the tournament
+type Pairing struct {
+	// Index is the game's position in the schedule starting at 0
+	Index int
+	// Round counts the times the engines have met starting at 1, a double
+	// round-robin has two rounds
+	Round int
+	// The names of the engines
+	White string
+	Black string
+	// The position the game starts from
+	Opening server.Opening
+}
+
+// Schedule returns every game of the tournament in the order they start.
+// Without openings each pair of engines meets once a round and colours are
+// balanced so no engine has more than one white game more than black games
+// in a round, with colours swapped in every second round. With openings each
+// pair plays every opening once with each colour every round.
+func Schedule(c Config) ([]Pairing, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Kind == Swiss || c.Kind == Knockout {
+		return nil, fmt.Errorf("The games of a %v tournament depend on its results", c.Kind)
+	}
+
+	var encounters [][2]string
+	switch c.Kind {
+	case RoundRobin:
+		encounters = roundRobin(c.Engines)
+	case Gauntlet:
+		encounters = gauntlet(c.Engines)
+	}
+
+	var pairings []Pairing
+	add := func(round int, white, black string, opening server.Opening) {
+		pairings = append(pairings, Pairing{Index: len(pairings), Round: round, White: white, Black: black, Opening: opening})
+	}
+
+	for round := 1; round <= c.rounds(); round++ {
+		for _, e := range encounters {
+			if round%2 == 0 {
+				e[0], e[1] = e[1], e[0]
+			}
+			if len(c.Openings) == 0 {
+				add(round, e[0], e[1], server.Opening{})
+				continue
+			}
+			for _, opening := range c.Openings {
+				add(round, e[0], e[1], opening)
+				add(round, e[1], e[0], opening)
+			}
+		}
+	}
+	return pairings, nil
+}
+
+// roundRobin pairs every engine with every other engine using the circle
+// method, each encounter lists white first
+func roundRobin(engines []string) [][2]string {
+	index := make(map[string]int)
+	for i, name := range engines {
+		index[name] = i
+	}
+
+	players := append([]string(nil), engines...)
+	if len(players)%2 == 1 {
+		// the engine paired with the empty name sits the round out
+		players = append(players, "")
+	}
+	n := len(players)
+
+	var encounters [][2]string
+	for r := 0; r < n-1; r++ {
+		for i := 0; i < n/2; i++ {
+			a, b := players[i], players[n-1-i]
+			if a == "" || b == "" {
+				continue
+			}
+			if !hasWhite(index[a], index[b], len(engines)) {
+				a, b = b, a
+			}
+			encounters = append(encounters, [2]string{a, b})
+		}
+
+		// keep the first player fixed and rotate the others
+		last := players[n-1]
+		copy(players[2:], players[1:n-1])
+		players[1] = last
+	}
+	return encounters
+}
+
+// hasWhite reports whether engine i has white against engine j in a round-robin
+// of n engines. Among an odd number of engines the lower index has white when
+// the indexes sum to an odd number so everyone has the same number of whites
+// and blacks. With an even number the last engine is left out of that and
+// alternates colours against the others, so no engine has more than one
+// white game more than black games.
+func hasWhite(i, j, n int) bool {
+	if n%2 == 0 {
+		switch n - 1 {
+		case i:
+			return j%2 == 0
+		case j:
+			return i%2 == 1
+		}
+	}
+	if (i+j)%2 == 1 {
+		return i < j
+	}
+	return i > j
+}
+
+// gauntlet pairs the first engine with each of the others alternating its colour
+func gauntlet(engines []string) [][2]string {
+	var encounters [][2]string
+	for i, opponent := range engines[1:] {
+		if i%2 == 0 {
+			encounters = append(encounters, [2]string{engines[0], opponent})
+		} else {
+			encounters = append(encounters, [2]string{opponent, engines[0]})
+		}
+	}
+	return encounters
+}
+
+// Validate checks the config describes a tournament that can be played
+func (c Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("The tournament needs a name")
+	}
+	switch c.Kind {
+	case RoundRobin, Gauntlet, Swiss, Knockout:
+	default:
+		return fmt.Errorf("Unknown kind of tournament %q", c.Kind)
+	}
+	if len(c.Engines) < 2 {
+		return fmt.Errorf("A tournament needs at least 2 engines got %v", len(c.Engines))
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range c.Engines {
+		if name == "" || seen[name] {
+			return fmt.Errorf("Engine names must be unique and not empty got %q", name)
+		}
+		seen[name] = true
+	}
+
+	// every engine has a bye once when there is an odd number
+	if c.Kind == Swiss && c.rounds() > len(c.Engines)-1+len(c.Engines)%2 {
+		return fmt.Errorf("A Swiss with %v engines can have at most %v rounds without repeating a pairing", len(c.Engines), len(c.Engines)-1+len(c.Engines)%2)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// Colours in a Swiss engine's history
+const (
+	white = 1
+	black = -1
+)
+
+// The strength of a colour preference
+const (
+	noPreference = iota
+	mild
+	strong
+	absolute
+)
+
+// swissPlayer is an engine's history going into a round of a Swiss
+type swissPlayer struct {
+	name   string
+	seed   int
+	points float64
+	// the engines it has played
+	opponents map[string]bool
+	// the colour of each game it has played
+	colours []int
+	bye     bool
+}
+
+// preference returns the colour the engine should have next and how strongly
+func (p *swissPlayer) preference() (colour, strength int) {
+	n := len(p.colours)
+	if n == 0 {
+		return 0, noPreference
+	}
+
+	difference := 0
+	for _, c := range p.colours {
+		difference += c
+	}
+	last := p.colours[n-1]
+
+	switch {
+	case difference > 1:
+		return black, absolute
+	case difference < -1:
+		return white, absolute
+	case n > 1 && p.colours[n-2] == last:
+		return -last, absolute
+	case difference != 0:
+		return -difference, strong
+	}
+	return -last, mild
+}
+
+// swiss plays a Swiss tournament round by round, the pairings of each round
+// come from the stored results of the rounds before it
+func (r *runner) swiss(ctx context.Context) (*Crosstable, error) {
+	var history []store.Game
+	for round := 1; round <= r.config.rounds(); round++ {
+		pairs, bye, err := swissPairings(r.config.Engines, history, round)
+		if err != nil {
+			return nil, err
+		}
+
+		if bye != "" {
+			g, err := r.bye(fmt.Sprintf("%v/%v.bye", r.config.Name, round), round, bye)
+			if err != nil {
+				return nil, err
+			}
+			history = append(history, g)
+		}
+
+		var opening server.Opening
+		if len(r.config.Openings) > 0 {
+			opening = r.config.Openings[(round-1)%len(r.config.Openings)]
+		}
+
+		var games []game
+		for board, pair := range pairs {
+			games = append(games, game{
+				id:      fmt.Sprintf("%v/%v.%v", r.config.Name, round, board+1),
+				round:   round,
+				white:   pair[0],
+				black:   pair[1],
+				opening: opening,
+			})
+		}
+		played, err := r.all(ctx, games)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, played...)
+	}
+	return r.crosstable()
+}
+
+// swissPairings pairs a round of a Swiss in the style of the FIDE Dutch
+// system. Engines are ranked by points then seed and each engine is paired
+// with the engine half a score group below it, falling back to the nearest
+// engine that keeps the rest of the round pairable. Engines never meet twice
+// and two engines that must have the same colour are not paired. With an
+// odd number of engines the lowest ranked engine that has not had a bye
+// sits the round out. Each pair lists white first.
+func swissPairings(engines []string, history []store.Game, round int) (pairs [][2]string, bye string, err error) {
+	players := make(map[string]*swissPlayer)
+	var ranked []*swissPlayer
+	for i, name := range engines {
+		p := &swissPlayer{name: name, seed: i, opponents: make(map[string]bool)}
+		players[name] = p
+		ranked = append(ranked, p)
+	}
+
+	for _, g := range history {
+		if g.Round >= round {
+			continue
+		}
+		w, b := players[g.White], players[g.Black]
+		if w == nil {
+			continue
+		}
+		if g.Black == "" {
+			w.bye = true
+			w.points++
+			continue
+		}
+		if b == nil {
+			continue
+		}
+		points, _ := whitePoints(g.Result)
+		w.points += points
+		b.points += 1 - points
+		w.opponents[b.name] = true
+		b.opponents[w.name] = true
+		w.colours = append(w.colours, white)
+		b.colours = append(b.colours, black)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].points > ranked[j].points
+	})
+
+	for _, strict := range []bool{true, false} {
+		matched, byePlayer, ok := pairRound(ranked, strict)
+		if !ok {
+			continue
+		}
+		for board, m := range matched {
+			pairs = append(pairs, colours(m[0], m[1], board))
+		}
+		if byePlayer != nil {
+			bye = byePlayer.name
+		}
+		return pairs, bye, nil
+	}
+	return nil, "", fmt.Errorf("Could not pair round %v without a repeat pairing", round)
+}
+
+// pairRound picks the engine with a bye and pairs the others, strict pairings respect colours
+func pairRound(ranked []*swissPlayer, strict bool) ([][2]*swissPlayer, *swissPlayer, bool) {
+	if len(ranked)%2 == 0 {
+		pairs, ok := pair(ranked, strict)
+		return pairs, nil, ok
+	}
+
+	for i := len(ranked) - 1; i >= 0; i-- {
+		if ranked[i].bye {
+			continue
+		}
+		rest := append(append([]*swissPlayer(nil), ranked[:i]...), ranked[i+1:]...)
+		if pairs, ok := pair(rest, strict); ok {
+			return pairs, ranked[i], true
+		}
+	}
+	return nil, nil, false
+}
+
+// pair pairs the ranked engines higher ranked first backtracking when an
+// engine cannot be paired
+func pair(ranked []*swissPlayer, strict bool) ([][2]*swissPlayer, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+
+	p, rest := ranked[0], ranked[1:]
+	for _, i := range candidates(p, rest) {
+		q := rest[i]
+		if !compatible(p, q, strict) {
+			continue
+		}
+
+		others := append(append([]*swissPlayer(nil), rest[:i]...), rest[i+1:]...)
+		if pairs, ok := pair(others, strict); ok {
+			return append([][2]*swissPlayer{{p, q}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// candidates orders the opponents for the top ranked engine. Its score group
+// is split in half and it is paired with the top of the lower half first,
+// then the engines below that, then the engines above it and then the
+// engines of lower score groups.
+func candidates(p *swissPlayer, rest []*swissPlayer) []int {
+	group := 0
+	for group < len(rest) && rest[group].points == p.points {
+		group++
+	}
+
+	var order []int
+	ideal := (group+1)/2 - 1
+	if ideal >= 0 {
+		for i := ideal; i < group; i++ {
+			order = append(order, i)
+		}
+		for i := ideal - 1; i >= 0; i-- {
+			order = append(order, i)
+		}
+	}
+	for i := group; i < len(rest); i++ {
+		order = append(order, i)
+	}
+	return order
+}
+
+func compatible(p, q *swissPlayer, strict bool) bool {
+	if p.opponents[q.name] {
+		return false
+	}
+	if !strict {
+		return true
+	}
+	pc, ps := p.preference()
+	qc, qs := q.preference()
+	return !(ps == absolute && qs == absolute && pc == qc)
+}
+
+// colours returns a pair white first, p is the higher ranked engine. Each
+// engine gets the colour it prefers when they differ, otherwise the stronger
+// preference wins and then the higher ranked engine's. Without preferences
+// the higher ranked engine has white on odd boards.
+func colours(p, q *swissPlayer, board int) [2]string {
+	pc, ps := p.preference()
+	qc, qs := q.preference()
+
+	var pWhite bool
+	switch {
+	case pc == 0 && qc == 0:
+		pWhite = board%2 == 0
+	case pc != qc && pc != 0:
+		pWhite = pc == white
+	case pc != qc:
+		pWhite = qc == black
+	case qs > ps:
+		pWhite = qc == black
+	default:
+		pWhite = pc == white
+	}
+
+	if pWhite {
+		return [2]string{p.name, q.name}
+	}
+	return [2]string{q.name, p.name}
+}
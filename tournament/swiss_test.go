@@ -0,0 +1,135 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// stronger makes the engine seeded higher win every game
+func stronger(engines []string) func(server.Game) rules.Result {
+	seeds := make(map[string]int)
+	for i, name := range engines {
+		seeds[name] = i
+	}
+	return func(g server.Game) rules.Result {
+		if seeds[g.White] < seeds[g.Black] {
+			return rules.WhiteWins
+		}
+		return rules.BlackWins
+	}
+}
+
+func TestSwissFirstRound(t *testing.T) {
+	pairs, bye, err := swissPairings(names(8), nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bye != "" {
+		t.Errorf("Unexpected bye for %v", bye)
+	}
+	expected := "[[engine0 engine4] [engine5 engine1] [engine2 engine6] [engine7 engine3]]"
+	if fmt.Sprint(pairs) != expected {
+		t.Errorf("Expecting %v got %v", expected, pairs)
+	}
+}
+
+func TestSwiss(t *testing.T) {
+	for _, n := range []int{6, 7, 8, 9} {
+		engines := names(n)
+		rounds := n/2 + 1
+		c := Config{Name: "swiss", Kind: Swiss, Engines: engines, Rounds: rounds, Concurrency: 3}
+		games := store.NewMemory()
+		table, err := Run(context.Background(), c, &scheduler{result: stronger(engines)}, games, logrus.NewEntry(logrus.New()))
+		if err != nil {
+			t.Fatalf("%v engines: %v", n, err)
+		}
+
+		played, _ := games.Tournament("swiss")
+		met := make(map[[2]string]bool)
+		byes := make(map[string]int)
+		balance := make(map[string]int)
+		seen := make(map[string]map[int]bool)
+		for _, g := range played {
+			for _, name := range []string{g.White, g.Black} {
+				if name == "" {
+					continue
+				}
+				if seen[name] == nil {
+					seen[name] = make(map[int]bool)
+				}
+				if seen[name][g.Round] {
+					t.Errorf("%v engines: %v plays twice in round %v", n, name, g.Round)
+				}
+				seen[name][g.Round] = true
+			}
+
+			if g.Black == "" {
+				byes[g.White]++
+				continue
+			}
+			key := [2]string{g.White, g.Black}
+			if g.Black < g.White {
+				key = [2]string{g.Black, g.White}
+			}
+			if met[key] {
+				t.Errorf("%v engines: %v met twice", n, key)
+			}
+			met[key] = true
+			balance[g.White]++
+			balance[g.Black]--
+		}
+
+		for _, name := range engines {
+			if b := balance[name]; b > 2 || b < -2 {
+				t.Errorf("%v engines: %v has a colour difference of %v", n, name, b)
+			}
+			if byes[name] > 1 {
+				t.Errorf("%v engines: expecting %v to have at most 1 bye got %v", n, name, byes[name])
+			}
+		}
+
+		if top := table.Standings[0]; top.Engine != "engine0" || top.Points != float64(rounds) {
+			t.Errorf("%v engines: expecting engine0 to win every round got %+v", n, top)
+		}
+	}
+}
+
+func TestTiebreaks(t *testing.T) {
+	games := []store.Game{
+		{White: "a", Black: "b", Result: "1-0"},
+		{White: "c", Black: "d", Result: "1/2-1/2"},
+		{White: "a", Black: "c", Result: "0-1"},
+		{White: "b", Black: "d", Result: "1-0"},
+		{White: "d", Black: "", Result: "1-0", Termination: "bye"},
+	}
+	table := NewCrosstable([]string{"a", "b", "c", "d"}, games)
+
+	expected := map[string][3]float64{
+		// points, Buchholz, Sonneborn-Berger
+		"a": {1, 2.5, 1},
+		"b": {1, 2.5, 1.5},
+		"c": {1.5, 2.5, 1.75},
+		"d": {1.5, 2.5, 0.75},
+	}
+	for _, s := range table.Standings {
+		got := [3]float64{s.Points, s.Buchholz, s.SonnebornBerger}
+		if got != expected[s.Engine] {
+			t.Errorf("Expecting %v to have %v got %v", s.Engine, expected[s.Engine], got)
+		}
+	}
+
+	order := ""
+	for _, s := range table.Standings {
+		order += s.Engine
+	}
+	if order != "cdba" {
+		t.Errorf("Expecting the standings cdba got %v", order)
+	}
+}
@@ -0,0 +1,414 @@
+// Package tournament runs round-robin, gauntlet, Swiss and knockout
+// tournaments between the engines connected to the server and keeps their
+// results in a game store
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+// Kind is the format of a tournament
+type Kind string
+
+// The kinds of tournament
+const (
+	// RoundRobin pairs every engine with every other engine
+	RoundRobin Kind = "round-robin"
+	// Gauntlet pairs the first engine with each of the others
+	Gauntlet Kind = "gauntlet"
+	// Swiss pairs engines on the same score each round
+	Swiss Kind = "swiss"
+	// Knockout plays matches in a bracket where the loser of each match is out
+	Knockout Kind = "knockout"
+)
+
+// Config describes a tournament
+type Config struct {
+	// Name identifies the tournament's games in the store
+	Name string
+	Kind Kind
+	// The names of the engines strongest first, the first engine of a
+	// gauntlet plays all the others and Swiss and knockout events are seeded
+	// in this order
+	Engines []string
+	// How many times each pair of engines meets in a round-robin or gauntlet,
+	// 2 for a double round-robin and 1 when zero. The number of rounds of a
+	// Swiss, enough to find a winner when zero. The number of games of a
+	// knockout match, 2 when zero.
+	Rounds int
+	// Openings each pair of engines plays with both colours, the standard
+	// starting position when empty. A Swiss plays one opening each round.
+	Openings []server.Opening
+	// How many games are played at once, 1 when zero
+	Concurrency int
+	// The time control of each game and whether engines ponder, the
+	// scheduler's when Ponder is nil
+	Time      time.Duration
+	Increment time.Duration
+	Ponder    *bool
+	// The adjudication rules of the games, the scheduler's when nil
+	Adjudication *server.Adjudication
+	// The variant of the games, standard chess when nil
+	Variant rules.Variant
+
+	// The number of games of a knockout tiebreak mini-match, 2 when zero
+	TiebreakGames int
+	// How many tiebreak mini-matches are played before an Armageddon game, 1 when zero
+	Tiebreaks int
+	// The time control of tiebreak games, the main time control when zero
+	TiebreakTime      time.Duration
+	TiebreakIncrement time.Duration
+	// The starting times of an Armageddon game where black wins a draw,
+	// white has the tiebreak time and black four fifths of it when zero
+	ArmageddonWhite time.Duration
+	ArmageddonBlack time.Duration
+}
+
+func (c Config) rounds() int {
+	if c.Rounds > 0 {
+		return c.Rounds
+	}
+	switch c.Kind {
+	case Swiss:
+		return int(math.Ceil(math.Log2(float64(len(c.Engines)))))
+	case Knockout:
+		return 2
+	}
+	return 1
+}
+
+func (c Config) concurrency() int {
+	if c.Concurrency < 1 {
+		return 1
+	}
+	return c.Concurrency
+}
+
+// Scheduler plays games between connected engines, it is implemented by *server.Scheduler
+type Scheduler interface {
+	Play(ctx context.Context, g server.Game) (server.GameRecord, error)
+}
+
+// GameID returns the ID a game of a round-robin or gauntlet is stored under
+func GameID(tournament string, index int) string {
+	return fmt.Sprintf("%v/%v", tournament, index)
+}
+
+// Run plays the games of the tournament that are not in the store yet, so an
+// interrupted tournament picks up where it stopped, and returns the
+// crosstable of all its games. Each game is saved as soon as it is over.
+func Run(ctx context.Context, c Config, scheduler Scheduler, games store.Store, logger *logrus.Entry) (*Crosstable, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		config:    c,
+		scheduler: scheduler,
+		games:     games,
+		logger:    logger.WithField("tournament", c.Name),
+	}
+	switch c.Kind {
+	case Swiss:
+		return r.swiss(ctx)
+	case Knockout:
+		return r.knockout(ctx)
+	}
+	return r.scheduled(ctx)
+}
+
+// runner plays the games of a tournament
+type runner struct {
+	config    Config
+	scheduler Scheduler
+	games     store.Store
+	logger    *logrus.Entry
+}
+
+// game is a game for the runner to play
+type game struct {
+	id      string
+	round   int
+	white   string
+	black   string
+	opening server.Opening
+	// the time control, the tournament's when zero
+	time      time.Duration
+	increment time.Duration
+	blackTime time.Duration
+}
+
+// scheduled plays a tournament whose games are all known before it starts
+func (r *runner) scheduled(ctx context.Context) (*Crosstable, error) {
+	pairings, err := Schedule(r.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []game
+	for _, p := range pairings {
+		games = append(games, game{
+			id:      GameID(r.config.Name, p.Index),
+			round:   p.Round,
+			white:   p.White,
+			black:   p.Black,
+			opening: p.Opening,
+		})
+	}
+	if _, err := r.all(ctx, games); err != nil {
+		return nil, err
+	}
+	return r.crosstable()
+}
+
+// crosstable tallies every stored game of the tournament
+func (r *runner) crosstable() (*Crosstable, error) {
+	played, err := r.games.Tournament(r.config.Name)
+	if err != nil {
+		return nil, err
+	}
+	return NewCrosstable(r.config.Engines, played), nil
+}
+
+// all plays games in order with up to the configured number at once and
+// returns them in the same order, games already in the store are not played
+// again and adjourned games are resumed. The first error stops any game that
+// has not started except for a game that keeps being aborted, the others are
+// still played and the error is returned once they are over.
+func (r *runner) all(ctx context.Context, games []game) ([]store.Game, error) {
+	results := make([]store.Game, len(games))
+
+	var pending []int
+	for i, g := range games {
+		stored, ok, err := r.stored(g)
+		if err != nil {
+			return nil, err
+		}
+		if ok && stored.Termination != string(server.Adjourned) {
+			results[i] = stored
+		} else {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) > 0 {
+		r.logger.Infof("Playing %v of %v games", len(pending), len(games))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	keep := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+	fail := func(err error) {
+		keep(err)
+		cancel()
+	}
+
+	queue := make(chan int)
+	for i := 0; i < r.config.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				result, err := r.play(ctx, games[i])
+				if _, ok := err.(*abortedError); ok {
+					keep(err)
+					continue
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				results[i] = result
+			}
+		}()
+	}
+
+Queue:
+	for _, i := range pending {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			break Queue
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// stored returns a game from the store, it is an error for the stored game
+// to have different players as the tournament's config must have changed
+func (r *runner) stored(g game) (store.Game, bool, error) {
+	stored, ok, err := r.games.Get(g.id)
+	if err != nil || !ok {
+		return store.Game{}, false, err
+	}
+	if stored.White != g.white || stored.Black != g.black {
+		return store.Game{}, false, fmt.Errorf("Stored game %v is %v vs %v but the schedule has %v vs %v, the tournament config changed",
+			stored.ID, stored.White, stored.Black, g.white, g.black)
+	}
+	return stored, true, nil
+}
+
+// replays is how many times an aborted game is played again before the
+// tournament gives up on it
+const replays = 2
+
+// abortedError is returned for a game that was aborted every time it was played
+type abortedError struct {
+	game game
+}
+
+func (e *abortedError) Error() string {
+	return fmt.Sprintf("Game %v between %v and %v was aborted %v times", e.game.id, e.game.white, e.game.black, replays+1)
+}
+
+// play plays a single game of the tournament and saves it. An aborted game
+// is not saved and is played again, it is left for the next run of the
+// tournament once it has been aborted too many times.
+func (r *runner) play(ctx context.Context, g game) (store.Game, error) {
+	for i := 0; i <= replays; i++ {
+		result, err := r.playOnce(ctx, g)
+		if _, ok := err.(*abortedError); !ok {
+			return result, err
+		}
+		r.logger.Warnf("Game %v between %v and %v was aborted", g.id, g.white, g.black)
+	}
+	return store.Game{}, &abortedError{game: g}
+}
+
+// playOnce plays a game or resumes it once it was adjourned and saves it. An
+// adjourned game stops the tournament until it is run again.
+func (r *runner) playOnce(ctx context.Context, g game) (store.Game, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Game{}, err
+	}
+	adjourned, _, err := r.stored(g)
+	if err != nil {
+		return store.Game{}, err
+	}
+
+	request := server.Game{
+		White:        g.white,
+		Black:        g.black,
+		Time:         r.config.Time,
+		Increment:    r.config.Increment,
+		Ponder:       r.config.Ponder,
+		Submitter:    r.config.Name,
+		Opening:      g.opening,
+		Adjudication: r.config.Adjudication,
+		Variant:      r.config.Variant,
+	}
+	if g.time > 0 {
+		request.Time = g.time
+		request.Increment = g.increment
+		request.BlackTime = g.blackTime
+	}
+	if adjourned.Termination == string(server.Adjourned) {
+		r.logger.Infof("Resuming game %v after %v moves", g.id, len(adjourned.Moves))
+		request.Opening = server.Opening{FEN: adjourned.FEN, Moves: adjourned.Moves, Chess960: adjourned.Chess960}
+		request.Time = adjourned.WhiteClock
+		request.BlackTime = adjourned.BlackClock
+	}
+
+	record, err := r.scheduler.Play(ctx, request)
+	if err != nil {
+		if ctx.Err() != nil {
+			return store.Game{}, ctx.Err()
+		}
+		return store.Game{}, fmt.Errorf("Game %v between %v and %v failed: %v", g.id, g.white, g.black, err)
+	}
+
+	r.logger.WithField("white", record.White).WithField("black", record.Black).
+		Infof("Game %v: %v by %v", g.id, record.Outcome.Result, record.Outcome.Termination)
+
+	result := store.Game{
+		ID:          g.id,
+		Tournament:  r.config.Name,
+		Round:       g.round,
+		White:       record.White,
+		Black:       record.Black,
+		FEN:         record.FEN,
+		Moves:       record.Moves,
+		Chess960:    record.Chess960,
+		Variant:     record.Variant,
+		Result:      record.Outcome.Result.String(),
+		Termination: string(record.Outcome.Termination),
+		Violations:  adjourned.Violations,
+		WhiteClock:  record.Clocks[rules.White],
+		BlackClock:  record.Clocks[rules.Black],
+		Finished:    time.Now(),
+	}
+	for _, v := range record.Violations {
+		result.Violations = append(result.Violations, store.Violation{
+			Engine:    v.Engine,
+			Side:      v.Color.String(),
+			Violation: string(v.Violation),
+			Action:    string(v.Action),
+			Ply:       v.Ply,
+			Detail:    v.Detail,
+		})
+	}
+	switch record.Outcome.Termination {
+	case server.Aborted:
+		// a game the tournament aborted as it was stopped is not played again
+		if err := ctx.Err(); err != nil {
+			return store.Game{}, err
+		}
+		return store.Game{}, &abortedError{game: g}
+	case server.Adjourned:
+		if err := r.games.Put(result); err != nil {
+			return store.Game{}, err
+		}
+		return store.Game{}, fmt.Errorf("Game %v between %v and %v was adjourned", g.id, g.white, g.black)
+	}
+	return result, r.games.Put(result)
+}
+
+// bye saves a round an engine sits out unless it is already stored
+func (r *runner) bye(id string, round int, engine string) (store.Game, error) {
+	g := game{id: id, round: round, white: engine}
+	if stored, ok, err := r.stored(g); err != nil || ok {
+		return stored, err
+	}
+
+	r.logger.WithField("engine", engine).Infof("Bye in round %v", round)
+	result := store.Game{
+		ID:          id,
+		Tournament:  r.config.Name,
+		Round:       round,
+		White:       engine,
+		Result:      "1-0",
+		Termination: "bye",
+		Finished:    time.Now(),
+	}
+	return result, r.games.Put(result)
+}
@@ -0,0 +1,266 @@
+package tournament
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/schafer14/grpc-chess/rules"
+	"github.com/schafer14/grpc-chess/server"
+	"github.com/schafer14/grpc-chess/store"
+)
+
+func names(n int) []string {
+	var engines []string
+	for i := 0; i < n; i++ {
+		engines = append(engines, fmt.Sprintf("engine%v", i))
+	}
+	return engines
+}
+
+func TestRoundRobinColours(t *testing.T) {
+	for n := 2; n <= 10; n++ {
+		pairings, err := Schedule(Config{Name: "rr", Kind: RoundRobin, Engines: names(n), Rounds: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pairings) != n*(n-1) {
+			t.Errorf("Expecting %v games with %v engines got %v", n*(n-1), n, len(pairings))
+		}
+
+		met := make(map[[2]string]int)
+		whites := make(map[int]map[string]int)
+		for _, p := range pairings {
+			met[[2]string{p.White, p.Black}]++
+			if whites[p.Round] == nil {
+				whites[p.Round] = make(map[string]int)
+			}
+			whites[p.Round][p.White]++
+			whites[p.Round][p.Black]--
+		}
+
+		for _, a := range names(n) {
+			for _, b := range names(n) {
+				if a != b && met[[2]string{a, b}] != 1 {
+					t.Errorf("Expecting %v to have white against %v once with %v engines got %v", a, b, n, met[[2]string{a, b}])
+				}
+			}
+		}
+		for round, balance := range whites {
+			for engine, b := range balance {
+				if b > 1 || b < -1 {
+					t.Errorf("Unbalanced colours for %v in round %v with %v engines: %v", engine, round, n, b)
+				}
+			}
+		}
+	}
+}
+
+func TestGauntlet(t *testing.T) {
+	pairings, err := Schedule(Config{Name: "g", Kind: Gauntlet, Engines: names(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairings) != 3 {
+		t.Fatalf("Expecting 3 games got %v", len(pairings))
+	}
+	for _, p := range pairings {
+		if p.White != "engine0" && p.Black != "engine0" {
+			t.Errorf("Expecting every game to involve the challenger got %v vs %v", p.White, p.Black)
+		}
+	}
+}
+
+func TestPairedOpenings(t *testing.T) {
+	openings := []server.Opening{{Moves: []string{"e2e4"}}, {Moves: []string{"d2d4"}}}
+	pairings, err := Schedule(Config{Name: "o", Kind: RoundRobin, Engines: names(3), Openings: openings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairings) != 12 {
+		t.Fatalf("Expecting 12 games got %v", len(pairings))
+	}
+
+	played := make(map[string]int)
+	for _, p := range pairings {
+		played[fmt.Sprintf("%v %v %v", p.White, p.Black, p.Opening.Moves)]++
+	}
+	for _, p := range pairings {
+		if played[fmt.Sprintf("%v %v %v", p.Black, p.White, p.Opening.Moves)] != 1 {
+			t.Errorf("Expecting %v to play %v with white against %v", p.Black, p.Opening.Moves, p.White)
+		}
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, c := range []Config{
+		{Kind: RoundRobin, Engines: names(2)},
+		{Name: "t", Kind: "arena", Engines: names(2)},
+		{Name: "t", Kind: Swiss, Engines: names(4), Rounds: 4},
+		{Name: "t", Kind: RoundRobin, Engines: names(1)},
+		{Name: "t", Kind: RoundRobin, Engines: []string{"a", "a"}},
+	} {
+		if _, err := Schedule(c); err == nil {
+			t.Errorf("Expecting an error for %+v", c)
+		}
+	}
+}
+
+func TestElo(t *testing.T) {
+	elo, margin := Elo(10, 0, 10)
+	if elo != 0 || margin <= 0 {
+		t.Errorf("Expecting an even score to give 0 with a margin got %v ± %v", elo, margin)
+	}
+
+	elo, _ = Elo(3, 0, 1)
+	if math.Abs(elo-190.85) > 0.01 {
+		t.Errorf("Expecting a 75%% score to give 190.85 got %v", elo)
+	}
+
+	_, wide := Elo(6, 0, 4)
+	_, narrow := Elo(60, 0, 40)
+	if narrow >= wide {
+		t.Errorf("Expecting the margin to shrink with more games got %v and %v", wide, narrow)
+	}
+
+	if elo, _ := Elo(5, 0, 0); !math.IsInf(elo, 1) {
+		t.Errorf("Expecting a perfect score to be infinite got %v", elo)
+	}
+}
+
+// scheduler plays games where white wins unless result says otherwise
+type scheduler struct {
+	mu     sync.Mutex
+	games  []server.Game
+	fail   int
+	played int
+	result func(g server.Game) rules.Result
+	// abort returns true for a game that is aborted when it is played
+	abort func(g server.Game) bool
+}
+
+func (s *scheduler) Play(ctx context.Context, g server.Game) (server.GameRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail > 0 && s.played == s.fail {
+		return server.GameRecord{}, fmt.Errorf("Engine disconnected")
+	}
+	s.played++
+	s.games = append(s.games, g)
+
+	outcome := rules.Outcome{Result: rules.WhiteWins, Termination: rules.Checkmate}
+	if s.result != nil {
+		outcome.Result = s.result(g)
+	}
+	if s.abort != nil && s.abort(g) {
+		outcome = rules.Outcome{Result: rules.NoResult, Termination: server.Aborted}
+	}
+	return server.GameRecord{
+		White:   g.White,
+		Black:   g.Black,
+		Moves:   g.Opening.Moves,
+		Outcome: outcome,
+	}, nil
+}
+
+func TestResume(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	games := store.NewMemory()
+	c := Config{Name: "resume", Kind: RoundRobin, Engines: names(3), Rounds: 2, Concurrency: 2}
+
+	// The first run fails after 2 games
+	_, err := Run(context.Background(), Config{Name: c.Name, Kind: c.Kind, Engines: c.Engines, Rounds: c.Rounds}, &scheduler{fail: 2}, games, logger)
+	if err == nil {
+		t.Fatal("Expecting the tournament to fail")
+	}
+	saved, _ := games.Tournament(c.Name)
+	if len(saved) != 2 {
+		t.Fatalf("Expecting 2 saved games got %v", len(saved))
+	}
+
+	s := &scheduler{}
+	table, err := Run(context.Background(), c, s, games, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.games) != 4 {
+		t.Errorf("Expecting the remaining 4 games to be played got %v", len(s.games))
+	}
+
+	for _, standing := range table.Standings {
+		if standing.Games != 4 || standing.Points != 2 {
+			t.Errorf("Expecting %v to score 2 of 4 got %v of %v", standing.Engine, standing.Points, standing.Games)
+		}
+	}
+	if points, games := table.Score("engine0", "engine1"); points != 1 || games != 2 {
+		t.Errorf("Expecting engine0 to score 1 of 2 against engine1 got %v of %v", points, games)
+	}
+	if !strings.Contains(table.String(), "1/2") {
+		t.Errorf("Expecting the crosstable to show head to head scores got\n%v", table)
+	}
+
+	// A stored game that no longer matches the schedule is an error
+	c.Engines = []string{"engine2", "engine1", "engine0"}
+	if _, err := Run(context.Background(), c, &scheduler{}, games, logger); err == nil {
+		t.Error("Expecting an error when the schedule changed")
+	}
+}
+
+func TestAbortedGame(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	games := store.NewMemory()
+	c := Config{Name: "aborted", Kind: RoundRobin, Engines: names(3), Rounds: 2, Concurrency: 2}
+
+	// engine0 vs engine1 is aborted the first time it is played
+	aborts := 0
+	once := func(g server.Game) bool {
+		if g.White == "engine0" && g.Black == "engine1" && aborts == 0 {
+			aborts++
+			return true
+		}
+		return false
+	}
+	s := &scheduler{abort: once}
+	table, err := Run(context.Background(), c, s, games, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.games) != 7 {
+		t.Errorf("Expecting the aborted game to be played again got %v games", len(s.games))
+	}
+	for _, standing := range table.Standings {
+		if standing.Games != 4 {
+			t.Errorf("Expecting %v to play 4 games got %v", standing.Engine, standing.Games)
+		}
+	}
+
+	// A game that is aborted every time does not stop the others
+	games = store.NewMemory()
+	always := func(g server.Game) bool { return g.White == "engine0" && g.Black == "engine1" }
+	s = &scheduler{abort: always}
+	if _, err := Run(context.Background(), c, s, games, logger); err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("Expecting the aborted game to be reported got %v", err)
+	}
+	if len(s.games) != 5+replays+1 {
+		t.Errorf("Expecting the other 5 games and %v tries of the aborted one got %v games", replays+1, len(s.games))
+	}
+	saved, _ := games.Tournament(c.Name)
+	if len(saved) != 5 {
+		t.Errorf("Expecting the other 5 games to be saved got %v", len(saved))
+	}
+
+	// The next run only plays the aborted game
+	s = &scheduler{}
+	if _, err := Run(context.Background(), c, s, games, logger); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.games) != 1 || s.games[0].White != "engine0" || s.games[0].Black != "engine1" {
+		t.Errorf("Expecting only the aborted game to be played got %v", s.games)
+	}
+}
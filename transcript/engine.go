@@ -0,0 +1,140 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineRecorder records complete lines passing through it
+type lineRecorder struct {
+	mu      sync.Mutex
+	t       *Writer
+	from    string
+	partial []byte
+}
+
+func (l *lineRecorder) record(p []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			return
+		}
+		line := strings.TrimRight(string(l.partial[:i]), "\r")
+		l.partial = l.partial[i+1:]
+		l.t.Record(Entry{Channel: Engine, From: l.from, Line: line})
+	}
+}
+
+type recordingReader struct {
+	r io.Reader
+	l *lineRecorder
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.l.record(p[:n])
+	return n, err
+}
+
+type recordingWriter struct {
+	w io.WriteCloser
+	l *lineRecorder
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.l.record(p[:n])
+	return n, err
+}
+
+func (w *recordingWriter) Close() error {
+	return w.w.Close()
+}
+
+// EngineOutput returns a reader recording each line the engine writes to r
+func (t *Writer) EngineOutput(r io.Reader) io.Reader {
+	return &recordingReader{r: r, l: &lineRecorder{t: t, from: FromEngine}}
+}
+
+// EngineInput returns a writer recording each line the GUI writes to the engine through w
+func (t *Writer) EngineInput(w io.WriteCloser) io.WriteCloser {
+	return &recordingWriter{w: w, l: &lineRecorder{t: t, from: FromGUI}}
+}
+
+// ReplayEngine acts as the engine recorded in a transcript. It reads GUI
+// commands from in and answers each one with the lines the engine wrote after
+// the same command in the transcript. When realtime is set the recorded delays
+// between lines are kept. A DivergenceError is returned when the GUI sends a
+// different line to the one recorded, lines only differing in whitespace are
+// the same.
+func ReplayEngine(entries []Entry, in io.Reader, out io.Writer, realtime bool) error {
+	entries = Filter(entries, Engine)
+
+	// emit writes the engine lines starting at i returning the index of the next GUI line
+	emit := func(i int) (int, error) {
+		for ; i < len(entries) && entries[i].From == FromEngine; i++ {
+			if realtime && i > 0 {
+				time.Sleep(entries[i].Time.Sub(entries[i-1].Time))
+			}
+			if _, err := fmt.Fprintln(out, entries[i].Line); err != nil {
+				return i, err
+			}
+		}
+		return i, nil
+	}
+
+	// Some engines write a banner before they receive any command
+	i, err := emit(0)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if i >= len(entries) {
+			return &DivergenceError{Index: i, Expected: "end of transcript", Got: line}
+		}
+
+		expected := entries[i].Line
+		if normalise(expected) != normalise(line) {
+			return &DivergenceError{Index: i, Expected: expected, Got: line}
+		}
+
+		i, err = emit(i + 1)
+		if err != nil {
+			return err
+		}
+		if command(line) == "quit" {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// normalise collapses the whitespace of a UCI line
+func normalise(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// command returns the first word of a UCI line
+func command(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
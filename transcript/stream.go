@@ -0,0 +1,152 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+
+	pb "github.com/schafer14/grpc-chess/service"
+)
+
+var marshaler = jsonpb.Marshaler{}
+
+// RecordMessage writes a gRPC message on the UCI stream to the transcript
+func (t *Writer) RecordMessage(from string, msg proto.Message) error {
+	var b bytes.Buffer
+	if err := marshaler.Marshal(&b, msg); err != nil {
+		return err
+	}
+	return t.Record(Entry{Channel: Stream, From: from, Message: json.RawMessage(b.Bytes())})
+}
+
+// ClientInterceptor is a grpc.StreamClientInterceptor recording the messages of client streams
+func (t *Writer) ClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingClientStream{ClientStream: stream, t: t}, nil
+}
+
+type recordingClientStream struct {
+	grpc.ClientStream
+	t *Writer
+}
+
+func (s *recordingClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if msg, ok := m.(proto.Message); ok && err == nil {
+		s.t.RecordMessage(FromClient, msg)
+	}
+	return err
+}
+
+func (s *recordingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if msg, ok := m.(proto.Message); ok && err == nil {
+		s.t.RecordMessage(FromServer, msg)
+	}
+	return err
+}
+
+// ServerInterceptor returns a grpc.StreamServerInterceptor recording each
+// server stream to its own transcript created by create
+func ServerInterceptor(create func() (*Writer, error)) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t, err := create()
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+
+		return handler(srv, &recordingServerStream{ServerStream: ss, t: t})
+	}
+}
+
+type recordingServerStream struct {
+	grpc.ServerStream
+	t *Writer
+}
+
+func (s *recordingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if msg, ok := m.(proto.Message); ok && err == nil {
+		s.t.RecordMessage(FromServer, msg)
+	}
+	return err
+}
+
+func (s *recordingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if msg, ok := m.(proto.Message); ok && err == nil {
+		s.t.RecordMessage(FromClient, msg)
+	}
+	return err
+}
+
+type replayServer struct {
+	pb.UnimplementedChessApplicationServer
+
+	entries []Entry
+	report  func(error)
+}
+
+// NewReplayServer returns a server that plays back the server side of a
+// recorded UCI stream to each client that connects. Every message the client
+// sends is compared with the recorded one and differences are passed to report
+// as a DivergenceError. Info messages depend on timing so they are skipped.
+func NewReplayServer(entries []Entry, report func(error)) pb.ChessApplicationServer {
+	return &replayServer{entries: Filter(entries, Stream), report: report}
+}
+
+// UCI replays the recorded session on the stream
+func (rs *replayServer) UCI(stream pb.ChessApplication_UCIServer) error {
+	for i, entry := range rs.entries {
+		if entry.From == FromServer {
+			var msg pb.UciResponse
+			if err := jsonpb.Unmarshal(bytes.NewReader(entry.Message), &msg); err != nil {
+				return err
+			}
+			if err := stream.Send(&msg); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var expected pb.UciRequest
+		if err := jsonpb.Unmarshal(bytes.NewReader(entry.Message), &expected); err != nil {
+			return err
+		}
+		if expected.GetMessageType() == pb.UciRequest_INFO {
+			continue
+		}
+
+		got, err := recvSkippingInfo(stream)
+		if err == io.EOF {
+			rs.report(&DivergenceError{Index: i, Expected: expected.String(), Got: "end of stream"})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !proto.Equal(&expected, got) {
+			rs.report(&DivergenceError{Index: i, Expected: expected.String(), Got: got.String()})
+		}
+	}
+
+	return nil
+}
+
+func recvSkippingInfo(stream pb.ChessApplication_UCIServer) (*pb.UciRequest, error) {
+	for {
+		msg, err := stream.Recv()
+		if err != nil || msg.GetMessageType() != pb.UciRequest_INFO {
+			return msg, err
+		}
+	}
+}
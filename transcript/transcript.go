@@ -0,0 +1,150 @@
+// Package transcript records UCI sessions to timestamped transcript files and
+// replays them, either as a stand-in engine or as a stand-in server
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channel is where an entry was recorded
+type Channel string
+
+// The channels a transcript records
+const (
+	// Engine entries are lines exchanged between engine/uci and the engine process
+	Engine Channel = "engine"
+	// Stream entries are gRPC messages on the UCI stream
+	Stream Channel = "stream"
+)
+
+// The senders of entries
+const (
+	// FromGUI is a line written to the engine
+	FromGUI = "gui"
+	// FromEngine is a line written by the engine
+	FromEngine = "engine"
+	// FromClient is a UciRequest sent by the client
+	FromClient = "client"
+	// FromServer is a UciResponse sent by the server
+	FromServer = "server"
+)
+
+// Entry is a single line or message in a transcript
+type Entry struct {
+	// When the line or message was recorded
+	Time time.Time `json:"time"`
+	// Where the entry was recorded
+	Channel Channel `json:"channel"`
+	// Who sent the line or message
+	From string `json:"from"`
+	// The line exchanged with the engine without the trailing newline
+	Line string `json:"line,omitempty"`
+	// The gRPC message in the protobuf JSON mapping
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+// Writer appends entries to a transcript, it is safe for concurrent use
+type Writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+	now func() time.Time
+}
+
+// NewWriter creates a writer recording entries to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Create creates a timestamped transcript file in dir named after prefix
+func Create(dir, prefix string) (*Writer, error) {
+	name := fmt.Sprintf("%v-%v.jsonl", prefix, time.Now().UTC().Format("20060102T150405.000000000"))
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(f), nil
+}
+
+// Record writes an entry to the transcript, the time is set when it is zero
+func (t *Writer) Record(entry Entry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry.Time.IsZero() {
+		entry.Time = t.now()
+	}
+	return t.enc.Encode(entry)
+}
+
+// Close closes the underlying file when the transcript was written to one
+func (t *Writer) Close() error {
+	if closer, ok := t.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Read reads all entries of a transcript
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("Invalid transcript entry on line %v: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Load reads all entries of a transcript file
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Filter returns the entries recorded on a channel
+func Filter(entries []Entry, channel Channel) []Entry {
+	var filtered []Entry
+	for _, entry := range entries {
+		if entry.Channel == channel {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// DivergenceError is returned when a replayed session departs from the transcript
+type DivergenceError struct {
+	// The index of the entry that was expected
+	Index int
+	// What the transcript recorded
+	Expected string
+	// What was received instead
+	Got string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("Session diverged from the transcript at entry %v: expecting %q got %q", e.Index, e.Expected, e.Got)
+}
@@ -0,0 +1,301 @@
+package transcript_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	cli "github.com/schafer14/grpc-chess/client"
+	"github.com/schafer14/grpc-chess/engine/fake"
+	engine "github.com/schafer14/grpc-chess/engine/uci"
+	pb "github.com/schafer14/grpc-chess/service"
+	"github.com/schafer14/grpc-chess/transcript"
+)
+
+var script = fake.Script{
+	Name:    "Recorded",
+	Author:  "transcript",
+	Options: []string{"name Hash type spin default 16 min 1 max 1024"},
+	Go: []fake.Reply{{
+		Output:   []string{"info depth 1 score cp 12 pv e2e4"},
+		BestMove: "e2e4",
+		Ponder:   "e7e5",
+	}},
+}
+
+// pipes starts run in process and returns a driver connected to it, lines
+// exchanged with the engine are recorded when t is set
+func pipes(run func(in io.Reader, out io.Writer) error, t *transcript.Writer) (cli.Engine, chan error) {
+	commands, commandWriter := io.Pipe()
+	output, outputWriter := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		err := run(commands, outputWriter)
+		commands.Close()
+		outputWriter.Close()
+		done <- err
+	}()
+
+	var in io.Reader = output
+	var out io.WriteCloser = commandWriter
+	if t != nil {
+		in = t.EngineOutput(in)
+		out = t.EngineInput(out)
+	}
+	return engine.NewFromPipes(in, out), done
+}
+
+// session runs a short session returning the messages the engine sent
+func session(t *testing.T, e cli.Engine) []string {
+	ident, options, err := e.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{ident.Name, options[0].Name}
+
+	exchanges := []struct {
+		commands []*pb.UciResponse
+		replies  []pb.UciRequest_MessageType
+	}{
+		{
+			[]*pb.UciResponse{{MessageType: pb.UciResponse_ISREADY}},
+			[]pb.UciRequest_MessageType{pb.UciRequest_READYOK},
+		},
+		{
+			[]*pb.UciResponse{
+				{MessageType: pb.UciResponse_POSITION, Position: &pb.UciResponse_Position{}},
+				{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Movetime: 100}},
+			},
+			[]pb.UciRequest_MessageType{pb.UciRequest_INFO, pb.UciRequest_BESTMOVE},
+		},
+	}
+	for _, exchange := range exchanges {
+		for _, cmd := range exchange.commands {
+			if err := e.Send(cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, expected := range exchange.replies {
+			msg, err := e.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.GetMessageType() != expected {
+				t.Fatalf("Expecting %v got %v", expected, msg.GetMessageType())
+			}
+			messages = append(messages, msg.String())
+		}
+	}
+
+	if err := e.Send(&pb.UciResponse{MessageType: pb.UciResponse_QUIT}); err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestRecordAndReplayEngine(t *testing.T) {
+	var b bytes.Buffer
+	w := transcript.NewWriter(&b)
+
+	recorded, done := pipes(func(in io.Reader, out io.Writer) error {
+		return fake.Run(script, in, out)
+	}, w)
+	original := session(t, recorded)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := transcript.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 || entries[0].From != transcript.FromGUI || entries[0].Line != "uci" {
+		t.Fatalf("Expecting the transcript to start with uci got %+v", entries)
+	}
+	for _, entry := range entries {
+		if entry.Time.IsZero() || entry.Channel != transcript.Engine {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+	}
+
+	replayed, done := pipes(func(in io.Reader, out io.Writer) error {
+		return transcript.ReplayEngine(entries, in, out, false)
+	}, nil)
+	replay := session(t, replayed)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replay) != len(original) {
+		t.Fatalf("Expecting %v got %v", original, replay)
+	}
+	for i := range original {
+		if original[i] != replay[i] {
+			t.Errorf("Expecting %v got %v", original[i], replay[i])
+		}
+	}
+}
+
+func TestReplayEngineDivergence(t *testing.T) {
+	entries := []transcript.Entry{
+		{Channel: transcript.Engine, From: transcript.FromGUI, Line: "uci"},
+		{Channel: transcript.Engine, From: transcript.FromEngine, Line: "uciok"},
+		{Channel: transcript.Engine, From: transcript.FromGUI, Line: "isready"},
+		{Channel: transcript.Engine, From: transcript.FromEngine, Line: "readyok"},
+	}
+
+	e, done := pipes(func(in io.Reader, out io.Writer) error {
+		return transcript.ReplayEngine(entries, in, out, false)
+	}, nil)
+	if _, _, err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	e.Send(&pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{}})
+
+	err := <-done
+	divergence, ok := err.(*transcript.DivergenceError)
+	if !ok {
+		t.Fatalf("Expecting a divergence error got %v", err)
+	}
+	if divergence.Expected != "isready" || divergence.Got != "go" {
+		t.Errorf("Unexpected divergence %v", divergence)
+	}
+}
+
+func TestReplayEngineArgumentDivergence(t *testing.T) {
+	entries := []transcript.Entry{
+		{Channel: transcript.Engine, From: transcript.FromGUI, Line: "uci"},
+		{Channel: transcript.Engine, From: transcript.FromEngine, Line: "uciok"},
+		{Channel: transcript.Engine, From: transcript.FromGUI, Line: "position  startpos moves e2e4"},
+		{Channel: transcript.Engine, From: transcript.FromGUI, Line: "go movetime 100"},
+		{Channel: transcript.Engine, From: transcript.FromEngine, Line: "bestmove e7e5"},
+	}
+
+	e, done := pipes(func(in io.Reader, out io.Writer) error {
+		return transcript.ReplayEngine(entries, in, out, false)
+	}, nil)
+	if _, _, err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	// Only the whitespace of the position differs, only the limit of go
+	e.Send(&pb.UciResponse{MessageType: pb.UciResponse_POSITION, Position: &pb.UciResponse_Position{Moves: []string{"e2e4"}}})
+	e.Send(&pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Movetime: 200}})
+
+	err := <-done
+	divergence, ok := err.(*transcript.DivergenceError)
+	if !ok {
+		t.Fatalf("Expecting a divergence error got %v", err)
+	}
+	if divergence.Expected != "go movetime 100" || divergence.Got != "go movetime 200" {
+		t.Errorf("Unexpected divergence %v", divergence)
+	}
+}
+
+func serverSession(t *testing.T) []transcript.Entry {
+	var b bytes.Buffer
+	w := transcript.NewWriter(&b)
+
+	messages := []struct {
+		from string
+		msg  interface{}
+	}{
+		{transcript.FromServer, &pb.UciResponse{MessageType: pb.UciResponse_UCI}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_ID, Id: &pb.UciRequest_Id{Name: "Recorded", Author: "transcript"}}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_OPTION, Option: &pb.UciRequest_Option{Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024}}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_UCIOK}},
+		{transcript.FromServer, &pb.UciResponse{MessageType: pb.UciResponse_ISREADY}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_READYOK}},
+		{transcript.FromServer, &pb.UciResponse{MessageType: pb.UciResponse_POSITION, Position: &pb.UciResponse_Position{}}},
+		{transcript.FromServer, &pb.UciResponse{MessageType: pb.UciResponse_GO, Go: &pb.UciResponse_Go{Movetime: 100}}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_INFO, Info: &pb.UciRequest_Info{Depth: 7}}},
+		{transcript.FromClient, &pb.UciRequest{MessageType: pb.UciRequest_BESTMOVE, BestMove: &pb.UciRequest_BestMove{Move: "e2e4", Ponder: []string{"e7e5"}}}},
+		{transcript.FromServer, &pb.UciResponse{MessageType: pb.UciResponse_QUIT}},
+	}
+	for _, m := range messages {
+		var err error
+		switch msg := m.msg.(type) {
+		case *pb.UciRequest:
+			err = w.RecordMessage(m.from, msg)
+		case *pb.UciResponse:
+			err = w.RecordMessage(m.from, msg)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := transcript.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// replayServer replays entries to a client backed by the script returning the divergences reported
+func replayServer(t *testing.T, entries []transcript.Entry, s fake.Script) []error {
+	var mu sync.Mutex
+	var divergences []error
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterChessApplicationServer(server, transcript.NewReplayServer(entries, func(err error) {
+		mu.Lock()
+		divergences = append(divergences, err)
+		mu.Unlock()
+	}))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	e, _ := pipes(func(in io.Reader, out io.Writer) error {
+		return fake.Run(s, in, out)
+	}, nil)
+	defer e.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	c := cli.New(e, *logrus.NewEntry(logger), pb.NewChessApplicationClient(conn))
+	if err := c.NewGameRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return divergences
+}
+
+func TestReplayServer(t *testing.T) {
+	divergences := replayServer(t, serverSession(t), script)
+	if len(divergences) != 0 {
+		t.Errorf("Expecting the client to follow the transcript got %v", divergences)
+	}
+}
+
+func TestReplayServerDivergence(t *testing.T) {
+	changed := script
+	changed.Go = []fake.Reply{{BestMove: "d2d4"}}
+
+	divergences := replayServer(t, serverSession(t), changed)
+	if len(divergences) != 1 {
+		t.Fatalf("Expecting one divergence got %v", divergences)
+	}
+	if _, ok := divergences[0].(*transcript.DivergenceError); !ok {
+		t.Errorf("Expecting a divergence error got %v", divergences[0])
+	}
+}